@@ -0,0 +1,34 @@
+-- Catálogo pregunta -> tipo de violencia por instrumento.
+-- Se llena al arrancar desde el JSON del instrumento (services.SyncCatalogo),
+-- así que ya no hay mapeos P1–P16 embebidos en SQL.
+create table if not exists instrumento_preguntas (
+    instrumento_id text not null,
+    pregunta_id text not null,
+    tipo_num integer not null,
+    tipo_nombre text not null,
+    primary key (instrumento_id, pregunta_id)
+);
+
+-- La vista anterior traía su propio VALUES con el mapeo.
+drop view if exists v_matriz_tipo_dimension;
+
+-- Preguntas, dimensiones y rangos de escala ahora los valida el backend
+-- contra el instrumento con el que se respondió la encuesta.
+alter table respuestas drop constraint if exists respuestas_pregunta_id_check;
+alter table respuestas drop constraint if exists respuestas_valor_check;
+alter table respuestas alter column dimension type text using dimension::text;
+drop type if exists dimension_enum;
+
+create view v_matriz_tipo_dimension as
+select
+    r.encuesta_id,
+    ip.tipo_num,
+    ip.tipo_nombre,
+    r.dimension,
+    round(avg(r.valor), 2) as promedio
+from respuestas r
+join encuestas e on e.id = r.encuesta_id
+join instrumento_preguntas ip
+  on ip.instrumento_id = e.instrumento_id
+ and ip.pregunta_id = r.pregunta_id
+group by r.encuesta_id, ip.tipo_num, ip.tipo_nombre, r.dimension;
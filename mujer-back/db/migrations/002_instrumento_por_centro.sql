@@ -0,0 +1,2 @@
+-- Versión del instrumento fijada por centro (null = instrumento default).
+alter table centros add column if not exists instrumento_id text;
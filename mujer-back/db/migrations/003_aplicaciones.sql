@@ -0,0 +1,29 @@
+-- Campañas de diagnóstico por centro: ventana de fechas, versión del
+-- instrumento y población objetivo. Solo se aceptan encuestas nuevas
+-- mientras el centro tenga una aplicación abierta y vigente.
+create table if not exists aplicaciones (
+    id bigserial primary key,
+    centro_id bigint not null references centros(id) on delete restrict,
+    instrumento_id text not null,
+    nombre text not null,
+    fecha_inicio date not null,
+    fecha_fin date not null,
+    poblacion_objetivo integer,
+    estado text not null default 'borrador',
+    created_at timestamptz not null default now(),
+    constraint aplicaciones_estado_check check (estado in ('borrador', 'abierta', 'cerrada')),
+    constraint aplicaciones_fechas_check check (fecha_fin >= fecha_inicio),
+    constraint aplicaciones_poblacion_check check (poblacion_objetivo is null or poblacion_objetivo > 0)
+);
+
+create index if not exists idx_aplicaciones_centro on aplicaciones (centro_id);
+
+-- A lo más una campaña abierta por centro
+create unique index if not exists idx_aplicaciones_una_abierta
+    on aplicaciones (centro_id)
+    where estado = 'abierta';
+
+alter table encuestas
+    add column if not exists aplicacion_id bigint references aplicaciones(id) on delete restrict;
+
+create index if not exists idx_encuestas_aplicacion on encuestas (aplicacion_id);
@@ -0,0 +1,16 @@
+-- Códigos de acceso por aplicación. Solo se guarda el hash y el contador de
+-- usos; no hay fecha de uso ni relación con la encuesta (anonimato).
+create table if not exists codigos_acceso (
+    id bigserial primary key,
+    aplicacion_id bigint not null references aplicaciones(id) on delete cascade,
+    codigo_hash text not null unique,
+    usos_max integer not null default 1,
+    usos integer not null default 0,
+    lote text not null,
+    created_by uuid,
+    created_at timestamptz not null default now(),
+    constraint codigos_acceso_usos_check check (usos >= 0 and usos <= usos_max),
+    constraint codigos_acceso_usos_max_check check (usos_max > 0)
+);
+
+create index if not exists idx_codigos_acceso_aplicacion on codigos_acceso (aplicacion_id, lote);
@@ -0,0 +1,2 @@
+-- Token para guardar avance parcial y retomar una encuesta (solo el hash).
+alter table encuestas add column if not exists resume_token_hash text;
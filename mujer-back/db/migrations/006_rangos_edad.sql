@@ -0,0 +1,56 @@
+-- Conjuntos de rangos de edad para los reportes de centro (la edad exacta
+-- nunca se publica). Cada conjunto puede ser el default de un tipo de centro.
+create table if not exists rangos_edad_sets (
+    id bigserial primary key,
+    clave text not null unique,
+    nombre text not null,
+    centro_tipo text,
+    created_at timestamptz not null default now(),
+    constraint rangos_edad_sets_tipo_check check (centro_tipo is null or centro_tipo in ('escolar', 'laboral'))
+);
+
+-- A lo más un conjunto default por tipo de centro
+create unique index if not exists idx_rangos_edad_sets_tipo
+    on rangos_edad_sets (centro_tipo)
+    where centro_tipo is not null;
+
+create table if not exists rangos_edad (
+    id bigserial primary key,
+    set_id bigint not null references rangos_edad_sets(id) on delete cascade,
+    orden integer not null,
+    edad_min integer not null,
+    edad_max integer, -- null = sin tope
+    etiqueta text not null,
+    constraint rangos_edad_orden_unique unique (set_id, orden),
+    constraint rangos_edad_min_check check (edad_min between 0 and 120),
+    constraint rangos_edad_max_check check (edad_max is null or edad_max >= edad_min)
+);
+
+with s as (
+    insert into rangos_edad_sets (clave, nombre, centro_tipo)
+    values ('escolar', 'Escolar', 'escolar')
+    on conflict (clave) do nothing
+    returning id
+)
+insert into rangos_edad (set_id, orden, edad_min, edad_max, etiqueta)
+select s.id, v.orden, v.edad_min, v.edad_max, v.etiqueta
+from s, (values
+    (1, 10, 14, '10-14'),
+    (2, 15, 17, '15-17'),
+    (3, 18, 24, '18-24'),
+    (4, 25, null, '25+')
+) as v (orden, edad_min, edad_max, etiqueta);
+
+with s as (
+    insert into rangos_edad_sets (clave, nombre, centro_tipo)
+    values ('laboral', 'Laboral', 'laboral')
+    on conflict (clave) do nothing
+    returning id
+)
+insert into rangos_edad (set_id, orden, edad_min, edad_max, etiqueta)
+select s.id, v.orden, v.edad_min, v.edad_max, v.etiqueta
+from s, (values
+    (1, 18, 29, '18-29'),
+    (2, 30, 44, '30-44'),
+    (3, 45, null, '45+')
+) as v (orden, edad_min, edad_max, etiqueta);
@@ -0,0 +1,54 @@
+-- Agregados precalculados para los reportes de centro.
+-- Una "celda" es (centro, día de finalización, campaña, género, edad, instrumento).
+-- Las tablas usan los mismos nombres de columna que encuestas (centro_id,
+-- finished_at, aplicacion_id, genero_id, edad) para que el WHERE de
+-- services.FiltroAgregado aplique igual con el alias e.
+-- aplicacion_id = 0 cuando la encuesta no pertenece a una campaña.
+-- Se llenan con services.ReconstruirAgregados y se mantienen por celda al guardar.
+
+-- Encuestas finalizadas con al menos una respuesta
+create table if not exists agg_encuestas (
+    centro_id bigint not null,
+    finished_at date not null,
+    aplicacion_id bigint not null,
+    genero_id bigint not null,
+    edad integer not null,
+    instrumento_id text not null,
+    encuestas bigint not null,
+    respuestas bigint not null,
+    primary key (centro_id, finished_at, aplicacion_id, genero_id, edad, instrumento_id)
+);
+
+-- Histograma de valores por pregunta y dimensión
+create table if not exists agg_valores (
+    centro_id bigint not null,
+    finished_at date not null,
+    aplicacion_id bigint not null,
+    genero_id bigint not null,
+    edad integer not null,
+    instrumento_id text not null,
+    pregunta_id text not null,
+    dimension text not null,
+    valor smallint not null,
+    n bigint not null,
+    primary key (centro_id, finished_at, aplicacion_id, genero_id, edad, instrumento_id, pregunta_id, dimension, valor)
+);
+
+-- Histograma del puntaje total por encuesta y dimensión (alpha de Cronbach,
+-- dispersión entre encuestas)
+create table if not exists agg_encuestas_dim (
+    centro_id bigint not null,
+    finished_at date not null,
+    aplicacion_id bigint not null,
+    genero_id bigint not null,
+    edad integer not null,
+    instrumento_id text not null,
+    dimension text not null,
+    total_score integer not null,
+    n_items integer not null,
+    n bigint not null,
+    primary key (centro_id, finished_at, aplicacion_id, genero_id, edad, instrumento_id, dimension, total_score, n_items)
+);
+
+create index if not exists idx_agg_valores_centro on agg_valores (centro_id, finished_at);
+create index if not exists idx_agg_encuestas_dim_centro on agg_encuestas_dim (centro_id, finished_at);
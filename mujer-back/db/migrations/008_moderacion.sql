@@ -0,0 +1,27 @@
+-- Cola de moderación de comentarios abiertos. El texto original sigue en
+-- encuestas.comentario; el centro solo ve comentarios aprobados (texto
+-- original) o redactados (texto_publicado). Si la persona cambia su
+-- comentario, vuelve a pendiente.
+create table if not exists comentarios_moderacion (
+    encuesta_id uuid primary key references encuestas(id) on delete cascade,
+    estado text not null default 'pendiente',
+    original_md5 text not null,
+    texto_publicado text,
+    revisado_por uuid references usuarios(id) on delete set null,
+    revisado_at timestamptz,
+    created_at timestamptz not null default now(),
+    updated_at timestamptz not null default now(),
+    constraint comentarios_moderacion_estado_check check (estado in ('pendiente', 'aprobado', 'redactado', 'oculto')),
+    constraint comentarios_moderacion_redactado_check check (estado <> 'redactado' or texto_publicado is not null)
+);
+
+create index if not exists idx_comentarios_moderacion_estado
+    on comentarios_moderacion (estado, updated_at);
+
+-- Los comentarios que ya existían entran a la cola como pendientes
+insert into comentarios_moderacion (encuesta_id, original_md5)
+select e.id, md5(e.comentario)
+from encuestas e
+where e.comentario is not null
+  and btrim(e.comentario) <> ''
+on conflict (encuesta_id) do nothing;
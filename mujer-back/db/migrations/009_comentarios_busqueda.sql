@@ -0,0 +1,21 @@
+-- Texto publicado también para los aprobados (copia del original al
+-- aprobar): así la búsqueda de /api/centro/comentarios corre sobre una sola
+-- columna indexada y nunca sobre el texto original de un comentario redactado.
+update comentarios_moderacion m
+set texto_publicado = e.comentario
+from encuestas e
+where e.id = m.encuesta_id
+  and m.estado = 'aprobado'
+  and m.texto_publicado is null;
+
+alter table comentarios_moderacion
+    drop constraint if exists comentarios_moderacion_redactado_check;
+
+alter table comentarios_moderacion
+    add constraint comentarios_moderacion_publicado_check
+    check (estado not in ('aprobado', 'redactado') or texto_publicado is not null);
+
+-- Búsqueda de texto completo en español (como idx_centros_nombre)
+create index if not exists idx_comentarios_publicados_fts
+    on comentarios_moderacion using gin (to_tsvector('spanish', texto_publicado))
+    where estado in ('aprobado', 'redactado');
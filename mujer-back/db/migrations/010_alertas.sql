@@ -0,0 +1,30 @@
+-- Alertas de riesgo por centro: una fila por (centro, regla, año).
+-- Las reglas viven en config/alertas.json (services.ReglaAlerta); aquí se
+-- copia lo necesario para que el historial no dependa de la configuración
+-- vigente. Se evalúan al guardar respuestas y periódicamente.
+create table if not exists alertas (
+    id bigserial primary key,
+    centro_id bigint not null references centros(id) on delete cascade,
+    regla_id text not null,
+    regla_nombre text not null,
+    severidad text not null,
+    condicion text not null,
+    metrica text not null,
+    tipo_num integer,
+    tipo_nombre text,
+    year integer not null,
+    estado text not null default 'activa',
+    valor double precision not null,      -- métrica del año evaluado
+    referencia double precision,          -- métrica del año anterior (alza_anual)
+    umbral double precision not null,
+    encuestas bigint not null,
+    mensaje text not null,
+    detectada_at timestamptz not null default now(),
+    actualizada_at timestamptz not null default now(),
+    resuelta_at timestamptz,
+    constraint alertas_estado_check check (estado in ('activa', 'resuelta')),
+    constraint alertas_severidad_check check (severidad in ('baja', 'media', 'alta'))
+);
+
+create unique index if not exists idx_alertas_centro_regla_year on alertas (centro_id, regla_id, year);
+create index if not exists idx_alertas_activas on alertas (detectada_at desc) where estado = 'activa';
@@ -0,0 +1,63 @@
+-- Catálogos geográficos con claves INEGI (Marco Geoestadístico):
+-- estados por CVE_ENT (2 dígitos) y municipios por CVEGEO (CVE_ENT || CVE_MUN, 5 dígitos).
+-- La geometría (GeoJSON) y los municipios se importan con
+-- POST /api/admin/geografia/importar; los 32 estados vienen sembrados aquí.
+create table if not exists estados (
+    clave char(2) primary key,
+    nombre text not null,
+    abreviatura text not null,
+    geometria jsonb
+);
+
+create table if not exists municipios (
+    clave char(5) primary key,
+    estado_clave char(2) not null references estados(clave),
+    nombre text not null,
+    geometria jsonb,
+    constraint municipios_estado_check check (left(clave, 2) = estado_clave)
+);
+
+create index if not exists idx_municipios_estado on municipios (estado_clave);
+
+-- Vínculo normalizado del centro; estado/ciudad (texto libre) se conservan.
+-- services.VincularCentros los llena por nombre (con alias y sin acentos).
+alter table centros add column if not exists estado_clave char(2) references estados(clave);
+alter table centros add column if not exists municipio_clave char(5) references municipios(clave);
+
+create index if not exists idx_centros_estado_clave on centros (estado_clave);
+create index if not exists idx_centros_municipio_clave on centros (municipio_clave);
+
+insert into estados (clave, nombre, abreviatura) values
+    ('01', 'Aguascalientes', 'Ags.'),
+    ('02', 'Baja California', 'BC'),
+    ('03', 'Baja California Sur', 'BCS'),
+    ('04', 'Campeche', 'Camp.'),
+    ('05', 'Coahuila de Zaragoza', 'Coah.'),
+    ('06', 'Colima', 'Col.'),
+    ('07', 'Chiapas', 'Chis.'),
+    ('08', 'Chihuahua', 'Chih.'),
+    ('09', 'Ciudad de México', 'CDMX'),
+    ('10', 'Durango', 'Dgo.'),
+    ('11', 'Guanajuato', 'Gto.'),
+    ('12', 'Guerrero', 'Gro.'),
+    ('13', 'Hidalgo', 'Hgo.'),
+    ('14', 'Jalisco', 'Jal.'),
+    ('15', 'México', 'Mex.'),
+    ('16', 'Michoacán de Ocampo', 'Mich.'),
+    ('17', 'Morelos', 'Mor.'),
+    ('18', 'Nayarit', 'Nay.'),
+    ('19', 'Nuevo León', 'NL'),
+    ('20', 'Oaxaca', 'Oax.'),
+    ('21', 'Puebla', 'Pue.'),
+    ('22', 'Querétaro', 'Qro.'),
+    ('23', 'Quintana Roo', 'Q. Roo'),
+    ('24', 'San Luis Potosí', 'SLP'),
+    ('25', 'Sinaloa', 'Sin.'),
+    ('26', 'Sonora', 'Son.'),
+    ('27', 'Tabasco', 'Tab.'),
+    ('28', 'Tamaulipas', 'Tamps.'),
+    ('29', 'Tlaxcala', 'Tlax.'),
+    ('30', 'Veracruz de Ignacio de la Llave', 'Ver.'),
+    ('31', 'Yucatán', 'Yuc.'),
+    ('32', 'Zacatecas', 'Zac.')
+on conflict (clave) do nothing;
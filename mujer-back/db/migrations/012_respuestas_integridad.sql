@@ -0,0 +1,34 @@
+-- 001 quitó los CHECK fijos de pregunta y valor (P1–P16, 1–5) porque ahora
+-- dependen del instrumento. Además de la validación en Go, la base revisa:
+--
+-- 1) Que cada respuesta sea una pregunta del instrumento de su encuesta.
+--    Es una llave foránea "a través" de encuestas.instrumento_id; va como
+--    trigger porque respuestas no guarda el instrumento y services.SyncCatalogo
+--    reescribe instrumento_preguntas al arrancar (una FK real lo bloquearía).
+create or replace function respuestas_pregunta_del_instrumento() returns trigger
+language plpgsql as $$
+begin
+    if not exists (
+        select 1
+        from encuestas e
+        join instrumento_preguntas ip
+          on ip.instrumento_id = e.instrumento_id
+         and ip.pregunta_id = new.pregunta_id
+        where e.id = new.encuesta_id
+    ) then
+        raise exception 'pregunta % no pertenece al instrumento de la encuesta %', new.pregunta_id, new.encuesta_id
+            using errcode = 'foreign_key_violation';
+    end if;
+    return new;
+end;
+$$;
+
+drop trigger if exists respuestas_pregunta_fk on respuestas;
+create trigger respuestas_pregunta_fk
+    before insert or update of encuesta_id, pregunta_id on respuestas
+    for each row execute function respuestas_pregunta_del_instrumento();
+
+-- 2) Un rango general para valor; cada escala del instrumento debe caber
+--    aquí (services.EscalaValorMin / EscalaValorMax en la validación).
+alter table respuestas drop constraint if exists respuestas_valor_rango_check;
+alter table respuestas add constraint respuestas_valor_rango_check check (valor between 0 and 10);
@@ -0,0 +1,28 @@
+-- Sumas por par de ítems para alpha de Cronbach y el análisis de ítems
+-- (correlación ítem-total, alpha si se elimina), que necesitan las
+-- covarianzas entre preguntas y no solo los histogramas de 007.
+-- Misma celda y mismas columnas de llave que agg_encuestas; se mantiene por
+-- celda al guardar y se llena con services.ReconstruirAgregados.
+--
+-- Por encuesta y dimensión se guarda cada par pregunta_a <= pregunta_b:
+--   suma_a  = Σ valor de pregunta_a (con a = b es la suma del ítem)
+--   suma_ab = Σ valor_a · valor_b   (con a = b es la suma de cuadrados)
+-- n_items es cuántas preguntas respondió la encuesta en la dimensión; al
+-- consultar se toman solo las encuestas con todos los ítems del filtro.
+create table if not exists agg_items_cruzados (
+    centro_id bigint not null,
+    finished_at date not null,
+    aplicacion_id bigint not null,
+    genero_id bigint not null,
+    edad integer not null,
+    instrumento_id text not null,
+    dimension text not null,
+    n_items integer not null,
+    pregunta_a text not null,
+    pregunta_b text not null,
+    suma_a bigint not null,
+    suma_ab bigint not null,
+    primary key (centro_id, finished_at, aplicacion_id, genero_id, edad, instrumento_id, dimension, n_items, pregunta_a, pregunta_b)
+);
+
+create index if not exists idx_agg_items_cruzados_centro on agg_items_cruzados (centro_id, finished_at);
@@ -0,0 +1,26 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"mujer-back/services"
+)
+
+// Agregados precalculados de los reportes de centro (admin).
+// Se mantienen solos al guardar encuestas; el rebuild es para cuando se
+// corrigen datos a mano en la base o cambia la forma de calcularlos.
+type AgregadosHandler struct {
+	DB *pgxpool.Pool
+}
+
+// POST /api/admin/agregados/rebuild
+func (h AgregadosHandler) Rebuild(w http.ResponseWriter, r *http.Request) {
+	out, err := services.ReconstruirAgregados(r.Context(), h.DB)
+	if err != nil {
+		http.Error(w, "db_error", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, out)
+}
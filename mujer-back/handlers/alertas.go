@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"mujer-back/services"
+)
+
+// Alertas de riesgo: listado por centro, listado global (admin) y
+// evaluación manual (admin)
+type AlertasHandler struct {
+	DB    *pgxpool.Pool
+	Motor *services.MotorAlertas
+}
+
+type AlertasAdminResponse struct {
+	services.PaginaAlertas
+	Reglas []services.ReglaAlerta `json:"reglas"`
+}
+
+// filtroAlertas lee ?estado=activa|resuelta|todas (default activa) ?year= ?severidad= ?limit= ?offset=
+func filtroAlertas(w http.ResponseWriter, r *http.Request) (services.FiltroAlertas, bool) {
+	q := r.URL.Query()
+	f := services.FiltroAlertas{Estado: services.AlertaActiva, Limit: 50}
+
+	switch e := strings.ToLower(strings.TrimSpace(q.Get("estado"))); e {
+	case "", services.AlertaActiva:
+	case services.AlertaResuelta:
+		f.Estado = e
+	case "todas":
+		f.Estado = ""
+	default:
+		http.Error(w, "bad_estado", http.StatusBadRequest)
+		return f, false
+	}
+
+	if v := strings.ToLower(strings.TrimSpace(q.Get("severidad"))); v != "" {
+		if v != "baja" && v != "media" && v != "alta" {
+			http.Error(w, "bad_severidad", http.StatusBadRequest)
+			return f, false
+		}
+		f.Severidad = v
+	}
+	if v := strings.TrimSpace(q.Get("year")); v != "" {
+		y, err := strconv.Atoi(v)
+		if err != nil || y < 2000 || y > 2100 {
+			http.Error(w, "bad_year", http.StatusBadRequest)
+			return f, false
+		}
+		f.Year = &y
+	}
+	if v := strings.TrimSpace(q.Get("limit")); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 200 {
+			http.Error(w, "bad_limit", http.StatusBadRequest)
+			return f, false
+		}
+		f.Limit = n
+	}
+	if v := strings.TrimSpace(q.Get("offset")); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			http.Error(w, "bad_offset", http.StatusBadRequest)
+			return f, false
+		}
+		f.Offset = n
+	}
+	return f, true
+}
+
+// GET /api/centro/alertas?estado=activa|resuelta|todas&year=2025
+// Solo las alertas de los centros del JWT.
+func (h AlertasHandler) ListCentro(w http.ResponseWriter, r *http.Request) {
+	if UserRolFromCtx(r.Context()) != "centro" {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+	centros := UserCentrosFromCtx(r.Context())
+	if len(centros) == 0 {
+		http.Error(w, "no_centros", http.StatusForbidden)
+		return
+	}
+
+	f, ok := filtroAlertas(w, r)
+	if !ok {
+		return
+	}
+	f.Centros = centros
+
+	p, err := services.ListarAlertas(r.Context(), h.DB, f)
+	if err != nil {
+		http.Error(w, "db_error", http.StatusInternalServerError)
+		return
+	}
+	writeJSONCentro(w, http.StatusOK, p)
+}
+
+// GET /api/admin/alertas?estado=&centro_id=&severidad=&year=&limit=50&offset=0
+func (h AlertasHandler) ListAdmin(w http.ResponseWriter, r *http.Request) {
+	f, ok := filtroAlertas(w, r)
+	if !ok {
+		return
+	}
+	if v := strings.TrimSpace(r.URL.Query().Get("centro_id")); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n <= 0 {
+			http.Error(w, "bad_centro_id", http.StatusBadRequest)
+			return
+		}
+		f.Centros = []int64{n}
+	}
+
+	p, err := services.ListarAlertas(r.Context(), h.DB, f)
+	if err != nil {
+		http.Error(w, "db_error", http.StatusInternalServerError)
+		return
+	}
+
+	resp := AlertasAdminResponse{PaginaAlertas: p, Reglas: []services.ReglaAlerta{}}
+	if h.Motor != nil {
+		resp.Reglas = h.Motor.Reglas
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// POST /api/admin/alertas/evaluar?year=2025 (default: año en curso)
+// Evalúa todas las reglas en todos los centros con datos, sin esperar al ciclo periódico.
+func (h AlertasHandler) Evaluar(w http.ResponseWriter, r *http.Request) {
+	if h.Motor == nil || len(h.Motor.Reglas) == 0 {
+		http.Error(w, "sin_reglas", http.StatusConflict)
+		return
+	}
+
+	year := time.Now().Year()
+	if v := strings.TrimSpace(r.URL.Query().Get("year")); v != "" {
+		y, err := strconv.Atoi(v)
+		if err != nil || y < 2000 || y > 2100 {
+			http.Error(w, "bad_year", http.StatusBadRequest)
+			return
+		}
+		year = y
+	}
+
+	res, err := h.Motor.EvaluarTodos(r.Context(), year)
+	if err != nil {
+		http.Error(w, "db_error", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
@@ -0,0 +1,334 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"mujer-back/services"
+)
+
+// Aplicaciones = campañas de diagnóstico por centro (ventana de fechas + versión del instrumento)
+type AplicacionesHandler struct {
+	DB       *pgxpool.Pool
+	Registro *services.Registro
+}
+
+type AplicacionDTO struct {
+	ID                int64  `json:"id"`
+	CentroID          int64  `json:"centro_id"`
+	CentroNombre      string `json:"centro_nombre,omitempty"`
+	InstrumentoID     string `json:"instrumento_id"`
+	Nombre            string `json:"nombre"`
+	FechaInicio       string `json:"fecha_inicio"` // YYYY-MM-DD
+	FechaFin          string `json:"fecha_fin"`    // YYYY-MM-DD
+	PoblacionObjetivo *int32 `json:"poblacion_objetivo,omitempty"`
+	Estado            string `json:"estado"`    // borrador|abierta|cerrada
+	Encuestas         int64  `json:"encuestas"` // encuestas finalizadas
+	CreatedAt         string `json:"created_at,omitempty"`
+}
+
+type AplicacionUpsertRequest struct {
+	CentroID          int64  `json:"centro_id"`
+	InstrumentoID     string `json:"instrumento_id,omitempty"` // vacío = el fijado al centro o el default
+	Nombre            string `json:"nombre"`
+	FechaInicio       string `json:"fecha_inicio"`
+	FechaFin          string `json:"fecha_fin"`
+	PoblacionObjetivo *int32 `json:"poblacion_objetivo,omitempty"`
+	Estado            string `json:"estado,omitempty"` // default borrador
+}
+
+const aplicacionSelect = `
+	select
+		a.id,
+		a.centro_id,
+		c.nombre,
+		a.instrumento_id,
+		a.nombre,
+		a.fecha_inicio::text,
+		a.fecha_fin::text,
+		a.poblacion_objetivo,
+		a.estado,
+		(select count(*) from encuestas e where e.aplicacion_id = a.id and e.finished_at is not null),
+		a.created_at::text
+	from aplicaciones a
+	join centros c on c.id = a.centro_id
+`
+
+func scanAplicacion(row pgx.Row, a *AplicacionDTO) error {
+	return row.Scan(
+		&a.ID, &a.CentroID, &a.CentroNombre, &a.InstrumentoID, &a.Nombre,
+		&a.FechaInicio, &a.FechaFin, &a.PoblacionObjetivo, &a.Estado, &a.Encuestas, &a.CreatedAt,
+	)
+}
+
+func normalizeAplicacionReq(req *AplicacionUpsertRequest) (errCode string) {
+	req.Nombre = strings.TrimSpace(req.Nombre)
+	req.InstrumentoID = strings.TrimSpace(req.InstrumentoID)
+	req.Estado = strings.ToLower(strings.TrimSpace(req.Estado))
+	if req.Estado == "" {
+		req.Estado = "borrador"
+	}
+
+	if req.CentroID <= 0 {
+		return "bad_centro"
+	}
+	if len(req.Nombre) < 3 || len(req.Nombre) > 200 {
+		return "bad_nombre"
+	}
+	ini, err1 := time.Parse("2006-01-02", strings.TrimSpace(req.FechaInicio))
+	fin, err2 := time.Parse("2006-01-02", strings.TrimSpace(req.FechaFin))
+	if err1 != nil || err2 != nil || fin.Before(ini) {
+		return "bad_fechas"
+	}
+	req.FechaInicio = ini.Format("2006-01-02")
+	req.FechaFin = fin.Format("2006-01-02")
+
+	if req.PoblacionObjetivo != nil && *req.PoblacionObjetivo <= 0 {
+		return "bad_poblacion"
+	}
+	if req.Estado != "borrador" && req.Estado != "abierta" && req.Estado != "cerrada" {
+		return "bad_estado"
+	}
+	return ""
+}
+
+// resolveInstrumento: instrumento explícito, o el fijado al centro, o el default
+func (h AplicacionesHandler) resolveInstrumento(r *http.Request, req *AplicacionUpsertRequest) (string, string) {
+	var pinned string
+	err := h.DB.QueryRow(r.Context(), `select coalesce(instrumento_id,'') from centros where id = $1`, req.CentroID).Scan(&pinned)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", "centro_not_found"
+	}
+	if err != nil {
+		return "", "db_error"
+	}
+
+	id := req.InstrumentoID
+	if id == "" {
+		id = pinned
+	}
+	inst, ok := h.Registro.Resolve(id)
+	if !ok {
+		return "", "instrumento_not_found"
+	}
+	return inst.ID, ""
+}
+
+// aplicacionDBError traduce violaciones de constraints a códigos para el front
+func aplicacionDBError(w http.ResponseWriter, err error) {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case "23505": // idx_aplicaciones_una_abierta
+			http.Error(w, "aplicacion_abierta_existente", http.StatusConflict)
+			return
+		case "23503":
+			http.Error(w, "aplicacion_con_encuestas", http.StatusConflict)
+			return
+		}
+	}
+	http.Error(w, "db_error", http.StatusInternalServerError)
+}
+
+// GET /api/admin/aplicaciones?centro_id=&estado=
+func (h AplicacionesHandler) List(w http.ResponseWriter, r *http.Request) {
+	args := []any{}
+	where := []string{"true"}
+
+	if s := strings.TrimSpace(r.URL.Query().Get("centro_id")); s != "" {
+		id, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || id <= 0 {
+			http.Error(w, "bad_centro", http.StatusBadRequest)
+			return
+		}
+		args = append(args, id)
+		where = append(where, "a.centro_id = $"+strconv.Itoa(len(args)))
+	}
+	if s := strings.TrimSpace(r.URL.Query().Get("estado")); s != "" {
+		args = append(args, s)
+		where = append(where, "a.estado = $"+strconv.Itoa(len(args)))
+	}
+
+	h.list(w, r, aplicacionSelect+` where `+strings.Join(where, " and ")+` order by a.fecha_inicio desc, a.id desc`, args...)
+}
+
+// GET /api/centro/aplicaciones (campañas de los centros del usuario)
+func (h AplicacionesHandler) ListCentro(w http.ResponseWriter, r *http.Request) {
+	if UserRolFromCtx(r.Context()) != "centro" {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+	centros := UserCentrosFromCtx(r.Context())
+	if len(centros) == 0 {
+		http.Error(w, "no_centros", http.StatusForbidden)
+		return
+	}
+
+	h.list(w, r, aplicacionSelect+` where a.centro_id = any($1::bigint[]) order by a.fecha_inicio desc, a.id desc`, centros)
+}
+
+func (h AplicacionesHandler) list(w http.ResponseWriter, r *http.Request, sql string, args ...any) {
+	rows, err := h.DB.Query(r.Context(), sql, args...)
+	if err != nil {
+		http.Error(w, "db_error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	out := make([]AplicacionDTO, 0, 16)
+	for rows.Next() {
+		var a AplicacionDTO
+		if err := scanAplicacion(rows, &a); err != nil {
+			http.Error(w, "db_error", http.StatusInternalServerError)
+			return
+		}
+		out = append(out, a)
+	}
+	if rows.Err() != nil {
+		http.Error(w, "db_error", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, out)
+}
+
+// POST /api/admin/aplicaciones
+func (h AplicacionesHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req AplicacionUpsertRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "bad_json", http.StatusBadRequest)
+		return
+	}
+	if errCode := normalizeAplicacionReq(&req); errCode != "" {
+		http.Error(w, errCode, http.StatusBadRequest)
+		return
+	}
+
+	instID, errCode := h.resolveInstrumento(r, &req)
+	if errCode == "db_error" {
+		http.Error(w, errCode, http.StatusInternalServerError)
+		return
+	}
+	if errCode != "" {
+		http.Error(w, errCode, http.StatusBadRequest)
+		return
+	}
+
+	var id int64
+	err := h.DB.QueryRow(r.Context(), `
+		insert into aplicaciones (centro_id, instrumento_id, nombre, fecha_inicio, fecha_fin, poblacion_objetivo, estado)
+		values ($1, $2, $3, $4::date, $5::date, $6, $7)
+		returning id
+	`, req.CentroID, instID, req.Nombre, req.FechaInicio, req.FechaFin, req.PoblacionObjetivo, req.Estado).Scan(&id)
+	if err != nil {
+		aplicacionDBError(w, err)
+		return
+	}
+
+	var a AplicacionDTO
+	if err := scanAplicacion(h.DB.QueryRow(r.Context(), aplicacionSelect+` where a.id = $1`, id), &a); err != nil {
+		http.Error(w, "db_error", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusCreated, a)
+}
+
+// GET /api/admin/aplicaciones/{id}
+func (h AplicacionesHandler) GetByID(w http.ResponseWriter, r *http.Request, id int64) {
+	var a AplicacionDTO
+	err := scanAplicacion(h.DB.QueryRow(r.Context(), aplicacionSelect+` where a.id = $1`, id), &a)
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, "aplicacion_not_found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "db_error", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, a)
+}
+
+// PUT /api/admin/aplicaciones/{id}
+// Con encuestas registradas ya no se puede cambiar ni el centro ni el instrumento.
+func (h AplicacionesHandler) Update(w http.ResponseWriter, r *http.Request, id int64) {
+	var req AplicacionUpsertRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "bad_json", http.StatusBadRequest)
+		return
+	}
+	if errCode := normalizeAplicacionReq(&req); errCode != "" {
+		http.Error(w, errCode, http.StatusBadRequest)
+		return
+	}
+
+	instID, errCode := h.resolveInstrumento(r, &req)
+	if errCode == "db_error" {
+		http.Error(w, errCode, http.StatusInternalServerError)
+		return
+	}
+	if errCode != "" {
+		http.Error(w, errCode, http.StatusBadRequest)
+		return
+	}
+
+	var curCentro int64
+	var curInst string
+	var conEncuestas bool
+	err := h.DB.QueryRow(r.Context(), `
+		select a.centro_id, a.instrumento_id, exists(select 1 from encuestas e where e.aplicacion_id = a.id)
+		from aplicaciones a
+		where a.id = $1
+	`, id).Scan(&curCentro, &curInst, &conEncuestas)
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, "aplicacion_not_found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "db_error", http.StatusInternalServerError)
+		return
+	}
+	if conEncuestas && (curCentro != req.CentroID || curInst != instID) {
+		http.Error(w, "aplicacion_con_encuestas", http.StatusConflict)
+		return
+	}
+
+	if _, err := h.DB.Exec(r.Context(), `
+		update aplicaciones
+		set centro_id = $2,
+		    instrumento_id = $3,
+		    nombre = $4,
+		    fecha_inicio = $5::date,
+		    fecha_fin = $6::date,
+		    poblacion_objetivo = $7,
+		    estado = $8
+		where id = $1
+	`, id, req.CentroID, instID, req.Nombre, req.FechaInicio, req.FechaFin, req.PoblacionObjetivo, req.Estado); err != nil {
+		aplicacionDBError(w, err)
+		return
+	}
+
+	h.GetByID(w, r, id)
+}
+
+// DELETE /api/admin/aplicaciones/{id} (solo si no tiene encuestas)
+func (h AplicacionesHandler) Delete(w http.ResponseWriter, r *http.Request, id int64) {
+	ct, err := h.DB.Exec(r.Context(), `delete from aplicaciones where id = $1`, id)
+	if err != nil {
+		aplicacionDBError(w, err)
+		return
+	}
+	if ct.RowsAffected() == 0 {
+		http.Error(w, "aplicacion_not_found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
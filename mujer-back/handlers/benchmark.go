@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"mujer-back/services"
+)
+
+// Comparación entre centros: ranking y distribuciones (admin) y posición
+// de un centro frente a sus pares del mismo tipo (centro)
+type BenchmarkHandler struct {
+	DB *pgxpool.Pool
+	K  int // umbral de k-anonimato (ANONIMATO_K); 0 = services.AnonimatoKDefault
+}
+
+type CompararResponse struct {
+	Por       string                    `json:"por"`
+	Grupos    []services.GrupoBenchmark `json:"grupos"`
+	Anonimato services.Anonimato        `json:"anonimato"`
+}
+
+func (h BenchmarkHandler) k() int {
+	if h.K > 0 {
+		return h.K
+	}
+	return services.AnonimatoKDefault
+}
+
+// parseFiltroBenchmark lee ?tipo= ?estado= ?ciudad= ?year= ?desde= ?hasta=
+// (las fechas por mes, como en los reportes del centro)
+func parseFiltroBenchmark(w http.ResponseWriter, r *http.Request) (services.FiltroBenchmark, bool) {
+	q := r.URL.Query()
+	f := services.FiltroBenchmark{
+		Tipo:   strings.ToLower(strings.TrimSpace(q.Get("tipo"))),
+		Estado: strings.TrimSpace(q.Get("estado")),
+		Ciudad: strings.TrimSpace(q.Get("ciudad")),
+	}
+	if f.Tipo != "" && f.Tipo != "escolar" && f.Tipo != "laboral" {
+		http.Error(w, "bad_tipo", http.StatusBadRequest)
+		return f, false
+	}
+
+	if ys := strings.TrimSpace(q.Get("year")); ys != "" {
+		yi, err := strconv.Atoi(ys)
+		if err != nil {
+			http.Error(w, "bad_year", http.StatusBadRequest)
+			return f, false
+		}
+		f.Year = &yi
+	}
+	desde, hasta, errCode := rangoMensual(q)
+	if errCode != "" {
+		http.Error(w, errCode, http.StatusBadRequest)
+		return f, false
+	}
+	f.Desde, f.Hasta = desde, hasta
+	return f, true
+}
+
+// GET /api/admin/resultados?metrica=total|frecuencia|normalidad|gravedad
+//
+//	&tipo=escolar|laboral &estado= &ciudad= &year= &desde= &hasta=
+//
+// Ranking de centros por la métrica (mayor = peor percepción) y la
+// distribución del conjunto. Los centros con menos de k encuestas salen sin valores.
+func (h BenchmarkHandler) Ranking(w http.ResponseWriter, r *http.Request) {
+	metrica := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("metrica")))
+	if metrica == "" {
+		metrica = services.MetricaBenchTotal
+	}
+	if !services.MetricaBenchValida(metrica) {
+		http.Error(w, "bad_metrica", http.StatusBadRequest)
+		return
+	}
+	f, ok := parseFiltroBenchmark(w, r)
+	if !ok {
+		return
+	}
+
+	cs, err := services.DatosBenchmark(r.Context(), h.DB, f)
+	if err != nil {
+		http.Error(w, "db_error", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, services.RankingBenchmark(cs, metrica, h.k()))
+}
+
+// GET /api/admin/resultados/comparar?por=tipo|estado|ciudad (default tipo)
+// + los filtros de /api/admin/resultados
+// Distribución de los promedios de centro dentro de cada grupo.
+func (h BenchmarkHandler) Comparar(w http.ResponseWriter, r *http.Request) {
+	por := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("por")))
+	switch por {
+	case "":
+		por = services.BenchPorTipo
+	case services.BenchPorTipo, services.BenchPorEstado, services.BenchPorCiudad:
+	default:
+		http.Error(w, "bad_por", http.StatusBadRequest)
+		return
+	}
+	f, ok := parseFiltroBenchmark(w, r)
+	if !ok {
+		return
+	}
+
+	cs, err := services.DatosBenchmark(r.Context(), h.DB, f)
+	if err != nil {
+		http.Error(w, "db_error", http.StatusInternalServerError)
+		return
+	}
+	resp := CompararResponse{Por: por, Anonimato: services.Anonimato{K: h.k()}}
+	resp.Grupos = services.CompararGrupos(cs, por, h.k(), &resp.Anonimato)
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// GET /api/centro/benchmark?centro=ID (default: el primero del JWT) &year= &desde= &hasta=
+// Posición del centro frente a los demás centros activos de su mismo tipo.
+// Los pares nunca se identifican y la referencia solo sale con
+// services.BenchmarkMinCentros pares publicables.
+func (h BenchmarkHandler) Centro(w http.ResponseWriter, r *http.Request) {
+	if UserRolFromCtx(r.Context()) != "centro" {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+	centros := UserCentrosFromCtx(r.Context())
+	if len(centros) == 0 {
+		http.Error(w, "no_centros", http.StatusForbidden)
+		return
+	}
+
+	id := centros[0]
+	if cs := strings.TrimSpace(r.URL.Query().Get("centro")); cs != "" {
+		n, err := strconv.ParseInt(cs, 10, 64)
+		if err != nil || n <= 0 {
+			http.Error(w, "bad_centro", http.StatusBadRequest)
+			return
+		}
+		permitido := false
+		for _, c := range centros {
+			if c == n {
+				permitido = true
+				break
+			}
+		}
+		if !permitido {
+			http.Error(w, "centro_forbidden", http.StatusForbidden)
+			return
+		}
+		id = n
+	}
+
+	f, ok := parseFiltroBenchmark(w, r)
+	if !ok {
+		return
+	}
+	// solo pares del mismo tipo, sin importar estado/ciudad
+	f.Estado, f.Ciudad = "", ""
+
+	ctx := r.Context()
+	centro := services.CentroBenchmark{CentroID: id}
+	err := h.DB.QueryRow(ctx, `select nombre, tipo from centros where id = $1`, id).Scan(&centro.Nombre, &centro.Tipo)
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, "centro_not_found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "db_error", http.StatusInternalServerError)
+		return
+	}
+	f.Tipo = centro.Tipo
+
+	cs, err := services.DatosBenchmark(ctx, h.DB, f)
+	if err != nil {
+		http.Error(w, "db_error", http.StatusInternalServerError)
+		return
+	}
+	writeJSONCentro(w, http.StatusOK, services.PosicionCentro(cs, centro, h.k()))
+}
@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"mujer-back/services"
+)
+
+type CentroComentariosResponse struct {
+	Centros    []int64               `json:"centros"`
+	Items      []services.Comentario `json:"items"`
+	Total      int64                 `json:"total"`       // con búsqueda
+	Publicados int64                 `json:"publicados"`  // sin búsqueda
+	EnRevision int64                 `json:"en_revision"` // pendientes de moderación
+	NextCursor string                `json:"next_cursor,omitempty"`
+	RangosEdad string                `json:"rangos_edad,omitempty"`
+	Anonimato  services.Anonimato    `json:"anonimato"`
+}
+
+// GET /api/centro/comentarios
+// Mismos filtros que /api/centro/resumen (?centro= ?year= ?aplicacion= ?genero=
+// ?rango_edad= ?desde= ?hasta= ?bands=) + ?q= (búsqueda) + ?cursor= + ?limit= (default 20, máx 100).
+// Solo comentarios moderados (aprobados o redactados), más recientes primero.
+func (h CentroResultadosHandler) GetComentarios(w http.ResponseWriter, r *http.Request) {
+	if !h.ensureCentroRole(w, r) {
+		return
+	}
+
+	centros := UserCentrosFromCtx(r.Context())
+	if len(centros) == 0 {
+		http.Error(w, "no_centros", http.StatusForbidden)
+		return
+	}
+
+	f, bandsClave, ok := h.filtroCompleto(w, r, centros)
+	if !ok {
+		return
+	}
+
+	q := r.URL.Query()
+	fc := services.FiltroComentarios{
+		FiltroAgregado: f,
+		Q:              strings.TrimSpace(q.Get("q")),
+		Limit:          20,
+	}
+	if len([]rune(fc.Q)) > 200 {
+		http.Error(w, "bad_q", http.StatusBadRequest)
+		return
+	}
+	if v := strings.TrimSpace(q.Get("limit")); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 100 {
+			http.Error(w, "bad_limit", http.StatusBadRequest)
+			return
+		}
+		fc.Limit = n
+	}
+	if v := strings.TrimSpace(q.Get("cursor")); v != "" {
+		c, err := services.AbrirCursorComentario(v, claveCursor())
+		if err != nil {
+			http.Error(w, "bad_cursor", http.StatusBadRequest)
+			return
+		}
+		fc.Cursor = c
+	}
+
+	p, err := services.PaginarComentarios(r.Context(), h.DB, fc)
+	if err != nil {
+		http.Error(w, "db_error", http.StatusInternalServerError)
+		return
+	}
+	anon := p.Anonimizar(h.k())
+	if h.Registro != nil {
+		p.Etiquetar(h.Registro)
+	}
+
+	resp := CentroComentariosResponse{
+		Centros:    f.Centros,
+		Items:      p.Items,
+		Total:      p.Total,
+		Publicados: p.Publicados,
+		EnRevision: p.EnRevision,
+		RangosEdad: bandsClave,
+		Anonimato:  anon,
+	}
+	if p.Siguiente != nil {
+		cur, err := p.Siguiente.Sellar(claveCursor())
+		if err != nil {
+			http.Error(w, "cursor_error", http.StatusInternalServerError)
+			return
+		}
+		resp.NextCursor = cur
+	}
+
+	writeJSONCentro(w, http.StatusOK, resp)
+}
+
+// claveCursor deriva del JWT_SECRET (RequireJWT ya revisó que exista) la
+// clave con la que se cifran los cursores de comentarios
+func claveCursor() [32]byte {
+	return sha256.Sum256([]byte("cursor-comentarios|" + strings.TrimSpace(os.Getenv("JWT_SECRET"))))
+}
+
+// etiquetarResumen cuenta los comentarios publicados por tipo de violencia
+// (clasificador por léxico del instrumento) para mostrarlos junto a la matriz
+func (h CentroResultadosHandler) etiquetarResumen(ctx context.Context, f services.FiltroAgregado, res *services.ResumenAgregado) error {
+	if h.Registro == nil {
+		return nil
+	}
+	e, err := services.ContarEtiquetas(ctx, h.DB, h.Registro, f)
+	if err != nil {
+		return err
+	}
+	res.Etiquetas = e
+	return nil
+}
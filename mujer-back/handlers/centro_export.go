@@ -0,0 +1,382 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"mujer-back/services"
+)
+
+// =======================================================
+// 📥 EXPORTACIÓN DE RESULTADOS DEL CENTRO
+// GET /api/centro/export?format=csv|xlsx (+ los filtros de /api/centro/resumen)
+// - csv: zip con un CSV por hoja
+// - xlsx: un libro con una pestaña por hoja
+// Mismo k-anonimato que los endpoints JSON; etiquetas del instrumento, no claves.
+// =======================================================
+
+// dimensiones que reportan los cortes por género / edad (en ese orden)
+var dimensionesGrupo = services.DimensionesReportes
+
+func (h CentroResultadosHandler) ExportCentro(w http.ResponseWriter, r *http.Request) {
+	if !h.ensureCentroRole(w, r) {
+		return
+	}
+
+	centros := UserCentrosFromCtx(r.Context())
+	if len(centros) == 0 {
+		http.Error(w, "no_centros", http.StatusForbidden)
+		return
+	}
+
+	format := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("format")))
+	if format == "" {
+		format = "csv"
+	}
+	if format != "csv" && format != "xlsx" {
+		http.Error(w, "bad_format", http.StatusBadRequest)
+		return
+	}
+
+	f, bandsClave, ok := h.filtroCompleto(w, r, centros)
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+
+	res, err := services.AgregarResumen(ctx, h.DB, f)
+	if errors.Is(err, services.ErrSinDatos) {
+		http.Error(w, "no_data", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "db_error", http.StatusInternalServerError)
+		return
+	}
+	if err := h.etiquetarResumen(ctx, f, &res); err != nil {
+		http.Error(w, "db_error", http.StatusInternalServerError)
+		return
+	}
+	anon := res.Anonimizar(h.k())
+
+	est, err := services.EstadisticaAvanzada(ctx, h.DB, f)
+	if err != nil && !errors.Is(err, services.ErrSinDatos) {
+		http.Error(w, "db_error", http.StatusInternalServerError)
+		return
+	}
+	services.AnonimizarEstadistica(est, &anon)
+
+	instIDs, err := services.InstrumentosDeFiltro(ctx, h.DB, f)
+	if err != nil {
+		http.Error(w, "db_error", http.StatusInternalServerError)
+		return
+	}
+	etiquetas, orden := h.etiquetasDimension(instIDs)
+	etiqueta := func(dim string) string {
+		if l, ok := etiquetas[dim]; ok {
+			return l
+		}
+		return dim
+	}
+
+	hojas := []services.Hoja{
+		hojaInfo(f, bandsClave, instIDs, res, anon),
+		hojaGlobal(res, anon, etiqueta),
+		hojaMatriz(res, orden, etiqueta),
+	}
+	if inst, ok := h.instrumentoDe(instIDs); ok {
+		if riesgo := inst.IndiceRiesgo(res.Matriz); riesgo != nil {
+			hojas = append(hojas, hojaRiesgo(riesgo))
+		}
+	}
+	hojas = append(hojas,
+		hojaGrupos("por_genero", "Por género", "Género", res.PorGenero, etiqueta),
+		hojaGrupos("por_edad", "Por rango de edad", "Rango de edad", ordenarPorRango(res.PorEdad, f.RangosEdad()), etiqueta),
+		hojaEstadistica(est, etiqueta),
+		hojaItems(est, etiqueta),
+	)
+
+	var buf bytes.Buffer
+	contentType := "application/zip"
+	ext := ".csv.zip"
+	if format == "xlsx" {
+		contentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+		ext = ".xlsx"
+		err = services.EscribirXLSX(&buf, hojas)
+	} else {
+		err = services.EscribirCSVZip(&buf, hojas)
+	}
+	if err != nil {
+		http.Error(w, "export_error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="`+nombreExport(f)+ext+`"`)
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	w.WriteHeader(http.StatusOK)
+	_, _ = buf.WriteTo(w)
+}
+
+// etiquetasDimension junta los labels de dimensiones de los instrumentos
+// involucrados; orden sigue el del primer instrumento que declara cada una
+func (h CentroResultadosHandler) etiquetasDimension(instIDs []string) (map[string]string, []string) {
+	etiquetas := map[string]string{}
+	orden := []string{}
+	if h.Registro == nil {
+		return etiquetas, orden
+	}
+	if len(instIDs) == 0 {
+		instIDs = []string{h.Registro.Default().ID}
+	}
+	for _, id := range instIDs {
+		inst, ok := h.Registro.Get(id)
+		if !ok {
+			continue
+		}
+		for _, d := range inst.Dimensions {
+			if _, ya := etiquetas[d.Key]; ya {
+				continue
+			}
+			etiquetas[d.Key] = d.Label
+			orden = append(orden, d.Key)
+		}
+	}
+	return etiquetas, orden
+}
+
+// nombreExport arma el nombre del archivo según el periodo filtrado
+func nombreExport(f services.FiltroAgregado) string {
+	nombre := "resultados_centro"
+	if len(f.Centros) == 1 {
+		nombre += "_" + strconv.FormatInt(f.Centros[0], 10)
+	}
+	switch {
+	case f.AplicacionID != nil:
+		nombre += "_campana_" + strconv.FormatInt(*f.AplicacionID, 10)
+	case f.Year != nil:
+		nombre += "_" + strconv.Itoa(*f.Year)
+	case f.Desde != nil || f.Hasta != nil:
+		if f.Desde != nil {
+			nombre += "_desde_" + f.Desde.Format("2006-01-02")
+		}
+		if f.Hasta != nil {
+			nombre += "_hasta_" + f.Hasta.Format("2006-01-02")
+		}
+	default:
+		nombre += "_historico"
+	}
+	return nombre
+}
+
+func hojaInfo(f services.FiltroAgregado, bandsClave string, instIDs []string, res services.ResumenAgregado, anon services.Anonimato) services.Hoja {
+	centros := make([]string, len(f.Centros))
+	for i, c := range f.Centros {
+		centros[i] = strconv.FormatInt(c, 10)
+	}
+
+	filas := [][]any{
+		{"Generado", time.Now().Format("2006-01-02 15:04")},
+		{"Centros", strings.Join(centros, ", ")},
+	}
+	if f.Year != nil {
+		filas = append(filas, []any{"Año", *f.Year})
+	}
+	if f.AplicacionID != nil {
+		filas = append(filas, []any{"Campaña", *f.AplicacionID})
+	}
+	if f.Desde != nil {
+		filas = append(filas, []any{"Desde", f.Desde.Format("2006-01-02")})
+	}
+	if f.Hasta != nil {
+		filas = append(filas, []any{"Hasta", f.Hasta.Format("2006-01-02")})
+	}
+	if f.Genero != "" {
+		filas = append(filas, []any{"Género", f.Genero})
+	}
+	if f.RangoEdad != "" {
+		filas = append(filas, []any{"Rango de edad", f.RangoEdad})
+	}
+	filas = append(filas,
+		[]any{"Rangos de edad", bandsClave},
+		[]any{"Instrumento", strings.Join(instIDs, ", ")},
+		[]any{"Participantes", res.TotalParticipantes},
+		[]any{"Respuestas", res.TotalRespuestas},
+		[]any{"Comentarios publicados", res.Etiquetas.Publicados},
+		[]any{"Comentarios etiquetados", res.Etiquetas.Etiquetados},
+		[]any{"k-anonimato", anon.K},
+		[]any{"Suprimido", strings.Join(anon.Campos, "; ")},
+	)
+
+	return services.Hoja{Clave: "info", Nombre: "Información", Columnas: []string{"Campo", "Valor"}, Filas: filas}
+}
+
+func hojaGlobal(res services.ResumenAgregado, anon services.Anonimato, etiqueta func(string) string) services.Hoja {
+	h := services.Hoja{Clave: "global", Nombre: "Promedios globales", Columnas: []string{"Dimensión", "Promedio"}}
+	if res.TotalParticipantes < int64(anon.K) {
+		return h
+	}
+	h.Filas = [][]any{
+		{etiqueta("frecuencia"), res.Global.Frecuencia},
+		{etiqueta("normalidad"), res.Global.Normalidad},
+		{etiqueta("gravedad"), res.Global.Gravedad},
+		{"Total", res.Global.Total},
+	}
+	return h
+}
+
+// hojaMatriz pivotea tipo × dimensión: una fila por tipo, una columna por dimensión
+func hojaMatriz(res services.ResumenAgregado, orden []string, etiqueta func(string) string) services.Hoja {
+	dims := append([]string(nil), orden...)
+	col := map[string]int{}
+	for i, d := range dims {
+		col[d] = i
+	}
+	for _, c := range res.Matriz {
+		if _, ok := col[c.Dimension]; !ok {
+			col[c.Dimension] = len(dims)
+			dims = append(dims, c.Dimension)
+		}
+	}
+
+	h := services.Hoja{Clave: "matriz", Nombre: "Matriz tipo x dimensión", Columnas: []string{"Tipo #", "Tipo de violencia"}}
+	for _, d := range dims {
+		h.Columnas = append(h.Columnas, etiqueta(d))
+	}
+
+	filaDe := map[int32]int{}
+	for _, c := range res.Matriz {
+		i, ok := filaDe[c.TipoNum]
+		if !ok {
+			fila := make([]any, 2+len(dims))
+			fila[0], fila[1] = int64(c.TipoNum), c.TipoNombre
+			h.Filas = append(h.Filas, fila)
+			i = len(h.Filas) - 1
+			filaDe[c.TipoNum] = i
+		}
+		h.Filas[i][2+col[c.Dimension]] = c.Promedio
+	}
+
+	// comentarios publicados que mencionan el tipo (clasificador por léxico)
+	if len(res.Etiquetas.Tipos) > 0 {
+		h.Columnas = append(h.Columnas, "Comentarios")
+		for i := range h.Filas {
+			h.Filas[i] = append(h.Filas[i], int64(0))
+		}
+		for _, e := range res.Etiquetas.Tipos {
+			i, ok := filaDe[e.TipoNum]
+			if !ok {
+				fila := make([]any, 2+len(dims)+1)
+				fila[0], fila[1] = int64(e.TipoNum), e.TipoNombre
+				h.Filas = append(h.Filas, fila)
+				i = len(h.Filas) - 1
+				filaDe[e.TipoNum] = i
+			}
+			h.Filas[i][len(h.Filas[i])-1] = e.Comentarios
+		}
+	}
+	return h
+}
+
+// hojaRiesgo: índice compuesto por tipo, el global al final
+func hojaRiesgo(r *services.ResumenRiesgo) services.Hoja {
+	h := services.Hoja{Clave: "riesgo", Nombre: "Índice de riesgo", Columnas: []string{"Tipo #", "Tipo de violencia", "Índice (0-100)", "Nivel"}}
+	for _, t := range r.Tipos {
+		h.Filas = append(h.Filas, []any{int64(t.TipoNum), t.TipoNombre, t.Indice, t.NivelLabel})
+	}
+	if r.Global != nil {
+		h.Filas = append(h.Filas, []any{nil, "Global (promedio de tipos)", r.Global.Indice, r.Global.NivelLabel})
+	}
+	return h
+}
+
+func hojaGrupos(clave, nombre, titulo string, grupos []services.Grupo, etiqueta func(string) string) services.Hoja {
+	h := services.Hoja{Clave: clave, Nombre: nombre, Columnas: []string{titulo, "Encuestas", "Respuestas"}}
+	for _, d := range dimensionesGrupo {
+		h.Columnas = append(h.Columnas, etiqueta(d))
+	}
+	for _, g := range grupos {
+		h.Filas = append(h.Filas, []any{g.Label, g.Encuestas, g.Respuestas, g.Frecuencia(), g.Normalidad(), g.Gravedad()})
+	}
+	return h
+}
+
+// ordenarPorRango deja los rangos en el orden del conjunto ("otros"/"sin dato" al final)
+func ordenarPorRango(grupos []services.Grupo, rangos []services.RangoEdad) []services.Grupo {
+	pos := make(map[string]int, len(rangos))
+	for i, rg := range rangos {
+		pos[rg.Label] = i
+	}
+	out := append([]services.Grupo(nil), grupos...)
+	sort.SliceStable(out, func(i, j int) bool {
+		pi, ok := pos[out[i].Clave]
+		if !ok {
+			pi = len(rangos)
+		}
+		pj, ok := pos[out[j].Clave]
+		if !ok {
+			pj = len(rangos)
+		}
+		return pi < pj
+	})
+	return out
+}
+
+func hojaEstadistica(est []services.EstadisticaDimension, etiqueta func(string) string) services.Hoja {
+	h := services.Hoja{
+		Clave:  "estadistica",
+		Nombre: "Estadística avanzada",
+		Columnas: []string{
+			"Dimensión", "N respuestas", "N encuestas", "Total respuestas", "Ítems",
+			"Promedio", "Desv. estándar", "Mediana", "P25", "P75", "IC95 inferior", "IC95 superior",
+			"Desv. estándar entre encuestas", "IC95 inferior (encuestas)", "IC95 superior (encuestas)",
+			"IC95 inferior (bootstrap)", "IC95 superior (bootstrap)",
+			"Alpha de Cronbach", "Alpha IC95 inferior", "Alpha IC95 superior", "Encuestas completas (alpha)", "Suprimido",
+		},
+	}
+	for _, d := range est {
+		fila := []any{etiqueta(d.Dimension), d.NRespuestas, d.NEncuestas, d.TotalRespuestas, d.KItems}
+		if d.Suprimido {
+			fila = append(fila, make([]any, 16)...)
+		} else {
+			fila = append(fila,
+				d.Promedio, d.StdDev, d.Mediana, d.P25, d.P75, d.IC95Inferior, d.IC95Superior,
+				d.StdDevEncuestas, d.IC95InferiorEncuestas, d.IC95SuperiorEncuestas,
+				d.IC95InferiorBootstrap, d.IC95SuperiorBootstrap,
+				d.AlphaCronbach, d.AlphaIC95Inferior, d.AlphaIC95Superior, d.NEncuestasCompletas,
+			)
+		}
+		h.Filas = append(h.Filas, append(fila, d.Suprimido))
+	}
+	return h
+}
+
+// hojaItems: análisis por ítem de cada dimensión (vacía si todo se suprimió)
+func hojaItems(est []services.EstadisticaDimension, etiqueta func(string) string) services.Hoja {
+	h := services.Hoja{
+		Clave:    "items",
+		Nombre:   "Análisis de ítems",
+		Columnas: []string{"Dimensión", "Pregunta", "Promedio", "Desv. estándar", "Correlación ítem-total", "Alpha si se elimina"},
+	}
+	opcional := func(v *float64) any {
+		if v == nil {
+			return nil
+		}
+		return *v
+	}
+	for _, d := range est {
+		for _, it := range d.Items {
+			h.Filas = append(h.Filas, []any{
+				etiqueta(d.Dimension), it.PreguntaID, it.Promedio, it.StdDev,
+				opcional(it.CorrelacionItemTotal), opcional(it.AlphaSiSeElimina),
+			})
+		}
+	}
+	return h
+}
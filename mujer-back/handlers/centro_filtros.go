@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"mujer-back/services"
+)
+
+// parseFiltroCentro lee los filtros comunes de los reportes /api/centro/*:
+// ?centro=, ?year=, ?aplicacion=, ?genero=, ?rango_edad=, ?desde= y ?hasta=
+// (por mes, ver rangoMensual).
+// Regresa un código de error si vienen mal. Siempre se limita a los centros
+// del JWT (?centro= solo puede ser uno de ellos) y a encuestas finalizadas.
+func parseFiltroCentro(r *http.Request, centros []int64) (services.FiltroAgregado, string) {
+	f := services.FiltroAgregado{Centros: centros}
+	q := r.URL.Query()
+
+	if cs := strings.TrimSpace(q.Get("centro")); cs != "" {
+		id, err := strconv.ParseInt(cs, 10, 64)
+		if err != nil || id <= 0 {
+			return f, "bad_centro"
+		}
+		permitido := false
+		for _, c := range centros {
+			if c == id {
+				permitido = true
+				break
+			}
+		}
+		if !permitido {
+			return f, "centro_forbidden"
+		}
+		f.Centros = []int64{id}
+	}
+
+	if ys := strings.TrimSpace(q.Get("year")); ys != "" {
+		yi, err := strconv.Atoi(ys)
+		if err != nil {
+			return f, "bad_year"
+		}
+		f.Year = &yi
+	}
+
+	if as := strings.TrimSpace(q.Get("aplicacion")); as != "" {
+		id, err := strconv.ParseInt(as, 10, 64)
+		if err != nil || id <= 0 {
+			return f, "bad_aplicacion"
+		}
+		f.AplicacionID = &id
+	}
+
+	f.Genero = strings.ToLower(strings.TrimSpace(q.Get("genero")))
+	f.RangoEdad = strings.TrimSpace(q.Get("rango_edad"))
+
+	desde, hasta, errCode := rangoMensual(q)
+	if errCode != "" {
+		return f, errCode
+	}
+	f.Desde, f.Hasta = desde, hasta
+
+	return f, ""
+}
+
+// rangoMensual lee ?desde= y ?hasta= con precisión de mes: "2025-03" o una
+// fecha, que se lleva a su mes (desde = día 1, hasta = último día). Con
+// precisión de día, restar dos consultas que difieren en un día daría los
+// promedios de las pocas personas que terminaron ese día.
+func rangoMensual(q url.Values) (desde, hasta *time.Time, errCode string) {
+	mes := func(name string) (*time.Time, bool) {
+		s := strings.TrimSpace(q.Get(name))
+		if s == "" {
+			return nil, true
+		}
+		t, err := time.Parse("2006-01", s)
+		if err != nil {
+			if t, err = time.Parse("2006-01-02", s); err != nil {
+				return nil, false
+			}
+		}
+		t = time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+		return &t, true
+	}
+
+	var ok bool
+	if desde, ok = mes("desde"); !ok {
+		return nil, nil, "bad_fechas"
+	}
+	if hasta, ok = mes("hasta"); !ok {
+		return nil, nil, "bad_fechas"
+	}
+	if hasta != nil {
+		fin := hasta.AddDate(0, 1, -1)
+		hasta = &fin
+	}
+	if desde != nil && hasta != nil && hasta.Before(*desde) {
+		return nil, nil, "bad_fechas"
+	}
+	return desde, hasta, ""
+}
+
+// filtroCompleto parsea los filtros, resuelve los rangos de edad (?bands=)
+// y valida genero/rango_edad. Si algo falla ya respondió y regresa ok=false.
+func (h CentroResultadosHandler) filtroCompleto(w http.ResponseWriter, r *http.Request, centros []int64) (f services.FiltroAgregado, bandsClave string, ok bool) {
+	f, errCode := parseFiltroCentro(r, centros)
+	if errCode == "centro_forbidden" {
+		http.Error(w, errCode, http.StatusForbidden)
+		return f, "", false
+	}
+	if errCode != "" {
+		http.Error(w, errCode, http.StatusBadRequest)
+		return f, "", false
+	}
+
+	ctx := r.Context()
+
+	bandsClave, rangos, err := services.CargarRangosEdad(ctx, h.DB, f.Centros, r.URL.Query().Get("bands"))
+	if errors.Is(err, services.ErrRangosEdadNoExiste) {
+		http.Error(w, "bad_bands", http.StatusBadRequest)
+		return f, "", false
+	}
+	if err != nil {
+		http.Error(w, "db_error", http.StatusInternalServerError)
+		return f, "", false
+	}
+	f.Rangos = rangos
+
+	if f.RangoEdad != "" {
+		existe := false
+		for _, rg := range rangos {
+			if rg.Label == f.RangoEdad {
+				existe = true
+				break
+			}
+		}
+		if !existe {
+			http.Error(w, "bad_rango_edad", http.StatusBadRequest)
+			return f, "", false
+		}
+	}
+
+	if f.Genero != "" {
+		var existe bool
+		if err := h.DB.QueryRow(ctx, `select exists(select 1 from generos where clave = $1)`, f.Genero).Scan(&existe); err != nil {
+			http.Error(w, "db_error", http.StatusInternalServerError)
+			return f, "", false
+		}
+		if !existe {
+			http.Error(w, "bad_genero", http.StatusBadRequest)
+			return f, "", false
+		}
+	}
+
+	return f, bandsClave, true
+}
+
+// instrumentoDe regresa el instrumento con el que se interpretan los
+// resultados del filtro: el primero de instIDs (o el default si no hay)
+func (h CentroResultadosHandler) instrumentoDe(instIDs []string) (services.Instrumento, bool) {
+	if h.Registro == nil {
+		return services.Instrumento{}, false
+	}
+	if len(instIDs) > 0 {
+		if i, ok := h.Registro.Get(instIDs[0]); ok {
+			return i, true
+		}
+	}
+	return h.Registro.Default(), true
+}
@@ -0,0 +1,522 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"mujer-back/services"
+)
+
+// =======================================================
+// 📄 INFORME DE DIAGNÓSTICO (PDF)
+// GET /api/centro/reporte.pdf?year=2025 (o ?aplicacion=ID / ?desde=)
+// + los filtros de /api/centro/resumen
+// Junta global, matriz, cortes por género/edad, serie anual,
+// IC95 y alpha con el mismo k-anonimato que los endpoints JSON,
+// y agrega las notas de metodología del instrumento.
+// =======================================================
+
+func (h CentroResultadosHandler) GetReportePDF(w http.ResponseWriter, r *http.Request) {
+	if !h.ensureCentroRole(w, r) {
+		return
+	}
+
+	centros := UserCentrosFromCtx(r.Context())
+	if len(centros) == 0 {
+		http.Error(w, "no_centros", http.StatusForbidden)
+		return
+	}
+
+	f, _, ok := h.filtroCompleto(w, r, centros)
+	if !ok {
+		return
+	}
+	if f.Year == nil && f.AplicacionID == nil && f.Desde == nil {
+		http.Error(w, "year_required", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	k := h.k()
+
+	res, err := services.AgregarResumen(ctx, h.DB, f)
+	if errors.Is(err, services.ErrSinDatos) {
+		http.Error(w, "no_data", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "db_error", http.StatusInternalServerError)
+		return
+	}
+	if err := h.etiquetarResumen(ctx, f, &res); err != nil {
+		http.Error(w, "db_error", http.StatusInternalServerError)
+		return
+	}
+	anon := res.Anonimizar(k)
+
+	est, err := services.EstadisticaAvanzada(ctx, h.DB, f)
+	if err != nil && !errors.Is(err, services.ErrSinDatos) {
+		http.Error(w, "db_error", http.StatusInternalServerError)
+		return
+	}
+	services.AnonimizarEstadistica(est, &anon)
+
+	// la serie anual es de los mismos centros, todos los años
+	serie, err := services.SerieAnual(ctx, h.DB, services.FiltroAgregado{Centros: f.Centros})
+	if err != nil && !errors.Is(err, services.ErrSinDatos) {
+		http.Error(w, "db_error", http.StatusInternalServerError)
+		return
+	}
+	for i := range serie {
+		if serie[i].Encuestas < int64(k) {
+			serie[i] = services.PuntoAnual{Year: serie[i].Year, Encuestas: serie[i].Encuestas, Respuestas: serie[i].Respuestas}
+			anon.Marcar(services.CampoAnio(serie[i].Year))
+		}
+	}
+
+	instIDs, err := services.InstrumentosDeFiltro(ctx, h.DB, f)
+	if err != nil {
+		http.Error(w, "db_error", http.StatusInternalServerError)
+		return
+	}
+	nombres, err := h.nombresCentros(ctx, f.Centros)
+	if err != nil {
+		http.Error(w, "db_error", http.StatusInternalServerError)
+		return
+	}
+
+	inst, _ := h.instrumentoDe(instIDs)
+
+	pdf := armarInforme(informeDatos{
+		Filtro:    f,
+		Centros:   nombres,
+		Periodo:   periodoTexto(f),
+		Inst:      inst,
+		Resumen:   res,
+		Est:       est,
+		Serie:     serie,
+		Anonimato: anon,
+	})
+
+	var buf bytes.Buffer
+	if err := pdf.Escribir(&buf); err != nil {
+		http.Error(w, "pdf_error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", `attachment; filename="informe_`+nombreExport(f)+`.pdf"`)
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	w.WriteHeader(http.StatusOK)
+	_, _ = buf.WriteTo(w)
+}
+
+// nombresCentros regresa los nombres en el orden de ids
+func (h CentroResultadosHandler) nombresCentros(ctx context.Context, ids []int64) ([]string, error) {
+	rows, err := h.DB.Query(ctx, `select id, nombre from centros where id = any($1::bigint[])`, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	porID := map[int64]string{}
+	for rows.Next() {
+		var id int64
+		var nombre string
+		if err := rows.Scan(&id, &nombre); err != nil {
+			return nil, err
+		}
+		porID[id] = nombre
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	out := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if n, ok := porID[id]; ok {
+			out = append(out, n)
+		}
+	}
+	return out, nil
+}
+
+func periodoTexto(f services.FiltroAgregado) string {
+	var partes []string
+	if f.Year != nil {
+		partes = append(partes, "Año "+strconv.Itoa(*f.Year))
+	}
+	if f.AplicacionID != nil {
+		partes = append(partes, "Campaña #"+strconv.FormatInt(*f.AplicacionID, 10))
+	}
+	if f.Desde != nil {
+		partes = append(partes, "desde "+f.Desde.Format("02/01/2006"))
+	}
+	if f.Hasta != nil {
+		partes = append(partes, "hasta "+f.Hasta.Format("02/01/2006"))
+	}
+	if f.Genero != "" {
+		partes = append(partes, "género: "+f.Genero)
+	}
+	if f.RangoEdad != "" {
+		partes = append(partes, "edad: "+f.RangoEdad)
+	}
+	return strings.Join(partes, " · ")
+}
+
+type informeDatos struct {
+	Filtro    services.FiltroAgregado
+	Centros   []string
+	Periodo   string
+	Inst      services.Instrumento
+	Resumen   services.ResumenAgregado
+	Est       []services.EstadisticaDimension
+	Serie     []services.PuntoAnual
+	Anonimato services.Anonimato
+}
+
+// etiqueta de una dimensión según el instrumento
+func (d informeDatos) etiqueta(dim string) string {
+	for _, x := range d.Inst.Dimensions {
+		if x.Key == dim {
+			return x.Label
+		}
+	}
+	return dim
+}
+
+// nivel traduce un promedio 1–5 a la opción de la escala más cercana
+func (d informeDatos) nivel(dim string, v float64) string {
+	for _, x := range d.Inst.Dimensions {
+		if x.Key != dim {
+			continue
+		}
+		sc := d.Inst.Scales[x.ScaleID]
+		best, dist := "", math.Inf(1)
+		for _, o := range sc.Options {
+			if dd := math.Abs(float64(o.Value) - v); dd < dist {
+				best, dist = o.Label, dd
+			}
+		}
+		return best
+	}
+	return ""
+}
+
+func f2(v float64) string {
+	return strconv.FormatFloat(v, 'f', 2, 64)
+}
+
+// calor va de blanco (1) al color de marca claro (5)
+func calor(v float64) services.Color {
+	t := math.Max(0, math.Min(1, (v-1)/4)) * 0.45
+	m := services.ColorMarca
+	return services.Color{R: 1 - t*(1-m.R), G: 1 - t*(1-m.G), B: 1 - t*(1-m.B)}
+}
+
+func interpretarAlpha(a float64) string {
+	switch {
+	case a >= 0.9:
+		return "excelente"
+	case a >= 0.8:
+		return "buena"
+	case a >= 0.7:
+		return "aceptable"
+	case a >= 0.6:
+		return "cuestionable"
+	default:
+		return "baja"
+	}
+}
+
+func armarInforme(d informeDatos) *services.Informe {
+	res := d.Resumen
+	k := d.Anonimato.K
+	suprimidoTotal := res.TotalParticipantes < int64(k)
+
+	titulo := "Informe de diagnóstico"
+	if d.Inst.Name != "" {
+		titulo += " — " + d.Inst.Name
+	}
+	in := services.NuevoInforme(titulo, titulo+" · "+strings.Join(d.Centros, ", "))
+
+	// ==========================
+	// PORTADA
+	// ==========================
+	in.Y += 30
+	in.Texto(in.Margen, in.Y, 22, true, services.ColorMarca, "Informe de diagnóstico")
+	in.Y += 22
+	if d.Inst.Name != "" {
+		in.Texto(in.Margen, in.Y, 13, false, services.ColorTexto, d.Inst.Name)
+		in.Y += 10
+	}
+	if d.Inst.Subtitle != "" {
+		in.Parrafo(d.Inst.Subtitle, 9.5, services.ColorSuave)
+	}
+	in.Y += 8
+	in.Tabla([]string{"Dato", "Valor"}, []float64{1, 3}, [][]string{
+		{"Centro(s)", strings.Join(d.Centros, ", ")},
+		{"Periodo", d.Periodo},
+		{"Participantes", strconv.FormatInt(res.TotalParticipantes, 10)},
+		{"Respuestas", strconv.FormatInt(res.TotalRespuestas, 10)},
+		{"Instrumento", strings.TrimSpace(d.Inst.ID + " v" + d.Inst.Version)},
+		{"Umbral de anonimato (k)", strconv.Itoa(k) + " encuestas"},
+		{"Generado", time.Now().Format("02/01/2006 15:04")},
+	}, nil)
+
+	if suprimidoTotal {
+		in.Titulo("Resultados no publicables", 14)
+		in.Parrafo(fmt.Sprintf("El periodo seleccionado tiene %d encuesta(s), menos que el umbral de anonimato (k = %d). "+
+			"Para proteger a las personas participantes, este informe no incluye promedios ni cortes.", res.TotalParticipantes, k), 10, services.ColorTexto)
+	} else {
+		// ==========================
+		// 1. GLOBAL
+		// ==========================
+		in.Titulo("1. Resultados globales", 14)
+		in.Parrafo("Promedio de todas las respuestas del periodo en cada dimensión (escala 1 a 5).", 9.5, services.ColorSuave)
+		dims := services.DimensionesReportes
+		globales := []float64{res.Global.Frecuencia, res.Global.Normalidad, res.Global.Gravedad}
+		grupos := make([]string, len(dims))
+		filas := make([][]string, 0, len(dims)+1)
+		for i, dim := range dims {
+			grupos[i] = d.etiqueta(dim)
+			filas = append(filas, []string{d.etiqueta(dim), f2(globales[i]), d.nivel(dim, globales[i])})
+		}
+		filas = append(filas, []string{"Índice total", f2(res.Global.Total), ""})
+		in.Barras(grupos, []services.Serie{{Nombre: "Promedio", Valores: globales}}, 5)
+		in.Tabla([]string{"Dimensión", "Promedio", "Nivel de referencia"}, []float64{2, 1, 2}, filas, nil)
+
+		// ==========================
+		// 2. MATRIZ
+		// ==========================
+		in.Titulo("2. Tipos de violencia por dimensión", 14)
+		in.Parrafo("Promedio por tipo de violencia; el color se intensifica conforme el valor se acerca a 5.", 9.5, services.ColorSuave)
+		if len(res.Etiquetas.Tipos) > 0 {
+			in.Parrafo(fmt.Sprintf("La columna Comentarios cuenta cuántos de los %d comentarios publicados mencionan el tipo "+
+				"(etiquetado automático por palabras clave; un comentario puede contar en varios tipos).", res.Etiquetas.Publicados), 9.5, services.ColorSuave)
+		}
+		type filaTipo struct {
+			num    int32
+			nombre string
+			vals   map[string]float64
+		}
+		var tipos []*filaTipo
+		idx := map[int32]*filaTipo{}
+		for _, c := range res.Matriz {
+			ft, ok := idx[c.TipoNum]
+			if !ok {
+				ft = &filaTipo{num: c.TipoNum, nombre: strconv.Itoa(int(c.TipoNum)) + ". " + c.TipoNombre, vals: map[string]float64{}}
+				idx[c.TipoNum] = ft
+				tipos = append(tipos, ft)
+			}
+			ft.vals[c.Dimension] = c.Promedio
+		}
+		// comentarios publicados que mencionan cada tipo (clasificador por léxico)
+		conComentarios := len(res.Etiquetas.Tipos) > 0
+		comentarios := map[int32]int64{}
+		for _, e := range res.Etiquetas.Tipos {
+			comentarios[e.TipoNum] = e.Comentarios
+		}
+		filasM := make([][]string, 0, len(tipos))
+		for _, ft := range tipos {
+			fila := []string{ft.nombre}
+			for _, dim := range dims {
+				if v, ok := ft.vals[dim]; ok {
+					fila = append(fila, f2(v))
+				} else {
+					fila = append(fila, "")
+				}
+			}
+			if conComentarios {
+				fila = append(fila, strconv.FormatInt(comentarios[ft.num], 10))
+			}
+			filasM = append(filasM, fila)
+		}
+		headM := []string{"Tipo de violencia", d.etiqueta("frecuencia"), d.etiqueta("normalidad"), d.etiqueta("gravedad")}
+		anchosM := []float64{3, 1, 1, 1}
+		if conComentarios {
+			headM = append(headM, "Comentarios")
+			anchosM = append(anchosM, 1)
+		}
+		in.Tabla(headM, anchosM, filasM, func(fi, ci int) (services.Color, bool) {
+			if ci == 0 || ci > len(dims) {
+				return services.Color{}, false
+			}
+			v, ok := tipos[fi].vals[dims[ci-1]]
+			return calor(v), ok
+		})
+
+		// índice compuesto (solo si el instrumento define scoring.risk_index)
+		if riesgo := d.Inst.IndiceRiesgo(res.Matriz); riesgo != nil && riesgo.Global != nil {
+			in.Titulo("Índice compuesto de riesgo", 12)
+			in.Parrafo("Combina frecuencia y normalidad, ponderadas por la gravedad percibida, en una escala de 0 a 100. "+
+				"El nivel se asigna con los cortes definidos por el instrumento.", 9.5, services.ColorSuave)
+			filasR := make([][]string, 0, len(riesgo.Tipos)+1)
+			for _, t := range riesgo.Tipos {
+				filasR = append(filasR, []string{strconv.Itoa(int(t.TipoNum)) + ". " + t.TipoNombre, strconv.FormatFloat(t.Indice, 'f', 1, 64), t.NivelLabel})
+			}
+			filasR = append(filasR, []string{"Global (promedio de tipos)", strconv.FormatFloat(riesgo.Global.Indice, 'f', 1, 64), riesgo.Global.NivelLabel})
+			in.Tabla([]string{"Tipo de violencia", "Índice", "Nivel"}, []float64{3, 1, 1}, filasR, nil)
+		}
+
+		// ==========================
+		// 3. CORTES
+		// ==========================
+		cortes := []struct {
+			titulo string
+			grupos []services.Grupo
+		}{
+			{"3. Resultados por género", res.PorGenero},
+			{"4. Resultados por rango de edad", ordenarPorRango(res.PorEdad, d.Filtro.RangosEdad())},
+		}
+		for _, c := range cortes {
+			in.Titulo(c.titulo, 14)
+			if len(c.grupos) == 0 {
+				in.Parrafo("Sin grupos publicables: ninguno alcanza el umbral de anonimato.", 9.5, services.ColorSuave)
+				continue
+			}
+			labels := make([]string, len(c.grupos))
+			series := make([]services.Serie, len(dims))
+			for i, dim := range dims {
+				series[i] = services.Serie{Nombre: d.etiqueta(dim), Valores: make([]float64, len(c.grupos))}
+			}
+			filasG := make([][]string, 0, len(c.grupos))
+			for gi, g := range c.grupos {
+				labels[gi] = g.Label
+				vals := []float64{g.Frecuencia(), g.Normalidad(), g.Gravedad()}
+				for i := range dims {
+					series[i].Valores[gi] = vals[i]
+				}
+				filasG = append(filasG, []string{g.Label, strconv.FormatInt(g.Encuestas, 10), f2(vals[0]), f2(vals[1]), f2(vals[2])})
+			}
+			in.Barras(labels, series, 5)
+			in.Tabla([]string{"Grupo", "Encuestas", d.etiqueta("frecuencia"), d.etiqueta("normalidad"), d.etiqueta("gravedad")},
+				[]float64{2.5, 1, 1, 1, 1}, filasG, nil)
+		}
+	}
+
+	// ==========================
+	// 5. SERIE ANUAL
+	// ==========================
+	if len(d.Serie) > 0 {
+		in.Titulo("5. Evolución anual", 14)
+		in.Parrafo("Promedios por año de los mismos centros (todos los años con datos). "+
+			"Los años con menos de k encuestas aparecen sin valor.", 9.5, services.ColorSuave)
+		xs := make([]string, len(d.Serie))
+		series := []services.Serie{
+			{Nombre: d.etiqueta("frecuencia")}, {Nombre: d.etiqueta("normalidad")},
+			{Nombre: d.etiqueta("gravedad")}, {Nombre: "Índice total"},
+		}
+		filasA := make([][]string, 0, len(d.Serie))
+		for i, p := range d.Serie {
+			xs[i] = strconv.Itoa(p.Year)
+			vals := []float64{p.Frecuencia, p.Normalidad, p.Gravedad, p.Total}
+			suprimido := p.Encuestas < int64(k)
+			fila := []string{xs[i], strconv.FormatInt(p.Encuestas, 10)}
+			for si := range series {
+				v := vals[si]
+				if suprimido {
+					v = math.NaN()
+				}
+				series[si].Valores = append(series[si].Valores, v)
+				if suprimido {
+					fila = append(fila, "—")
+				} else {
+					fila = append(fila, f2(vals[si]))
+				}
+			}
+			filasA = append(filasA, fila)
+		}
+		in.Lineas(xs, series, 5)
+		in.Tabla([]string{"Año", "Encuestas", series[0].Nombre, series[1].Nombre, series[2].Nombre, "Índice total"},
+			[]float64{1, 1, 1, 1, 1, 1}, filasA, nil)
+	}
+
+	// ==========================
+	// 6. ESTADÍSTICA AVANZADA
+	// ==========================
+	if len(d.Est) > 0 {
+		in.Titulo("6. Precisión y consistencia", 14)
+		in.Parrafo("IC95: intervalo de confianza del 95 % del promedio entre encuestas (t de Student con n - 1 "+
+			"grados de libertad). Alpha de Cronbach: consistencia interna de los ítems de cada dimensión, "+
+			"con su intervalo de Feldt entre corchetes.", 9.5, services.ColorSuave)
+		filasE := make([][]string, 0, len(d.Est))
+		for _, e := range d.Est {
+			if e.Suprimido {
+				filasE = append(filasE, []string{d.etiqueta(e.Dimension), strconv.FormatInt(e.NEncuestas, 10), "—", "—", "—", "—", "—"})
+				continue
+			}
+			filasE = append(filasE, []string{
+				d.etiqueta(e.Dimension),
+				strconv.FormatInt(e.NEncuestas, 10),
+				f2(e.Promedio),
+				f2(e.StdDev),
+				f2(e.Mediana),
+				f2(e.IC95InferiorEncuestas) + " – " + f2(e.IC95SuperiorEncuestas),
+				f2(e.AlphaCronbach) + " [" + f2(e.AlphaIC95Inferior) + " – " + f2(e.AlphaIC95Superior) + "] (" + interpretarAlpha(e.AlphaCronbach) + ")",
+			})
+		}
+		in.Tabla([]string{"Dimensión", "Encuestas", "Promedio", "Desv. est.", "Mediana", "IC95", "Alpha"},
+			[]float64{1.5, 0.9, 0.9, 0.9, 0.9, 1.5, 2.4}, filasE, nil)
+	}
+
+	// ==========================
+	// METODOLOGÍA (del instrumento)
+	// ==========================
+	in.Titulo("Metodología", 14)
+	if d.Inst.Instructions != "" {
+		in.Parrafo(d.Inst.Instructions, 9.5, services.ColorTexto)
+	}
+	for _, dim := range d.Inst.Dimensions {
+		sc, ok := d.Inst.Scales[dim.ScaleID]
+		if !ok {
+			continue
+		}
+		opciones := make([]string, 0, len(sc.Options))
+		for _, o := range sc.Options {
+			opciones = append(opciones, strconv.Itoa(o.Value)+" = "+o.Label)
+		}
+		in.Vineta(dim.Label+": "+strings.Join(opciones, ", ")+".", 9.5)
+	}
+	if n := d.Inst.Scoring.PerTypeIndices.Notes; n != "" {
+		in.Vineta("Índices por tipo: "+n, 9.5)
+	}
+	if ri := d.Inst.Scoring.RiskIndex; ri != nil {
+		cortes := make([]string, 0, len(ri.Levels))
+		for _, l := range ri.Levels {
+			cortes = append(cortes, l.Label+" desde "+strconv.FormatFloat(l.Min, 'f', -1, 64))
+		}
+		texto := "Índice compuesto de riesgo: 100 × raíz(F × N) × ((1 - w) + w × G), con F, N y G los promedios de frecuencia, " +
+			"normalidad y gravedad llevados a 0–1 y w = " + strconv.FormatFloat(ri.PesoGravedad(), 'f', -1, 64) + ". Niveles: " + strings.Join(cortes, ", ") + "."
+		if ri.Notes != "" {
+			texto += " " + ri.Notes
+		}
+		in.Vineta(texto, 9.5)
+	}
+	if len(d.Inst.TypesOfViolence) > 0 {
+		in.Y += 6
+		filasT := make([][]string, 0, len(d.Inst.TypesOfViolence))
+		for _, t := range d.Inst.TypesOfViolence {
+			filasT = append(filasT, []string{strconv.Itoa(t.Order) + ". " + t.Label, t.Notes, strconv.Itoa(len(t.Questions))})
+		}
+		in.Tabla([]string{"Tipo de violencia", "Descripción", "Preguntas"}, []float64{2, 3, 0.8}, filasT, nil)
+	}
+
+	in.Titulo("Protección de datos", 12)
+	in.Parrafo(fmt.Sprintf("Ningún valor de este informe proviene de un grupo con menos de %d encuestas. "+
+		"Los grupos pequeños se combinan en \"Otros\" y la edad se reporta solo por rango. "+
+		"Los comentarios abiertos no se incluyen.", k), 9.5, services.ColorTexto)
+	if d.Anonimato.Suprimido {
+		in.Parrafo("Elementos suprimidos o agrupados: "+strings.Join(d.Anonimato.Campos, ", ")+".", 8.5, services.ColorSuave)
+	}
+
+	in.Cerrar(strings.Join(d.Centros, ", ") + " · " + d.Periodo)
+	return in
+}
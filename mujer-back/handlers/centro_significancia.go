@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"mujer-back/services"
+)
+
+// GET /api/centro/comparar?por=year|genero|rango_edad&a=...&b=...
+// + los filtros de /api/centro/resumen (el del mismo criterio se ignora)
+//
+// Compara dos años, dos géneros o dos rangos de edad por dimensión y por
+// tipo con Welch t y Mann–Whitney U sobre el puntaje de cada encuesta,
+// d de Cohen, delta de Cliff y p corregidos por Holm. "a" es la referencia.
+// Si algún grupo tiene menos de k encuestas no se reportan pruebas.
+func (h CentroResultadosHandler) GetComparacionGrupos(w http.ResponseWriter, r *http.Request) {
+	if !h.ensureCentroRole(w, r) {
+		return
+	}
+
+	centros := UserCentrosFromCtx(r.Context())
+	if len(centros) == 0 {
+		http.Error(w, "no_centros", http.StatusForbidden)
+		return
+	}
+
+	q := r.URL.Query()
+	g := services.GruposComparacion{
+		Por: strings.ToLower(strings.TrimSpace(q.Get("por"))),
+		A:   strings.TrimSpace(q.Get("a")),
+		B:   strings.TrimSpace(q.Get("b")),
+	}
+	if g.Por == "" {
+		g.Por = services.CompararPorYear
+	}
+	if g.A == "" || g.B == "" {
+		http.Error(w, "grupos_required", http.StatusBadRequest)
+		return
+	}
+	if g.Por == services.CompararPorGenero {
+		g.A, g.B = strings.ToLower(g.A), strings.ToLower(g.B)
+	}
+
+	f, _, ok := h.filtroCompleto(w, r, centros)
+	if !ok {
+		return
+	}
+
+	if g.Por == services.CompararPorRangoEdad {
+		for _, v := range []string{g.A, g.B} {
+			existe := false
+			for _, rg := range f.RangosEdad() {
+				if rg.Label == v {
+					existe = true
+					break
+				}
+			}
+			if !existe {
+				http.Error(w, "bad_rango_edad", http.StatusBadRequest)
+				return
+			}
+		}
+	}
+
+	res, err := services.CompararGruposSignificancia(r.Context(), h.DB, f, g, h.k())
+	if errors.Is(err, services.ErrGrupoInvalido) {
+		http.Error(w, "bad_grupos", http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, services.ErrSinDatos) {
+		http.Error(w, "no_data", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "db_error", http.StatusInternalServerError)
+		return
+	}
+	writeJSONCentro(w, http.StatusOK, res)
+}
@@ -2,25 +2,38 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 	"strings"
 
 	"github.com/jackc/pgx/v5/pgxpool"
+
+	"mujer-back/services"
 )
 
 type CentrosHandler struct {
-	DB *pgxpool.Pool
+	DB       *pgxpool.Pool
+	Registro *services.Registro
 }
 
 type CentroDTO struct {
-	ID     int64  `json:"id"`
-	Tipo   string `json:"tipo"`
-	Nombre string `json:"nombre"`
-	Clave  string `json:"clave,omitempty"`
-	Ciudad string `json:"ciudad,omitempty"`
-	Estado string `json:"estado,omitempty"`
-	Activo bool   `json:"activo,omitempty"`
+	ID            int64  `json:"id"`
+	Tipo          string `json:"tipo"`
+	Nombre        string `json:"nombre"`
+	Clave         string `json:"clave,omitempty"`
+	Ciudad        string `json:"ciudad,omitempty"`
+	Estado        string `json:"estado,omitempty"`
+	Activo        bool   `json:"activo,omitempty"`
+	InstrumentoID string `json:"instrumento_id,omitempty"`
+
+	// vínculo a los catálogos INEGI (CVE_ENT / CVEGEO)
+	EstadoClave    string `json:"estado_clave,omitempty"`
+	MunicipioClave string `json:"municipio_clave,omitempty"`
+}
+
+type CentroInstrumentoRequest struct {
+	InstrumentoID string `json:"instrumento_id"` // "" = usar el default
 }
 
 type CentroUpsertRequest struct {
@@ -29,6 +42,24 @@ type CentroUpsertRequest struct {
 	Clave  string `json:"clave,omitempty"`
 	Ciudad string `json:"ciudad,omitempty"`
 	Estado string `json:"estado,omitempty"`
+
+	// CVEGEO del municipio; si no viene, se intenta ligar por estado/ciudad
+	MunicipioClave string `json:"municipio_clave,omitempty"`
+}
+
+// resolverGeografia liga el centro a los catálogos; escribe el error y
+// regresa false si municipio_clave no existe
+func (h CentrosHandler) resolverGeografia(w http.ResponseWriter, r *http.Request, req CentroUpsertRequest, estado, ciudad string) (string, string, bool) {
+	ent, mun, err := services.ResolverGeografia(r.Context(), h.DB, estado, ciudad, strings.TrimSpace(req.MunicipioClave))
+	if errors.Is(err, services.ErrMunicipioNoExiste) {
+		http.Error(w, "bad_municipio", http.StatusBadRequest)
+		return "", "", false
+	}
+	if err != nil {
+		http.Error(w, "db_error", http.StatusInternalServerError)
+		return "", "", false
+	}
+	return ent, mun, true
 }
 
 func normalizeCentroReq(req *CentroUpsertRequest) (tipo, nombre, clave, ciudad, estado string, errCode string) {
@@ -85,7 +116,8 @@ func (h CentrosHandler) List(w http.ResponseWriter, r *http.Request) {
 	args = append(args, limit)
 
 	sql := `
-		select id, tipo, nombre, coalesce(clave,''), coalesce(ciudad,''), coalesce(estado,'')
+		select id, tipo, nombre, coalesce(clave,''), coalesce(ciudad,''), coalesce(estado,''),
+		       coalesce(estado_clave,''), coalesce(municipio_clave,'')
 		from centros
 		where ` + strings.Join(where, " and ") + `
 		order by nombre asc
@@ -101,7 +133,7 @@ func (h CentrosHandler) List(w http.ResponseWriter, r *http.Request) {
 	out := make([]CentroDTO, 0, limit)
 	for rows.Next() {
 		var c CentroDTO
-		if err := rows.Scan(&c.ID, &c.Tipo, &c.Nombre, &c.Clave, &c.Ciudad, &c.Estado); err != nil {
+		if err := rows.Scan(&c.ID, &c.Tipo, &c.Nombre, &c.Clave, &c.Ciudad, &c.Estado, &c.EstadoClave, &c.MunicipioClave); err != nil {
 			http.Error(w, "db_error", http.StatusInternalServerError)
 			return
 		}
@@ -129,13 +161,17 @@ func (h CentrosHandler) Create(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, errCode, http.StatusBadRequest)
 		return
 	}
+	estadoClave, municipioClave, ok := h.resolverGeografia(w, r, req, estado, ciudad)
+	if !ok {
+		return
+	}
 
 	var id int64
 	err := h.DB.QueryRow(r.Context(), `
-		insert into centros (tipo, nombre, clave, ciudad, estado, activo)
-		values ($1, $2, nullif($3,''), nullif($4,''), nullif($5,''), true)
+		insert into centros (tipo, nombre, clave, ciudad, estado, activo, estado_clave, municipio_clave)
+		values ($1, $2, nullif($3,''), nullif($4,''), nullif($5,''), true, nullif($6,''), nullif($7,''))
 		returning id
-	`, tipo, nombre, clave, ciudad, estado).Scan(&id)
+	`, tipo, nombre, clave, ciudad, estado, estadoClave, municipioClave).Scan(&id)
 	if err != nil {
 		http.Error(w, "db_error", http.StatusInternalServerError)
 		return
@@ -151,6 +187,9 @@ func (h CentrosHandler) Create(w http.ResponseWriter, r *http.Request) {
 		Ciudad: ciudad,
 		Estado: estado,
 		Activo: true,
+
+		EstadoClave:    estadoClave,
+		MunicipioClave: municipioClave,
 	})
 }
 
@@ -158,10 +197,11 @@ func (h CentrosHandler) Create(w http.ResponseWriter, r *http.Request) {
 func (h CentrosHandler) GetByID(w http.ResponseWriter, r *http.Request, id int64) {
 	var c CentroDTO
 	err := h.DB.QueryRow(r.Context(), `
-		select id, tipo, nombre, coalesce(clave,''), coalesce(ciudad,''), coalesce(estado,''), activo
+		select id, tipo, nombre, coalesce(clave,''), coalesce(ciudad,''), coalesce(estado,''), activo, coalesce(instrumento_id,''),
+		       coalesce(estado_clave,''), coalesce(municipio_clave,'')
 		from centros
 		where id = $1
-	`, id).Scan(&c.ID, &c.Tipo, &c.Nombre, &c.Clave, &c.Ciudad, &c.Estado, &c.Activo)
+	`, id).Scan(&c.ID, &c.Tipo, &c.Nombre, &c.Clave, &c.Ciudad, &c.Estado, &c.Activo, &c.InstrumentoID, &c.EstadoClave, &c.MunicipioClave)
 
 	if err != nil {
 		http.Error(w, "centro_not_found", http.StatusNotFound)
@@ -185,6 +225,10 @@ func (h CentrosHandler) Update(w http.ResponseWriter, r *http.Request, id int64)
 		http.Error(w, errCode, http.StatusBadRequest)
 		return
 	}
+	estadoClave, municipioClave, ok := h.resolverGeografia(w, r, req, estado, ciudad)
+	if !ok {
+		return
+	}
 
 	ct, err := h.DB.Exec(r.Context(), `
 		update centros
@@ -192,9 +236,11 @@ func (h CentrosHandler) Update(w http.ResponseWriter, r *http.Request, id int64)
 		    nombre = $3,
 		    clave = nullif($4,''),
 		    ciudad = nullif($5,''),
-		    estado = nullif($6,'')
+		    estado = nullif($6,''),
+		    estado_clave = nullif($7,''),
+		    municipio_clave = nullif($8,'')
 		where id = $1
-	`, id, tipo, nombre, clave, ciudad, estado)
+	`, id, tipo, nombre, clave, ciudad, estado, estadoClave, municipioClave)
 	if err != nil {
 		http.Error(w, "db_error", http.StatusInternalServerError)
 		return
@@ -212,6 +258,9 @@ func (h CentrosHandler) Update(w http.ResponseWriter, r *http.Request, id int64)
 		Clave:  clave,
 		Ciudad: ciudad,
 		Estado: estado,
+
+		EstadoClave:    estadoClave,
+		MunicipioClave: municipioClave,
 	})
 }
 
@@ -233,3 +282,37 @@ func (h CentrosHandler) Delete(w http.ResponseWriter, r *http.Request, id int64)
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// ADMIN: fijar versión del instrumento
+// PUT /api/centros/{id}/instrumento
+func (h CentrosHandler) SetInstrumento(w http.ResponseWriter, r *http.Request, id int64) {
+	var req CentroInstrumentoRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "bad_json", http.StatusBadRequest)
+		return
+	}
+
+	instID := strings.TrimSpace(req.InstrumentoID)
+	if instID != "" {
+		if _, ok := h.Registro.Get(instID); !ok {
+			http.Error(w, "instrumento_not_found", http.StatusBadRequest)
+			return
+		}
+	}
+
+	ct, err := h.DB.Exec(r.Context(), `
+		update centros
+		set instrumento_id = nullif($2,'')
+		where id = $1
+	`, id, instID)
+	if err != nil {
+		http.Error(w, "db_error", http.StatusInternalServerError)
+		return
+	}
+	if ct.RowsAffected() == 0 {
+		http.Error(w, "centro_not_found", http.StatusNotFound)
+		return
+	}
+
+	h.GetByID(w, r, id)
+}
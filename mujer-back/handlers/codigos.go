@@ -0,0 +1,253 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Códigos de acceso por aplicación (campaña).
+// Solo se guarda el hash del código y un contador de usos: ni la encuesta
+// guarda qué código se usó ni el código guarda cuándo se usó.
+type CodigosHandler struct {
+	DB       *pgxpool.Pool
+	FrontURL string // base del front para armar los enlaces/QR (ej. https://mujeralerta.mx)
+}
+
+type GenerarCodigosRequest struct {
+	Cantidad int `json:"cantidad"` // 1..500
+	UsosMax  int `json:"usos_max"` // default 1 (un solo uso)
+}
+
+type CodigoDTO struct {
+	Codigo string `json:"codigo"`
+	Link   string `json:"link"` // para imprimir como QR
+}
+
+type GenerarCodigosResponse struct {
+	AplicacionID int64       `json:"aplicacion_id"`
+	Lote         string      `json:"lote"`
+	UsosMax      int         `json:"usos_max"`
+	Codigos      []CodigoDTO `json:"codigos"` // solo se muestran una vez
+}
+
+type LoteCodigosDTO struct {
+	Lote      string `json:"lote"`
+	Cantidad  int64  `json:"cantidad"`
+	UsosMax   int64  `json:"usos_max"`   // suma de usos permitidos del lote
+	Usos      int64  `json:"usos"`       // suma de usos consumidos
+	CreatedAt string `json:"created_at"` // fecha de generación del lote
+}
+
+// Sin 0/O ni 1/I/L para que se puedan dictar o copiar de un papel
+const codigoAlfabeto = "ABCDEFGHJKMNPQRSTUVWXYZ23456789"
+
+// nuevoCodigo genera XXXXX-XXXXX (~49 bits de entropía)
+func nuevoCodigo() (string, error) {
+	max := big.NewInt(int64(len(codigoAlfabeto)))
+	out := make([]byte, 0, 11)
+	for i := 0; i < 10; i++ {
+		if i == 5 {
+			out = append(out, '-')
+		}
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		out = append(out, codigoAlfabeto[n.Int64()])
+	}
+	return string(out), nil
+}
+
+// hashCodigo normaliza (mayúsculas, sin guiones/espacios) y regresa sha256 hex
+func hashCodigo(codigo string) string {
+	var sb strings.Builder
+	for _, c := range strings.ToUpper(codigo) {
+		if (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') {
+			sb.WriteRune(c)
+		}
+	}
+	sum := sha256.Sum256([]byte(sb.String()))
+	return hex.EncodeToString(sum[:])
+}
+
+// puedeGestionarAplicacion: admin siempre; centro solo si la aplicación es de sus centros
+func (h CodigosHandler) puedeGestionarAplicacion(r *http.Request, aplicacionID int64) (bool, error) {
+	var centroID int64
+	err := h.DB.QueryRow(r.Context(), `select centro_id from aplicaciones where id = $1`, aplicacionID).Scan(&centroID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	switch UserRolFromCtx(r.Context()) {
+	case "admin":
+		return true, nil
+	case "centro":
+		for _, c := range UserCentrosFromCtx(r.Context()) {
+			if c == centroID {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// POST /api/centro/aplicaciones/{id}/codigos
+func (h CodigosHandler) Generar(w http.ResponseWriter, r *http.Request, aplicacionID int64) {
+	ok, err := h.puedeGestionarAplicacion(r, aplicacionID)
+	if err != nil {
+		http.Error(w, "db_error", http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		http.Error(w, "aplicacion_not_found", http.StatusNotFound)
+		return
+	}
+
+	var req GenerarCodigosRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "bad_json", http.StatusBadRequest)
+		return
+	}
+	if req.UsosMax == 0 {
+		req.UsosMax = 1
+	}
+	if req.Cantidad < 1 || req.Cantidad > 500 || req.UsosMax < 1 || req.UsosMax > 1000 {
+		http.Error(w, "bad_request", http.StatusBadRequest)
+		return
+	}
+
+	lote, err := nuevoCodigo()
+	if err != nil {
+		http.Error(w, "rand_error", http.StatusInternalServerError)
+		return
+	}
+
+	ctx := r.Context()
+	tx, err := h.DB.Begin(ctx)
+	if err != nil {
+		http.Error(w, "db_error", http.StatusInternalServerError)
+		return
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	base := strings.TrimRight(h.FrontURL, "/")
+	out := GenerarCodigosResponse{
+		AplicacionID: aplicacionID,
+		Lote:         lote,
+		UsosMax:      req.UsosMax,
+		Codigos:      make([]CodigoDTO, 0, req.Cantidad),
+	}
+
+	batch := &pgx.Batch{}
+	for i := 0; i < req.Cantidad; i++ {
+		c, err := nuevoCodigo()
+		if err != nil {
+			http.Error(w, "rand_error", http.StatusInternalServerError)
+			return
+		}
+		batch.Queue(`
+			insert into codigos_acceso (aplicacion_id, codigo_hash, usos_max, lote, created_by)
+			values ($1, $2, $3, $4, nullif($5,'')::uuid)
+		`, aplicacionID, hashCodigo(c), req.UsosMax, lote, UserIDFromCtx(ctx))
+
+		out.Codigos = append(out.Codigos, CodigoDTO{
+			Codigo: c,
+			Link:   base + "/diagnostico?codigo=" + url.QueryEscape(c),
+		})
+	}
+
+	if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+		http.Error(w, "db_error", http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		http.Error(w, "db_error", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, out)
+}
+
+// GET /api/centro/aplicaciones/{id}/codigos (resumen por lote; los códigos no se pueden recuperar)
+func (h CodigosHandler) ListLotes(w http.ResponseWriter, r *http.Request, aplicacionID int64) {
+	ok, err := h.puedeGestionarAplicacion(r, aplicacionID)
+	if err != nil {
+		http.Error(w, "db_error", http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		http.Error(w, "aplicacion_not_found", http.StatusNotFound)
+		return
+	}
+
+	rows, err := h.DB.Query(r.Context(), `
+		select lote, count(*), sum(usos_max), sum(usos), min(created_at)::date::text
+		from codigos_acceso
+		where aplicacion_id = $1
+		group by lote
+		order by min(created_at) desc
+	`, aplicacionID)
+	if err != nil {
+		http.Error(w, "db_error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	out := make([]LoteCodigosDTO, 0, 8)
+	for rows.Next() {
+		var it LoteCodigosDTO
+		if err := rows.Scan(&it.Lote, &it.Cantidad, &it.UsosMax, &it.Usos, &it.CreatedAt); err != nil {
+			http.Error(w, "db_error", http.StatusInternalServerError)
+			return
+		}
+		out = append(out, it)
+	}
+	if rows.Err() != nil {
+		http.Error(w, "db_error", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, out)
+}
+
+// DELETE /api/centro/aplicaciones/{id}/codigos/{lote} (revoca: agota los usos restantes)
+func (h CodigosHandler) RevocarLote(w http.ResponseWriter, r *http.Request, aplicacionID int64, lote string) {
+	ok, err := h.puedeGestionarAplicacion(r, aplicacionID)
+	if err != nil {
+		http.Error(w, "db_error", http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		http.Error(w, "aplicacion_not_found", http.StatusNotFound)
+		return
+	}
+
+	ct, err := h.DB.Exec(r.Context(), `
+		update codigos_acceso
+		set usos = usos_max
+		where aplicacion_id = $1 and lote = $2
+	`, aplicacionID, lote)
+	if err != nil {
+		http.Error(w, "db_error", http.StatusInternalServerError)
+		return
+	}
+	if ct.RowsAffected() == 0 {
+		http.Error(w, "lote_not_found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
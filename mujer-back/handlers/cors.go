@@ -6,6 +6,7 @@ type CORSOptions struct {
 	AllowedOrigins []string
 	AllowedMethods string
 	AllowedHeaders string
+	ExposedHeaders string // ej. Content-Disposition para descargas
 }
 
 func CORS(next http.Handler, opt CORSOptions) http.Handler {
@@ -28,6 +29,9 @@ func CORS(next http.Handler, opt CORSOptions) http.Handler {
 			w.Header().Set("Access-Control-Allow-Methods", opt.AllowedMethods)
 			w.Header().Set("Access-Control-Allow-Headers", opt.AllowedHeaders)
 			w.Header().Set("Access-Control-Allow-Credentials", "true")
+			if opt.ExposedHeaders != "" {
+				w.Header().Set("Access-Control-Expose-Headers", opt.ExposedHeaders)
+			}
 		}
 
 		if r.Method == http.MethodOptions {
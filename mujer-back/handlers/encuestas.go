@@ -2,25 +2,34 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strings"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
+
+	"mujer-back/services"
 )
 
 type EncuestasHandler struct {
-	DB *pgxpool.Pool
+	DB       *pgxpool.Pool
+	Registro *services.Registro
 }
 
 type CreateEncuestaRequest struct {
-	CentroID int64  `json:"centro_id"`
+	CentroID int64  `json:"centro_id,omitempty"` // opcional: el centro sale del código
 	Email    string `json:"email,omitempty"`
 	GeneroID int64  `json:"genero_id"`
 	Edad     int16  `json:"edad"`
+	Codigo   string `json:"codigo"` // código de acceso de la aplicación
 }
 
 type CreateEncuestaResponse struct {
-	EncuestaID string `json:"encuesta_id"`
+	EncuestaID    string `json:"encuesta_id"`
+	InstrumentoID string `json:"instrumento_id"`
+	AplicacionID  int64  `json:"aplicacion_id"`
+	ResumeToken   string `json:"resume_token"` // para guardar avance y retomar (header X-Resume-Token)
 }
 
 func (h EncuestasHandler) Create(w http.ResponseWriter, r *http.Request) {
@@ -30,29 +39,138 @@ func (h EncuestasHandler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if req.CentroID <= 0 || req.GeneroID <= 0 || req.Edad < 10 || req.Edad > 120 {
+	if req.CentroID < 0 || req.GeneroID <= 0 || req.Edad < 10 || req.Edad > 120 {
 		http.Error(w, "bad_request", http.StatusBadRequest)
 		return
 	}
+	if strings.TrimSpace(req.Codigo) == "" {
+		http.Error(w, "codigo_required", http.StatusBadRequest)
+		return
+	}
 
 	email := strings.TrimSpace(req.Email)
 	if email == "" {
 		email = ""
 	}
 
+	ctx := r.Context()
+	tx, err := h.DB.Begin(ctx)
+	if err != nil {
+		http.Error(w, "db_error", http.StatusInternalServerError)
+		return
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	// Se consume un uso del código. Solo vale dentro de una aplicación (campaña)
+	// abierta y vigente; centro e instrumento salen de esa aplicación.
+	// El consumo y el insert van en la misma transacción: si algo falla antes
+	// del commit el uso no se pierde. La encuesta no guarda ninguna
+	// referencia al código (anonimato).
+	var aplicacionID, centroID int64
+	var instID string
+	err = tx.QueryRow(ctx, `
+		update codigos_acceso k
+		set usos = k.usos + 1
+		from aplicaciones a
+		join centros c on c.id = a.centro_id
+		where k.codigo_hash = $1
+		  and k.usos < k.usos_max
+		  and a.id = k.aplicacion_id
+		  and c.activo = true
+		  and a.estado = 'abierta'
+		  and current_date between a.fecha_inicio and a.fecha_fin
+		returning a.id, a.centro_id, a.instrumento_id
+	`, hashCodigo(req.Codigo)).Scan(&aplicacionID, &centroID, &instID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, "codigo_invalido", http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		http.Error(w, "db_error", http.StatusInternalServerError)
+		return
+	}
+	if req.CentroID != 0 && req.CentroID != centroID {
+		http.Error(w, "codigo_otro_centro", http.StatusForbidden)
+		return
+	}
+
+	inst, ok := h.Registro.Get(instID)
+	if !ok {
+		http.Error(w, "instrumento_not_available", http.StatusConflict)
+		return
+	}
+
+	resumeToken, err := nuevoResumeToken()
+	if err != nil {
+		http.Error(w, "rand_error", http.StatusInternalServerError)
+		return
+	}
+
 	var id string
-	err := h.DB.QueryRow(r.Context(), `
-		insert into encuestas (centro_id, email, genero_id, edad)
-		values ($1, nullif($2,''), $3, $4)
+	err = tx.QueryRow(ctx, `
+		insert into encuestas (centro_id, email, genero_id, edad, instrumento_id, aplicacion_id, resume_token_hash)
+		values ($1, nullif($2,''), $3, $4, $5, $6, $7)
 		returning id::text
-	`, req.CentroID, email, req.GeneroID, req.Edad).Scan(&id)
-
+	`, centroID, email, req.GeneroID, req.Edad, inst.ID, aplicacionID, hashResumeToken(resumeToken)).Scan(&id)
 	if err != nil {
 		http.Error(w, "db_error", http.StatusInternalServerError)
 		return
 	}
 
+	if err := tx.Commit(ctx); err != nil {
+		http.Error(w, "db_error", http.StatusInternalServerError)
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	w.WriteHeader(http.StatusCreated)
-	_ = json.NewEncoder(w).Encode(CreateEncuestaResponse{EncuestaID: id})
+	_ = json.NewEncoder(w).Encode(CreateEncuestaResponse{
+		EncuestaID:    id,
+		InstrumentoID: inst.ID,
+		AplicacionID:  aplicacionID,
+		ResumeToken:   resumeToken,
+	})
+}
+
+// GET /api/encuestas/{id}/instrumento
+// Regresa el instrumento con el que se está respondiendo esa encuesta.
+func (h EncuestasHandler) GetInstrumento(w http.ResponseWriter, r *http.Request, encuestaID string) {
+	inst, ok, err := instrumentoDeEncuesta(r, h.DB, h.Registro, encuestaID)
+	if errors.Is(err, errInstrumentoNoDisponible) {
+		http.Error(w, "instrumento_not_available", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "db_error", http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		http.Error(w, "encuesta_not_found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	_ = json.NewEncoder(w).Encode(inst)
+}
+
+var errInstrumentoNoDisponible = errors.New("instrumento_not_available")
+
+// instrumentoDeEncuesta busca la versión con la que se tomó la encuesta.
+// ok=false si la encuesta no existe; errInstrumentoNoDisponible si su
+// instrumento ya no está cargado en el registro.
+func instrumentoDeEncuesta(r *http.Request, db *pgxpool.Pool, reg *services.Registro, encuestaID string) (services.Instrumento, bool, error) {
+	var instID string
+	err := db.QueryRow(r.Context(), `select instrumento_id from encuestas where id = $1`, encuestaID).Scan(&instID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return services.Instrumento{}, false, nil
+	}
+	if err != nil {
+		return services.Instrumento{}, false, err
+	}
+
+	inst, ok := reg.Get(instID)
+	if !ok {
+		return services.Instrumento{}, true, errInstrumentoNoDisponible
+	}
+	return inst, true, nil
 }
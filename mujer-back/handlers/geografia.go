@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"mujer-back/services"
+)
+
+// Catálogos INEGI (estados/municipios), vínculo de centros y mapas GeoJSON
+// con los índices agregados por región (admin)
+type GeografiaHandler struct {
+	DB *pgxpool.Pool
+	K  int // umbral de k-anonimato (ANONIMATO_K); 0 = services.AnonimatoKDefault
+}
+
+// límite del GeoJSON importado (los municipios de INEGI simplificados pesan decenas de MB)
+const maxGeoJSONBytes = 64 << 20
+
+var claveEstadoRe = regexp.MustCompile(`^[0-9]{2}$`)
+
+func (h GeografiaHandler) k() int {
+	if h.K > 0 {
+		return h.K
+	}
+	return services.AnonimatoKDefault
+}
+
+// parseEstado lee ?estado= como CVE_ENT ("09") o por nombre ("CDMX")
+func (h GeografiaHandler) parseEstado(w http.ResponseWriter, r *http.Request) (string, bool) {
+	s := strings.TrimSpace(r.URL.Query().Get("estado"))
+	if s == "" || claveEstadoRe.MatchString(s) {
+		return s, true
+	}
+	ent, _, err := services.ResolverGeografia(r.Context(), h.DB, s, "", "")
+	if err != nil {
+		http.Error(w, "db_error", http.StatusInternalServerError)
+		return "", false
+	}
+	if ent == "" {
+		http.Error(w, "bad_estado", http.StatusBadRequest)
+		return "", false
+	}
+	return ent, true
+}
+
+// GET /api/admin/geografia/estados
+func (h GeografiaHandler) Estados(w http.ResponseWriter, r *http.Request) {
+	out, err := services.ListarEstados(r.Context(), h.DB)
+	if err != nil {
+		http.Error(w, "db_error", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// GET /api/admin/geografia/municipios?estado=CVE_ENT
+func (h GeografiaHandler) Municipios(w http.ResponseWriter, r *http.Request) {
+	estado, ok := h.parseEstado(w, r)
+	if !ok {
+		return
+	}
+	if estado == "" {
+		http.Error(w, "estado_required", http.StatusBadRequest)
+		return
+	}
+	out, err := services.ListarMunicipios(r.Context(), h.DB, estado)
+	if err != nil {
+		http.Error(w, "db_error", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// POST /api/admin/geografia/importar?nivel=estados|municipios
+// Body: FeatureCollection del Marco Geoestadístico (propiedades CVE_ENT,
+// CVE_MUN / CVEGEO, NOMGEO). Al terminar vuelve a vincular centros.
+func (h GeografiaHandler) Importar(w http.ResponseWriter, r *http.Request) {
+	nivel := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("nivel")))
+	if nivel != services.NivelEstados && nivel != services.NivelMunicipios {
+		http.Error(w, "bad_nivel", http.StatusBadRequest)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxGeoJSONBytes)
+	res, err := services.ImportarGeoJSON(r.Context(), h.DB, nivel, r.Body)
+	if errors.Is(err, services.ErrGeoJSONInvalido) {
+		http.Error(w, "bad_geojson", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, "db_error", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+// POST /api/admin/geografia/vincular
+// Liga por nombre los centros que aún no tienen estado/municipio.
+func (h GeografiaHandler) Vincular(w http.ResponseWriter, r *http.Request) {
+	res, err := services.VincularCentros(r.Context(), h.DB)
+	if err != nil {
+		http.Error(w, "db_error", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+// GET /api/admin/mapa/estados?tipo= &year= &desde= &hasta=
+// GET /api/admin/mapa/municipios?estado= + los mismos filtros
+// FeatureCollection (application/geo+json) con frecuencia, normalidad,
+// gravedad y total por región. Las regiones con menos de k encuestas
+// salen con los índices nulos y suprimido=true.
+func (h GeografiaHandler) Mapa(w http.ResponseWriter, r *http.Request, nivel string) {
+	fb, ok := parseFiltroBenchmark(w, r)
+	if !ok {
+		return
+	}
+	estado, ok := h.parseEstado(w, r)
+	if !ok {
+		return
+	}
+
+	f := services.FiltroMapa{
+		Nivel:  nivel,
+		Estado: estado,
+		Tipo:   fb.Tipo,
+		Year:   fb.Year,
+		Desde:  fb.Desde,
+		Hasta:  fb.Hasta,
+	}
+	fc, err := services.MapaRegiones(r.Context(), h.DB, f, h.k())
+	if err != nil {
+		http.Error(w, "db_error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/geo+json; charset=utf-8")
+	_ = json.NewEncoder(w).Encode(fc)
+}
@@ -3,13 +3,41 @@ package handlers
 import (
 	"encoding/json"
 	"net/http"
+	"strings"
+
+	"mujer-back/services"
 )
 
 type InstrumentoHandler struct {
-	Data any
+	Registro *services.Registro
 }
 
+// GET /api/instrumento (instrumento default, compatibilidad)
 func (h InstrumentoHandler) Get(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
-	_ = json.NewEncoder(w).Encode(h.Data)
+	_ = json.NewEncoder(w).Encode(h.Registro.Default())
+}
+
+// GET /api/instrumentos
+func (h InstrumentoHandler) List(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	_ = json.NewEncoder(w).Encode(h.Registro.List())
+}
+
+// GET /api/instrumentos/{id}
+func (h InstrumentoHandler) GetByPath(w http.ResponseWriter, r *http.Request) {
+	id := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/instrumentos/"), "/")
+	if id == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	inst, ok := h.Registro.Get(id)
+	if !ok {
+		http.Error(w, "instrumento_not_found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	_ = json.NewEncoder(w).Encode(inst)
 }
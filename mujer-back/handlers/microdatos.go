@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"mujer-back/services"
+)
+
+// Microdatos anonimizados para investigación externa (solo admin).
+// GET /api/admin/microdatos?format=csv|sav|parquet
+//
+//	&instrumento= (default: el instrumento default)
+//	&year= &centro_tipo=escolar|laboral &bands=clave
+type MicrodatosHandler struct {
+	DB       *pgxpool.Pool
+	Registro *services.Registro
+	K        int // umbral de k-anonimato (ANONIMATO_K)
+}
+
+func (h MicrodatosHandler) Export(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	format := strings.ToLower(strings.TrimSpace(q.Get("format")))
+	if format == "" {
+		format = "csv"
+	}
+	if format != "csv" && format != "sav" && format != "parquet" {
+		http.Error(w, "bad_format", http.StatusBadRequest)
+		return
+	}
+
+	f := services.FiltroMicrodatos{K: h.K, Instrumento: h.Registro.Default()}
+	if id := strings.TrimSpace(q.Get("instrumento")); id != "" {
+		inst, ok := h.Registro.Get(id)
+		if !ok {
+			http.Error(w, "bad_instrumento", http.StatusBadRequest)
+			return
+		}
+		f.Instrumento = inst
+	}
+
+	if ys := strings.TrimSpace(q.Get("year")); ys != "" {
+		yi, err := strconv.Atoi(ys)
+		if err != nil {
+			http.Error(w, "bad_year", http.StatusBadRequest)
+			return
+		}
+		f.Year = &yi
+	}
+
+	f.CentroTipo = strings.ToLower(strings.TrimSpace(q.Get("centro_tipo")))
+	if f.CentroTipo != "" && f.CentroTipo != "escolar" && f.CentroTipo != "laboral" {
+		http.Error(w, "bad_tipo", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+
+	// ?bands= aplica un mismo conjunto a todos; sin él, el default de cada tipo
+	if clave := strings.TrimSpace(q.Get("bands")); clave != "" {
+		_, rangos, err := services.CargarRangosEdad(ctx, h.DB, nil, clave)
+		if errors.Is(err, services.ErrRangosEdadNoExiste) {
+			http.Error(w, "bad_bands", http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			http.Error(w, "db_error", http.StatusInternalServerError)
+			return
+		}
+		f.Rangos = rangos
+	}
+
+	m, err := services.ExtraerMicrodatos(ctx, h.DB, f)
+	if err != nil {
+		http.Error(w, "db_error", http.StatusInternalServerError)
+		return
+	}
+	if len(m.Filas) == 0 {
+		http.Error(w, "no_data", http.StatusNotFound)
+		return
+	}
+
+	var buf bytes.Buffer
+	contentType := "text/csv; charset=utf-8"
+	switch format {
+	case "sav":
+		contentType = "application/x-spss-sav"
+		err = services.EscribirSAV(&buf, m, "Microdatos "+f.Instrumento.Name)
+	case "parquet":
+		contentType = "application/vnd.apache.parquet"
+		err = services.EscribirParquet(&buf, m)
+	default:
+		err = services.EscribirCSV(&buf, m.Hoja())
+	}
+	if err != nil {
+		http.Error(w, "export_error", http.StatusInternalServerError)
+		return
+	}
+
+	// lo que se quitó viaja en headers para que quien descarga lo sepa
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="`+f.NombreArchivo()+"."+format+`"`)
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	w.Header().Set("X-Microdatos-Filas", strconv.Itoa(len(m.Filas)))
+	w.Header().Set("X-Microdatos-Centros-Suprimidos", strconv.Itoa(m.CentrosSuprimidos))
+	w.Header().Set("X-Microdatos-Generalizadas", strconv.Itoa(m.CeldasGeneralizadas))
+	w.WriteHeader(http.StatusOK)
+	_, _ = buf.WriteTo(w)
+}
@@ -0,0 +1,302 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"mujer-back/services"
+)
+
+// Cola de moderación de comentarios (solo admin)
+type ModeracionHandler struct {
+	DB *pgxpool.Pool
+}
+
+// id de encuesta en la ruta; se revisa antes del $1::uuid de las consultas
+var encuestaIDRe = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+type ComentarioModeracionDTO struct {
+	EncuestaID     string              `json:"encuesta_id"`
+	CentroID       int64               `json:"centro_id"`
+	CentroNombre   string              `json:"centro_nombre"`
+	Fecha          string              `json:"fecha"` // YYYY-MM-DD
+	Estado         string              `json:"estado"`
+	Texto          string              `json:"texto"` // original
+	TextoPublicado *string             `json:"texto_publicado,omitempty"`
+	Hallazgos      []services.Hallazgo `json:"hallazgos"`
+	Sugerido       string              `json:"sugerido"` // texto con los hallazgos tachados
+	MD5            string              `json:"md5"`      // para detectar cambios entre listar y revisar
+	RevisadoPor    string              `json:"revisado_por,omitempty"`
+	RevisadoAt     string              `json:"revisado_at,omitempty"`
+	UpdatedAt      string              `json:"updated_at"`
+}
+
+type ModeracionListResponse struct {
+	Items   []ComentarioModeracionDTO `json:"items"`
+	Total   int64                     `json:"total"`
+	Conteos map[string]int64          `json:"conteos"` // por estado (todos los centros del filtro)
+}
+
+type RevisarComentarioRequest struct {
+	Estado         string  `json:"estado"`                    // aprobado|redactado|oculto|pendiente
+	TextoPublicado *string `json:"texto_publicado,omitempty"` // redactado: vacío = sugerencia automática
+	MD5            string  `json:"md5,omitempty"`             // opcional: 409 si el comentario cambió
+}
+
+const comentarioModeracionSelect = `
+	select
+		e.id::text,
+		e.centro_id,
+		c.nombre,
+		to_char(e.finished_at, 'YYYY-MM-DD'),
+		m.estado,
+		e.comentario,
+		m.texto_publicado,
+		m.original_md5,
+		coalesce(u.email, ''),
+		coalesce(to_char(m.revisado_at, 'YYYY-MM-DD HH24:MI'), ''),
+		to_char(m.updated_at, 'YYYY-MM-DD HH24:MI')
+	from comentarios_moderacion m
+	join encuestas e on e.id = m.encuesta_id
+	join centros c on c.id = e.centro_id
+	left join usuarios u on u.id = m.revisado_por
+`
+
+func scanComentarioModeracion(row pgx.Row, it *ComentarioModeracionDTO) error {
+	if err := row.Scan(
+		&it.EncuestaID, &it.CentroID, &it.CentroNombre, &it.Fecha, &it.Estado, &it.Texto,
+		&it.TextoPublicado, &it.MD5, &it.RevisadoPor, &it.RevisadoAt, &it.UpdatedAt,
+	); err != nil {
+		return err
+	}
+	it.Hallazgos = services.DetectarPII(it.Texto)
+	it.Sugerido = services.RedactarPII(it.Texto, it.Hallazgos)
+	return nil
+}
+
+// GET /api/admin/comentarios?estado=pendiente|aprobado|redactado|oculto|todos
+//
+//	&centro_id= &limit=50 &offset=0
+//
+// Solo encuestas finalizadas; las más antiguas primero (cola).
+func (h ModeracionHandler) List(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	estado := strings.ToLower(strings.TrimSpace(q.Get("estado")))
+	if estado == "" {
+		estado = services.ModeracionPendiente
+	}
+	if estado != "todos" && !services.EstadoModeracionValido(estado) {
+		http.Error(w, "bad_estado", http.StatusBadRequest)
+		return
+	}
+
+	limit := 50
+	if v := strings.TrimSpace(q.Get("limit")); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 200 {
+			http.Error(w, "bad_limit", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+	offset := 0
+	if v := strings.TrimSpace(q.Get("offset")); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			http.Error(w, "bad_offset", http.StatusBadRequest)
+			return
+		}
+		offset = n
+	}
+
+	var centroID *int64
+	if v := strings.TrimSpace(q.Get("centro_id")); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n <= 0 {
+			http.Error(w, "bad_centro_id", http.StatusBadRequest)
+			return
+		}
+		centroID = &n
+	}
+
+	ctx := r.Context()
+	base := `
+		where e.finished_at is not null
+		  and ($1::bigint is null or e.centro_id = $1)`
+
+	resp := ModeracionListResponse{Items: []ComentarioModeracionDTO{}, Conteos: map[string]int64{}}
+	for _, s := range []string{services.ModeracionPendiente, services.ModeracionAprobado, services.ModeracionRedactado, services.ModeracionOculto} {
+		resp.Conteos[s] = 0
+	}
+
+	rows, err := h.DB.Query(ctx, `
+		select m.estado, count(*)
+		from comentarios_moderacion m
+		join encuestas e on e.id = m.encuesta_id
+		`+base+`
+		group by m.estado
+	`, centroID)
+	if err != nil {
+		http.Error(w, "db_error", http.StatusInternalServerError)
+		return
+	}
+	for rows.Next() {
+		var s string
+		var n int64
+		if err := rows.Scan(&s, &n); err != nil {
+			rows.Close()
+			http.Error(w, "db_error", http.StatusInternalServerError)
+			return
+		}
+		resp.Conteos[s] = n
+	}
+	rows.Close()
+	if rows.Err() != nil {
+		http.Error(w, "db_error", http.StatusInternalServerError)
+		return
+	}
+
+	if estado == "todos" {
+		for _, n := range resp.Conteos {
+			resp.Total += n
+		}
+	} else {
+		resp.Total = resp.Conteos[estado]
+	}
+
+	rows, err = h.DB.Query(ctx, comentarioModeracionSelect+base+`
+		  and ($2 = 'todos' or m.estado = $2)
+		order by e.finished_at asc, e.id
+		limit $3 offset $4
+	`, centroID, estado, limit, offset)
+	if err != nil {
+		http.Error(w, "db_error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var it ComentarioModeracionDTO
+		if err := scanComentarioModeracion(rows, &it); err != nil {
+			http.Error(w, "db_error", http.StatusInternalServerError)
+			return
+		}
+		resp.Items = append(resp.Items, it)
+	}
+	if rows.Err() != nil {
+		http.Error(w, "db_error", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// PUT /api/admin/comentarios/{encuesta_id}
+// body: { "estado": "redactado", "texto_publicado": "...", "md5": "..." }
+func (h ModeracionHandler) Revisar(w http.ResponseWriter, r *http.Request, encuestaID string) {
+	if !encuestaIDRe.MatchString(encuestaID) {
+		http.Error(w, "bad_id", http.StatusBadRequest)
+		return
+	}
+
+	var req RevisarComentarioRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "bad_json", http.StatusBadRequest)
+		return
+	}
+	req.Estado = strings.ToLower(strings.TrimSpace(req.Estado))
+	if !services.EstadoModeracionValido(req.Estado) {
+		http.Error(w, "bad_estado", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	tx, err := h.DB.Begin(ctx)
+	if err != nil {
+		http.Error(w, "db_error", http.StatusInternalServerError)
+		return
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	// bloquea la fila: un comentario editado a media revisión no se aprueba a ciegas
+	var it ComentarioModeracionDTO
+	err = scanComentarioModeracion(tx.QueryRow(ctx, comentarioModeracionSelect+`
+		where m.encuesta_id = $1::uuid
+		for update of m
+	`, encuestaID), &it)
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, "comentario_not_found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "db_error", http.StatusInternalServerError)
+		return
+	}
+	if req.MD5 != "" && req.MD5 != it.MD5 {
+		http.Error(w, "comentario_modificado", http.StatusConflict)
+		return
+	}
+
+	// lo que verá el centro: el original al aprobar, el texto tachado al redactar
+	var publicado *string
+	switch req.Estado {
+	case services.ModeracionAprobado:
+		publicado = &it.Texto
+	case services.ModeracionRedactado:
+		t := ""
+		if req.TextoPublicado != nil {
+			t = strings.TrimSpace(*req.TextoPublicado)
+		}
+		if t == "" {
+			t = it.Sugerido
+		}
+		if len([]rune(t)) > 2000 {
+			http.Error(w, "bad_texto_publicado", http.StatusBadRequest)
+			return
+		}
+		publicado = &t
+	}
+
+	// pendiente = devolver a la cola (sin revisión)
+	var revisor *string
+	if req.Estado != services.ModeracionPendiente {
+		if id := UserIDFromCtx(ctx); id != "" {
+			revisor = &id
+		}
+	}
+
+	if _, err := tx.Exec(ctx, `
+		update comentarios_moderacion
+		set
+			estado = $2,
+			texto_publicado = $3,
+			revisado_por = $4::uuid,
+			revisado_at = case when $4::uuid is null then null else now() end,
+			updated_at = now()
+		where encuesta_id = $1::uuid
+	`, encuestaID, req.Estado, publicado, revisor); err != nil {
+		http.Error(w, "db_error", http.StatusInternalServerError)
+		return
+	}
+
+	if err := scanComentarioModeracion(tx.QueryRow(ctx, comentarioModeracionSelect+`
+		where m.encuesta_id = $1::uuid
+	`, encuestaID), &it); err != nil {
+		http.Error(w, "db_error", http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		http.Error(w, "db_error", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, it)
+}
@@ -0,0 +1,328 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+
+	"mujer-back/services"
+)
+
+// =======================================================
+// Avance parcial de una encuesta
+// Todas las rutas piden el header X-Resume-Token que regresó POST /api/encuestas.
+//
+// GET  /api/encuestas/{id}/progreso   → respuestas guardadas + faltantes
+// PUT  /api/encuestas/{id}/respuestas → upsert de cualquier subconjunto de tarjetas
+// POST /api/encuestas/{id}/finalizar  → valida completitud y marca finished_at
+// =======================================================
+
+type GuardarParcialRequest struct {
+	Respuestas []RespuestaItem `json:"respuestas"`
+	Comentario *string         `json:"comentario,omitempty"`
+}
+
+type FinalizarRequest struct {
+	Comentario *string `json:"comentario,omitempty"`
+}
+
+type ProgresoResponse struct {
+	EncuestaID    string          `json:"encuesta_id"`
+	InstrumentoID string          `json:"instrumento_id"`
+	Respuestas    []RespuestaItem `json:"respuestas"`
+	Comentario    string          `json:"comentario,omitempty"`
+	Respondidas   int             `json:"respondidas"`
+	Esperadas     int             `json:"esperadas"`
+	Faltantes     []string        `json:"faltantes"` // "P3|gravedad"
+	Finalizada    bool            `json:"finalizada"`
+}
+
+type FinalizarErrorResponse struct {
+	Error     string   `json:"error"`
+	Faltantes []string `json:"faltantes"`
+}
+
+func nuevoResumeToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashResumeToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// encuestaParcial es el estado mínimo que necesitan las rutas de avance
+type encuestaParcial struct {
+	Inst       services.Instrumento
+	Finalizada bool
+}
+
+// cargarEncuestaParcial valida el token y regresa el instrumento de la encuesta.
+// Si algo falla ya respondió al cliente y regresa ok=false.
+func (h RespuestasHandler) cargarEncuestaParcial(w http.ResponseWriter, r *http.Request, encuestaID string) (encuestaParcial, bool) {
+	token := strings.TrimSpace(r.Header.Get("X-Resume-Token"))
+	if token == "" {
+		http.Error(w, "missing_resume_token", http.StatusUnauthorized)
+		return encuestaParcial{}, false
+	}
+
+	var instID, tokenHash string
+	var finalizada bool
+	err := h.DB.QueryRow(r.Context(), `
+		select instrumento_id, coalesce(resume_token_hash, ''), finished_at is not null
+		from encuestas
+		where id = $1
+	`, encuestaID).Scan(&instID, &tokenHash, &finalizada)
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, "encuesta_not_found", http.StatusNotFound)
+		return encuestaParcial{}, false
+	}
+	if err != nil {
+		http.Error(w, "db_error", http.StatusInternalServerError)
+		return encuestaParcial{}, false
+	}
+
+	// Mismo error para "sin token" y "token incorrecto": no revela qué encuestas existen
+	if tokenHash == "" || subtle.ConstantTimeCompare([]byte(tokenHash), []byte(hashResumeToken(token))) != 1 {
+		http.Error(w, "encuesta_not_found", http.StatusNotFound)
+		return encuestaParcial{}, false
+	}
+
+	inst, ok := h.Registro.Get(instID)
+	if !ok {
+		http.Error(w, "instrumento_not_available", http.StatusConflict)
+		return encuestaParcial{}, false
+	}
+
+	return encuestaParcial{Inst: inst, Finalizada: finalizada}, true
+}
+
+// respondidas regresa las respuestas guardadas y el set "P|dim"
+func (h RespuestasHandler) respondidas(r *http.Request, encuestaID string) ([]RespuestaItem, map[string]bool, error) {
+	rows, err := h.DB.Query(r.Context(), `
+		select pregunta_id, dimension::text, valor
+		from respuestas
+		where encuesta_id = $1
+		order by id
+	`, encuestaID)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	items := make([]RespuestaItem, 0, 64)
+	set := make(map[string]bool, 64)
+	for rows.Next() {
+		var it RespuestaItem
+		if err := rows.Scan(&it.PreguntaID, &it.Dimension, &it.Valor); err != nil {
+			return nil, nil, err
+		}
+		items = append(items, it)
+		set[it.PreguntaID+"|"+it.Dimension] = true
+	}
+	return items, set, rows.Err()
+}
+
+// GET /api/encuestas/{id}/progreso
+func (h RespuestasHandler) Progreso(w http.ResponseWriter, r *http.Request, encuestaID string) {
+	ep, ok := h.cargarEncuestaParcial(w, r, encuestaID)
+	if !ok {
+		return
+	}
+
+	items, set, err := h.respondidas(r, encuestaID)
+	if err != nil {
+		http.Error(w, "db_error", http.StatusInternalServerError)
+		return
+	}
+
+	var comentario string
+	if err := h.DB.QueryRow(r.Context(), `select coalesce(comentario, '') from encuestas where id = $1`, encuestaID).Scan(&comentario); err != nil {
+		http.Error(w, "db_error", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, ProgresoResponse{
+		EncuestaID:    encuestaID,
+		InstrumentoID: ep.Inst.ID,
+		Respuestas:    items,
+		Comentario:    comentario,
+		Respondidas:   len(items),
+		Esperadas:     ep.Inst.TotalRespuestasEsperadas(),
+		Faltantes:     ep.Inst.Faltantes(set),
+		Finalizada:    ep.Finalizada,
+	})
+}
+
+// PUT /api/encuestas/{id}/respuestas
+func (h RespuestasHandler) GuardarParcial(w http.ResponseWriter, r *http.Request, encuestaID string) {
+	ep, ok := h.cargarEncuestaParcial(w, r, encuestaID)
+	if !ok {
+		return
+	}
+	if ep.Finalizada {
+		http.Error(w, "encuesta_finalizada", http.StatusConflict)
+		return
+	}
+
+	var req GuardarParcialRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "bad_json", http.StatusBadRequest)
+		return
+	}
+	if len(req.Respuestas) == 0 && req.Comentario == nil {
+		http.Error(w, "bad_request", http.StatusBadRequest)
+		return
+	}
+
+	comentario, errCode := normalizarComentario(req.Comentario)
+	if errCode != "" {
+		http.Error(w, errCode, http.StatusBadRequest)
+		return
+	}
+	items, errCode := validarRespuestas(ep.Inst, req.Respuestas)
+	if errCode != "" {
+		http.Error(w, errCode, http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	tx, err := h.DB.Begin(ctx)
+	if err != nil {
+		http.Error(w, "db_error", http.StatusInternalServerError)
+		return
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	// Bloquea la encuesta antes de tocar respuestas (mismo orden que
+	// /finalizar y POST /respuestas): si otro la finalizó entre la revisión
+	// de arriba y aquí, sus respuestas ya están en los agregados
+	var finalizada bool
+	if err := tx.QueryRow(ctx, `
+		select finished_at is not null from encuestas where id = $1 for update
+	`, encuestaID).Scan(&finalizada); err != nil {
+		http.Error(w, "db_error", http.StatusInternalServerError)
+		return
+	}
+	if finalizada {
+		http.Error(w, "encuesta_finalizada", http.StatusConflict)
+		return
+	}
+
+	batch := &pgx.Batch{}
+	queueRespuestas(batch, encuestaID, items)
+	if comentario != nil {
+		batch.Queue(`update encuestas set comentario = $2 where id = $1`, encuestaID, *comentario)
+	}
+	if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+		http.Error(w, "db_error", http.StatusInternalServerError)
+		return
+	}
+	if comentario != nil {
+		if err := services.EncolarComentario(ctx, tx, encuestaID); err != nil {
+			http.Error(w, "db_error", http.StatusInternalServerError)
+			return
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		http.Error(w, "db_error", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, SaveRespuestasResponse{Ok: true, Inserted: len(items)})
+}
+
+// POST /api/encuestas/{id}/finalizar
+// Revisa completitud contra validation.required_all_cards / allow_skip del instrumento.
+func (h RespuestasHandler) Finalizar(w http.ResponseWriter, r *http.Request, encuestaID string) {
+	ep, ok := h.cargarEncuestaParcial(w, r, encuestaID)
+	if !ok {
+		return
+	}
+	if ep.Finalizada {
+		http.Error(w, "encuesta_finalizada", http.StatusConflict)
+		return
+	}
+
+	var req FinalizarRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "bad_json", http.StatusBadRequest)
+			return
+		}
+	}
+	comentario, errCode := normalizarComentario(req.Comentario)
+	if errCode != "" {
+		http.Error(w, errCode, http.StatusBadRequest)
+		return
+	}
+
+	items, set, err := h.respondidas(r, encuestaID)
+	if err != nil {
+		http.Error(w, "db_error", http.StatusInternalServerError)
+		return
+	}
+	if len(items) == 0 {
+		writeJSON(w, http.StatusUnprocessableEntity, FinalizarErrorResponse{Error: "sin_respuestas", Faltantes: ep.Inst.Faltantes(set)})
+		return
+	}
+	if faltantes := ep.Inst.Faltantes(set); len(faltantes) > 0 {
+		writeJSON(w, http.StatusUnprocessableEntity, FinalizarErrorResponse{Error: "encuesta_incompleta", Faltantes: faltantes})
+		return
+	}
+
+	ctx := r.Context()
+	tx, err := h.DB.Begin(ctx)
+	if err != nil {
+		http.Error(w, "db_error", http.StatusInternalServerError)
+		return
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	// finished_at solo se marca una vez (carrera entre dos "finalizar")
+	ct, err := tx.Exec(ctx, `
+		update encuestas
+		set
+			comentario = coalesce($2, comentario),
+			finished_at = now()
+		where id = $1 and finished_at is null
+	`, encuestaID, comentario)
+	if err != nil {
+		http.Error(w, "db_error", http.StatusInternalServerError)
+		return
+	}
+	if ct.RowsAffected() == 0 {
+		http.Error(w, "encuesta_finalizada", http.StatusConflict)
+		return
+	}
+	if err := services.RefrescarEncuesta(ctx, tx, encuestaID, nil); err != nil {
+		http.Error(w, "db_error", http.StatusInternalServerError)
+		return
+	}
+	if comentario != nil {
+		if err := services.EncolarComentario(ctx, tx, encuestaID); err != nil {
+			http.Error(w, "db_error", http.StatusInternalServerError)
+			return
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		http.Error(w, "db_error", http.StatusInternalServerError)
+		return
+	}
+
+	go h.Alertas.ProgramarEncuesta(encuestaID)
+
+	writeJSON(w, http.StatusOK, SaveRespuestasResponse{Ok: true, Inserted: len(items)})
+}
@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"mujer-back/services"
+)
+
+// Análisis psicométrico del instrumento (solo admin).
+// GET /api/admin/psicometria?instrumento= (default: el instrumento default)
+type PsicometriaHandler struct {
+	DB       *pgxpool.Pool
+	Registro *services.Registro
+	K        int // umbral de k-anonimato (ANONIMATO_K)
+}
+
+func (h PsicometriaHandler) Get(w http.ResponseWriter, r *http.Request) {
+	inst := h.Registro.Default()
+	if id := strings.TrimSpace(r.URL.Query().Get("instrumento")); id != "" {
+		var ok bool
+		inst, ok = h.Registro.Get(id)
+		if !ok {
+			http.Error(w, "bad_instrumento", http.StatusBadRequest)
+			return
+		}
+	}
+
+	out, err := services.AnalizarInstrumento(r.Context(), h.DB, inst, h.K)
+	if errors.Is(err, services.ErrSinDatos) {
+		http.Error(w, "no_data", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "db_error", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, out)
+}
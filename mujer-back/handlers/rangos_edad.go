@@ -0,0 +1,275 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Conjuntos de rangos de edad (admin). Los reportes de centro agrupan por
+// rango usando ?bands=clave o, si no viene, el conjunto del tipo de centro.
+type RangosEdadHandler struct {
+	DB *pgxpool.Pool
+}
+
+type RangoEdadDTO struct {
+	EdadMin  int    `json:"edad_min"`
+	EdadMax  *int   `json:"edad_max"` // null = sin tope (ej. "45+")
+	Etiqueta string `json:"etiqueta"`
+}
+
+type RangosEdadSetDTO struct {
+	ID         int64          `json:"id"`
+	Clave      string         `json:"clave"`
+	Nombre     string         `json:"nombre"`
+	CentroTipo *string        `json:"centro_tipo"` // escolar|laboral|null (default de ese tipo)
+	Rangos     []RangoEdadDTO `json:"rangos"`
+}
+
+type RangosEdadSetRequest struct {
+	Clave      string         `json:"clave"`
+	Nombre     string         `json:"nombre"`
+	CentroTipo string         `json:"centro_tipo,omitempty"`
+	Rangos     []RangoEdadDTO `json:"rangos"`
+}
+
+var claveRangosRe = regexp.MustCompile(`^[a-z0-9_-]{2,40}$`)
+
+func normalizeRangosEdadReq(req *RangosEdadSetRequest) (errCode string) {
+	req.Clave = strings.ToLower(strings.TrimSpace(req.Clave))
+	req.Nombre = strings.TrimSpace(req.Nombre)
+	req.CentroTipo = strings.ToLower(strings.TrimSpace(req.CentroTipo))
+
+	if !claveRangosRe.MatchString(req.Clave) {
+		return "bad_clave"
+	}
+	if len(req.Nombre) < 2 || len(req.Nombre) > 100 {
+		return "bad_nombre"
+	}
+	if req.CentroTipo != "" && req.CentroTipo != "escolar" && req.CentroTipo != "laboral" {
+		return "bad_tipo"
+	}
+	if len(req.Rangos) == 0 || len(req.Rangos) > 20 {
+		return "bad_rangos"
+	}
+
+	sort.SliceStable(req.Rangos, func(i, j int) bool { return req.Rangos[i].EdadMin < req.Rangos[j].EdadMin })
+
+	etiquetas := make(map[string]bool, len(req.Rangos))
+	for i := range req.Rangos {
+		rg := &req.Rangos[i]
+		rg.Etiqueta = strings.TrimSpace(rg.Etiqueta)
+		if rg.Etiqueta == "" || len(rg.Etiqueta) > 40 || etiquetas[rg.Etiqueta] {
+			return "bad_etiqueta"
+		}
+		etiquetas[rg.Etiqueta] = true
+
+		if rg.EdadMin < 0 || rg.EdadMin > 120 {
+			return "bad_rangos"
+		}
+		// solo el último rango puede quedar abierto
+		if rg.EdadMax == nil {
+			if i != len(req.Rangos)-1 {
+				return "bad_rangos"
+			}
+		} else if *rg.EdadMax < rg.EdadMin || *rg.EdadMax > 120 {
+			return "bad_rangos"
+		}
+		// sin traslapes
+		if i > 0 && rg.EdadMin <= *req.Rangos[i-1].EdadMax {
+			return "bad_rangos"
+		}
+	}
+	return ""
+}
+
+// rangosEdadDBError traduce violaciones de constraints a códigos para el front
+func rangosEdadDBError(w http.ResponseWriter, err error) {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		// clave repetida o ya hay un conjunto default para ese tipo de centro
+		http.Error(w, "rangos_edad_duplicado", http.StatusConflict)
+		return
+	}
+	http.Error(w, "db_error", http.StatusInternalServerError)
+}
+
+// listRangosEdad regresa los conjuntos (con sus rangos en orden); setID = 0 trae todos
+func listRangosEdad(ctx context.Context, db *pgxpool.Pool, setID int64) ([]RangosEdadSetDTO, error) {
+	rows, err := db.Query(ctx, `
+		select s.id, s.clave, s.nombre, s.centro_tipo, re.edad_min, re.edad_max, re.etiqueta
+		from rangos_edad_sets s
+		join rangos_edad re on re.set_id = s.id
+		where $1::bigint = 0 or s.id = $1
+		order by s.clave, re.orden
+	`, setID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]RangosEdadSetDTO, 0, 4)
+	for rows.Next() {
+		var s RangosEdadSetDTO
+		var rg RangoEdadDTO
+		if err := rows.Scan(&s.ID, &s.Clave, &s.Nombre, &s.CentroTipo, &rg.EdadMin, &rg.EdadMax, &rg.Etiqueta); err != nil {
+			return nil, err
+		}
+		if n := len(out); n == 0 || out[n-1].ID != s.ID {
+			out = append(out, s)
+		}
+		out[len(out)-1].Rangos = append(out[len(out)-1].Rangos, rg)
+	}
+	return out, rows.Err()
+}
+
+// GET /api/admin/rangos-edad (también /api/centro/rangos-edad, solo lectura)
+func (h RangosEdadHandler) List(w http.ResponseWriter, r *http.Request) {
+	out, err := listRangosEdad(r.Context(), h.DB, 0)
+	if err != nil {
+		http.Error(w, "db_error", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// GET /api/admin/rangos-edad/{id}
+func (h RangosEdadHandler) GetByID(w http.ResponseWriter, r *http.Request, id int64) {
+	out, err := listRangosEdad(r.Context(), h.DB, id)
+	if err != nil {
+		http.Error(w, "db_error", http.StatusInternalServerError)
+		return
+	}
+	if len(out) == 0 {
+		http.Error(w, "rangos_edad_not_found", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, out[0])
+}
+
+// insertRangos reemplaza los rangos del conjunto dentro de la transacción
+func insertRangos(ctx context.Context, tx pgx.Tx, setID int64, rangos []RangoEdadDTO) error {
+	batch := &pgx.Batch{}
+	batch.Queue(`delete from rangos_edad where set_id = $1`, setID)
+	for i, rg := range rangos {
+		batch.Queue(`
+			insert into rangos_edad (set_id, orden, edad_min, edad_max, etiqueta)
+			values ($1, $2, $3, $4, $5)
+		`, setID, i+1, rg.EdadMin, rg.EdadMax, rg.Etiqueta)
+	}
+	return tx.SendBatch(ctx, batch).Close()
+}
+
+// POST /api/admin/rangos-edad
+func (h RangosEdadHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req RangosEdadSetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "bad_json", http.StatusBadRequest)
+		return
+	}
+	if errCode := normalizeRangosEdadReq(&req); errCode != "" {
+		http.Error(w, errCode, http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	tx, err := h.DB.Begin(ctx)
+	if err != nil {
+		http.Error(w, "db_error", http.StatusInternalServerError)
+		return
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	var id int64
+	if err := tx.QueryRow(ctx, `
+		insert into rangos_edad_sets (clave, nombre, centro_tipo)
+		values ($1, $2, nullif($3, ''))
+		returning id
+	`, req.Clave, req.Nombre, req.CentroTipo).Scan(&id); err != nil {
+		rangosEdadDBError(w, err)
+		return
+	}
+	if err := insertRangos(ctx, tx, id, req.Rangos); err != nil {
+		rangosEdadDBError(w, err)
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		http.Error(w, "db_error", http.StatusInternalServerError)
+		return
+	}
+
+	out, err := listRangosEdad(ctx, h.DB, id)
+	if err != nil || len(out) == 0 {
+		http.Error(w, "db_error", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusCreated, out[0])
+}
+
+// PUT /api/admin/rangos-edad/{id} (reemplaza nombre, tipo y rangos)
+func (h RangosEdadHandler) Update(w http.ResponseWriter, r *http.Request, id int64) {
+	var req RangosEdadSetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "bad_json", http.StatusBadRequest)
+		return
+	}
+	if errCode := normalizeRangosEdadReq(&req); errCode != "" {
+		http.Error(w, errCode, http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	tx, err := h.DB.Begin(ctx)
+	if err != nil {
+		http.Error(w, "db_error", http.StatusInternalServerError)
+		return
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	ct, err := tx.Exec(ctx, `
+		update rangos_edad_sets
+		set clave = $2, nombre = $3, centro_tipo = nullif($4, '')
+		where id = $1
+	`, id, req.Clave, req.Nombre, req.CentroTipo)
+	if err != nil {
+		rangosEdadDBError(w, err)
+		return
+	}
+	if ct.RowsAffected() == 0 {
+		http.Error(w, "rangos_edad_not_found", http.StatusNotFound)
+		return
+	}
+	if err := insertRangos(ctx, tx, id, req.Rangos); err != nil {
+		rangosEdadDBError(w, err)
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		http.Error(w, "db_error", http.StatusInternalServerError)
+		return
+	}
+
+	h.GetByID(w, r, id)
+}
+
+// DELETE /api/admin/rangos-edad/{id}
+func (h RangosEdadHandler) Delete(w http.ResponseWriter, r *http.Request, id int64) {
+	ct, err := h.DB.Exec(r.Context(), `delete from rangos_edad_sets where id = $1`, id)
+	if err != nil {
+		http.Error(w, "db_error", http.StatusInternalServerError)
+		return
+	}
+	if ct.RowsAffected() == 0 {
+		http.Error(w, "rangos_edad_not_found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
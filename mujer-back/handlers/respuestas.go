@@ -3,15 +3,18 @@ package handlers
 import (
 	"encoding/json"
 	"net/http"
-	"regexp"
 	"strings"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
+
+	"mujer-back/services"
 )
 
 type RespuestasHandler struct {
-	DB *pgxpool.Pool
+	DB       *pgxpool.Pool
+	Registro *services.Registro
+	Alertas  *services.MotorAlertas // nil = sin alertas
 }
 
 type RespuestaItem struct {
@@ -33,8 +36,9 @@ type SaveRespuestasResponse struct {
 	Inserted int  `json:"inserted"`
 }
 
-var rePregunta = regexp.MustCompile(`^P([1-9]|1[0-6])$`)
-
+// POST /api/respuestas (header X-Resume-Token)
+// Guarda todas las respuestas y finaliza en un solo paso. Igual que las rutas
+// de avance, pide el token de la encuesta y no toca encuestas ya finalizadas.
 func (h RespuestasHandler) Save(w http.ResponseWriter, r *http.Request) {
 	var req SaveRespuestasRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -48,42 +52,37 @@ func (h RespuestasHandler) Save(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if len(req.Respuestas) != 48 {
-		http.Error(w, "need_48_answers", http.StatusBadRequest)
+	// NUEVO: normalizar comentario (opcional)
+	comentario, errCode := normalizarComentario(req.Comentario)
+	if errCode != "" {
+		http.Error(w, errCode, http.StatusBadRequest)
 		return
 	}
 
-	// NUEVO: normalizar comentario (opcional)
-	var comentario *string = nil
-	if req.Comentario != nil {
-		c := strings.TrimSpace(*req.Comentario)
-		if c == "" {
-			comentario = nil
-		} else {
-			// límite defensivo para evitar payloads enormes
-			// (si también pusiste CHECK en BD, mejor)
-			if len([]rune(c)) > 2000 {
-				http.Error(w, "bad_comentario", http.StatusBadRequest)
-				return
-			}
-			comentario = &c
-		}
+	// Se valida contra la versión del instrumento con la que se creó la encuesta
+	ep, ok := h.cargarEncuestaParcial(w, r, req.EncuestaID)
+	if !ok {
+		return
 	}
-
-	ctx := r.Context()
-
-	var exists bool
-	if err := h.DB.QueryRow(ctx, `select exists(select 1 from encuestas where id = $1)`, req.EncuestaID).Scan(&exists); err != nil {
-		http.Error(w, "db_error", http.StatusInternalServerError)
+	if ep.Finalizada {
+		http.Error(w, "encuesta_finalizada", http.StatusConflict)
 		return
 	}
-	if !exists {
-		http.Error(w, "encuesta_not_found", http.StatusNotFound)
+	inst := ep.Inst
+
+	// El número esperado de respuestas sale del instrumento (scoring.total_responses_expected)
+	if len(req.Respuestas) != inst.TotalRespuestasEsperadas() {
+		http.Error(w, "bad_answer_count", http.StatusBadRequest)
 		return
 	}
 
-	seen := make(map[string]struct{}, 64)
+	items, errCode := validarRespuestas(inst, req.Respuestas)
+	if errCode != "" {
+		http.Error(w, errCode, http.StatusBadRequest)
+		return
+	}
 
+	ctx := r.Context()
 	tx, err := h.DB.Begin(ctx)
 	if err != nil {
 		http.Error(w, "db_error", http.StatusInternalServerError)
@@ -91,43 +90,30 @@ func (h RespuestasHandler) Save(w http.ResponseWriter, r *http.Request) {
 	}
 	defer func() { _ = tx.Rollback(ctx) }()
 
-	batch := &pgx.Batch{}
-	inserted := 0
-
-	for _, it := range req.Respuestas {
-		pid := strings.TrimSpace(it.PreguntaID)
-		dim := strings.TrimSpace(strings.ToLower(it.Dimension))
-
-		if !rePregunta.MatchString(pid) {
-			http.Error(w, "bad_pregunta_id", http.StatusBadRequest)
-			return
-		}
-		if dim != "frecuencia" && dim != "normalidad" && dim != "gravedad" {
-			http.Error(w, "bad_dimension", http.StatusBadRequest)
-			return
-		}
-		if it.Valor < 1 || it.Valor > 5 {
-			http.Error(w, "bad_valor", http.StatusBadRequest)
-			return
-		}
-
-		key := pid + "|" + dim
-		if _, ok := seen[key]; ok {
-			http.Error(w, "duplicate_answer", http.StatusBadRequest)
-			return
-		}
-		seen[key] = struct{}{}
-
-		batch.Queue(`
-			insert into respuestas (encuesta_id, pregunta_id, dimension, valor)
-			values ($1, $2, $3, $4)
-			on conflict (encuesta_id, pregunta_id, dimension)
-			do update set valor = excluded.valor
-		`, req.EncuestaID, pid, dim, it.Valor)
-
-		inserted++
+	// Comentario opcional (1 por encuesta) + finished_at, solo una vez
+	// (carrera con otro "guardar", con /finalizar o con PUT /respuestas).
+	// Va antes de las respuestas: bloquea la encuesta primero, igual que
+	// los otros dos
+	ct, err := tx.Exec(ctx, `
+		update encuestas
+		set
+			comentario = coalesce($2, comentario),
+			finished_at = now()
+		where id = $1 and finished_at is null
+	`, req.EncuestaID, comentario)
+	if err != nil {
+		http.Error(w, "db_error", http.StatusInternalServerError)
+		return
+	}
+	if ct.RowsAffected() == 0 {
+		http.Error(w, "encuesta_finalizada", http.StatusConflict)
+		return
 	}
 
+	batch := &pgx.Batch{}
+	queueRespuestas(batch, req.EncuestaID, items)
+	inserted := len(items)
+
 	// Ejecutar batch de respuestas
 	br := tx.SendBatch(ctx, batch)
 	if err := br.Close(); err != nil {
@@ -135,16 +121,16 @@ func (h RespuestasHandler) Save(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// NUEVO: guardar comentario opcional (1 por encuesta) + marcar finished_at
-	// - Si comentario == nil, lo dejamos como NULL (no forzamos a borrar nada existente).
-	// - finished_at siempre se marca al guardar respuestas (si prefieres otra lógica, lo ajustamos).
-	if _, err := tx.Exec(ctx, `
-		update encuestas
-		set
-			comentario = coalesce($2, comentario),
-			finished_at = now()
-		where id = $1
-	`, req.EncuestaID, comentario); err != nil {
+	// ✅ NUEVO: comentario nuevo o modificado → cola de moderación
+	if comentario != nil {
+		if err := services.EncolarComentario(ctx, tx, req.EncuestaID); err != nil {
+			http.Error(w, "db_error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	// ✅ NUEVO: agregados precalculados en la misma transacción
+	if err := services.RefrescarEncuesta(ctx, tx, req.EncuestaID, nil); err != nil {
 		http.Error(w, "db_error", http.StatusInternalServerError)
 		return
 	}
@@ -154,6 +140,71 @@ func (h RespuestasHandler) Save(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// ✅ NUEVO: reglas de alerta del centro, en segundo plano
+	go h.Alertas.ProgramarEncuesta(req.EncuestaID)
+
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	_ = json.NewEncoder(w).Encode(SaveRespuestasResponse{Ok: true, Inserted: inserted})
 }
+
+// normalizarComentario: trim, vacío = nil, límite defensivo de 2000 caracteres
+func normalizarComentario(in *string) (*string, string) {
+	if in == nil {
+		return nil, ""
+	}
+	c := strings.TrimSpace(*in)
+	if c == "" {
+		return nil, ""
+	}
+	// límite defensivo para evitar payloads enormes
+	// (si también pusiste CHECK en BD, mejor)
+	if len([]rune(c)) > 2000 {
+		return nil, "bad_comentario"
+	}
+	return &c, ""
+}
+
+// validarRespuestas revisa cada respuesta contra el instrumento de la encuesta
+// (pregunta, dimensión, rango de la escala) y que no haya duplicados.
+func validarRespuestas(inst services.Instrumento, in []RespuestaItem) ([]RespuestaItem, string) {
+	seen := make(map[string]struct{}, len(in))
+	out := make([]RespuestaItem, 0, len(in))
+
+	for _, it := range in {
+		pid := strings.TrimSpace(it.PreguntaID)
+		dim := strings.TrimSpace(strings.ToLower(it.Dimension))
+
+		if !inst.HasPregunta(pid) {
+			return nil, "bad_pregunta_id"
+		}
+		card, ok := inst.CardFor(pid, dim)
+		if !ok || !inst.HasDimension(dim) {
+			return nil, "bad_dimension"
+		}
+		if int(it.Valor) < card.Scale.Min || int(it.Valor) > card.Scale.Max {
+			return nil, "bad_valor"
+		}
+
+		key := pid + "|" + dim
+		if _, ok := seen[key]; ok {
+			return nil, "duplicate_answer"
+		}
+		seen[key] = struct{}{}
+
+		out = append(out, RespuestaItem{PreguntaID: pid, Dimension: dim, Valor: it.Valor})
+	}
+
+	return out, ""
+}
+
+// queueRespuestas agrega un upsert por respuesta al batch
+func queueRespuestas(batch *pgx.Batch, encuestaID string, items []RespuestaItem) {
+	for _, it := range items {
+		batch.Queue(`
+			insert into respuestas (encuesta_id, pregunta_id, dimension, valor)
+			values ($1, $2, $3, $4)
+			on conflict (encuesta_id, pregunta_id, dimension)
+			do update set valor = excluded.valor
+		`, encuestaID, it.PreguntaID, it.Dimension, it.Valor)
+	}
+}
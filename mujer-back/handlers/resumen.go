@@ -2,14 +2,18 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strings"
 
 	"github.com/jackc/pgx/v5/pgxpool"
+
+	"mujer-back/services"
 )
 
 type ResumenHandler struct {
-	DB *pgxpool.Pool
+	DB       *pgxpool.Pool
+	Registro *services.Registro
 }
 
 type ResumenGlobal struct {
@@ -30,6 +34,12 @@ type EncuestaResumenResponse struct {
 	EncuestaID string        `json:"encuesta_id"`
 	Global     ResumenGlobal `json:"global"`
 	Matriz     []MatrizItem  `json:"matriz"`
+
+	// índice compuesto por tipo y global (si el instrumento define scoring.risk_index)
+	Riesgo *services.ResumenRiesgo `json:"riesgo,omitempty"`
+
+	// tarjetas de interpretación y recursos de apoyo (si el instrumento define feedback)
+	Retroalimentacion *services.Retroalimentacion `json:"retroalimentacion,omitempty"`
 }
 
 func (h ResumenHandler) GetByPath(w http.ResponseWriter, r *http.Request) {
@@ -43,8 +53,11 @@ func (h ResumenHandler) GetByPath(w http.ResponseWriter, r *http.Request) {
 
 	encuestaID := parts[0]
 
-	var exists bool
-	if err := h.DB.QueryRow(r.Context(), `select exists(select 1 from encuestas where id = $1)`, encuestaID).Scan(&exists); err != nil {
+	// el índice de riesgo se calcula con el instrumento de la encuesta; si esa
+	// versión ya no está cargada, el resumen sale sin índice
+	inst, exists, err := instrumentoDeEncuesta(r, h.DB, h.Registro, encuestaID)
+	conInstrumento := err == nil
+	if err != nil && !errors.Is(err, errInstrumentoNoDisponible) {
 		http.Error(w, "db_error", http.StatusInternalServerError)
 		return
 	}
@@ -54,6 +67,7 @@ func (h ResumenHandler) GetByPath(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var g ResumenGlobal
+	globales := map[string]float64{} // solo las dimensiones con respuestas
 
 	rows, err := h.DB.Query(r.Context(), `
 		select dimension::text, avg(valor)::float8
@@ -74,6 +88,7 @@ func (h ResumenHandler) GetByPath(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, "db_error", http.StatusInternalServerError)
 			return
 		}
+		globales[dim] = avg
 		switch dim {
 		case "frecuencia":
 			g.Frecuencia = avg
@@ -123,10 +138,20 @@ func (h ResumenHandler) GetByPath(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json; charset=utf-8")
-	_ = json.NewEncoder(w).Encode(EncuestaResumenResponse{
+	resp := EncuestaResumenResponse{
 		EncuestaID: encuestaID,
 		Global:     g,
 		Matriz:     matriz,
-	})
+	}
+	if conInstrumento {
+		celdas := make([]services.CeldaMatriz, len(matriz))
+		for i, it := range matriz {
+			celdas[i] = services.CeldaMatriz(it)
+		}
+		resp.Riesgo = inst.IndiceRiesgo(celdas)
+		resp.Retroalimentacion = inst.Retroalimentar(globales, celdas)
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	_ = json.NewEncoder(w).Encode(resp)
 }
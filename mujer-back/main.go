@@ -2,7 +2,9 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io/fs"
 	"net/http"
 	"os"
 	"strconv"
@@ -35,13 +37,102 @@ func main() {
 	}
 	defer pool.Close()
 
-	instrumento, err := services.LoadInstrumento("config/instrumento_mujer_alerta.json")
+	// Las migraciones pueden reescribir tablas grandes: les damos más margen
+	mctx, mcancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer mcancel()
+
+	if err := db.Migrate(mctx, pool); err != nil {
+		fmt.Println("Migraciones error:", err)
+		os.Exit(1)
+	}
+
+	// Un archivo por versión del instrumento; INSTRUMENTO_DEFAULT es el que
+	// se usa para centros sin versión fijada.
+	instDir := os.Getenv("INSTRUMENTOS_DIR")
+	if instDir == "" {
+		instDir = "config/instrumentos"
+	}
+	instDefault := os.Getenv("INSTRUMENTO_DEFAULT")
+	if instDefault == "" {
+		instDefault = "mujer_alerta_v1"
+	}
+
+	registro, err := services.LoadRegistro(instDir, instDefault)
 	if err != nil {
 		fmt.Println("Instrumento error:", err)
 		os.Exit(1)
 	}
 
-	fmt.Println("Instrumento cargado:", instrumento.Name, instrumento.Version)
+	for _, inst := range registro.All() {
+		if err := services.SyncCatalogo(mctx, pool, inst); err != nil {
+			fmt.Println("Instrumento error:", err)
+			os.Exit(1)
+		}
+		fmt.Println("Instrumento cargado:", inst.ID, inst.Name, inst.Version)
+	}
+
+	// ANONIMATO_K: mínimo de encuestas por grupo para publicar un valor (default 5)
+	anonK := 0
+	if v := os.Getenv("ANONIMATO_K"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			fmt.Println("ANONIMATO_K inválido:", v)
+			os.Exit(1)
+		}
+		anonK = n
+	}
+
+	// Agregados de reportes: si la tabla está vacía pero ya hay encuestas
+	// (primer arranque después de la migración), se llenan aquí
+	if vacios, err := services.AgregadosVacios(mctx, pool); err != nil {
+		fmt.Println("Agregados error:", err)
+		os.Exit(1)
+	} else if vacios {
+		res, err := services.ReconstruirAgregados(mctx, pool)
+		if err != nil {
+			fmt.Println("Agregados error:", err)
+			os.Exit(1)
+		}
+		fmt.Println("Agregados reconstruidos:", res.Encuestas, "encuestas,", res.Celdas, "celdas en", res.Duracion, "ms")
+	}
+
+	// Centros sin estado_clave/municipio_clave (p. ej. los previos a la
+	// migración 011): se ligan por nombre al arrancar
+	if v, err := services.VincularCentros(mctx, pool); err != nil {
+		fmt.Println("Geografía error:", err)
+		os.Exit(1)
+	} else if v.Revisados > 0 {
+		fmt.Println("Centros vinculados:", v.Estados, "con estado,", v.Municipios, "con municipio,", v.SinVincular, "sin vincular")
+	}
+
+	// Reglas de alerta: ALERTAS_CONFIG (default config/alertas.json; si no
+	// existe, no hay alertas). Se evalúan al guardar respuestas y cada
+	// ALERTAS_INTERVALO (default 1h; 0 = solo al guardar).
+	alertasPath := os.Getenv("ALERTAS_CONFIG")
+	alertasOpcional := alertasPath == ""
+	if alertasOpcional {
+		alertasPath = "config/alertas.json"
+	}
+	reglas, err := services.LoadReglasAlerta(alertasPath)
+	if alertasOpcional && errors.Is(err, fs.ErrNotExist) {
+		reglas, err = nil, nil
+	}
+	if err != nil {
+		fmt.Println("Alertas error:", err)
+		os.Exit(1)
+	}
+	alertasCada := time.Hour
+	if v := os.Getenv("ALERTAS_INTERVALO"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			fmt.Println("ALERTAS_INTERVALO inválido:", v)
+			os.Exit(1)
+		}
+		alertasCada = d
+	}
+	motorAlertas := services.NuevoMotorAlertas(pool, reglas, anonK)
+	motorAlertas.Iniciar(context.Background(), alertasCada)
+	fmt.Println("Reglas de alerta:", len(reglas))
 
 	mux := http.NewServeMux()
 
@@ -57,13 +148,31 @@ func main() {
 	// ======================
 	// Instrumento
 	// ======================
-	ih := handlers.InstrumentoHandler{Data: instrumento}
+	ih := handlers.InstrumentoHandler{Registro: registro}
 	mux.HandleFunc("/api/instrumento", ih.Get)
 
+	// /api/instrumentos → versiones cargadas
+	mux.HandleFunc("/api/instrumentos", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			ih.List(w, r)
+			return
+		}
+		http.Error(w, "method_not_allowed", http.StatusMethodNotAllowed)
+	})
+
+	// /api/instrumentos/{id} → JSON completo de esa versión
+	mux.HandleFunc("/api/instrumentos/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			ih.GetByPath(w, r)
+			return
+		}
+		http.Error(w, "method_not_allowed", http.StatusMethodNotAllowed)
+	})
+
 	// ======================
 	// Encuestas
 	// ======================
-	eh := handlers.EncuestasHandler{DB: pool}
+	eh := handlers.EncuestasHandler{DB: pool, Registro: registro}
 	mux.HandleFunc("/api/encuestas", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == http.MethodPost {
 			eh.Create(w, r)
@@ -73,9 +182,9 @@ func main() {
 	})
 
 	// ======================
-	// Respuestas
+	// Respuestas (POST en un paso; X-Resume-Token)
 	// ======================
-	rh := handlers.RespuestasHandler{DB: pool}
+	rh := handlers.RespuestasHandler{DB: pool, Registro: registro, Alertas: motorAlertas}
 	mux.HandleFunc("/api/respuestas", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == http.MethodPost {
 			rh.Save(w, r)
@@ -86,20 +195,51 @@ func main() {
 
 	// ======================
 	// Resumen por encuesta
+	// /api/encuestas/{id}/resumen
+	// /api/encuestas/{id}/instrumento
+	// /api/encuestas/{id}/progreso   (GET, X-Resume-Token)
+	// /api/encuestas/{id}/respuestas (PUT, X-Resume-Token)
+	// /api/encuestas/{id}/finalizar  (POST, X-Resume-Token)
 	// ======================
-	rhResumen := handlers.ResumenHandler{DB: pool}
+	rhResumen := handlers.ResumenHandler{DB: pool, Registro: registro}
 	mux.HandleFunc("/api/encuestas/", func(w http.ResponseWriter, r *http.Request) {
-		if r.Method == http.MethodGet {
-			rhResumen.GetByPath(w, r)
+		rest := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/encuestas/"), "/")
+		parts := strings.Split(rest, "/")
+
+		if len(parts) == 2 && parts[0] != "" {
+			switch {
+			case parts[1] == "respuestas" && r.Method == http.MethodPut:
+				rh.GuardarParcial(w, r, parts[0])
+				return
+			case parts[1] == "finalizar" && r.Method == http.MethodPost:
+				rh.Finalizar(w, r, parts[0])
+				return
+			}
+		}
+
+		if r.Method != http.MethodGet {
+			http.Error(w, "method_not_allowed", http.StatusMethodNotAllowed)
 			return
 		}
-		http.Error(w, "method_not_allowed", http.StatusMethodNotAllowed)
+
+		if len(parts) == 2 && parts[0] != "" {
+			switch parts[1] {
+			case "instrumento":
+				eh.GetInstrumento(w, r, parts[0])
+				return
+			case "progreso":
+				rh.Progreso(w, r, parts[0])
+				return
+			}
+		}
+
+		rhResumen.GetByPath(w, r)
 	})
 
 	// ======================
 	// Centros (CRUD)
 	// ======================
-	ch := handlers.CentrosHandler{DB: pool}
+	ch := handlers.CentrosHandler{DB: pool, Registro: registro}
 
 	// /api/centros → GET (público), POST (admin)
 	mux.HandleFunc("/api/centros", func(w http.ResponseWriter, r *http.Request) {
@@ -124,6 +264,7 @@ func main() {
 	})
 
 	// /api/centros/{id} → GET / PUT / DELETE (admin)
+	// /api/centros/{id}/instrumento → PUT (admin, fija versión del instrumento)
 	mux.HandleFunc("/api/centros/", func(w http.ResponseWriter, r *http.Request) {
 		handlers.RequireJWT(
 			handlers.RequireAdmin(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -135,12 +276,30 @@ func main() {
 					return
 				}
 
+				sub := ""
+				if i := strings.Index(idStr, "/"); i >= 0 {
+					idStr, sub = idStr[:i], idStr[i+1:]
+				}
+
 				id, err := strconv.ParseInt(idStr, 10, 64)
 				if err != nil || id <= 0 {
 					http.Error(w, "bad_id", http.StatusBadRequest)
 					return
 				}
 
+				if sub == "instrumento" {
+					if r.Method == http.MethodPut {
+						ch.SetInstrumento(w, r, id)
+						return
+					}
+					http.Error(w, "method_not_allowed", http.StatusMethodNotAllowed)
+					return
+				}
+				if sub != "" {
+					http.NotFound(w, r)
+					return
+				}
+
 				switch r.Method {
 				case http.MethodGet:
 					ch.GetByID(w, r, id)
@@ -238,10 +397,484 @@ func main() {
 	})
 
 
+	// ======================
+	// Admin: Aplicaciones (campañas por centro)
+	// ======================
+	aph := handlers.AplicacionesHandler{DB: pool, Registro: registro}
+
+	// /api/admin/aplicaciones → GET, POST (admin)
+	mux.HandleFunc("/api/admin/aplicaciones", func(w http.ResponseWriter, r *http.Request) {
+		handlers.RequireJWT(
+			handlers.RequireAdmin(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+				switch r.Method {
+				case http.MethodGet:
+					aph.List(w, r)
+					return
+				case http.MethodPost:
+					aph.Create(w, r)
+					return
+				default:
+					http.Error(w, "method_not_allowed", http.StatusMethodNotAllowed)
+					return
+				}
+
+			})),
+		).ServeHTTP(w, r)
+	})
+
+	// /api/admin/aplicaciones/{id} → GET / PUT / DELETE (admin)
+	mux.HandleFunc("/api/admin/aplicaciones/", func(w http.ResponseWriter, r *http.Request) {
+		handlers.RequireJWT(
+			handlers.RequireAdmin(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+				idStr := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/admin/aplicaciones/"), "/")
+				if idStr == "" {
+					http.NotFound(w, r)
+					return
+				}
+
+				id, err := strconv.ParseInt(idStr, 10, 64)
+				if err != nil || id <= 0 {
+					http.Error(w, "bad_id", http.StatusBadRequest)
+					return
+				}
+
+				switch r.Method {
+				case http.MethodGet:
+					aph.GetByID(w, r, id)
+					return
+				case http.MethodPut:
+					aph.Update(w, r, id)
+					return
+				case http.MethodDelete:
+					aph.Delete(w, r, id)
+					return
+				default:
+					http.Error(w, "method_not_allowed", http.StatusMethodNotAllowed)
+					return
+				}
+
+			})),
+		).ServeHTTP(w, r)
+	})
+
+	// ======================
+	// Admin: Agregados precalculados de reportes
+	// ======================
+	agh := handlers.AgregadosHandler{DB: pool}
+
+	// /api/admin/agregados/rebuild → POST (admin)
+	mux.HandleFunc("/api/admin/agregados/rebuild", func(w http.ResponseWriter, r *http.Request) {
+		handlers.RequireJWT(
+			handlers.RequireAdmin(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+				if r.Method != http.MethodPost {
+					http.Error(w, "method_not_allowed", http.StatusMethodNotAllowed)
+					return
+				}
+				agh.Rebuild(w, r)
+
+			})),
+		).ServeHTTP(w, r)
+	})
+
+	// ======================
+	// Admin: Moderación de comentarios
+	// ======================
+	mdr := handlers.ModeracionHandler{DB: pool}
+
+	// /api/admin/comentarios → GET cola (admin)
+	mux.HandleFunc("/api/admin/comentarios", func(w http.ResponseWriter, r *http.Request) {
+		handlers.RequireJWT(
+			handlers.RequireAdmin(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+				if r.Method != http.MethodGet {
+					http.Error(w, "method_not_allowed", http.StatusMethodNotAllowed)
+					return
+				}
+				mdr.List(w, r)
+
+			})),
+		).ServeHTTP(w, r)
+	})
+
+	// /api/admin/comentarios/{encuesta_id} → PUT revisión (admin)
+	mux.HandleFunc("/api/admin/comentarios/", func(w http.ResponseWriter, r *http.Request) {
+		handlers.RequireJWT(
+			handlers.RequireAdmin(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+				id := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/admin/comentarios/"), "/")
+				if id == "" {
+					http.NotFound(w, r)
+					return
+				}
+				if r.Method != http.MethodPut {
+					http.Error(w, "method_not_allowed", http.StatusMethodNotAllowed)
+					return
+				}
+				mdr.Revisar(w, r, id)
+
+			})),
+		).ServeHTTP(w, r)
+	})
+
+	// ======================
+	// Admin: Microdatos anonimizados (investigación)
+	// GET /api/admin/microdatos?format=csv|sav|parquet&year=2025
+	// ======================
+	mdh := handlers.MicrodatosHandler{DB: pool, Registro: registro, K: anonK}
+
+	mux.HandleFunc("/api/admin/microdatos", func(w http.ResponseWriter, r *http.Request) {
+		handlers.RequireJWT(
+			handlers.RequireAdmin(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+				if r.Method != http.MethodGet {
+					http.Error(w, "method_not_allowed", http.StatusMethodNotAllowed)
+					return
+				}
+				mdh.Export(w, r)
+
+			})),
+		).ServeHTTP(w, r)
+	})
+
+	// ======================
+	// Admin: Análisis psicométrico del instrumento
+	// GET /api/admin/psicometria?instrumento=mujer_alerta_v1
+	// ======================
+	psh := handlers.PsicometriaHandler{DB: pool, Registro: registro, K: anonK}
+
+	mux.HandleFunc("/api/admin/psicometria", func(w http.ResponseWriter, r *http.Request) {
+		handlers.RequireJWT(
+			handlers.RequireAdmin(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+				if r.Method != http.MethodGet {
+					http.Error(w, "method_not_allowed", http.StatusMethodNotAllowed)
+					return
+				}
+				psh.Get(w, r)
+
+			})),
+		).ServeHTTP(w, r)
+	})
+
+	// ======================
+	// Alertas de riesgo
+	// ======================
+	alh := handlers.AlertasHandler{DB: pool, Motor: motorAlertas}
+
+	// /api/admin/alertas → GET todas las alertas (admin)
+	mux.HandleFunc("/api/admin/alertas", func(w http.ResponseWriter, r *http.Request) {
+		handlers.RequireJWT(
+			handlers.RequireAdmin(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+				if r.Method != http.MethodGet {
+					http.Error(w, "method_not_allowed", http.StatusMethodNotAllowed)
+					return
+				}
+				alh.ListAdmin(w, r)
+
+			})),
+		).ServeHTTP(w, r)
+	})
+
+	// /api/admin/alertas/evaluar → POST (admin, evalúa todos los centros ya)
+	mux.HandleFunc("/api/admin/alertas/evaluar", func(w http.ResponseWriter, r *http.Request) {
+		handlers.RequireJWT(
+			handlers.RequireAdmin(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+				if r.Method != http.MethodPost {
+					http.Error(w, "method_not_allowed", http.StatusMethodNotAllowed)
+					return
+				}
+				alh.Evaluar(w, r)
+
+			})),
+		).ServeHTTP(w, r)
+	})
+
+	// /api/centro/alertas → GET alertas de los centros del usuario
+	mux.HandleFunc("/api/centro/alertas", func(w http.ResponseWriter, r *http.Request) {
+		handlers.RequireJWT(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodGet {
+				alh.ListCentro(w, r)
+				return
+			}
+			http.Error(w, "method_not_allowed", http.StatusMethodNotAllowed)
+		})).ServeHTTP(w, r)
+	})
+
+	// ======================
+	// Comparación entre centros (benchmarking)
+	// ======================
+	bmh := handlers.BenchmarkHandler{DB: pool, K: anonK}
+
+	// /api/admin/resultados → GET ranking de centros + distribución (admin)
+	mux.HandleFunc("/api/admin/resultados", func(w http.ResponseWriter, r *http.Request) {
+		handlers.RequireJWT(
+			handlers.RequireAdmin(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+				if r.Method != http.MethodGet {
+					http.Error(w, "method_not_allowed", http.StatusMethodNotAllowed)
+					return
+				}
+				bmh.Ranking(w, r)
+
+			})),
+		).ServeHTTP(w, r)
+	})
+
+	// /api/admin/resultados/comparar → GET distribuciones por tipo, estado o ciudad (admin)
+	mux.HandleFunc("/api/admin/resultados/comparar", func(w http.ResponseWriter, r *http.Request) {
+		handlers.RequireJWT(
+			handlers.RequireAdmin(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+				if r.Method != http.MethodGet {
+					http.Error(w, "method_not_allowed", http.StatusMethodNotAllowed)
+					return
+				}
+				bmh.Comparar(w, r)
+
+			})),
+		).ServeHTTP(w, r)
+	})
+
+	// /api/centro/benchmark → GET posición del centro frente a pares anónimos del mismo tipo
+	mux.HandleFunc("/api/centro/benchmark", func(w http.ResponseWriter, r *http.Request) {
+		handlers.RequireJWT(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodGet {
+				bmh.Centro(w, r)
+				return
+			}
+			http.Error(w, "method_not_allowed", http.StatusMethodNotAllowed)
+		})).ServeHTTP(w, r)
+	})
+
+	// ======================
+	// Geografía: catálogos INEGI y mapas por estado/municipio (admin)
+	// ======================
+	geh := handlers.GeografiaHandler{DB: pool, K: anonK}
+
+	// /api/admin/geografia/estados → GET catálogo de estados (admin)
+	mux.HandleFunc("/api/admin/geografia/estados", func(w http.ResponseWriter, r *http.Request) {
+		handlers.RequireJWT(
+			handlers.RequireAdmin(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+				if r.Method != http.MethodGet {
+					http.Error(w, "method_not_allowed", http.StatusMethodNotAllowed)
+					return
+				}
+				geh.Estados(w, r)
+
+			})),
+		).ServeHTTP(w, r)
+	})
+
+	// /api/admin/geografia/municipios?estado= → GET municipios de un estado (admin)
+	mux.HandleFunc("/api/admin/geografia/municipios", func(w http.ResponseWriter, r *http.Request) {
+		handlers.RequireJWT(
+			handlers.RequireAdmin(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+				if r.Method != http.MethodGet {
+					http.Error(w, "method_not_allowed", http.StatusMethodNotAllowed)
+					return
+				}
+				geh.Municipios(w, r)
+
+			})),
+		).ServeHTTP(w, r)
+	})
+
+	// /api/admin/geografia/importar?nivel=estados|municipios → POST GeoJSON de INEGI (admin)
+	mux.HandleFunc("/api/admin/geografia/importar", func(w http.ResponseWriter, r *http.Request) {
+		handlers.RequireJWT(
+			handlers.RequireAdmin(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+				if r.Method != http.MethodPost {
+					http.Error(w, "method_not_allowed", http.StatusMethodNotAllowed)
+					return
+				}
+				geh.Importar(w, r)
+
+			})),
+		).ServeHTTP(w, r)
+	})
+
+	// /api/admin/geografia/vincular → POST liga centros sin estado/municipio (admin)
+	mux.HandleFunc("/api/admin/geografia/vincular", func(w http.ResponseWriter, r *http.Request) {
+		handlers.RequireJWT(
+			handlers.RequireAdmin(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+				if r.Method != http.MethodPost {
+					http.Error(w, "method_not_allowed", http.StatusMethodNotAllowed)
+					return
+				}
+				geh.Vincular(w, r)
+
+			})),
+		).ServeHTTP(w, r)
+	})
+
+	// /api/admin/mapa/estados|municipios → GET FeatureCollection con índices por región (admin)
+	mux.HandleFunc("/api/admin/mapa/", func(w http.ResponseWriter, r *http.Request) {
+		handlers.RequireJWT(
+			handlers.RequireAdmin(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+				nivel := strings.TrimPrefix(r.URL.Path, "/api/admin/mapa/")
+				if nivel != services.NivelEstados && nivel != services.NivelMunicipios {
+					http.NotFound(w, r)
+					return
+				}
+				if r.Method != http.MethodGet {
+					http.Error(w, "method_not_allowed", http.StatusMethodNotAllowed)
+					return
+				}
+				geh.Mapa(w, r, nivel)
+
+			})),
+		).ServeHTTP(w, r)
+	})
+
+	// ======================
+	// Admin: Conjuntos de rangos de edad (reportes por rango, nunca edad exacta)
+	// ======================
+	reh := handlers.RangosEdadHandler{DB: pool}
+
+	// /api/admin/rangos-edad → GET, POST (admin)
+	mux.HandleFunc("/api/admin/rangos-edad", func(w http.ResponseWriter, r *http.Request) {
+		handlers.RequireJWT(
+			handlers.RequireAdmin(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+				switch r.Method {
+				case http.MethodGet:
+					reh.List(w, r)
+					return
+				case http.MethodPost:
+					reh.Create(w, r)
+					return
+				default:
+					http.Error(w, "method_not_allowed", http.StatusMethodNotAllowed)
+					return
+				}
+
+			})),
+		).ServeHTTP(w, r)
+	})
+
+	// /api/admin/rangos-edad/{id} → GET / PUT / DELETE (admin)
+	mux.HandleFunc("/api/admin/rangos-edad/", func(w http.ResponseWriter, r *http.Request) {
+		handlers.RequireJWT(
+			handlers.RequireAdmin(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+				idStr := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/admin/rangos-edad/"), "/")
+				if idStr == "" {
+					http.NotFound(w, r)
+					return
+				}
+
+				id, err := strconv.ParseInt(idStr, 10, 64)
+				if err != nil || id <= 0 {
+					http.Error(w, "bad_id", http.StatusBadRequest)
+					return
+				}
+
+				switch r.Method {
+				case http.MethodGet:
+					reh.GetByID(w, r, id)
+					return
+				case http.MethodPut:
+					reh.Update(w, r, id)
+					return
+				case http.MethodDelete:
+					reh.Delete(w, r, id)
+					return
+				default:
+					http.Error(w, "method_not_allowed", http.StatusMethodNotAllowed)
+					return
+				}
+
+			})),
+		).ServeHTTP(w, r)
+	})
+
+	// /api/centro/rangos-edad → GET (para el selector ?bands= de los reportes)
+	mux.HandleFunc("/api/centro/rangos-edad", func(w http.ResponseWriter, r *http.Request) {
+		handlers.RequireJWT(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodGet {
+				reh.List(w, r)
+				return
+			}
+			http.Error(w, "method_not_allowed", http.StatusMethodNotAllowed)
+		})).ServeHTTP(w, r)
+	})
+
+	// ======================
+	// Centro: Aplicaciones propias (para filtrar reportes por campaña)
+	// ======================
+	mux.HandleFunc("/api/centro/aplicaciones", func(w http.ResponseWriter, r *http.Request) {
+		handlers.RequireJWT(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodGet {
+				aph.ListCentro(w, r)
+				return
+			}
+			http.Error(w, "method_not_allowed", http.StatusMethodNotAllowed)
+		})).ServeHTTP(w, r)
+	})
+
+	// ======================
+	// Centro: Códigos de acceso por aplicación
+	// /api/centro/aplicaciones/{id}/codigos → GET (lotes), POST (generar)
+	// /api/centro/aplicaciones/{id}/codigos/{lote} → DELETE (revocar)
+	// ======================
+	frontURL := os.Getenv("FRONT_URL")
+	if frontURL == "" {
+		frontURL = "http://localhost:3000"
+	}
+	coh := handlers.CodigosHandler{DB: pool, FrontURL: frontURL}
+	mux.HandleFunc("/api/centro/aplicaciones/", func(w http.ResponseWriter, r *http.Request) {
+		handlers.RequireJWT(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+			rest := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/centro/aplicaciones/"), "/")
+			parts := strings.Split(rest, "/")
+			if len(parts) < 2 || len(parts) > 3 || parts[1] != "codigos" {
+				http.NotFound(w, r)
+				return
+			}
+
+			id, err := strconv.ParseInt(parts[0], 10, 64)
+			if err != nil || id <= 0 {
+				http.Error(w, "bad_id", http.StatusBadRequest)
+				return
+			}
+
+			if len(parts) == 3 {
+				if r.Method == http.MethodDelete {
+					coh.RevocarLote(w, r, id, parts[2])
+					return
+				}
+				http.Error(w, "method_not_allowed", http.StatusMethodNotAllowed)
+				return
+			}
+
+			switch r.Method {
+			case http.MethodGet:
+				coh.ListLotes(w, r, id)
+				return
+			case http.MethodPost:
+				coh.Generar(w, r, id)
+				return
+			default:
+				http.Error(w, "method_not_allowed", http.StatusMethodNotAllowed)
+				return
+			}
+		})).ServeHTTP(w, r)
+	})
+
 	// ======================
 	// Centro: Resumen agregado (ÚNICO endpoint válido)
 	// ======================
-	crh := handlers.CentroResultadosHandler{DB: pool}
+	crh := handlers.CentroResultadosHandler{DB: pool, Registro: registro, K: anonK}
 	mux.HandleFunc("/api/centro/resumen", func(w http.ResponseWriter, r *http.Request) {
 		handlers.RequireJWT(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			if r.Method == http.MethodGet {
@@ -266,6 +899,48 @@ func main() {
 	})
 
 
+	// ======================
+	// Centro: Comentarios moderados (paginados, con búsqueda)
+	// GET /api/centro/comentarios?q=acoso&year=2025&cursor=...
+	// ======================
+	mux.HandleFunc("/api/centro/comentarios", func(w http.ResponseWriter, r *http.Request) {
+		handlers.RequireJWT(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodGet {
+				crh.GetComentarios(w, r)
+				return
+			}
+			http.Error(w, "method_not_allowed", http.StatusMethodNotAllowed)
+		})).ServeHTTP(w, r)
+	})
+
+	// ======================
+	// Centro: Exportar resultados (CSV en zip o XLSX)
+	// GET /api/centro/export?format=csv|xlsx&year=2025
+	// ======================
+	mux.HandleFunc("/api/centro/export", func(w http.ResponseWriter, r *http.Request) {
+		handlers.RequireJWT(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodGet {
+				crh.ExportCentro(w, r)
+				return
+			}
+			http.Error(w, "method_not_allowed", http.StatusMethodNotAllowed)
+		})).ServeHTTP(w, r)
+	})
+
+	// ======================
+	// Centro: Informe de diagnóstico (PDF)
+	// GET /api/centro/reporte.pdf?year=2025
+	// ======================
+	mux.HandleFunc("/api/centro/reporte.pdf", func(w http.ResponseWriter, r *http.Request) {
+		handlers.RequireJWT(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodGet {
+				crh.GetReportePDF(w, r)
+				return
+			}
+			http.Error(w, "method_not_allowed", http.StatusMethodNotAllowed)
+		})).ServeHTTP(w, r)
+	})
+
 	// ======================
 	// Centro: Serie anual (comparar años)
 	// GET /api/centro/resumen-anual?years=2022,2023,2024
@@ -294,6 +969,20 @@ func main() {
 		})).ServeHTTP(w, r)
 	})
 
+	// ======================
+	// Centro: Significancia de diferencias entre dos años o dos grupos
+	// GET /api/centro/comparar?por=year&a=2024&b=2025
+	// ======================
+	mux.HandleFunc("/api/centro/comparar", func(w http.ResponseWriter, r *http.Request) {
+		handlers.RequireJWT(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodGet {
+				crh.GetComparacionGrupos(w, r)
+				return
+			}
+			http.Error(w, "method_not_allowed", http.StatusMethodNotAllowed)
+		})).ServeHTTP(w, r)
+	})
+
 
 	// ======================
 	// CORS
@@ -304,7 +993,8 @@ func main() {
 			"http://127.0.0.1:3000",
 		},
 		AllowedMethods: "GET, POST, PUT, DELETE, OPTIONS",
-		AllowedHeaders: "Content-Type, Authorization",
+		AllowedHeaders: "Content-Type, Authorization, X-Resume-Token",
+		ExposedHeaders: "Content-Disposition, X-Microdatos-Filas, X-Microdatos-Centros-Suprimidos, X-Microdatos-Generalizadas",
 	})
 
 	addr := os.Getenv("ADDR")
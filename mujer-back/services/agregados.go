@@ -0,0 +1,320 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// =======================================================
+// Agregados por centro
+// Un solo lugar calcula los números que ven el dashboard, las exportaciones
+// y los jobs programados: todas las consultas viajan en un solo batch y leen
+// de las tablas agg_* (ver agregados_tablas.go). Los comentarios van aparte,
+// paginados (ver comentarios.go).
+// =======================================================
+
+// ErrSinDatos: el filtro no tiene encuestas finalizadas con respuestas
+var ErrSinDatos = errors.New("no_data")
+
+// AgregadoError indica en qué consulta del batch falló el agregado
+type AgregadoError struct {
+	Etapa string
+	Err   error
+}
+
+func (e *AgregadoError) Error() string {
+	return "agregado " + e.Etapa + ": " + e.Err.Error()
+}
+
+func (e *AgregadoError) Unwrap() error {
+	return e.Err
+}
+
+// FiltroAgregado delimita las encuestas que entran a un agregado.
+// Siempre son encuestas finalizadas de los centros indicados.
+type FiltroAgregado struct {
+	Centros      []int64
+	Year         *int       // extract(year from finished_at)
+	Years        []int      // extract(year from finished_at) = any(years)
+	AplicacionID *int64     // campaña
+	Genero       string     // clave de generos
+	RangoEdad    string     // etiqueta de Rangos
+	Desde        *time.Time // finished_at::date >= desde
+	Hasta        *time.Time // finished_at::date <= hasta
+
+	// Rangos de edad con los que se agrupa (y filtra por RangoEdad); vacío = RangosEdadDefault
+	Rangos []RangoEdad
+}
+
+// RangosEdad regresa los rangos del filtro o el default
+func (f FiltroAgregado) RangosEdad() []RangoEdad {
+	if len(f.Rangos) == 0 {
+		return RangosEdadDefault
+	}
+	return f.Rangos
+}
+
+// SQL arma el WHERE sobre el alias e y sus args posicionales.
+// e puede ser encuestas o cualquiera de las tablas agg_* (mismas columnas).
+func (f FiltroAgregado) SQL() (string, []any) {
+	args := []any{f.Centros}
+	where := []string{
+		"e.centro_id = any($1::bigint[])",
+		"e.finished_at is not null",
+	}
+
+	if f.Year != nil {
+		args = append(args, *f.Year)
+		where = append(where, "extract(year from e.finished_at)::int = $"+strconv.Itoa(len(args)))
+	}
+	if len(f.Years) > 0 {
+		args = append(args, f.Years)
+		where = append(where, "extract(year from e.finished_at)::int = any($"+strconv.Itoa(len(args))+"::int[])")
+	}
+	if f.AplicacionID != nil {
+		args = append(args, *f.AplicacionID)
+		where = append(where, "e.aplicacion_id = $"+strconv.Itoa(len(args)))
+	}
+	if f.Genero != "" {
+		args = append(args, f.Genero)
+		where = append(where, "e.genero_id in (select id from generos where clave = $"+strconv.Itoa(len(args))+")")
+	}
+	if f.RangoEdad != "" {
+		args = append(args, f.RangoEdad)
+		where = append(where, RangoEdadSQL(f.RangosEdad())+" = $"+strconv.Itoa(len(args)))
+	}
+	if f.Desde != nil {
+		args = append(args, *f.Desde)
+		where = append(where, "e.finished_at::date >= $"+strconv.Itoa(len(args))+"::date")
+	}
+	if f.Hasta != nil {
+		args = append(args, *f.Hasta)
+		where = append(where, "e.finished_at::date <= $"+strconv.Itoa(len(args))+"::date")
+	}
+
+	return strings.Join(where, "\n\t\t  and "), args
+}
+
+// Dimensiones son promedios 1–5 por dimensión; sus campos son
+// DimensionesReportes, que Validate exige en todo instrumento
+type Dimensiones struct {
+	Frecuencia float64 `json:"frecuencia"`
+	Normalidad float64 `json:"normalidad"`
+	Gravedad   float64 `json:"gravedad"`
+	Total      float64 `json:"total"`
+}
+
+// CeldaMatriz es el promedio de un tipo de violencia en una dimensión
+type CeldaMatriz struct {
+	TipoNum    int32   `json:"tipo_num"`
+	TipoNombre string  `json:"tipo_nombre"`
+	Dimension  string  `json:"dimension"`
+	Promedio   float64 `json:"promedio"`
+}
+
+// Grupo es un corte (género o rango de edad) con sumas por dimensión,
+// para que fusionar grupos chicos no pierda precisión en los promedios.
+type Grupo struct {
+	Clave      string     `json:"clave"`
+	Label      string     `json:"label"`
+	Encuestas  int64      `json:"encuestas"`
+	Respuestas int64      `json:"respuestas"`
+	Suma       [3]float64 `json:"-"` // frecuencia, normalidad, gravedad
+	N          [3]int64   `json:"-"`
+}
+
+func (g *Grupo) absorber(o Grupo) {
+	g.Encuestas += o.Encuestas
+	g.Respuestas += o.Respuestas
+	for i := range g.Suma {
+		g.Suma[i] += o.Suma[i]
+		g.N[i] += o.N[i]
+	}
+}
+
+func (g Grupo) promedio(i int) float64 {
+	if g.N[i] == 0 {
+		return 0
+	}
+	return g.Suma[i] / float64(g.N[i])
+}
+
+func (g Grupo) Frecuencia() float64 { return g.promedio(0) }
+func (g Grupo) Normalidad() float64 { return g.promedio(1) }
+func (g Grupo) Gravedad() float64   { return g.promedio(2) }
+
+// ResumenAgregado es todo lo que publica /api/centro/resumen (antes de k-anonimato)
+type ResumenAgregado struct {
+	TotalParticipantes int64
+	TotalRespuestas    int64
+	Global             Dimensiones
+	Matriz             []CeldaMatriz
+	PorGenero          []Grupo
+	PorEdad            []Grupo
+
+	// comentarios publicados por tipo (lo llena ContarEtiquetas)
+	Etiquetas EtiquetasComentarios
+}
+
+// AgregarResumen calcula el resumen de un filtro en un solo round trip.
+// Regresa ErrSinDatos si no hay respuestas y *AgregadoError si falla una consulta.
+func AgregarResumen(ctx context.Context, pool *pgxpool.Pool, f FiltroAgregado) (ResumenAgregado, error) {
+	where, args := f.SQL()
+	rangoSQL := RangoEdadSQL(f.RangosEdad())
+
+	res := ResumenAgregado{
+		Matriz:    []CeldaMatriz{},
+		PorGenero: []Grupo{},
+		PorEdad:   []Grupo{},
+		Etiquetas: EtiquetasComentarios{Tipos: []EtiquetaTipo{}},
+	}
+
+	batch := &pgx.Batch{}
+
+	// totales: participantes = encuestas (finalizadas) con al menos 1 respuesta
+	batch.Queue(`
+		select
+			(select coalesce(sum(e.encuestas), 0)::bigint from agg_encuestas e where `+where+`),
+			(select coalesce(sum(e.respuestas), 0)::bigint from agg_encuestas e where `+where+`),
+			`+promedioHist("e.dimension = 'frecuencia'")+`,
+			`+promedioHist("e.dimension = 'normalidad'")+`,
+			`+promedioHist("e.dimension = 'gravedad'")+`,
+			`+promedioHist("true")+`
+		from agg_valores e
+		where `+where+`
+	`, args...).QueryRow(func(row pgx.Row) error {
+		return etapa("global", row.Scan(
+			&res.TotalParticipantes, &res.TotalRespuestas,
+			&res.Global.Frecuencia, &res.Global.Normalidad, &res.Global.Gravedad, &res.Global.Total,
+		))
+	})
+
+	// matriz por tipo + dimensión (mapeo pregunta -> tipo desde instrumento_preguntas)
+	batch.Queue(`
+		select
+			ip.tipo_num,
+			ip.tipo_nombre,
+			e.dimension,
+			round(sum(e.valor * e.n)::numeric / sum(e.n), 2)::float8
+		from agg_valores e
+		join instrumento_preguntas ip
+		  on ip.instrumento_id = e.instrumento_id
+		 and ip.pregunta_id = e.pregunta_id
+		where `+where+`
+		group by ip.tipo_num, ip.tipo_nombre, e.dimension
+		order by ip.tipo_num, e.dimension
+	`, args...).Query(func(rows pgx.Rows) error {
+		for rows.Next() {
+			var it CeldaMatriz
+			if err := rows.Scan(&it.TipoNum, &it.TipoNombre, &it.Dimension, &it.Promedio); err != nil {
+				return etapa("matriz", err)
+			}
+			res.Matriz = append(res.Matriz, it)
+		}
+		return etapa("matriz", rows.Err())
+	})
+
+	queueGrupos(batch, "por_genero", `g.clave`, `g.etiqueta`, `join generos g on g.id = e.genero_id`, where, args, &res.PorGenero)
+	queueGrupos(batch, "por_edad", rangoSQL, rangoSQL, "", where, args, &res.PorEdad)
+
+	if err := enviarBatch(ctx, pool, batch); err != nil {
+		return res, err
+	}
+
+	if res.TotalRespuestas == 0 {
+		return res, ErrSinDatos
+	}
+	return res, nil
+}
+
+// InstrumentosDeFiltro regresa los instrument_id con encuestas en el filtro
+// (para resolver etiquetas de dimensiones al exportar)
+func InstrumentosDeFiltro(ctx context.Context, pool *pgxpool.Pool, f FiltroAgregado) ([]string, error) {
+	where, args := f.SQL()
+	rows, err := pool.Query(ctx, `
+		select distinct e.instrumento_id
+		from agg_encuestas e
+		where `+where+`
+		order by 1
+	`, args...)
+	if err != nil {
+		return nil, etapa("instrumentos", err)
+	}
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, etapa("instrumentos", err)
+	}
+	return ids, nil
+}
+
+// promedioHist es el promedio ponderado de agg_valores e para las filas que cumplen cond
+func promedioHist(cond string) string {
+	return `coalesce(sum(e.valor * e.n) filter (where ` + cond + `)::float8 / nullif(sum(e.n) filter (where ` + cond + `), 0), 0)::float8`
+}
+
+// queueGrupos agrega al batch el corte por claveSQL/labelSQL (sobre e / joins)
+func queueGrupos(batch *pgx.Batch, nombre, claveSQL, labelSQL, joins, where string, args []any, dst *[]Grupo) {
+	sumaDim := func(dim string) string {
+		return `coalesce(sum(e.valor * e.n) filter (where e.dimension = '` + dim + `'), 0)::float8,
+				coalesce(sum(e.n) filter (where e.dimension = '` + dim + `'), 0)::bigint`
+	}
+	batch.Queue(`
+		with enc as (
+			select
+				`+claveSQL+` as clave,
+				`+labelSQL+` as label,
+				sum(e.encuestas)::bigint as encuestas,
+				sum(e.respuestas)::bigint as respuestas
+			from agg_encuestas e
+			`+joins+`
+			where `+where+`
+			group by 1, 2
+		),
+		val as (
+			select
+				`+claveSQL+` as clave,
+				`+sumaDim("frecuencia")+`,
+				`+sumaDim("normalidad")+`,
+				`+sumaDim("gravedad")+`
+			from agg_valores e
+			`+joins+`
+			where `+where+`
+			group by 1
+		)
+		select
+			enc.clave, enc.label, enc.encuestas, enc.respuestas,
+			coalesce(v.sf, 0), coalesce(v.nf, 0),
+			coalesce(v.sn, 0), coalesce(v.nn, 0),
+			coalesce(v.sg, 0), coalesce(v.ng, 0)
+		from enc
+		left join val v (clave, sf, nf, sn, nn, sg, ng) on v.clave = enc.clave
+	`, args...).Query(func(rows pgx.Rows) error {
+		for rows.Next() {
+			var g Grupo
+			if err := rows.Scan(
+				&g.Clave, &g.Label, &g.Encuestas, &g.Respuestas,
+				&g.Suma[0], &g.N[0],
+				&g.Suma[1], &g.N[1],
+				&g.Suma[2], &g.N[2],
+			); err != nil {
+				return etapa(nombre, err)
+			}
+			*dst = append(*dst, g)
+		}
+		return etapa(nombre, rows.Err())
+	})
+}
+
+// etapa envuelve err (si hay) en *AgregadoError
+func etapa(nombre string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &AgregadoError{Etapa: nombre, Err: err}
+}
@@ -0,0 +1,392 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"mujer-back/stats"
+)
+
+// =======================================================
+// Serie anual y estadística avanzada a partir de los histogramas agg_*.
+// Los estadísticos se calculan con el paquete stats: σ y percentiles sobre
+// los histogramas (dan lo mismo que stddev_samp/percentile_cont sobre las
+// filas); alpha y el análisis de ítems sobre las sumas por par de ítems de
+// agg_items_cruzados (dan lo mismo que la matriz encuestas × ítems).
+// =======================================================
+
+// PuntoAnual son los promedios de un año
+type PuntoAnual struct {
+	Year       int     `json:"year"`
+	Frecuencia float64 `json:"frecuencia"`
+	Normalidad float64 `json:"normalidad"`
+	Gravedad   float64 `json:"gravedad"`
+	Total      float64 `json:"total"`
+	Encuestas  int64   `json:"encuestas"`  // encuestas finalizadas con al menos 1 respuesta
+	Respuestas int64   `json:"respuestas"` // total respuestas
+}
+
+// SerieAnual regresa un punto por año con datos (orden ascendente)
+func SerieAnual(ctx context.Context, pool *pgxpool.Pool, f FiltroAgregado) ([]PuntoAnual, error) {
+	where, args := f.SQL()
+	porAnio := map[int]*PuntoAnual{}
+	punto := func(y int) *PuntoAnual {
+		p, ok := porAnio[y]
+		if !ok {
+			p = &PuntoAnual{Year: y}
+			porAnio[y] = p
+		}
+		return p
+	}
+
+	batch := &pgx.Batch{}
+	batch.Queue(`
+		select extract(year from e.finished_at)::int, sum(e.encuestas)::bigint, sum(e.respuestas)::bigint
+		from agg_encuestas e
+		where `+where+`
+		group by 1
+	`, args...).Query(func(rows pgx.Rows) error {
+		for rows.Next() {
+			var y int
+			var enc, resp int64
+			if err := rows.Scan(&y, &enc, &resp); err != nil {
+				return etapa("anual_conteos", err)
+			}
+			p := punto(y)
+			p.Encuestas, p.Respuestas = enc, resp
+		}
+		return etapa("anual_conteos", rows.Err())
+	})
+	batch.Queue(`
+		select
+			extract(year from e.finished_at)::int,
+			`+promedioHist("e.dimension = 'frecuencia'")+`,
+			`+promedioHist("e.dimension = 'normalidad'")+`,
+			`+promedioHist("e.dimension = 'gravedad'")+`,
+			`+promedioHist("true")+`
+		from agg_valores e
+		where `+where+`
+		group by 1
+	`, args...).Query(func(rows pgx.Rows) error {
+		for rows.Next() {
+			var y int
+			var d Dimensiones
+			if err := rows.Scan(&y, &d.Frecuencia, &d.Normalidad, &d.Gravedad, &d.Total); err != nil {
+				return etapa("anual_promedios", err)
+			}
+			p := punto(y)
+			p.Frecuencia, p.Normalidad, p.Gravedad, p.Total = d.Frecuencia, d.Normalidad, d.Gravedad, d.Total
+		}
+		return etapa("anual_promedios", rows.Err())
+	})
+
+	if err := enviarBatch(ctx, pool, batch); err != nil {
+		return nil, err
+	}
+
+	out := make([]PuntoAnual, 0, len(porAnio))
+	for _, p := range porAnio {
+		out = append(out, *p)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Year < out[j].Year })
+	if len(out) == 0 {
+		return out, ErrSinDatos
+	}
+	return out, nil
+}
+
+// Confianza de los intervalos y réplicas del bootstrap de la estadística avanzada
+const (
+	ConfianzaIC       = 0.95
+	ReplicasBootstrap = 2000
+)
+
+// EstadisticaDimension es la estadística avanzada de una dimensión
+type EstadisticaDimension struct {
+	Dimension string `json:"dimension"`
+
+	// ✅ tamaños muestrales
+	NRespuestas     int64 `json:"n_respuestas"`     // por dimensión (ej. 112 = 7*16)
+	NEncuestas      int64 `json:"n_encuestas"`      // encuestas finalizadas (ej. 7)
+	TotalRespuestas int64 `json:"total_respuestas"` // total del periodo (ej. 336 = 7*48)
+	KItems          int64 `json:"k_items"`          // #ítems por dimensión (ej. 16)
+
+	// ✅ estadística por ítems
+	Promedio float64 `json:"promedio"`
+	StdDev   float64 `json:"std_dev"` // σ (ítems)
+
+	Mediana float64 `json:"mediana"`
+	P25     float64 `json:"p25"`
+	P75     float64 `json:"p75"`
+
+	// ✅ IC95 por ítems (t con n_respuestas - 1 gl)
+	IC95Inferior float64 `json:"ic95_inferior"`
+	IC95Superior float64 `json:"ic95_superior"`
+
+	// ✅ estadística entre encuestas (más conservador; t con n_encuestas - 1 gl)
+	StdDevEncuestas       float64 `json:"std_dev_encuestas"`
+	IC95InferiorEncuestas float64 `json:"ic95_inferior_encuestas"`
+	IC95SuperiorEncuestas float64 `json:"ic95_superior_encuestas"`
+
+	// IC95 bootstrap (percentil) del promedio entre encuestas; no supone normalidad
+	IC95InferiorBootstrap float64 `json:"ic95_inferior_bootstrap"`
+	IC95SuperiorBootstrap float64 `json:"ic95_superior_bootstrap"`
+
+	// alpha sobre las encuestas que respondieron todos los ítems, con IC de Feldt
+	AlphaCronbach       float64 `json:"alpha_cronbach"`
+	AlphaIC95Inferior   float64 `json:"alpha_ic95_inferior"`
+	AlphaIC95Superior   float64 `json:"alpha_ic95_superior"`
+	NEncuestasCompletas int64   `json:"n_encuestas_completas"`
+
+	// análisis por ítem (correlación ítem-total corregida, alpha si se elimina)
+	Items []ItemEstadistica `json:"items,omitempty"`
+
+	// menos de k encuestas: solo se reportan los tamaños muestrales
+	Suprimido bool `json:"suprimido,omitempty"`
+}
+
+// ItemEstadistica es un ítem dentro de su dimensión (nil = indefinido)
+type ItemEstadistica struct {
+	PreguntaID           string   `json:"pregunta_id"`
+	Promedio             float64  `json:"promedio"`
+	StdDev               float64  `json:"std_dev"`
+	CorrelacionItemTotal *float64 `json:"correlacion_item_total"`
+	AlphaSiSeElimina     *float64 `json:"alpha_si_se_elimina"`
+}
+
+// finito regresa nil para NaN (indefinido en stats)
+func finito(v float64) *float64 {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return nil
+	}
+	return &v
+}
+
+// cruzadoItems es una fila de agg_items_cruzados sumada sobre las celdas
+// del filtro: un par de ítems en las encuestas con nItems respuestas
+type cruzadoItems struct {
+	nItems        int
+	a, b          string
+	sumaA, sumaAB float64
+}
+
+// sumasCompletas arma las sumas de los ítems (en ese orden) con las
+// encuestas que respondieron exactamente esos ítems; porNItems son las
+// encuestas por número de respuestas en la dimensión (agg_encuestas_dim)
+func sumasCompletas(items []string, porNItems map[int]int64, cruzados []cruzadoItems) stats.SumasItems {
+	idx := make(map[string]int, len(items))
+	for j, pid := range items {
+		idx[pid] = j
+	}
+	sumas := stats.NuevasSumasItems(len(items))
+	sumas.N = porNItems[len(items)]
+	for _, c := range cruzados {
+		ia, okA := idx[c.a]
+		ib, okB := idx[c.b]
+		if c.nItems != len(items) || !okA || !okB {
+			continue
+		}
+		if ia == ib {
+			sumas.Suma[ia] += c.sumaA
+		}
+		sumas.Cruzados[ia][ib] += c.sumaAB
+		if ia != ib {
+			sumas.Cruzados[ib][ia] += c.sumaAB
+		}
+	}
+	return sumas
+}
+
+// ordenPregunta ordena P1, P2, ..., P10 por número (y si no, por texto)
+func ordenPregunta(a, b string) bool {
+	na, errA := strconv.Atoi(strings.TrimLeft(a, "Pp"))
+	nb, errB := strconv.Atoi(strings.TrimLeft(b, "Pp"))
+	if errA == nil && errB == nil && na != nb {
+		return na < nb
+	}
+	return a < b
+}
+
+// EstadisticaAvanzada calcula, por dimensión, dispersión, percentiles,
+// intervalos (t y bootstrap), alpha de Cronbach con su IC y el análisis por
+// ítem. Regresa ErrSinDatos si no hay respuestas.
+func EstadisticaAvanzada(ctx context.Context, pool *pgxpool.Pool, f FiltroAgregado) ([]EstadisticaDimension, error) {
+	where, args := f.SQL()
+
+	type dimAcc struct {
+		items     stats.Histograma // todos los valores de la dimensión
+		porItem   map[string]bool  // preguntas vistas
+		totales   stats.Histograma // puntaje total por encuesta
+		promedios stats.Histograma // promedio por encuesta
+		porNItems map[int]int64    // encuestas por número de ítems respondidos
+		cruzados  []cruzadoItems
+	}
+	dims := map[string]*dimAcc{}
+	acc := func(d string) *dimAcc {
+		a, ok := dims[d]
+		if !ok {
+			a = &dimAcc{porItem: map[string]bool{}, porNItems: map[int]int64{}}
+			dims[d] = a
+		}
+		return a
+	}
+
+	batch := &pgx.Batch{}
+	batch.Queue(`
+		select e.dimension, e.pregunta_id, e.valor::float8, sum(e.n)::bigint
+		from agg_valores e
+		where `+where+`
+		group by 1, 2, 3
+	`, args...).Query(func(rows pgx.Rows) error {
+		for rows.Next() {
+			var dim, pid string
+			var v float64
+			var n int64
+			if err := rows.Scan(&dim, &pid, &v, &n); err != nil {
+				return etapa("avanzada_valores", err)
+			}
+			a := acc(dim)
+			a.items.Add(v, n)
+			a.porItem[pid] = true
+		}
+		return etapa("avanzada_valores", rows.Err())
+	})
+	batch.Queue(`
+		select e.dimension, e.total_score::float8, e.n_items, sum(e.n)::bigint
+		from agg_encuestas_dim e
+		where `+where+`
+		group by 1, 2, 3
+	`, args...).Query(func(rows pgx.Rows) error {
+		for rows.Next() {
+			var dim string
+			var total float64
+			var nItems int
+			var n int64
+			if err := rows.Scan(&dim, &total, &nItems, &n); err != nil {
+				return etapa("avanzada_encuestas", err)
+			}
+			a := acc(dim)
+			a.totales.Add(total, n)
+			a.porNItems[nItems] += n
+			if nItems > 0 {
+				a.promedios.Add(total/float64(nItems), n)
+			}
+		}
+		return etapa("avanzada_encuestas", rows.Err())
+	})
+	batch.Queue(`
+		select e.dimension, e.n_items, e.pregunta_a, e.pregunta_b, sum(e.suma_a)::float8, sum(e.suma_ab)::float8
+		from agg_items_cruzados e
+		where `+where+`
+		group by 1, 2, 3, 4
+	`, args...).Query(func(rows pgx.Rows) error {
+		for rows.Next() {
+			var dim string
+			var c cruzadoItems
+			if err := rows.Scan(&dim, &c.nItems, &c.a, &c.b, &c.sumaA, &c.sumaAB); err != nil {
+				return etapa("avanzada_items", err)
+			}
+			a := acc(dim)
+			a.cruzados = append(a.cruzados, c)
+		}
+		return etapa("avanzada_items", rows.Err())
+	})
+
+	if err := enviarBatch(ctx, pool, batch); err != nil {
+		return nil, err
+	}
+
+	var totalRespuestas int64
+	for _, a := range dims {
+		totalRespuestas += a.items.N()
+	}
+
+	out := make([]EstadisticaDimension, 0, len(dims))
+	for dim, a := range dims {
+		if a.items.N() == 0 {
+			continue
+		}
+		d := EstadisticaDimension{
+			Dimension:       dim,
+			NRespuestas:     a.items.N(),
+			NEncuestas:      a.totales.N(),
+			TotalRespuestas: totalRespuestas,
+			KItems:          int64(len(a.porItem)),
+			Promedio:        a.items.Media(),
+			StdDev:          a.items.DesvEst(),
+			Mediana:         a.items.Percentil(0.5),
+			P25:             a.items.Percentil(0.25),
+			P75:             a.items.Percentil(0.75),
+		}
+		d.IC95Inferior, d.IC95Superior = stats.IntervaloT(d.Promedio, d.StdDev, d.NRespuestas, ConfianzaIC)
+
+		// σ e IC95 conservador entre encuestas (centrado en el promedio por ítems)
+		d.StdDevEncuestas = a.promedios.DesvEst()
+		d.IC95InferiorEncuestas, d.IC95SuperiorEncuestas = stats.IntervaloT(d.Promedio, d.StdDevEncuestas, a.promedios.N(), ConfianzaIC)
+
+		// bootstrap del promedio entre encuestas; semilla fija para que el
+		// mismo filtro dé siempre el mismo intervalo
+		var proms []float64
+		for _, b := range a.promedios.Bins {
+			for i := int64(0); i < b.N; i++ {
+				proms = append(proms, b.V)
+			}
+		}
+		d.IC95InferiorBootstrap, d.IC95SuperiorBootstrap = stats.IntervaloBootstrap(
+			proms, stats.Media, ReplicasBootstrap, ConfianzaIC, rand.New(rand.NewSource(1)),
+		)
+
+		// alpha y análisis de ítems: solo encuestas que respondieron todos
+		// los ítems que aparecen en el filtro
+		items := make([]string, 0, len(a.porItem))
+		for pid := range a.porItem {
+			items = append(items, pid)
+		}
+		sort.Slice(items, func(i, j int) bool { return ordenPregunta(items[i], items[j]) })
+		sumas := sumasCompletas(items, a.porNItems, a.cruzados)
+		d.NEncuestasCompletas = sumas.N
+		if alpha, ok := sumas.Alpha(); ok {
+			d.AlphaCronbach = alpha
+			d.AlphaIC95Inferior, d.AlphaIC95Superior, _ = stats.IntervaloAlphaFeldt(alpha, int(sumas.N), len(items), ConfianzaIC)
+		}
+		if sumas.N >= 2 {
+			for j, it := range sumas.AnalisisItems() {
+				d.Items = append(d.Items, ItemEstadistica{
+					PreguntaID:           items[j],
+					Promedio:             it.Media,
+					StdDev:               math.Sqrt(it.Varianza),
+					CorrelacionItemTotal: finito(it.CorrelacionItemTotal),
+					AlphaSiSeElimina:     finito(it.AlphaSiSeElimina),
+				})
+			}
+		}
+
+		out = append(out, d)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Dimension < out[j].Dimension })
+
+	if len(out) == 0 {
+		return out, ErrSinDatos
+	}
+	return out, nil
+}
+
+// enviarBatch manda el batch y asegura que el error sea *AgregadoError
+func enviarBatch(ctx context.Context, pool *pgxpool.Pool, batch *pgx.Batch) error {
+	if err := pool.SendBatch(ctx, batch).Close(); err != nil {
+		var ae *AgregadoError
+		if errors.As(err, &ae) {
+			return err
+		}
+		return &AgregadoError{Etapa: "batch", Err: err}
+	}
+	return nil
+}
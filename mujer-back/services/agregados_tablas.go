@@ -0,0 +1,261 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// =======================================================
+// Mantenimiento de agg_encuestas / agg_valores / agg_encuestas_dim /
+// agg_items_cruzados
+// Al guardar una encuesta se recalculan solo las celdas que tocó;
+// ReconstruirAgregados las vuelve a calcular todas.
+// =======================================================
+
+// dbtx es lo común entre *pgxpool.Pool y pgx.Tx
+type dbtx interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// CeldaAgregado es la llave de una fila de agregados
+type CeldaAgregado struct {
+	CentroID      int64
+	Fecha         time.Time // finished_at::date
+	AplicacionID  int64     // 0 = sin campaña
+	GeneroID      int64
+	Edad          int
+	InstrumentoID string
+}
+
+func (c CeldaAgregado) lockKey() string {
+	return "agg:" + strconv.FormatInt(c.CentroID, 10) + ":" + c.Fecha.Format("2006-01-02") + ":" +
+		strconv.FormatInt(c.AplicacionID, 10) + ":" + strconv.FormatInt(c.GeneroID, 10) + ":" +
+		strconv.Itoa(c.Edad) + ":" + c.InstrumentoID
+}
+
+// columnas de la llave, calculadas sobre encuestas e
+const aggLlaveSelect = `
+	e.centro_id,
+	e.finished_at::date,
+	coalesce(e.aplicacion_id, 0),
+	e.genero_id,
+	e.edad::int,
+	e.instrumento_id`
+
+const aggLlaveCols = `centro_id, finished_at, aplicacion_id, genero_id, edad, instrumento_id`
+
+// condición de una celda sobre encuestas e ($1..$6)
+const aggCeldaWhere = `
+	e.centro_id = $1
+	and e.finished_at::date = $2
+	and coalesce(e.aplicacion_id, 0) = $3
+	and e.genero_id = $4
+	and e.edad = $5
+	and e.instrumento_id = $6`
+
+// inserts de las tablas a partir de encuestas finalizadas; %s = condición extra
+const (
+	aggInsertEncuestas = `
+		insert into agg_encuestas (` + aggLlaveCols + `, encuestas, respuestas)
+		select ` + aggLlaveSelect + `, count(distinct e.id), count(*)
+		from encuestas e
+		join respuestas r on r.encuesta_id = e.id
+		where e.finished_at is not null and %s
+		group by 1, 2, 3, 4, 5, 6`
+
+	aggInsertValores = `
+		insert into agg_valores (` + aggLlaveCols + `, pregunta_id, dimension, valor, n)
+		select ` + aggLlaveSelect + `, r.pregunta_id, r.dimension::text, r.valor, count(*)
+		from encuestas e
+		join respuestas r on r.encuesta_id = e.id
+		where e.finished_at is not null and %s
+		group by 1, 2, 3, 4, 5, 6, 7, 8, 9`
+
+	aggInsertEncuestasDim = `
+		insert into agg_encuestas_dim (` + aggLlaveCols + `, dimension, total_score, n_items, n)
+		select ` + aggLlaveCols + `, dimension, total_score, n_items, count(*)
+		from (
+			select ` + aggLlaveSelect + `,
+				r.dimension::text as dimension,
+				sum(r.valor)::int as total_score,
+				count(*)::int as n_items
+			from encuestas e
+			join respuestas r on r.encuesta_id = e.id
+			where e.finished_at is not null and %s
+			group by e.id, 1, 2, 3, 4, 5, 6, r.dimension
+		) t (` + aggLlaveCols + `, dimension, total_score, n_items)
+		group by 1, 2, 3, 4, 5, 6, 7, 8, 9`
+
+	aggInsertItemsCruzados = `
+		insert into agg_items_cruzados (` + aggLlaveCols + `, dimension, n_items, pregunta_a, pregunta_b, suma_a, suma_ab)
+		select ` + aggLlaveCols + `, dimension, n_items, pregunta_a, pregunta_b, sum(valor_a), sum(valor_a * valor_b)
+		from (
+			select ` + aggLlaveSelect + `,
+				ra.dimension::text,
+				d.n_items,
+				ra.pregunta_id,
+				rb.pregunta_id,
+				ra.valor::bigint,
+				rb.valor::bigint
+			from encuestas e
+			join respuestas ra on ra.encuesta_id = e.id
+			join respuestas rb on rb.encuesta_id = e.id
+				and rb.dimension = ra.dimension
+				and rb.pregunta_id >= ra.pregunta_id
+			cross join lateral (
+				select count(*)::int as n_items
+				from respuestas r
+				where r.encuesta_id = e.id and r.dimension = ra.dimension
+			) d
+			where e.finished_at is not null and %s
+		) t (` + aggLlaveCols + `, dimension, n_items, pregunta_a, pregunta_b, valor_a, valor_b)
+		group by 1, 2, 3, 4, 5, 6, 7, 8, 9, 10`
+)
+
+var aggTablas = []string{"agg_encuestas", "agg_valores", "agg_encuestas_dim", "agg_items_cruzados"}
+
+// CeldaDeEncuesta regresa la celda de una encuesta; ok=false si no está finalizada
+func CeldaDeEncuesta(ctx context.Context, db dbtx, encuestaID string) (CeldaAgregado, bool, error) {
+	var c CeldaAgregado
+	err := db.QueryRow(ctx, `
+		select `+aggLlaveSelect+`
+		from encuestas e
+		where e.id = $1 and e.finished_at is not null
+	`, encuestaID).Scan(&c.CentroID, &c.Fecha, &c.AplicacionID, &c.GeneroID, &c.Edad, &c.InstrumentoID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return c, false, nil
+	}
+	if err != nil {
+		return c, false, fmt.Errorf("celda encuesta: %w", err)
+	}
+	return c, true, nil
+}
+
+// RefrescarCelda recalcula los agregados de una celda desde las tablas base.
+// Es idempotente; un advisory lock por celda evita que dos guardados
+// simultáneos se pisen.
+func RefrescarCelda(ctx context.Context, tx pgx.Tx, c CeldaAgregado) error {
+	if _, err := tx.Exec(ctx, `select pg_advisory_xact_lock(hashtext($1))`, c.lockKey()); err != nil {
+		return fmt.Errorf("refrescar agregados: %w", err)
+	}
+
+	args := []any{c.CentroID, c.Fecha, c.AplicacionID, c.GeneroID, c.Edad, c.InstrumentoID}
+
+	batch := &pgx.Batch{}
+	for _, t := range aggTablas {
+		batch.Queue(`
+			delete from `+t+`
+			where centro_id = $1 and finished_at = $2 and aplicacion_id = $3
+			  and genero_id = $4 and edad = $5 and instrumento_id = $6
+		`, args...)
+	}
+	for _, q := range []string{aggInsertEncuestas, aggInsertValores, aggInsertEncuestasDim, aggInsertItemsCruzados} {
+		batch.Queue(fmt.Sprintf(q, aggCeldaWhere), args...)
+	}
+	if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+		return fmt.Errorf("refrescar agregados: %w", err)
+	}
+	return nil
+}
+
+// RefrescarEncuesta recalcula la celda actual de la encuesta y, si cambió
+// (ej. se volvió a guardar otro día), también la anterior.
+func RefrescarEncuesta(ctx context.Context, tx pgx.Tx, encuestaID string, antes *CeldaAgregado) error {
+	despues, ok, err := CeldaDeEncuesta(ctx, tx, encuestaID)
+	if err != nil {
+		return err
+	}
+	if antes != nil && (!ok || *antes != despues) {
+		if err := RefrescarCelda(ctx, tx, *antes); err != nil {
+			return err
+		}
+	}
+	if ok {
+		return RefrescarCelda(ctx, tx, despues)
+	}
+	return nil
+}
+
+// ReconstruccionResultado resume una reconstrucción completa
+type ReconstruccionResultado struct {
+	Encuestas int64 `json:"encuestas"`
+	Celdas    int64 `json:"celdas"`
+	Valores   int64 `json:"valores"`
+	Duracion  int64 `json:"duracion_ms"`
+}
+
+// ReconstruirAgregados borra y recalcula todas las tablas de agregados en
+// una transacción. Bloquea los refrescos por celda mientras corre.
+func ReconstruirAgregados(ctx context.Context, pool *pgxpool.Pool) (ReconstruccionResultado, error) {
+	start := time.Now()
+	var out ReconstruccionResultado
+
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return out, fmt.Errorf("reconstruir agregados: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if _, err := tx.Exec(ctx, `lock table agg_encuestas, agg_valores, agg_encuestas_dim, agg_items_cruzados in share row exclusive mode`); err != nil {
+		return out, fmt.Errorf("reconstruir agregados: %w", err)
+	}
+	for _, t := range aggTablas {
+		if _, err := tx.Exec(ctx, `delete from `+t); err != nil {
+			return out, fmt.Errorf("reconstruir agregados %s: %w", t, err)
+		}
+	}
+
+	ct, err := tx.Exec(ctx, fmt.Sprintf(aggInsertEncuestas, "true"))
+	if err != nil {
+		return out, fmt.Errorf("reconstruir agregados agg_encuestas: %w", err)
+	}
+	out.Celdas = ct.RowsAffected()
+
+	ct, err = tx.Exec(ctx, fmt.Sprintf(aggInsertValores, "true"))
+	if err != nil {
+		return out, fmt.Errorf("reconstruir agregados agg_valores: %w", err)
+	}
+	out.Valores = ct.RowsAffected()
+
+	if _, err := tx.Exec(ctx, fmt.Sprintf(aggInsertEncuestasDim, "true")); err != nil {
+		return out, fmt.Errorf("reconstruir agregados agg_encuestas_dim: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, fmt.Sprintf(aggInsertItemsCruzados, "true")); err != nil {
+		return out, fmt.Errorf("reconstruir agregados agg_items_cruzados: %w", err)
+	}
+
+	if err := tx.QueryRow(ctx, `select coalesce(sum(encuestas), 0)::bigint from agg_encuestas`).Scan(&out.Encuestas); err != nil {
+		return out, fmt.Errorf("reconstruir agregados: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return out, fmt.Errorf("reconstruir agregados: %w", err)
+	}
+	out.Duracion = time.Since(start).Milliseconds()
+	return out, nil
+}
+
+// AgregadosVacios indica si hay encuestas finalizadas que aún no están en
+// los agregados (ej. justo después de crear las tablas; agg_items_cruzados
+// llegó en la migración 013, después de las demás).
+func AgregadosVacios(ctx context.Context, pool *pgxpool.Pool) (bool, error) {
+	var vacios bool
+	err := pool.QueryRow(ctx, `
+		select (not exists(select 1 from agg_encuestas) or not exists(select 1 from agg_items_cruzados))
+		   and exists(select 1 from encuestas where finished_at is not null)
+	`).Scan(&vacios)
+	if err != nil {
+		return false, fmt.Errorf("agregados vacios: %w", err)
+	}
+	return vacios, nil
+}
@@ -0,0 +1,586 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// =======================================================
+// Alertas de riesgo
+// Cada regla se evalúa por centro y año calendario contra las respuestas
+// crudas (la frecuencia ponderada necesita emparejar frecuencia y gravedad
+// de la misma pregunta). Una alerta vive en la tabla alertas mientras la
+// regla se siga cumpliendo; cuando deja de cumplirse pasa a "resuelta".
+// Con menos de max(k, min_encuestas) encuestas la regla no se evalúa.
+// =======================================================
+
+const (
+	AlertaActiva   = "activa"
+	AlertaResuelta = "resuelta"
+)
+
+// Alerta es una fila de la tabla alertas
+type Alerta struct {
+	ID            int64    `json:"id"`
+	CentroID      int64    `json:"centro_id"`
+	CentroNombre  string   `json:"centro_nombre"`
+	ReglaID       string   `json:"regla_id"`
+	ReglaNombre   string   `json:"regla_nombre"`
+	Severidad     string   `json:"severidad"`
+	Condicion     string   `json:"condicion"`
+	Metrica       string   `json:"metrica"`
+	TipoNum       *int32   `json:"tipo_num,omitempty"`
+	TipoNombre    *string  `json:"tipo_nombre,omitempty"`
+	Year          int      `json:"year"`
+	Estado        string   `json:"estado"`
+	Valor         float64  `json:"valor"`
+	Referencia    *float64 `json:"referencia,omitempty"` // año anterior (alza_anual)
+	Umbral        float64  `json:"umbral"`
+	Encuestas     int64    `json:"encuestas"`
+	Mensaje       string   `json:"mensaje"`
+	DetectadaAt   string   `json:"detectada_at"`
+	ActualizadaAt string   `json:"actualizada_at"`
+	ResueltaAt    string   `json:"resuelta_at,omitempty"`
+}
+
+// ResultadoAlertas resume una evaluación
+type ResultadoAlertas struct {
+	Centros   int `json:"centros"`
+	Activas   int `json:"activas"`   // reglas que se cumplen
+	Nuevas    int `json:"nuevas"`    // de ésas, las que no estaban activas
+	Resueltas int `json:"resueltas"` // dejaron de cumplirse
+}
+
+func (r *ResultadoAlertas) sumar(o ResultadoAlertas) {
+	r.Centros += o.Centros
+	r.Activas += o.Activas
+	r.Nuevas += o.Nuevas
+	r.Resueltas += o.Resueltas
+}
+
+// =======================================================
+// Métricas por centro
+// =======================================================
+
+// llaveMetrica: tipo 0 = todos los tipos
+type llaveMetrica struct {
+	Year    int
+	TipoNum int32
+}
+
+type metricaAnual struct {
+	TipoNombre string
+	Encuestas  int64
+	Valores    map[string]float64 // por métrica (solo las que tienen datos)
+}
+
+// metricasCentro calcula las métricas del año y del anterior, por tipo y global
+func metricasCentro(ctx context.Context, q dbtx, centroID int64, year int) (map[llaveMetrica]metricaAnual, error) {
+	rows, err := q.Query(ctx, `
+		with base as (
+			select
+				extract(year from e.finished_at)::int as y,
+				e.id as encuesta_id,
+				ip.tipo_num,
+				ip.tipo_nombre,
+				max(r.valor) filter (where r.dimension = 'frecuencia') as f,
+				max(r.valor) filter (where r.dimension = 'normalidad') as n,
+				max(r.valor) filter (where r.dimension = 'gravedad') as g
+			from respuestas r
+			join encuestas e on e.id = r.encuesta_id
+			join instrumento_preguntas ip
+			  on ip.instrumento_id = e.instrumento_id
+			 and ip.pregunta_id = r.pregunta_id
+			where e.centro_id = $1
+			  and e.finished_at >= make_date($2 - 1, 1, 1)
+			  and e.finished_at < make_date($2 + 1, 1, 1)
+			group by 1, 2, 3, 4, r.pregunta_id
+		)
+		select
+			y,
+			tipo_num,
+			coalesce(min(tipo_nombre), ''),
+			count(distinct encuesta_id),
+			avg(f)::float8,
+			avg(n)::float8,
+			avg(g)::float8,
+			(coalesce(sum(f), 0) + coalesce(sum(n), 0) + coalesce(sum(g), 0))::float8
+				/ nullif(count(f) + count(n) + count(g), 0),
+			(sum(f * g) filter (where g is not null))::float8
+				/ nullif(sum(g) filter (where f is not null), 0)
+		from base
+		group by grouping sets ((y, tipo_num), (y))
+	`, centroID, year)
+	if err != nil {
+		return nil, etapa("alertas_metricas", err)
+	}
+	defer rows.Close()
+
+	out := map[llaveMetrica]metricaAnual{}
+	for rows.Next() {
+		var (
+			y        int
+			tipoNum  *int32
+			nombre   string
+			n        int64
+			f, nn, g *float64
+			tot, fp  *float64
+		)
+		if err := rows.Scan(&y, &tipoNum, &nombre, &n, &f, &nn, &g, &tot, &fp); err != nil {
+			return nil, etapa("alertas_metricas", err)
+		}
+		m := metricaAnual{Encuestas: n, Valores: map[string]float64{}}
+		k := llaveMetrica{Year: y}
+		if tipoNum != nil {
+			k.TipoNum = *tipoNum
+			m.TipoNombre = nombre
+		}
+		for metrica, v := range map[string]*float64{
+			MetricaFrecuencia: f, MetricaNormalidad: nn, MetricaGravedad: g,
+			MetricaTotal: tot, MetricaFrecuenciaPonderada: fp,
+		} {
+			if v != nil {
+				m.Valores[metrica] = *v
+			}
+		}
+		out[k] = m
+	}
+	return out, etapa("alertas_metricas", rows.Err())
+}
+
+// evaluacionRegla es el resultado de una regla en un centro y año
+type evaluacionRegla struct {
+	Evaluable  bool
+	Dispara    bool
+	Valor      float64
+	Referencia *float64
+	Encuestas  int64
+	TipoNombre string
+	Mensaje    string
+}
+
+func evaluarRegla(r ReglaAlerta, mets map[llaveMetrica]metricaAnual, year, k int) evaluacionRegla {
+	minimo := int64(k)
+	if int64(r.MinEncuestas) > minimo {
+		minimo = int64(r.MinEncuestas)
+	}
+
+	tipo := int32(0)
+	if r.TipoNum != nil {
+		tipo = int32(*r.TipoNum)
+	}
+	actual, ok := mets[llaveMetrica{Year: year, TipoNum: tipo}]
+	if !ok || actual.Encuestas < minimo {
+		return evaluacionRegla{}
+	}
+	v, ok := actual.Valores[r.Metrica]
+	if !ok {
+		return evaluacionRegla{}
+	}
+
+	ev := evaluacionRegla{Evaluable: true, Valor: v, Encuestas: actual.Encuestas, TipoNombre: actual.TipoNombre}
+	ambito := "Todos los tipos"
+	if tipo != 0 {
+		ambito = actual.TipoNombre
+	}
+
+	switch r.Condicion {
+	case CondicionUmbral:
+		ev.Dispara = operadoresAlerta[r.Operador](v, r.Valor)
+		ev.Mensaje = fmt.Sprintf("%s: %s %.2f %s %.2f (%d encuestas, %d)",
+			ambito, strings.ToLower(EtiquetaMetrica(r.Metrica)), v, r.Operador, r.Valor, actual.Encuestas, year)
+
+	case CondicionAlzaAnual:
+		prev, ok := mets[llaveMetrica{Year: year - 1, TipoNum: tipo}]
+		if !ok || prev.Encuestas < minimo {
+			return evaluacionRegla{}
+		}
+		pv, ok := prev.Valores[r.Metrica]
+		if !ok {
+			return evaluacionRegla{}
+		}
+		ev.Referencia = &pv
+		ev.Dispara = v-pv > r.Valor
+		ev.Mensaje = fmt.Sprintf("%s: %s subió %.2f (%.2f en %d → %.2f en %d; umbral %.2f)",
+			ambito, strings.ToLower(EtiquetaMetrica(r.Metrica)), v-pv, pv, year-1, v, year, r.Valor)
+	}
+	return ev
+}
+
+// =======================================================
+// Motor: evaluación por centro, después de guardar y periódica
+// =======================================================
+
+type MotorAlertas struct {
+	DB     *pgxpool.Pool
+	Reglas []ReglaAlerta
+	K      int
+
+	mu         sync.Mutex
+	enCurso    map[llaveCentroAnio]bool
+	pendientes map[llaveCentroAnio]bool
+}
+
+type llaveCentroAnio struct {
+	CentroID int64
+	Year     int
+}
+
+func NuevoMotorAlertas(pool *pgxpool.Pool, reglas []ReglaAlerta, k int) *MotorAlertas {
+	if k <= 0 {
+		k = AnonimatoKDefault
+	}
+	return &MotorAlertas{
+		DB:         pool,
+		Reglas:     reglas,
+		K:          k,
+		enCurso:    map[llaveCentroAnio]bool{},
+		pendientes: map[llaveCentroAnio]bool{},
+	}
+}
+
+// Regla busca una regla por id
+func (m *MotorAlertas) Regla(id string) (ReglaAlerta, bool) {
+	for _, r := range m.Reglas {
+		if r.ID == id {
+			return r, true
+		}
+	}
+	return ReglaAlerta{}, false
+}
+
+// EvaluarCentro aplica todas las reglas a un centro y año y actualiza la
+// tabla alertas en una sola transacción
+func (m *MotorAlertas) EvaluarCentro(ctx context.Context, centroID int64, year int) (ResultadoAlertas, error) {
+	res := ResultadoAlertas{Centros: 1}
+
+	tx, err := m.DB.Begin(ctx)
+	if err != nil {
+		return res, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	mets, err := metricasCentro(ctx, tx, centroID, year)
+	if err != nil {
+		return res, err
+	}
+
+	ids := make([]string, 0, len(m.Reglas))
+	for _, r := range m.Reglas {
+		ids = append(ids, r.ID)
+		ev := evaluarRegla(r, mets, year, m.K)
+		if !ev.Evaluable {
+			// sin datos suficientes no se crea ni se resuelve nada
+			continue
+		}
+
+		var tipoNum *int32
+		var tipoNombre *string
+		if r.TipoNum != nil {
+			n := int32(*r.TipoNum)
+			tipoNum, tipoNombre = &n, &ev.TipoNombre
+		}
+
+		if !ev.Dispara {
+			tag, err := tx.Exec(ctx, `
+				update alertas
+				set estado = 'resuelta', resuelta_at = now(), actualizada_at = now(),
+				    valor = $4, referencia = $5, encuestas = $6
+				where centro_id = $1 and regla_id = $2 and year = $3 and estado = 'activa'
+			`, centroID, r.ID, year, ev.Valor, ev.Referencia, ev.Encuestas)
+			if err != nil {
+				return res, err
+			}
+			res.Resueltas += int(tag.RowsAffected())
+			continue
+		}
+
+		var estadoPrevio *string
+		err := tx.QueryRow(ctx, `
+			select estado from alertas
+			where centro_id = $1 and regla_id = $2 and year = $3
+			for update
+		`, centroID, r.ID, year).Scan(&estadoPrevio)
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return res, err
+		}
+
+		if _, err := tx.Exec(ctx, `
+			insert into alertas (
+				centro_id, regla_id, regla_nombre, severidad, condicion, metrica,
+				tipo_num, tipo_nombre, year, estado, valor, referencia, umbral, encuestas, mensaje
+			)
+			values ($1, $2, $3, $4, $5, $6, $7, $8, $9, 'activa', $10, $11, $12, $13, $14)
+			on conflict (centro_id, regla_id, year) do update set
+				regla_nombre = excluded.regla_nombre,
+				severidad = excluded.severidad,
+				condicion = excluded.condicion,
+				metrica = excluded.metrica,
+				tipo_num = excluded.tipo_num,
+				tipo_nombre = excluded.tipo_nombre,
+				valor = excluded.valor,
+				referencia = excluded.referencia,
+				umbral = excluded.umbral,
+				encuestas = excluded.encuestas,
+				mensaje = excluded.mensaje,
+				detectada_at = case when alertas.estado = 'resuelta' then now() else alertas.detectada_at end,
+				estado = 'activa',
+				resuelta_at = null,
+				actualizada_at = now()
+		`, centroID, r.ID, r.Nombre, r.Severidad, r.Condicion, r.Metrica,
+			tipoNum, tipoNombre, year, ev.Valor, ev.Referencia, r.Valor, ev.Encuestas, ev.Mensaje); err != nil {
+			return res, err
+		}
+		res.Activas++
+		if estadoPrevio == nil || *estadoPrevio != AlertaActiva {
+			res.Nuevas++
+		}
+	}
+
+	// reglas que ya no están en la configuración
+	tag, err := tx.Exec(ctx, `
+		update alertas
+		set estado = 'resuelta', resuelta_at = now(), actualizada_at = now()
+		where centro_id = $1 and year = $2 and estado = 'activa'
+		  and not (regla_id = any($3::text[]))
+	`, centroID, year, ids)
+	if err != nil {
+		return res, err
+	}
+	res.Resueltas += int(tag.RowsAffected())
+
+	return res, tx.Commit(ctx)
+}
+
+// EvaluarTodos evalúa los centros con encuestas en el año o con alertas activas
+func (m *MotorAlertas) EvaluarTodos(ctx context.Context, year int) (ResultadoAlertas, error) {
+	var res ResultadoAlertas
+
+	rows, err := m.DB.Query(ctx, `
+		select distinct centro_id from agg_encuestas
+		where finished_at >= make_date($1, 1, 1) and finished_at < make_date($1 + 1, 1, 1)
+		union
+		select centro_id from alertas where year = $1 and estado = 'activa'
+		order by 1
+	`, year)
+	if err != nil {
+		return res, err
+	}
+	centros, err := pgx.CollectRows(rows, pgx.RowTo[int64])
+	if err != nil {
+		return res, err
+	}
+
+	for _, c := range centros {
+		r, err := m.EvaluarCentro(ctx, c, year)
+		if err != nil {
+			return res, fmt.Errorf("centro %d: %w", c, err)
+		}
+		res.sumar(r)
+	}
+	return res, nil
+}
+
+// Programar evalúa un centro en segundo plano. Si ya hay una evaluación en
+// curso para el mismo centro y año, se repite una sola vez al terminar
+// (varias respuestas seguidas no disparan varias evaluaciones).
+func (m *MotorAlertas) Programar(centroID int64, year int) {
+	if m == nil || len(m.Reglas) == 0 {
+		return
+	}
+	key := llaveCentroAnio{CentroID: centroID, Year: year}
+
+	m.mu.Lock()
+	if m.enCurso[key] {
+		m.pendientes[key] = true
+		m.mu.Unlock()
+		return
+	}
+	m.enCurso[key] = true
+	m.mu.Unlock()
+
+	go func() {
+		for {
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			if _, err := m.EvaluarCentro(ctx, centroID, year); err != nil {
+				fmt.Println("Alertas error: centro", centroID, err)
+			}
+			cancel()
+
+			m.mu.Lock()
+			if m.pendientes[key] {
+				delete(m.pendientes, key)
+				m.mu.Unlock()
+				continue
+			}
+			delete(m.enCurso, key)
+			m.mu.Unlock()
+			return
+		}
+	}()
+}
+
+// ProgramarEncuesta evalúa en segundo plano el centro y año de una encuesta finalizada
+func (m *MotorAlertas) ProgramarEncuesta(encuestaID string) {
+	if m == nil || len(m.Reglas) == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var centroID int64
+	var year int
+	err := m.DB.QueryRow(ctx, `
+		select centro_id, extract(year from finished_at)::int
+		from encuestas
+		where id = $1::uuid and finished_at is not null
+	`, encuestaID).Scan(&centroID, &year)
+	if err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			fmt.Println("Alertas error: encuesta", encuestaID, err)
+		}
+		return
+	}
+	m.Programar(centroID, year)
+}
+
+// Iniciar evalúa todos los centros del año en curso cada intervalo
+// (y una vez al arrancar) hasta que se cancele ctx
+func (m *MotorAlertas) Iniciar(ctx context.Context, cada time.Duration) {
+	if m == nil || len(m.Reglas) == 0 || cada <= 0 {
+		return
+	}
+	go func() {
+		t := time.NewTicker(cada)
+		defer t.Stop()
+		for {
+			ectx, cancel := context.WithTimeout(ctx, cada)
+			res, err := m.EvaluarTodos(ectx, time.Now().Year())
+			cancel()
+			if err != nil {
+				fmt.Println("Alertas error:", err)
+			} else if res.Nuevas > 0 || res.Resueltas > 0 {
+				fmt.Println("Alertas:", res.Centros, "centros,", res.Activas, "activas,", res.Nuevas, "nuevas,", res.Resueltas, "resueltas")
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+			}
+		}
+	}()
+}
+
+// =======================================================
+// Consulta
+// =======================================================
+
+type FiltroAlertas struct {
+	Centros   []int64 // vacío = todos (admin)
+	Estado    string  // activa | resuelta | "" (todas)
+	Severidad string
+	Year      *int
+	Limit     int
+	Offset    int
+}
+
+type PaginaAlertas struct {
+	Items   []Alerta         `json:"items"`
+	Total   int64            `json:"total"`
+	Conteos map[string]int64 `json:"conteos"` // por estado, con los demás filtros
+}
+
+// ListarAlertas: activas primero, luego por severidad y lo más reciente
+func ListarAlertas(ctx context.Context, pool *pgxpool.Pool, f FiltroAlertas) (PaginaAlertas, error) {
+	out := PaginaAlertas{Items: []Alerta{}, Conteos: map[string]int64{AlertaActiva: 0, AlertaResuelta: 0}}
+
+	where := []string{"true"}
+	args := []any{}
+	add := func(cond string, v any) {
+		args = append(args, v)
+		where = append(where, strings.ReplaceAll(cond, "$?", "$"+strconv.Itoa(len(args))))
+	}
+	if len(f.Centros) > 0 {
+		add("a.centro_id = any($?::bigint[])", f.Centros)
+	}
+	if f.Severidad != "" {
+		add("a.severidad = $?", f.Severidad)
+	}
+	if f.Year != nil {
+		add("a.year = $?", *f.Year)
+	}
+	base := strings.Join(where, " and ")
+
+	rows, err := pool.Query(ctx, `
+		select a.estado, count(*) from alertas a where `+base+` group by a.estado
+	`, args...)
+	if err != nil {
+		return out, err
+	}
+	for rows.Next() {
+		var s string
+		var n int64
+		if err := rows.Scan(&s, &n); err != nil {
+			rows.Close()
+			return out, err
+		}
+		out.Conteos[s] = n
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return out, err
+	}
+
+	if f.Estado != "" {
+		add("a.estado = $?", f.Estado)
+		out.Total = out.Conteos[f.Estado]
+	} else {
+		out.Total = out.Conteos[AlertaActiva] + out.Conteos[AlertaResuelta]
+	}
+	args = append(args, f.Limit, f.Offset)
+
+	rows, err = pool.Query(ctx, `
+		select
+			a.id, a.centro_id, c.nombre, a.regla_id, a.regla_nombre, a.severidad,
+			a.condicion, a.metrica, a.tipo_num, a.tipo_nombre, a.year, a.estado,
+			a.valor, a.referencia, a.umbral, a.encuestas, a.mensaje,
+			to_char(a.detectada_at, 'YYYY-MM-DD HH24:MI'),
+			to_char(a.actualizada_at, 'YYYY-MM-DD HH24:MI'),
+			coalesce(to_char(a.resuelta_at, 'YYYY-MM-DD HH24:MI'), '')
+		from alertas a
+		join centros c on c.id = a.centro_id
+		where `+strings.Join(where, " and ")+`
+		order by
+			a.estado = 'activa' desc,
+			case a.severidad when 'alta' then 0 when 'media' then 1 else 2 end,
+			a.actualizada_at desc,
+			a.id desc
+		limit $`+strconv.Itoa(len(args)-1)+` offset $`+strconv.Itoa(len(args)), args...)
+	if err != nil {
+		return out, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var a Alerta
+		if err := rows.Scan(
+			&a.ID, &a.CentroID, &a.CentroNombre, &a.ReglaID, &a.ReglaNombre, &a.Severidad,
+			&a.Condicion, &a.Metrica, &a.TipoNum, &a.TipoNombre, &a.Year, &a.Estado,
+			&a.Valor, &a.Referencia, &a.Umbral, &a.Encuestas, &a.Mensaje,
+			&a.DetectadaAt, &a.ActualizadaAt, &a.ResueltaAt,
+		); err != nil {
+			return out, err
+		}
+		out.Items = append(out.Items, a)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,163 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// =======================================================
+// Reglas de alerta (config/alertas.json)
+// Ejemplos:
+//   - frecuencia ponderada por gravedad del tipo 4 >= 3.5
+//   - normalidad global que sube más de 0.5 respecto al año anterior
+// =======================================================
+
+// Métricas que puede vigilar una regla (promedios 1–5)
+const (
+	MetricaFrecuencia          = "frecuencia"
+	MetricaNormalidad          = "normalidad"
+	MetricaGravedad            = "gravedad"
+	MetricaTotal               = "total"
+	MetricaFrecuenciaPonderada = "frecuencia_ponderada" // frecuencia ponderada por la gravedad de la misma pregunta
+)
+
+// Condiciones
+const (
+	CondicionUmbral    = "umbral"     // métrica del año <operador> valor
+	CondicionAlzaAnual = "alza_anual" // métrica del año - métrica del año anterior > valor
+)
+
+var metricasAlerta = map[string]string{
+	MetricaFrecuencia:          "Frecuencia",
+	MetricaNormalidad:          "Normalidad",
+	MetricaGravedad:            "Gravedad",
+	MetricaTotal:               "Índice total",
+	MetricaFrecuenciaPonderada: "Frecuencia ponderada por gravedad",
+}
+
+var severidadesAlerta = map[string]bool{"baja": true, "media": true, "alta": true}
+
+var operadoresAlerta = map[string]func(a, b float64) bool{
+	">=": func(a, b float64) bool { return a >= b },
+	">":  func(a, b float64) bool { return a > b },
+	"<=": func(a, b float64) bool { return a <= b },
+	"<":  func(a, b float64) bool { return a < b },
+}
+
+type ReglaAlerta struct {
+	ID          string  `json:"id"`
+	Nombre      string  `json:"nombre"`
+	Descripcion string  `json:"descripcion,omitempty"`
+	Severidad   string  `json:"severidad"` // baja | media | alta
+	Metrica     string  `json:"metrica"`
+	TipoNum     *int    `json:"tipo_num,omitempty"` // nil = todos los tipos
+	Condicion   string  `json:"condicion"`
+	Operador    string  `json:"operador,omitempty"` // solo umbral: >=, >, <=, <
+	Valor       float64 `json:"valor"`              // umbral, o alza mínima en alza_anual
+
+	// Encuestas mínimas para evaluar (nunca menos que k de anonimato)
+	MinEncuestas int `json:"min_encuestas,omitempty"`
+}
+
+// EtiquetaMetrica regresa el nombre legible de la métrica
+func EtiquetaMetrica(m string) string {
+	if l, ok := metricasAlerta[m]; ok {
+		return l
+	}
+	return m
+}
+
+// ReglasInvalidas junta todos los problemas de config/alertas.json
+type ReglasInvalidas struct {
+	Problemas []string
+}
+
+func (e *ReglasInvalidas) Error() string {
+	return "reglas de alerta inválidas:\n  - " + strings.Join(e.Problemas, "\n  - ")
+}
+
+// LoadReglasAlerta lee y valida el archivo de reglas ({"reglas": [...]})
+func LoadReglasAlerta(path string) ([]ReglaAlerta, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read reglas de alerta: %w", err)
+	}
+	var cfg struct {
+		Reglas []ReglaAlerta `json:"reglas"`
+	}
+	if err := json.Unmarshal(b, &cfg); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	for i := range cfg.Reglas {
+		r := &cfg.Reglas[i]
+		r.ID = strings.TrimSpace(r.ID)
+		r.Severidad = strings.ToLower(strings.TrimSpace(r.Severidad))
+		r.Metrica = strings.ToLower(strings.TrimSpace(r.Metrica))
+		r.Condicion = strings.ToLower(strings.TrimSpace(r.Condicion))
+		r.Operador = strings.TrimSpace(r.Operador)
+	}
+	if err := ValidarReglasAlerta(cfg.Reglas); err != nil {
+		return nil, err
+	}
+	return cfg.Reglas, nil
+}
+
+// ValidarReglasAlerta revisa ids únicos, métricas, condiciones y operadores
+func ValidarReglasAlerta(reglas []ReglaAlerta) error {
+	var p []string
+	add := func(format string, args ...any) {
+		p = append(p, fmt.Sprintf(format, args...))
+	}
+
+	ids := map[string]bool{}
+	for i, r := range reglas {
+		if r.ID == "" {
+			add("regla #%d sin id", i+1)
+		} else if ids[r.ID] {
+			add("regla %s duplicada", r.ID)
+		}
+		ids[r.ID] = true
+
+		if strings.TrimSpace(r.Nombre) == "" {
+			add("regla %s sin nombre", r.ID)
+		}
+		if !severidadesAlerta[r.Severidad] {
+			add("regla %s: severidad %q (baja, media o alta)", r.ID, r.Severidad)
+		}
+		if _, ok := metricasAlerta[r.Metrica]; !ok {
+			add("regla %s: métrica %q no soportada", r.ID, r.Metrica)
+		}
+		if r.TipoNum != nil && *r.TipoNum <= 0 {
+			add("regla %s: tipo_num debe ser > 0", r.ID)
+		}
+		if r.MinEncuestas < 0 {
+			add("regla %s: min_encuestas negativo", r.ID)
+		}
+
+		switch r.Condicion {
+		case CondicionUmbral:
+			if _, ok := operadoresAlerta[r.Operador]; !ok {
+				add("regla %s: operador %q (>=, >, <=, <)", r.ID, r.Operador)
+			}
+			if r.Valor < 1 || r.Valor > 5 {
+				add("regla %s: umbral %.2f fuera de [1,5]", r.ID, r.Valor)
+			}
+		case CondicionAlzaAnual:
+			if r.Operador != "" {
+				add("regla %s: alza_anual no usa operador", r.ID)
+			}
+			if r.Valor <= 0 || r.Valor >= 4 {
+				add("regla %s: alza %.2f debe estar en (0,4)", r.ID, r.Valor)
+			}
+		default:
+			add("regla %s: condición %q (umbral o alza_anual)", r.ID, r.Condicion)
+		}
+	}
+
+	if len(p) > 0 {
+		return &ReglasInvalidas{Problemas: p}
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package services
+
+import (
+	"sort"
+	"strconv"
+)
+
+// =======================================================
+// k-anonimato
+// Ningún valor publicado puede salir de un grupo con menos de k encuestas:
+// - los grupos chicos se fusionan en "otros" (y si aun así no llegan a k, se ocultan)
+// - la edad nunca sale exacta, solo por rango
+// - los comentarios pierden género/edad cuando su celda tiene menos de k encuestas
+// =======================================================
+
+const AnonimatoKDefault = 5
+
+// Anonimato viaja en cada reporte para que el front sepa qué se ocultó
+type Anonimato struct {
+	K         int      `json:"k"`
+	Suprimido bool     `json:"suprimido"`        // true si algo se ocultó o se agrupó
+	Campos    []string `json:"campos,omitempty"` // ej. "stats.por_genero:no_binario", "matriz"
+}
+
+// Marcar registra un campo suprimido (sin repetir)
+func (a *Anonimato) Marcar(campo string) {
+	a.Suprimido = true
+	for _, c := range a.Campos {
+		if c == campo {
+			return
+		}
+	}
+	a.Campos = append(a.Campos, campo)
+}
+
+// CampoSuprimido arma la etiqueta "campo:clave" que se reporta en Anonimato.Campos
+func CampoSuprimido(campo, clave string) string {
+	return campo + ":" + clave
+}
+
+// CampoAnio es el campo de una serie anual que se reporta suprimido
+func CampoAnio(year int) string {
+	return CampoSuprimido("series", strconv.Itoa(year))
+}
+
+// AgruparPequenos fusiona los grupos con menos de k encuestas en uno solo
+// (clave "otros"). Si esa fusión no llega a k, absorbe al grupo visible más
+// chico hasta alcanzarlo; si ni así, no se publica nada.
+// Regresa los grupos publicables y las claves que se ocultaron.
+func AgruparPequenos(grupos []Grupo, k int, labelOtros string) ([]Grupo, []string) {
+	visibles := make([]Grupo, 0, len(grupos))
+	otros := Grupo{Clave: "otros", Label: labelOtros}
+	ocultos := []string{}
+
+	for _, g := range grupos {
+		if g.Encuestas < int64(k) {
+			otros.absorber(g)
+			ocultos = append(ocultos, g.Clave)
+			continue
+		}
+		visibles = append(visibles, g)
+	}
+	if len(ocultos) == 0 {
+		return visibles, ocultos
+	}
+
+	sort.Slice(visibles, func(i, j int) bool { return visibles[i].Encuestas < visibles[j].Encuestas })
+	for otros.Encuestas < int64(k) && len(visibles) > 0 {
+		otros.absorber(visibles[0])
+		ocultos = append(ocultos, visibles[0].Clave)
+		visibles = visibles[1:]
+	}
+
+	if otros.Encuestas >= int64(k) {
+		visibles = append(visibles, otros)
+	}
+	return visibles, ocultos
+}
+
+// Anonimizar aplica k-anonimato al resumen (en sitio) y regresa qué se suprimió.
+// Con menos de k participantes solo quedan los totales.
+func (res *ResumenAgregado) Anonimizar(k int) Anonimato {
+	if k <= 0 {
+		k = AnonimatoKDefault
+	}
+	anon := Anonimato{K: k}
+
+	if res.TotalParticipantes < int64(k) {
+		res.Global = Dimensiones{}
+		res.Matriz = []CeldaMatriz{}
+		res.PorGenero = []Grupo{}
+		res.PorEdad = []Grupo{}
+		res.Etiquetas = EtiquetasComentarios{Tipos: []EtiquetaTipo{}}
+		anon.Marcar("global")
+		anon.Marcar("matriz")
+		anon.Marcar("stats")
+		anon.Marcar("etiquetas_comentarios")
+		return anon
+	}
+
+	var ocultos []string
+	res.PorGenero, ocultos = AgruparPequenos(res.PorGenero, k, "Otros")
+	for _, c := range ocultos {
+		anon.Marcar(CampoSuprimido("stats.por_genero", c))
+	}
+	res.PorEdad, ocultos = AgruparPequenos(res.PorEdad, k, "Otras edades")
+	for _, c := range ocultos {
+		anon.Marcar(CampoSuprimido("stats.por_edad", c))
+	}
+
+	return anon
+}
+
+// AnonimizarEstadistica deja solo los tamaños muestrales de las dimensiones
+// con menos de anon.K encuestas (en sitio)
+func AnonimizarEstadistica(est []EstadisticaDimension, anon *Anonimato) {
+	k := anon.K
+	if k <= 0 {
+		k = AnonimatoKDefault
+	}
+	for i, d := range est {
+		if d.NEncuestas >= int64(k) {
+			continue
+		}
+		est[i] = EstadisticaDimension{
+			Dimension:       d.Dimension,
+			NRespuestas:     d.NRespuestas,
+			NEncuestas:      d.NEncuestas,
+			TotalRespuestas: d.TotalRespuestas,
+			KItems:          d.KItems,
+			Suprimido:       true,
+		}
+		anon.Marcar(CampoSuprimido("datos", d.Dimension))
+	}
+}
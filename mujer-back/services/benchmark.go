@@ -0,0 +1,489 @@
+package services
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"mujer-back/stats"
+)
+
+// =======================================================
+// Comparación entre centros (benchmarking)
+// Un centro entra a rankings y distribuciones solo si tiene al menos k
+// encuestas en el periodo; una distribución (por grupo o de pares) se
+// publica solo con BenchmarkMinCentros centros publicables, para que
+// ningún valor de la referencia se pueda atribuir a un centro.
+// =======================================================
+
+// BenchmarkMinCentros es el mínimo de centros publicables para publicar
+// una distribución. Con 3 o 4, promedio, P25, mediana y P75 bastan para
+// despejar el valor de cada centro.
+const BenchmarkMinCentros = 5
+
+// métricas por las que se ordena el ranking (las de Dimensiones)
+const (
+	MetricaBenchTotal      = "total"
+	MetricaBenchFrecuencia = "frecuencia"
+	MetricaBenchNormalidad = "normalidad"
+	MetricaBenchGravedad   = "gravedad"
+)
+
+// agrupaciones de /api/admin/resultados/comparar
+const (
+	BenchPorTipo   = "tipo"
+	BenchPorEstado = "estado"
+	BenchPorCiudad = "ciudad"
+)
+
+// MetricaBenchValida indica si m es una de las métricas del ranking
+func MetricaBenchValida(m string) bool {
+	switch m {
+	case MetricaBenchTotal, MetricaBenchFrecuencia, MetricaBenchNormalidad, MetricaBenchGravedad:
+		return true
+	}
+	return false
+}
+
+func (d Dimensiones) metrica(m string) float64 {
+	switch m {
+	case MetricaBenchFrecuencia:
+		return d.Frecuencia
+	case MetricaBenchNormalidad:
+		return d.Normalidad
+	case MetricaBenchGravedad:
+		return d.Gravedad
+	}
+	return d.Total
+}
+
+// FiltroBenchmark delimita los centros (activos) y el periodo que se comparan
+type FiltroBenchmark struct {
+	Tipo   string // escolar|laboral|"" (todos)
+	Estado string // sin distinguir mayúsculas
+	Ciudad string
+	Year   *int
+	Desde  *time.Time
+	Hasta  *time.Time
+}
+
+// CentroBenchmark son los promedios de un centro en el periodo.
+// Valores es nil cuando el centro no llega a k encuestas.
+type CentroBenchmark struct {
+	CentroID   int64        `json:"centro_id"`
+	Nombre     string       `json:"nombre"`
+	Tipo       string       `json:"tipo"`
+	Estado     string       `json:"estado,omitempty"`
+	Ciudad     string       `json:"ciudad,omitempty"`
+	Encuestas  int64        `json:"encuestas"`
+	Respuestas int64        `json:"respuestas"`
+	Valores    *Dimensiones `json:"valores,omitempty"`
+	Posicion   int          `json:"posicion,omitempty"`  // 1 = valor más alto en la métrica
+	Percentil  *float64     `json:"percentil,omitempty"` // % de los demás centros publicables por debajo (empates a la mitad)
+}
+
+// Distribucion resume los promedios de varios centros en una métrica.
+// Promedio es el promedio simple de los centros (cada centro pesa igual).
+type Distribucion struct {
+	Promedio float64 `json:"promedio"`
+	Min      float64 `json:"min"`
+	P25      float64 `json:"p25"`
+	Mediana  float64 `json:"mediana"`
+	P75      float64 `json:"p75"`
+	Max      float64 `json:"max"`
+}
+
+type DistribucionDimensiones struct {
+	Centros    int          `json:"centros"`
+	Frecuencia Distribucion `json:"frecuencia"`
+	Normalidad Distribucion `json:"normalidad"`
+	Gravedad   Distribucion `json:"gravedad"`
+	Total      Distribucion `json:"total"`
+}
+
+// Benchmark es el ranking de /api/admin/resultados
+type Benchmark struct {
+	Metrica      string                   `json:"metrica"`
+	Centros      []CentroBenchmark        `json:"centros"` // por posición; los suprimidos al final
+	Distribucion *DistribucionDimensiones `json:"distribucion,omitempty"`
+	Anonimato    Anonimato                `json:"anonimato"`
+}
+
+// GrupoBenchmark es un tipo, estado o ciudad en /api/admin/resultados/comparar
+type GrupoBenchmark struct {
+	Clave        string                   `json:"clave"`
+	Label        string                   `json:"label"`
+	Centros      int                      `json:"centros"`     // con encuestas en el periodo
+	Publicables  int                      `json:"publicables"` // con al menos k encuestas
+	Encuestas    int64                    `json:"encuestas"`
+	Distribucion *DistribucionDimensiones `json:"distribucion,omitempty"` // nil con menos de BenchmarkMinCentros publicables
+}
+
+// DatosBenchmark regresa los promedios de cada centro activo del filtro con
+// encuestas en el periodo (sin anonimizar; ver Publicables).
+func DatosBenchmark(ctx context.Context, pool *pgxpool.Pool, f FiltroBenchmark) ([]CentroBenchmark, error) {
+	rows, err := pool.Query(ctx, `
+		select id, nombre, tipo, coalesce(trim(estado), ''), coalesce(trim(ciudad), '')
+		from centros
+		where activo = true
+		  and ($1 = '' or tipo = $1)
+		  and ($2 = '' or lower(trim(estado)) = lower($2))
+		  and ($3 = '' or lower(trim(ciudad)) = lower($3))
+		order by id
+	`, f.Tipo, strings.TrimSpace(f.Estado), strings.TrimSpace(f.Ciudad))
+	if err != nil {
+		return nil, etapa("benchmark_centros", err)
+	}
+	porID := map[int64]*CentroBenchmark{}
+	ids := []int64{}
+	for rows.Next() {
+		c := &CentroBenchmark{}
+		if err := rows.Scan(&c.CentroID, &c.Nombre, &c.Tipo, &c.Estado, &c.Ciudad); err != nil {
+			rows.Close()
+			return nil, etapa("benchmark_centros", err)
+		}
+		porID[c.CentroID] = c
+		ids = append(ids, c.CentroID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, etapa("benchmark_centros", err)
+	}
+	if len(ids) == 0 {
+		return []CentroBenchmark{}, nil
+	}
+
+	where, args := FiltroAgregado{Centros: ids, Year: f.Year, Desde: f.Desde, Hasta: f.Hasta}.SQL()
+
+	batch := &pgx.Batch{}
+	batch.Queue(`
+		select e.centro_id, sum(e.encuestas)::bigint, sum(e.respuestas)::bigint
+		from agg_encuestas e
+		where `+where+`
+		group by e.centro_id
+	`, args...).Query(func(rows pgx.Rows) error {
+		for rows.Next() {
+			var id, enc, resp int64
+			if err := rows.Scan(&id, &enc, &resp); err != nil {
+				return etapa("benchmark_encuestas", err)
+			}
+			if c, ok := porID[id]; ok {
+				c.Encuestas, c.Respuestas = enc, resp
+			}
+		}
+		return etapa("benchmark_encuestas", rows.Err())
+	})
+
+	valores := map[int64]Dimensiones{}
+	batch.Queue(`
+		select
+			e.centro_id,
+			`+promedioHist("e.dimension = 'frecuencia'")+`,
+			`+promedioHist("e.dimension = 'normalidad'")+`,
+			`+promedioHist("e.dimension = 'gravedad'")+`,
+			`+promedioHist("true")+`
+		from agg_valores e
+		where `+where+`
+		group by e.centro_id
+	`, args...).Query(func(rows pgx.Rows) error {
+		for rows.Next() {
+			var id int64
+			var d Dimensiones
+			if err := rows.Scan(&id, &d.Frecuencia, &d.Normalidad, &d.Gravedad, &d.Total); err != nil {
+				return etapa("benchmark_valores", err)
+			}
+			valores[id] = d
+		}
+		return etapa("benchmark_valores", rows.Err())
+	})
+
+	if err := enviarBatch(ctx, pool, batch); err != nil {
+		return nil, err
+	}
+
+	out := make([]CentroBenchmark, 0, len(ids))
+	for _, id := range ids {
+		c := porID[id]
+		d, ok := valores[id]
+		if c.Respuestas == 0 || !ok {
+			continue
+		}
+		c.Valores = &d
+		out = append(out, *c)
+	}
+	return out, nil
+}
+
+// Publicables quita los valores de los centros con menos de k encuestas
+// (los deja en la lista, sin Valores) y los marca en anon
+func Publicables(cs []CentroBenchmark, k int, anon *Anonimato) []CentroBenchmark {
+	out := make([]CentroBenchmark, len(cs))
+	for i, c := range cs {
+		if c.Encuestas < int64(k) {
+			c.Valores = nil
+			anon.Marcar("centros")
+		}
+		out[i] = c
+	}
+	return out
+}
+
+// distribuir calcula la distribución de los centros con valores;
+// nil con menos de BenchmarkMinCentros
+func distribuir(cs []CentroBenchmark) *DistribucionDimensiones {
+	var h [4]stats.Histograma
+	n := 0
+	for _, c := range cs {
+		if c.Valores == nil {
+			continue
+		}
+		n++
+		for i, v := range []float64{c.Valores.Frecuencia, c.Valores.Normalidad, c.Valores.Gravedad, c.Valores.Total} {
+			h[i].Add(v, 1)
+		}
+	}
+	if n < BenchmarkMinCentros {
+		return nil
+	}
+	dist := func(h stats.Histograma) Distribucion {
+		return Distribucion{
+			Promedio: redondear2(h.Media()),
+			Min:      redondear2(h.Percentil(0)),
+			P25:      redondear2(h.Percentil(0.25)),
+			Mediana:  redondear2(h.Percentil(0.5)),
+			P75:      redondear2(h.Percentil(0.75)),
+			Max:      redondear2(h.Percentil(1)),
+		}
+	}
+	return &DistribucionDimensiones{
+		Centros:    n,
+		Frecuencia: dist(h[0]),
+		Normalidad: dist(h[1]),
+		Gravedad:   dist(h[2]),
+		Total:      dist(h[3]),
+	}
+}
+
+func redondear2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// percentilEn es el % de valores por debajo de v (los empates cuentan la mitad)
+func percentilEn(v float64, otros []float64) float64 {
+	if len(otros) == 0 {
+		return 0
+	}
+	var abajo float64
+	for _, o := range otros {
+		switch {
+		case o < v:
+			abajo++
+		case o == v:
+			abajo += 0.5
+		}
+	}
+	return math.Round(abajo/float64(len(otros))*1000) / 10
+}
+
+// RankingBenchmark ordena los centros publicables por la métrica (de mayor
+// a menor) y calcula la distribución del conjunto
+func RankingBenchmark(cs []CentroBenchmark, metrica string, k int) Benchmark {
+	b := Benchmark{Metrica: metrica, Anonimato: Anonimato{K: k}}
+	b.Centros = Publicables(cs, k, &b.Anonimato)
+
+	sort.SliceStable(b.Centros, func(i, j int) bool {
+		ci, cj := b.Centros[i], b.Centros[j]
+		if (ci.Valores == nil) != (cj.Valores == nil) {
+			return ci.Valores != nil
+		}
+		if ci.Valores == nil {
+			return ci.Nombre < cj.Nombre
+		}
+		return ci.Valores.metrica(metrica) > cj.Valores.metrica(metrica)
+	})
+
+	for i := range b.Centros {
+		c := &b.Centros[i]
+		if c.Valores == nil {
+			continue
+		}
+		// posición de competencia: empates comparten lugar
+		c.Posicion = i + 1
+		if i > 0 && b.Centros[i-1].Valores != nil && b.Centros[i-1].Valores.metrica(metrica) == c.Valores.metrica(metrica) {
+			c.Posicion = b.Centros[i-1].Posicion
+		}
+		otros := []float64{}
+		for j, o := range b.Centros {
+			if j != i && o.Valores != nil {
+				otros = append(otros, o.Valores.metrica(metrica))
+			}
+		}
+		pct := percentilEn(c.Valores.metrica(metrica), otros)
+		c.Percentil = &pct
+	}
+
+	b.Distribucion = distribuir(b.Centros)
+	if b.Distribucion == nil {
+		b.Anonimato.Marcar("distribucion")
+	}
+	return b
+}
+
+// CompararGrupos agrupa los centros por tipo, estado o ciudad
+func CompararGrupos(cs []CentroBenchmark, por string, k int, anon *Anonimato) []GrupoBenchmark {
+	cs = Publicables(cs, k, anon)
+
+	clave := func(c CentroBenchmark) (string, string) {
+		switch por {
+		case BenchPorEstado:
+			if c.Estado == "" {
+				return "", "Sin estado"
+			}
+			return strings.ToLower(c.Estado), c.Estado
+		case BenchPorCiudad:
+			if c.Ciudad == "" {
+				return "", "Sin ciudad"
+			}
+			l := c.Ciudad
+			if c.Estado != "" {
+				l += ", " + c.Estado
+			}
+			return strings.ToLower(c.Ciudad + "|" + c.Estado), l
+		}
+		if c.Tipo == "" {
+			return "", "Sin tipo"
+		}
+		return c.Tipo, strings.ToUpper(c.Tipo[:1]) + c.Tipo[1:]
+	}
+
+	orden := []string{}
+	porClave := map[string]*GrupoBenchmark{}
+	miembros := map[string][]CentroBenchmark{}
+	for _, c := range cs {
+		cl, label := clave(c)
+		g, ok := porClave[cl]
+		if !ok {
+			g = &GrupoBenchmark{Clave: cl, Label: label}
+			porClave[cl] = g
+			orden = append(orden, cl)
+		}
+		g.Centros++
+		g.Encuestas += c.Encuestas
+		if c.Valores != nil {
+			g.Publicables++
+		}
+		miembros[cl] = append(miembros[cl], c)
+	}
+
+	out := make([]GrupoBenchmark, 0, len(orden))
+	for _, cl := range orden {
+		g := porClave[cl]
+		g.Distribucion = distribuir(miembros[cl])
+		if g.Distribucion == nil {
+			anon.Marcar(CampoSuprimido("grupos", g.Label))
+		}
+		out = append(out, *g)
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Encuestas != out[j].Encuestas {
+			return out[i].Encuestas > out[j].Encuestas
+		}
+		return out[i].Label < out[j].Label
+	})
+	return out
+}
+
+// ReferenciaPares es la posición de un centro frente a sus pares en una
+// métrica. Sin mínimo ni máximo: con pocos pares serían el valor de un centro.
+type ReferenciaPares struct {
+	Valor     float64 `json:"valor"`
+	Percentil float64 `json:"percentil"` // % de pares por debajo del centro
+	Promedio  float64 `json:"promedio"`
+	P25       float64 `json:"p25"`
+	Mediana   float64 `json:"mediana"`
+	P75       float64 `json:"p75"`
+}
+
+// PosicionPares es lo que ve un centro en /api/centro/benchmark
+type PosicionPares struct {
+	CentroID   int64  `json:"centro_id"`
+	Nombre     string `json:"nombre"`
+	Tipo       string `json:"tipo"`
+	Encuestas  int64  `json:"encuestas"`
+	Pares      int    `json:"pares"` // centros publicables del mismo tipo, sin contar al propio
+	Publicable bool   `json:"publicable"`
+	Motivo     string `json:"motivo,omitempty"` // centro_bajo_k | pocos_pares | sin_datos
+
+	Frecuencia *ReferenciaPares `json:"frecuencia,omitempty"`
+	Normalidad *ReferenciaPares `json:"normalidad,omitempty"`
+	Gravedad   *ReferenciaPares `json:"gravedad,omitempty"`
+	Total      *ReferenciaPares `json:"total,omitempty"`
+
+	Anonimato Anonimato `json:"anonimato"`
+}
+
+// PosicionCentro compara un centro contra los demás centros de su tipo
+// (cs debe venir de DatosBenchmark con Tipo = el del centro)
+func PosicionCentro(cs []CentroBenchmark, centro CentroBenchmark, k int) PosicionPares {
+	p := PosicionPares{CentroID: centro.CentroID, Nombre: centro.Nombre, Tipo: centro.Tipo, Anonimato: Anonimato{K: k}}
+
+	var propio *CentroBenchmark
+	pares := []CentroBenchmark{}
+	for _, c := range Publicables(cs, k, &p.Anonimato) {
+		if c.CentroID == centro.CentroID {
+			c := c
+			propio = &c
+			continue
+		}
+		if c.Valores != nil {
+			pares = append(pares, c)
+		}
+	}
+	p.Pares = len(pares)
+
+	switch {
+	case propio == nil:
+		p.Motivo = "sin_datos"
+		return p
+	case propio.Valores == nil:
+		p.Encuestas = propio.Encuestas
+		p.Motivo = "centro_bajo_k"
+		return p
+	case len(pares) < BenchmarkMinCentros:
+		p.Encuestas = propio.Encuestas
+		p.Motivo = "pocos_pares"
+		p.Anonimato.Marcar("pares")
+		return p
+	}
+	p.Encuestas = propio.Encuestas
+	p.Publicable = true
+
+	ref := func(m string) *ReferenciaPares {
+		var h stats.Histograma
+		vals := make([]float64, 0, len(pares))
+		for _, c := range pares {
+			v := c.Valores.metrica(m)
+			h.Add(v, 1)
+			vals = append(vals, v)
+		}
+		v := propio.Valores.metrica(m)
+		return &ReferenciaPares{
+			Valor:     redondear2(v),
+			Percentil: percentilEn(v, vals),
+			Promedio:  redondear2(h.Media()),
+			P25:       redondear2(h.Percentil(0.25)),
+			Mediana:   redondear2(h.Percentil(0.5)),
+			P75:       redondear2(h.Percentil(0.75)),
+		}
+	}
+	p.Frecuencia = ref(MetricaBenchFrecuencia)
+	p.Normalidad = ref(MetricaBenchNormalidad)
+	p.Gravedad = ref(MetricaBenchGravedad)
+	p.Total = ref(MetricaBenchTotal)
+	return p
+}
@@ -0,0 +1,139 @@
+package services
+
+import (
+	"strings"
+	"unicode"
+)
+
+// =======================================================
+// Etiquetado temático de comentarios abiertos
+// Sin dependencias ni modelos: cada tipo de violencia del instrumento trae
+// su léxico ("keywords") y un comentario recibe la etiqueta del tipo si
+// contiene al menos uno de sus términos. La comparación ignora mayúsculas
+// y acentos; "humill*" cubre humillar, humillación, humillante, ...
+// =======================================================
+
+// patronPalabra es una palabra del término ya normalizada
+type patronPalabra struct {
+	texto   string
+	prefijo bool // terminaba en "*"
+}
+
+type lexicoTipo struct {
+	tipoNum  int
+	terminos [][]patronPalabra
+}
+
+// normalizarTexto: minúsculas y sin acentos (ñ → n, ü → u)
+func normalizarTexto(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+	for _, r := range strings.ToLower(s) {
+		switch r {
+		case 'á', 'à', 'ä', 'â':
+			r = 'a'
+		case 'é', 'è', 'ë', 'ê':
+			r = 'e'
+		case 'í', 'ì', 'ï', 'î':
+			r = 'i'
+		case 'ó', 'ò', 'ö', 'ô':
+			r = 'o'
+		case 'ú', 'ù', 'ü', 'û':
+			r = 'u'
+		case 'ñ':
+			r = 'n'
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// tokens parte el texto normalizado en palabras (letras y dígitos)
+func tokens(s string) []string {
+	return strings.FieldsFunc(normalizarTexto(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// compilarTermino convierte "acoso sexual", "humill*" en patrones; nil si no
+// queda ninguna palabra
+func compilarTermino(t string) []patronPalabra {
+	var out []patronPalabra
+	for _, w := range strings.Fields(normalizarTexto(t)) {
+		p := patronPalabra{}
+		if strings.HasSuffix(w, "*") {
+			p.prefijo = true
+			w = strings.TrimSuffix(w, "*")
+		}
+		// lo que no sea letra o dígito se ignora, igual que en el texto
+		for _, tk := range tokens(w) {
+			out = append(out, patronPalabra{texto: tk})
+		}
+		if len(out) > 0 && p.prefijo {
+			out[len(out)-1].prefijo = true
+		}
+	}
+	return out
+}
+
+func compilarLexico(tipos []TipoViolencia) []lexicoTipo {
+	out := make([]lexicoTipo, 0, len(tipos))
+	for _, t := range tipos {
+		lt := lexicoTipo{tipoNum: t.Order}
+		for _, k := range t.Keywords {
+			if p := compilarTermino(k); len(p) > 0 {
+				lt.terminos = append(lt.terminos, p)
+			}
+		}
+		if len(lt.terminos) > 0 {
+			out = append(out, lt)
+		}
+	}
+	return out
+}
+
+// coincide: el término aparece como palabras consecutivas en toks
+func coincide(toks []string, termino []patronPalabra) bool {
+	for i := 0; i+len(termino) <= len(toks); i++ {
+		ok := true
+		for j, p := range termino {
+			tk := toks[i+j]
+			if p.prefijo {
+				ok = strings.HasPrefix(tk, p.texto)
+			} else {
+				ok = tk == p.texto
+			}
+			if !ok {
+				break
+			}
+		}
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+// EtiquetarComentario regresa los tipos (order de types_of_violence) cuyo
+// léxico aparece en el texto, en el orden del instrumento
+func (i Instrumento) EtiquetarComentario(texto string) []int {
+	if len(i.lexico) == 0 {
+		return nil
+	}
+	toks := tokens(texto)
+	var out []int
+	for _, lt := range i.lexico {
+		for _, t := range lt.terminos {
+			if coincide(toks, t) {
+				out = append(out, lt.tipoNum)
+				break
+			}
+		}
+	}
+	return out
+}
+
+// TieneLexico indica si algún tipo del instrumento trae keywords
+func (i Instrumento) TieneLexico() bool {
+	return len(i.lexico) > 0
+}
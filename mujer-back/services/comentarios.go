@@ -0,0 +1,349 @@
+package services
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// =======================================================
+// Comentarios publicados del centro (ya moderados)
+// Paginación por cursor sobre (mes, id): la fecha va solo a nivel mes (como
+// los filtros, ver rangoMensual) porque día + género + rango de edad puede
+// señalar a una persona; dentro del mes el orden es por id, no por día. El
+// cursor viaja cifrado: el id de la encuesta nunca llega al centro.
+// La búsqueda es sobre texto_publicado, nunca sobre el original.
+// =======================================================
+
+// Comentario es un comentario libre con sus metadatos ya generalizados
+type Comentario struct {
+	Fecha     string `json:"fecha"` // YYYY-MM (solo mes)
+	Genero    string `json:"genero,omitempty"`
+	RangoEdad string `json:"rango_edad,omitempty"`
+	Texto     string `json:"texto"`
+	Tipos     []int  `json:"tipos,omitempty"` // etiquetas del clasificador (order del tipo)
+
+	encuestaID    string // solo para el cursor; con él se podría leer el resumen individual
+	instrumentoID string
+}
+
+// FiltroComentarios = filtros del resumen + búsqueda + página
+type FiltroComentarios struct {
+	FiltroAgregado
+	Q      string            // texto libre (websearch_to_tsquery en español)
+	Cursor *CursorComentario // nil = primera página
+	Limit  int
+}
+
+// CursorComentario apunta al último comentario de la página anterior
+type CursorComentario struct {
+	Fecha      string // YYYY-MM
+	EncuestaID string
+}
+
+var ErrCursorInvalido = errors.New("cursor inválido")
+
+var reCursorFecha = regexp.MustCompile(`^\d{4}-\d{2}$`)
+var reUUID = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// Sellar cifra el cursor (AES-GCM) con la clave del servidor: el front no
+// puede leer la fecha ni el id, ni fabricar un cursor
+func (c CursorComentario) Sellar(clave [32]byte) (string, error) {
+	aead, err := aeadCursor(clave)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sellado := aead.Seal(nonce, nonce, []byte(c.Fecha+"|"+c.EncuestaID), nil)
+	return base64.RawURLEncoding.EncodeToString(sellado), nil
+}
+
+// AbrirCursorComentario descifra lo que produjo CursorComentario.Sellar
+func AbrirCursorComentario(s string, clave [32]byte) (*CursorComentario, error) {
+	aead, err := aeadCursor(clave)
+	if err != nil {
+		return nil, err
+	}
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(b) < aead.NonceSize() {
+		return nil, ErrCursorInvalido
+	}
+	plano, err := aead.Open(nil, b[:aead.NonceSize()], b[aead.NonceSize():], nil)
+	if err != nil {
+		return nil, ErrCursorInvalido
+	}
+	fecha, id, ok := strings.Cut(string(plano), "|")
+	if !ok || !reCursorFecha.MatchString(fecha) || !reUUID.MatchString(id) {
+		return nil, ErrCursorInvalido
+	}
+	return &CursorComentario{Fecha: fecha, EncuestaID: id}, nil
+}
+
+func aeadCursor(clave [32]byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(clave[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// PaginaComentarios es una página de comentarios (antes de k-anonimato)
+type PaginaComentarios struct {
+	Items      []Comentario
+	Total      int64 // publicados que cumplen filtros + búsqueda
+	Publicados int64 // publicados que cumplen los filtros (sin búsqueda)
+	EnRevision int64 // pendientes de moderación en el filtro
+	Siguiente  *CursorComentario
+
+	participantes int64
+	// encuestas por (género, rango de edad) para decidir los metadatos
+	celdas map[string]int64
+}
+
+func celdaKey(genero, rango string) string {
+	return genero + "|" + rango
+}
+
+// comentarios visibles para el centro
+const comentarioPublicadoSQL = `
+	m.estado in ('aprobado', 'redactado')
+	and m.original_md5 = md5(e.comentario)`
+
+// PaginarComentarios regresa una página de comentarios publicados, más
+// recientes primero, en un solo round trip.
+func PaginarComentarios(ctx context.Context, pool *pgxpool.Pool, f FiltroComentarios) (PaginaComentarios, error) {
+	where, filtroArgs := f.SQL()
+	rangoSQL := RangoEdadSQL(f.RangosEdad())
+
+	p := PaginaComentarios{Items: []Comentario{}, celdas: map[string]int64{}}
+
+	// búsqueda: mismo índice que idx_comentarios_publicados_fts
+	args := append([]any{}, filtroArgs...)
+	busqueda := "true"
+	if q := strings.TrimSpace(f.Q); q != "" {
+		args = append(args, q)
+		busqueda = "to_tsvector('spanish', m.texto_publicado) @@ websearch_to_tsquery('spanish', $" + strconv.Itoa(len(args)) + ")"
+	}
+	nFiltro := len(args)
+
+	batch := &pgx.Batch{}
+
+	// participantes y celdas género × rango (para k-anonimato de los metadatos)
+	batch.Queue(`
+		select coalesce(g.etiqueta, ''), `+rangoSQL+`, sum(e.encuestas)::bigint
+		from agg_encuestas e
+		left join generos g on g.id = e.genero_id
+		where `+where+`
+		group by 1, 2
+	`, filtroArgs...).Query(func(rows pgx.Rows) error {
+		for rows.Next() {
+			var genero, rango string
+			var n int64
+			if err := rows.Scan(&genero, &rango, &n); err != nil {
+				return etapa("comentarios_celdas", err)
+			}
+			p.celdas[celdaKey(genero, rango)] = n
+			p.participantes += n
+		}
+		return etapa("comentarios_celdas", rows.Err())
+	})
+
+	// totales
+	batch.Queue(`
+		select
+			count(*) filter (where `+comentarioPublicadoSQL+` and `+busqueda+`),
+			count(*) filter (where `+comentarioPublicadoSQL+`),
+			count(*) filter (where m.estado = 'pendiente')
+		from encuestas e
+		join comentarios_moderacion m on m.encuesta_id = e.id
+		where `+where+`
+	`, args...).QueryRow(func(row pgx.Row) error {
+		return etapa("comentarios_totales", row.Scan(&p.Total, &p.Publicados, &p.EnRevision))
+	})
+
+	// página (limit + 1 para saber si hay siguiente)
+	pagArgs := append([]any{}, args...)
+	despues := ""
+	if f.Cursor != nil {
+		pagArgs = append(pagArgs, f.Cursor.Fecha, f.Cursor.EncuestaID)
+		despues = `
+		  and (date_trunc('month', e.finished_at)::date, e.id) < (to_date($` + strconv.Itoa(nFiltro+1) + `, 'YYYY-MM'), $` + strconv.Itoa(nFiltro+2) + `::uuid)`
+	}
+	pagArgs = append(pagArgs, f.Limit+1)
+
+	batch.Queue(`
+		select
+			e.id::text,
+			to_char(e.finished_at, 'YYYY-MM'),
+			coalesce(g.etiqueta, ''),
+			`+rangoSQL+`,
+			m.texto_publicado,
+			e.instrumento_id
+		from encuestas e
+		join comentarios_moderacion m on m.encuesta_id = e.id
+		left join generos g on g.id = e.genero_id
+		where `+where+`
+		  and `+comentarioPublicadoSQL+`
+		  and `+busqueda+despues+`
+		order by date_trunc('month', e.finished_at)::date desc, e.id desc
+		limit $`+strconv.Itoa(len(pagArgs))+`
+	`, pagArgs...).Query(func(rows pgx.Rows) error {
+		for rows.Next() {
+			var it Comentario
+			if err := rows.Scan(&it.encuestaID, &it.Fecha, &it.Genero, &it.RangoEdad, &it.Texto, &it.instrumentoID); err != nil {
+				return etapa("comentarios", err)
+			}
+			p.Items = append(p.Items, it)
+		}
+		return etapa("comentarios", rows.Err())
+	})
+
+	if err := enviarBatch(ctx, pool, batch); err != nil {
+		return p, err
+	}
+
+	if len(p.Items) > f.Limit {
+		p.Items = p.Items[:f.Limit]
+		last := p.Items[len(p.Items)-1]
+		p.Siguiente = &CursorComentario{Fecha: last.Fecha, EncuestaID: last.encuestaID}
+	}
+	return p, nil
+}
+
+// Anonimizar aplica k-anonimato a la página (en sitio):
+// - con menos de k participantes en el filtro no sale ningún comentario
+// - los comentarios pierden género/edad cuando su celda tiene menos de k encuestas
+func (p *PaginaComentarios) Anonimizar(k int) Anonimato {
+	if k <= 0 {
+		k = AnonimatoKDefault
+	}
+	anon := Anonimato{K: k}
+
+	if p.participantes < int64(k) {
+		p.Items = []Comentario{}
+		p.Total, p.Publicados, p.EnRevision = 0, 0, 0
+		p.Siguiente = nil
+		anon.Marcar("comentarios")
+		return anon
+	}
+
+	for i := range p.Items {
+		c := &p.Items[i]
+		if p.celdas[celdaKey(c.Genero, c.RangoEdad)] < int64(k) {
+			anon.Marcar("comentarios.metadatos")
+			c.Genero = ""
+			c.RangoEdad = ""
+		}
+		if c.RangoEdad == RangoEdadSinDato {
+			c.RangoEdad = ""
+		}
+	}
+	return anon
+}
+
+// Etiquetar agrega a cada comentario de la página los tipos de violencia que
+// menciona, según el léxico de su instrumento
+func (p *PaginaComentarios) Etiquetar(reg *Registro) {
+	for i := range p.Items {
+		c := &p.Items[i]
+		if inst, ok := reg.Get(c.instrumentoID); ok {
+			c.Tipos = inst.EtiquetarComentario(c.Texto)
+		}
+	}
+}
+
+// =======================================================
+// Etiquetas por tipo (junto a la matriz del resumen)
+// =======================================================
+
+// EtiquetaTipo cuenta los comentarios publicados que mencionan un tipo
+type EtiquetaTipo struct {
+	TipoNum     int32  `json:"tipo_num"`
+	TipoNombre  string `json:"tipo_nombre"`
+	Comentarios int64  `json:"comentarios"`
+}
+
+// EtiquetasComentarios: un comentario puede caer en varios tipos o en ninguno
+type EtiquetasComentarios struct {
+	Publicados  int64          `json:"publicados"`
+	Etiquetados int64          `json:"etiquetados"` // con al menos un tipo
+	Tipos       []EtiquetaTipo `json:"tipos"`
+}
+
+// ContarEtiquetas clasifica los comentarios publicados del filtro (texto
+// ya moderado) y cuenta cuántos caen en cada tipo. Los tipos de los
+// instrumentos con léxico salen aunque tengan 0 comentarios.
+func ContarEtiquetas(ctx context.Context, pool *pgxpool.Pool, reg *Registro, f FiltroAgregado) (EtiquetasComentarios, error) {
+	where, args := f.SQL()
+	out := EtiquetasComentarios{Tipos: []EtiquetaTipo{}}
+
+	rows, err := pool.Query(ctx, `
+		select e.instrumento_id, m.texto_publicado
+		from encuestas e
+		join comentarios_moderacion m on m.encuesta_id = e.id
+		where `+where+`
+		  and `+comentarioPublicadoSQL, args...)
+	if err != nil {
+		return out, etapa("etiquetas", err)
+	}
+	defer rows.Close()
+
+	conteo := map[int]int64{}
+	instrumentos := map[string]Instrumento{}
+	for rows.Next() {
+		var instID, texto string
+		if err := rows.Scan(&instID, &texto); err != nil {
+			return out, etapa("etiquetas", err)
+		}
+		out.Publicados++
+
+		inst, ok := instrumentos[instID]
+		if !ok {
+			if inst, ok = reg.Get(instID); !ok {
+				continue
+			}
+			instrumentos[instID] = inst
+		}
+		tipos := inst.EtiquetarComentario(texto)
+		if len(tipos) > 0 {
+			out.Etiquetados++
+		}
+		for _, t := range tipos {
+			conteo[t]++
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return out, etapa("etiquetas", err)
+	}
+
+	// nombres de los tipos (el primer instrumento del registro que lo declare)
+	nombres := map[int]string{}
+	for _, inst := range reg.All() {
+		if _, ok := instrumentos[inst.ID]; !ok || !inst.TieneLexico() {
+			continue
+		}
+		for _, t := range inst.TypesOfViolence {
+			if _, ok := nombres[t.Order]; !ok {
+				nombres[t.Order] = t.Label
+			}
+		}
+	}
+	for num, nombre := range nombres {
+		out.Tipos = append(out.Tipos, EtiquetaTipo{TipoNum: int32(num), TipoNombre: nombre, Comentarios: conteo[num]})
+	}
+	sort.Slice(out.Tipos, func(i, j int) bool { return out.Tipos[i].TipoNum < out.Tipos[j].TipoNum })
+	return out, nil
+}
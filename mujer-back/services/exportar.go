@@ -0,0 +1,258 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// =======================================================
+// Exportación tabular (CSV en zip y XLSX) sin dependencias externas.
+// Un reporte es una lista de hojas; en CSV cada hoja es un archivo.
+// El XLSX se arma a mano: es un zip con unas cuantas partes XML.
+// =======================================================
+
+// Hoja es una tabla exportable. Las celdas pueden ser string, int, int64,
+// float64, bool o nil.
+type Hoja struct {
+	Clave    string // nombre de archivo en el zip CSV (ej. "matriz")
+	Nombre   string // título de la pestaña en XLSX (máx. 31 caracteres)
+	Columnas []string
+	Filas    [][]any
+}
+
+// EscribirCSVZip escribe un zip con un CSV (UTF-8 con BOM, para Excel) por hoja
+func EscribirCSVZip(w io.Writer, hojas []Hoja) error {
+	zw := zip.NewWriter(w)
+	for i, h := range hojas {
+		f, err := zw.CreateHeader(&zip.FileHeader{
+			Name:     fmt.Sprintf("%02d_%s.csv", i+1, h.Clave),
+			Method:   zip.Deflate,
+			Modified: time.Now(),
+		})
+		if err != nil {
+			return err
+		}
+		if _, err := f.Write([]byte("\ufeff")); err != nil {
+			return err
+		}
+		if err := EscribirCSV(f, h); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// EscribirCSV escribe una hoja como CSV UTF-8 (sin BOM)
+func EscribirCSV(w io.Writer, h Hoja) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(h.Columnas); err != nil {
+		return err
+	}
+	for _, fila := range h.Filas {
+		rec := make([]string, len(fila))
+		for j, v := range fila {
+			rec[j] = celdaTexto(v)
+		}
+		if err := cw.Write(rec); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// celdaTexto formatea una celda para CSV (promedios a 4 decimales)
+func celdaTexto(v any) string {
+	switch x := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return x
+	case int:
+		return strconv.Itoa(x)
+	case int64:
+		return strconv.FormatInt(x, 10)
+	case float64:
+		if math.IsNaN(x) || math.IsInf(x, 0) {
+			return ""
+		}
+		return strconv.FormatFloat(x, 'f', 4, 64)
+	case bool:
+		if x {
+			return "sí"
+		}
+		return "no"
+	default:
+		return fmt.Sprint(x)
+	}
+}
+
+const (
+	xlsxHeader = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n"
+	xlsxNSMain = `http://schemas.openxmlformats.org/spreadsheetml/2006/main`
+	xlsxNSRel  = `http://schemas.openxmlformats.org/officeDocument/2006/relationships`
+	xlsxNSPkg  = `http://schemas.openxmlformats.org/package/2006/relationships`
+
+	// estilos: 0 = normal, 1 = encabezado en negritas, 2 = número con 2 decimales
+	xlsxStyles = xlsxHeader + `<styleSheet xmlns="` + xlsxNSMain + `">` +
+		`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>` +
+		`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+		`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+		`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+		`<cellXfs count="3">` +
+		`<xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>` +
+		`<xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/>` +
+		`<xf numFmtId="2" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+		`</cellXfs>` +
+		`</styleSheet>`
+)
+
+// EscribirXLSX escribe un libro de Excel con una pestaña por hoja
+func EscribirXLSX(w io.Writer, hojas []Hoja) error {
+	zw := zip.NewWriter(w)
+	parte := func(nombre, contenido string) error {
+		f, err := zw.CreateHeader(&zip.FileHeader{Name: nombre, Method: zip.Deflate, Modified: time.Now()})
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(f, contenido)
+		return err
+	}
+
+	var tipos, libro, rels strings.Builder
+	tipos.WriteString(xlsxHeader + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>`)
+	libro.WriteString(xlsxHeader + `<workbook xmlns="` + xlsxNSMain + `" xmlns:r="` + xlsxNSRel + `"><sheets>`)
+	rels.WriteString(xlsxHeader + `<Relationships xmlns="` + xlsxNSPkg + `">`)
+
+	usados := map[string]bool{}
+	for i, h := range hojas {
+		n := i + 1
+		fmt.Fprintf(&tipos, `<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, n)
+		fmt.Fprintf(&libro, `<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, xmlTexto(nombrePestana(h.Nombre, n, usados)), n, n)
+		fmt.Fprintf(&rels, `<Relationship Id="rId%d" Type="%s/worksheet" Target="worksheets/sheet%d.xml"/>`, n, xlsxNSRel, n)
+	}
+	tipos.WriteString(`</Types>`)
+	libro.WriteString(`</sheets></workbook>`)
+	fmt.Fprintf(&rels, `<Relationship Id="rId%d" Type="%s/styles" Target="styles.xml"/></Relationships>`, len(hojas)+1, xlsxNSRel)
+
+	// [Content_Types].xml primero: algunos lectores lo esperan al inicio del zip
+	for _, p := range []struct{ nombre, contenido string }{
+		{"[Content_Types].xml", tipos.String()},
+		{"_rels/.rels", xlsxHeader + `<Relationships xmlns="` + xlsxNSPkg + `"><Relationship Id="rId1" Type="` + xlsxNSRel + `/officeDocument" Target="xl/workbook.xml"/></Relationships>`},
+		{"xl/workbook.xml", libro.String()},
+		{"xl/_rels/workbook.xml.rels", rels.String()},
+		{"xl/styles.xml", xlsxStyles},
+	} {
+		if err := parte(p.nombre, p.contenido); err != nil {
+			return err
+		}
+	}
+	for i, h := range hojas {
+		if err := parte(fmt.Sprintf("xl/worksheets/sheet%d.xml", i+1), hojaXML(h)); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// nombrePestana limpia el nombre (Excel no acepta []:*?/\ ni más de 31
+// caracteres) y lo hace único dentro del libro
+func nombrePestana(nombre string, n int, usados map[string]bool) string {
+	nombre = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return '-'
+		}
+		return r
+	}, strings.TrimSpace(nombre))
+	if nombre == "" {
+		nombre = "Hoja " + strconv.Itoa(n)
+	}
+	if rs := []rune(nombre); len(rs) > 31 {
+		nombre = string(rs[:31])
+	}
+	for base, i := nombre, 2; usados[strings.ToLower(nombre)]; i++ {
+		suf := " (" + strconv.Itoa(i) + ")"
+		rs := []rune(base)
+		if len(rs)+len(suf) > 31 {
+			rs = rs[:31-len(suf)]
+		}
+		nombre = string(rs) + suf
+	}
+	usados[strings.ToLower(nombre)] = true
+	return nombre
+}
+
+// hojaXML arma sheetN.xml con cadenas en línea (sin sharedStrings)
+func hojaXML(h Hoja) string {
+	var b strings.Builder
+	b.WriteString(xlsxHeader + `<worksheet xmlns="` + xlsxNSMain + `"><sheetData>`)
+
+	fila := func(r int, celdas []any, estilo int) {
+		fmt.Fprintf(&b, `<row r="%d">`, r)
+		for j, v := range celdas {
+			ref := columnaExcel(j) + strconv.Itoa(r)
+			switch x := v.(type) {
+			case nil:
+				continue
+			case int:
+				fmt.Fprintf(&b, `<c r="%s"><v>%d</v></c>`, ref, x)
+			case int64:
+				fmt.Fprintf(&b, `<c r="%s"><v>%d</v></c>`, ref, x)
+			case float64:
+				if math.IsNaN(x) || math.IsInf(x, 0) {
+					continue
+				}
+				fmt.Fprintf(&b, `<c r="%s" s="2"><v>%s</v></c>`, ref, strconv.FormatFloat(x, 'g', -1, 64))
+			case bool:
+				v := 0
+				if x {
+					v = 1
+				}
+				fmt.Fprintf(&b, `<c r="%s" t="b"><v>%d</v></c>`, ref, v)
+			default:
+				fmt.Fprintf(&b, `<c r="%s" t="inlineStr" s="%d"><is><t xml:space="preserve">%s</t></is></c>`, ref, estilo, xmlTexto(celdaTexto(v)))
+			}
+		}
+		b.WriteString(`</row>`)
+	}
+
+	encabezado := make([]any, len(h.Columnas))
+	for i, c := range h.Columnas {
+		encabezado[i] = c
+	}
+	fila(1, encabezado, 1)
+	for i, f := range h.Filas {
+		fila(i+2, f, 0)
+	}
+
+	b.WriteString(`</sheetData></worksheet>`)
+	return b.String()
+}
+
+// columnaExcel convierte 0 → A, 25 → Z, 26 → AA
+func columnaExcel(i int) string {
+	s := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		s = string(rune('A'+(i-1)%26)) + s
+	}
+	return s
+}
+
+// xmlTexto escapa texto y atributos (EscapeText también escapa comillas)
+func xmlTexto(s string) string {
+	var b bytes.Buffer
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
@@ -0,0 +1,245 @@
+package services
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+)
+
+// =======================================================
+// Parquet mínimo: un row group, una página PLAIN sin comprimir por
+// columna. Enteros → INT32 opcional; cadenas → BYTE_ARRAY (UTF8) opcional.
+// Los metadatos van en Thrift compact protocol (escrito a mano).
+// =======================================================
+
+// tipos de Parquet que usamos
+const (
+	pqInt32     = 1
+	pqByteArray = 6
+
+	pqOptional = 1
+	pqUTF8     = 0 // ConvertedType
+
+	pqPlain = 0
+	pqRLE   = 3
+)
+
+// tipos del compact protocol
+const (
+	tcI32    = 5
+	tcI64    = 6
+	tcBinary = 8
+	tcList   = 9
+	tcStruct = 12
+)
+
+// thriftCompact escribe structs en compact protocol
+type thriftCompact struct {
+	buf    bytes.Buffer
+	ultimo []int16 // último field id por nivel de struct
+}
+
+func (t *thriftCompact) varint(v uint64) {
+	var b [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(b[:], v)
+	t.buf.Write(b[:n])
+}
+
+func zigzag(v int64) uint64 {
+	return uint64((v << 1) ^ (v >> 63))
+}
+
+func (t *thriftCompact) campo(id int16, tipo byte) {
+	nivel := len(t.ultimo) - 1
+	delta := id - t.ultimo[nivel]
+	if delta > 0 && delta <= 15 {
+		t.buf.WriteByte(byte(delta)<<4 | tipo)
+	} else {
+		t.buf.WriteByte(tipo)
+		t.varint(zigzag(int64(id)))
+	}
+	t.ultimo[nivel] = id
+}
+
+func (t *thriftCompact) i32(id int16, v int32) {
+	t.campo(id, tcI32)
+	t.varint(zigzag(int64(v)))
+}
+
+func (t *thriftCompact) i64(id int16, v int64) {
+	t.campo(id, tcI64)
+	t.varint(zigzag(v))
+}
+
+func (t *thriftCompact) binario(id int16, s string) {
+	t.campo(id, tcBinary)
+	t.varint(uint64(len(s)))
+	t.buf.WriteString(s)
+}
+
+// lista escribe el encabezado de una lista de n elementos
+func (t *thriftCompact) lista(id int16, tipo byte, n int) {
+	t.campo(id, tcList)
+	if n < 15 {
+		t.buf.WriteByte(byte(n)<<4 | tipo)
+	} else {
+		t.buf.WriteByte(0xF0 | tipo)
+		t.varint(uint64(n))
+	}
+}
+
+// abrir empieza un struct (como campo id, o como elemento de lista si id = 0)
+func (t *thriftCompact) abrir(id int16) {
+	if id != 0 {
+		t.campo(id, tcStruct)
+	}
+	t.ultimo = append(t.ultimo, 0)
+}
+
+func (t *thriftCompact) cerrar() {
+	t.buf.WriteByte(0) // STOP
+	t.ultimo = t.ultimo[:len(t.ultimo)-1]
+}
+
+func nuevoThrift() *thriftCompact {
+	return &thriftCompact{ultimo: []int16{0}}
+}
+
+type pqColumna struct {
+	nombre  string
+	tipo    int32
+	offset  int64 // inicio de la página en el archivo
+	tamano  int64 // encabezado + datos
+	valores int64
+}
+
+// EscribirParquet escribe los microdatos como archivo .parquet
+func EscribirParquet(w io.Writer, m Microdatos) error {
+	var out bytes.Buffer
+	out.WriteString("PAR1")
+
+	n := len(m.Filas)
+	cols := make([]pqColumna, len(m.Variables))
+
+	for ci, v := range m.Variables {
+		col := pqColumna{nombre: v.Nombre, tipo: pqInt32, valores: int64(n)}
+		if v.Texto {
+			col.tipo = pqByteArray
+		}
+
+		// niveles de definición (1 = hay valor) en RLE/bit-packed híbrido:
+		// un solo run bit-packed de ⌈n/8⌉ grupos, ancho 1 bit
+		var niveles bytes.Buffer
+		if n > 0 {
+			grupos := (n + 7) / 8
+			var hdr [binary.MaxVarintLen64]byte
+			niveles.Write(hdr[:binary.PutUvarint(hdr[:], uint64(grupos<<1|1))])
+			bits := make([]byte, grupos)
+			for i, fila := range m.Filas {
+				if fila[ci] != nil {
+					bits[i/8] |= 1 << (i % 8)
+				}
+			}
+			niveles.Write(bits)
+		}
+
+		var datos bytes.Buffer
+		_ = binary.Write(&datos, binary.LittleEndian, uint32(niveles.Len()))
+		datos.Write(niveles.Bytes())
+		for _, fila := range m.Filas {
+			switch x := fila[ci].(type) {
+			case nil:
+			case string:
+				_ = binary.Write(&datos, binary.LittleEndian, uint32(len(x)))
+				datos.WriteString(x)
+			case int64:
+				_ = binary.Write(&datos, binary.LittleEndian, int32(x))
+			case int:
+				_ = binary.Write(&datos, binary.LittleEndian, int32(x))
+			}
+		}
+
+		// PageHeader + DataPageHeader
+		ph := nuevoThrift()
+		ph.i32(1, 0) // DATA_PAGE
+		ph.i32(2, int32(datos.Len()))
+		ph.i32(3, int32(datos.Len()))
+		ph.abrir(5)
+		ph.i32(1, int32(n))
+		ph.i32(2, pqPlain)
+		ph.i32(3, pqRLE)
+		ph.i32(4, pqRLE)
+		ph.cerrar()
+		ph.buf.WriteByte(0)
+
+		col.offset = int64(out.Len())
+		col.tamano = int64(ph.buf.Len() + datos.Len())
+		out.Write(ph.buf.Bytes())
+		out.Write(datos.Bytes())
+		cols[ci] = col
+	}
+
+	// ==========================
+	// FileMetaData
+	// ==========================
+	md := nuevoThrift()
+	md.i32(1, 1) // version
+
+	md.lista(2, tcStruct, len(cols)+1)
+	md.abrir(0) // raíz
+	md.binario(4, "schema")
+	md.i32(5, int32(len(cols)))
+	md.cerrar()
+	for _, c := range cols {
+		md.abrir(0)
+		md.i32(1, c.tipo)
+		md.i32(3, pqOptional)
+		md.binario(4, c.nombre)
+		if c.tipo == pqByteArray {
+			md.i32(6, pqUTF8)
+		}
+		md.cerrar()
+	}
+
+	md.i64(3, int64(n))
+
+	var total int64
+	for _, c := range cols {
+		total += c.tamano
+	}
+	md.lista(4, tcStruct, 1)
+	md.abrir(0) // RowGroup
+	md.lista(1, tcStruct, len(cols))
+	for _, c := range cols {
+		md.abrir(0) // ColumnChunk
+		md.i64(2, c.offset)
+		md.abrir(3) // ColumnMetaData
+		md.i32(1, c.tipo)
+		md.lista(2, tcI32, 2)
+		md.varint(zigzag(pqPlain))
+		md.varint(zigzag(pqRLE))
+		md.lista(3, tcBinary, 1)
+		md.varint(uint64(len(c.nombre)))
+		md.buf.WriteString(c.nombre)
+		md.i32(4, 0) // UNCOMPRESSED
+		md.i64(5, c.valores)
+		md.i64(6, c.tamano)
+		md.i64(7, c.tamano)
+		md.i64(9, c.offset)
+		md.cerrar()
+		md.cerrar()
+	}
+	md.i64(2, total)
+	md.i64(3, int64(n))
+	md.cerrar()
+
+	md.binario(6, "mujer-back")
+	md.buf.WriteByte(0) // fin de FileMetaData
+
+	out.Write(md.buf.Bytes())
+	_ = binary.Write(&out, binary.LittleEndian, uint32(md.buf.Len()))
+	out.WriteString("PAR1")
+
+	_, err := out.WriteTo(w)
+	return err
+}
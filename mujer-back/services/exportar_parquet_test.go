@@ -0,0 +1,264 @@
+package services
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// microdatosPrueba tiene enteros y cadenas con nulos, una cadena de más
+// de 8 bytes con acentos y más de 8 filas (varios grupos de bits)
+func microdatosPrueba() Microdatos {
+	escala := []ScaleOption{{Value: 1, Label: "Nunca"}, {Value: 5, Label: "Muy frecuentemente"}}
+	m := Microdatos{
+		Variables: []VariableMicro{
+			{Nombre: "fila", Etiqueta: "Número de fila"},
+			{Nombre: "centro", Etiqueta: "Código de centro", Texto: true},
+			{Nombre: "genero", Texto: true},
+			{Nombre: "P1_frecuencia", Etiqueta: "P1 frecuencia: ¿Con qué frecuencia…?", Valores: escala},
+			{Nombre: "P1_gravedad", Valores: escala},
+		},
+	}
+	for i := 0; i < 19; i++ {
+		var genero, p1 any
+		if i%3 != 0 {
+			genero = "mujer"
+		}
+		if i%4 != 1 {
+			p1 = int64(i%5 + 1)
+		}
+		centro := fmt.Sprintf("C%02d", i%4)
+		if i == 7 {
+			centro = "Centro Educación Núm. 7"
+		}
+		m.Filas = append(m.Filas, []any{int64(i + 1), centro, genero, p1, int64(5 - i%5)})
+	}
+	return m
+}
+
+// ==========================
+// Lector de Thrift compact protocol (solo lo que usa Parquet)
+// ==========================
+
+type lectorThrift struct {
+	b []byte
+	i int
+}
+
+func (l *lectorThrift) uvarint() uint64 {
+	v, n := binary.Uvarint(l.b[l.i:])
+	if n <= 0 {
+		panic("varint inválido")
+	}
+	l.i += n
+	return v
+}
+
+func (l *lectorThrift) entero() int64 {
+	u := l.uvarint()
+	return int64(u>>1) ^ -int64(u&1)
+}
+
+func (l *lectorThrift) valor(tipo byte) any {
+	switch tipo {
+	case 1:
+		return true
+	case 2:
+		return false
+	case tcI32, tcI64:
+		return l.entero()
+	case tcBinary:
+		n := int(l.uvarint())
+		s := string(l.b[l.i : l.i+n])
+		l.i += n
+		return s
+	case tcList:
+		h := l.b[l.i]
+		l.i++
+		n, et := int(h>>4), h&0x0f
+		if n == 15 {
+			n = int(l.uvarint())
+		}
+		out := make([]any, n)
+		for j := range out {
+			out[j] = l.valor(et)
+		}
+		return out
+	case tcStruct:
+		return l.estructura()
+	}
+	panic(fmt.Sprintf("tipo thrift %d", tipo))
+}
+
+// estructura regresa field id -> valor
+func (l *lectorThrift) estructura() map[int16]any {
+	out := map[int16]any{}
+	var ultimo int16
+	for {
+		h := l.b[l.i]
+		l.i++
+		if h == 0 {
+			return out
+		}
+		tipo := h & 0x0f
+		id := ultimo + int16(h>>4)
+		if h>>4 == 0 {
+			id = int16(l.entero())
+		}
+		out[id] = l.valor(tipo)
+		ultimo = id
+	}
+}
+
+func TestEscribirParquet(t *testing.T) {
+	m := microdatosPrueba()
+	var buf bytes.Buffer
+	if err := EscribirParquet(&buf, m); err != nil {
+		t.Fatal(err)
+	}
+	b := buf.Bytes()
+
+	if string(b[:4]) != "PAR1" || string(b[len(b)-4:]) != "PAR1" {
+		t.Fatalf("magic: %q ... %q", b[:4], b[len(b)-4:])
+	}
+	largo := int(binary.LittleEndian.Uint32(b[len(b)-8:]))
+	inicio := len(b) - 8 - largo
+	l := &lectorThrift{b: b[:len(b)-8], i: inicio}
+	md := l.estructura()
+	if l.i != len(b)-8 {
+		t.Fatalf("FileMetaData: leí %d bytes, el pie dice %d", l.i-inicio, largo)
+	}
+
+	// FileMetaData: 1 version, 2 schema, 3 num_rows, 4 row_groups
+	n := int64(len(m.Filas))
+	if md[1] != int64(1) || md[3] != n {
+		t.Errorf("version = %v, num_rows = %v", md[1], md[3])
+	}
+	schema := md[2].([]any)
+	if len(schema) != len(m.Variables)+1 {
+		t.Fatalf("schema con %d elementos", len(schema))
+	}
+	raiz := schema[0].(map[int16]any)
+	if raiz[4] != "schema" || raiz[5] != int64(len(m.Variables)) {
+		t.Errorf("raíz = %v", raiz)
+	}
+	for i, v := range m.Variables {
+		// SchemaElement: 1 type, 3 repetition_type, 4 name, 6 converted_type
+		se := schema[i+1].(map[int16]any)
+		quiero := map[int16]any{1: int64(pqInt32), 3: int64(pqOptional), 4: v.Nombre}
+		if v.Texto {
+			quiero[1] = int64(pqByteArray)
+			quiero[6] = int64(pqUTF8)
+		}
+		if !reflect.DeepEqual(se, quiero) {
+			t.Errorf("schema %s = %v, quiero %v", v.Nombre, se, quiero)
+		}
+	}
+
+	grupos := md[4].([]any)
+	if len(grupos) != 1 {
+		t.Fatalf("%d row groups", len(grupos))
+	}
+	// RowGroup: 1 columns, 2 total_byte_size, 3 num_rows
+	rg := grupos[0].(map[int16]any)
+	cols := rg[1].([]any)
+	if len(cols) != len(m.Variables) || rg[3] != n {
+		t.Fatalf("row group: %d columnas, num_rows %v", len(cols), rg[3])
+	}
+
+	var total int64
+	for ci, v := range m.Variables {
+		// ColumnChunk: 2 file_offset, 3 meta_data
+		cc := cols[ci].(map[int16]any)
+		meta := cc[3].(map[int16]any)
+		offset, ok1 := meta[9].(int64)
+		tamano, ok2 := meta[7].(int64)
+		if !ok1 || !ok2 {
+			t.Fatalf("%s: column meta_data sin data_page_offset o tamaño: %v", v.Nombre, meta)
+		}
+		total += tamano
+		if cc[2] != offset || meta[6] != tamano {
+			t.Errorf("%s: file_offset %v, data_page_offset %v, tamaños %v/%v", v.Nombre, cc[2], offset, meta[6], tamano)
+		}
+		if !reflect.DeepEqual(meta[3], []any{v.Nombre}) || meta[4] != int64(0) || meta[5] != n {
+			t.Errorf("%s: path %v, codec %v, num_values %v", v.Nombre, meta[3], meta[4], meta[5])
+		}
+
+		// PageHeader: 1 type, 2/3 tamaños, 5 DataPageHeader
+		pl := &lectorThrift{b: b, i: int(offset)}
+		ph := pl.estructura()
+		dph := ph[5].(map[int16]any)
+		datos := int(ph[3].(int64))
+		if ph[1] != int64(0) || ph[2] != ph[3] || int64(pl.i-int(offset)+datos) != tamano {
+			t.Errorf("%s: page header %v, %d bytes de encabezado + %d, quiero %d", v.Nombre, ph, pl.i-int(offset), datos, tamano)
+		}
+		if dph[1] != n || dph[2] != int64(pqPlain) || dph[3] != int64(pqRLE) {
+			t.Errorf("%s: data page header %v", v.Nombre, dph)
+		}
+
+		got := leerPagina(t, b[pl.i:pl.i+datos], len(m.Filas), v.Texto)
+		for i, fila := range m.Filas {
+			if got[i] != fila[ci] {
+				t.Errorf("%s fila %d = %v, quiero %v", v.Nombre, i, got[i], fila[ci])
+			}
+		}
+	}
+	if rg[2] != total {
+		t.Errorf("total_byte_size = %v, quiero %d", rg[2], total)
+	}
+}
+
+// leerPagina decodifica una página PLAIN de una columna opcional: niveles
+// de definición (RLE/bit-packed) y luego los valores presentes
+func leerPagina(t *testing.T, p []byte, n int, texto bool) []any {
+	t.Helper()
+	largo := int(binary.LittleEndian.Uint32(p))
+	niveles := &lectorThrift{b: p[4 : 4+largo]}
+	var hay []bool
+	for niveles.i < len(niveles.b) {
+		h := niveles.uvarint()
+		if h&1 == 1 { // bit-packed: h>>1 grupos de 8
+			for g := 0; g < int(h>>1); g++ {
+				byt := niveles.b[niveles.i]
+				niveles.i++
+				for k := 0; k < 8; k++ {
+					hay = append(hay, byt&(1<<k) != 0)
+				}
+			}
+		} else { // RLE: h>>1 repeticiones de un byte
+			v := niveles.b[niveles.i] == 1
+			niveles.i++
+			for k := 0; k < int(h>>1); k++ {
+				hay = append(hay, v)
+			}
+		}
+	}
+	if len(hay) < n {
+		t.Fatalf("%d niveles de definición, quiero %d", len(hay), n)
+	}
+
+	out := make([]any, n)
+	r := bytes.NewReader(p[4+largo:])
+	for i := 0; i < n; i++ {
+		if !hay[i] {
+			continue
+		}
+		if texto {
+			var l uint32
+			_ = binary.Read(r, binary.LittleEndian, &l)
+			s := make([]byte, l)
+			_, _ = r.Read(s)
+			out[i] = string(s)
+		} else {
+			var v int32
+			_ = binary.Read(r, binary.LittleEndian, &v)
+			out[i] = int64(v)
+		}
+	}
+	if r.Len() != 0 {
+		t.Errorf("sobran %d bytes en la página", r.Len())
+	}
+	return out
+}
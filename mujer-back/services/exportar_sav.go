@@ -0,0 +1,269 @@
+package services
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// =======================================================
+// Archivo de sistema SPSS (.sav) sin compresión, little-endian, UTF-8.
+// Registros: encabezado, variables (2), etiquetas de valor (3/4),
+// info de máquina (7.3 / 7.4), nombres largos (7.13), codificación
+// (7.20), fin de diccionario (999) y los casos.
+// =======================================================
+
+const savSysmis = -math.MaxFloat64
+
+type savWriter struct {
+	w   *bufio.Writer
+	err error
+}
+
+func (s *savWriter) i32(v int32) {
+	if s.err == nil {
+		s.err = binary.Write(s.w, binary.LittleEndian, v)
+	}
+}
+
+func (s *savWriter) f64(v float64) {
+	if s.err == nil {
+		s.err = binary.Write(s.w, binary.LittleEndian, v)
+	}
+}
+
+// txt escribe s en exactamente n bytes (relleno con espacios)
+func (s *savWriter) txt(v string, n int) {
+	if s.err != nil {
+		return
+	}
+	b := []byte(truncarUTF8(v, n))
+	for len(b) < n {
+		b = append(b, ' ')
+	}
+	_, s.err = s.w.Write(b)
+}
+
+// truncarUTF8 corta s a lo más n bytes sin partir un carácter
+func truncarUTF8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+type savVar struct {
+	VariableMicro
+	corto     string // nombre de 8 bytes
+	ancho     int    // 0 = numérica; 1..255 = cadena
+	segmentos int    // bloques de 8 bytes que ocupa en cada caso
+	indice    int    // posición (1..) en el diccionario, contando segmentos
+}
+
+// EscribirSAV escribe los microdatos como archivo .sav de SPSS
+func EscribirSAV(w io.Writer, m Microdatos, etiqueta string) error {
+	vars := make([]savVar, len(m.Variables))
+	usados := map[string]bool{}
+	siguiente := 1
+	for i, v := range m.Variables {
+		sv := savVar{VariableMicro: v, segmentos: 1}
+
+		// nombre corto: los primeros 8 bytes en mayúsculas si no choca; si no, Vn
+		corto := strings.ToUpper(truncarUTF8(v.Nombre, 8))
+		if usados[corto] {
+			corto = fmt.Sprintf("V%d", i+1)
+		}
+		usados[corto] = true
+		sv.corto = corto
+
+		if v.Texto {
+			sv.ancho = 1
+			for _, fila := range m.Filas {
+				if s, ok := fila[i].(string); ok && len(s) > sv.ancho {
+					sv.ancho = len(s)
+				}
+			}
+			if sv.ancho > 255 {
+				sv.ancho = 255
+			}
+			sv.segmentos = (sv.ancho + 7) / 8
+		}
+		sv.indice = siguiente
+		siguiente += sv.segmentos
+		vars[i] = sv
+	}
+	totalSegmentos := siguiente - 1
+
+	s := &savWriter{w: bufio.NewWriter(w)}
+	now := time.Now()
+
+	// ==========================
+	// ENCABEZADO (176 bytes)
+	// ==========================
+	s.txt("$FL2", 4)
+	s.txt("@(#) SPSS DATA FILE mujer-back", 60)
+	s.i32(2)                     // layout_code
+	s.i32(int32(totalSegmentos)) // nominal_case_size
+	s.i32(0)                     // sin compresión
+	s.i32(0)                     // sin ponderador
+	s.i32(int32(len(m.Filas)))
+	s.f64(100) // bias
+	s.txt(now.Format("02 Jan 06"), 9)
+	s.txt(now.Format("15:04:05"), 8)
+	s.txt(etiqueta, 64)
+	s.txt("", 3)
+
+	// ==========================
+	// VARIABLES
+	// ==========================
+	for _, v := range vars {
+		s.i32(2)
+		s.i32(int32(v.ancho))
+		tieneEtiqueta := int32(0)
+		if v.Etiqueta != "" {
+			tieneEtiqueta = 1
+		}
+		s.i32(tieneEtiqueta)
+		s.i32(0)                       // sin valores perdidos definidos
+		formato := int32(5<<16 | 8<<8) // F8.0
+		if v.ancho > 0 {
+			formato = int32(1<<16 | v.ancho<<8) // A<ancho>
+		}
+		s.i32(formato) // print
+		s.i32(formato) // write
+		s.txt(v.corto, 8)
+		if tieneEtiqueta == 1 {
+			lbl := truncarUTF8(v.Etiqueta, 255)
+			s.i32(int32(len(lbl)))
+			s.txt(lbl, (len(lbl)+3)/4*4)
+		}
+		// continuaciones de las cadenas largas
+		for i := 1; i < v.segmentos; i++ {
+			s.i32(2)
+			s.i32(-1)
+			s.i32(0)
+			s.i32(0)
+			s.i32(0)
+			s.i32(0)
+			s.txt("", 8)
+		}
+	}
+
+	// ==========================
+	// ETIQUETAS DE VALOR (un par 3/4 por escala distinta)
+	// ==========================
+	grupos := map[string][]int{}
+	var orden []string
+	opciones := map[string][]ScaleOption{}
+	for _, v := range vars {
+		if v.ancho > 0 || len(v.Valores) == 0 {
+			continue
+		}
+		var k strings.Builder
+		for _, o := range v.Valores {
+			fmt.Fprintf(&k, "%d=%s;", o.Value, o.Label)
+		}
+		if _, ok := grupos[k.String()]; !ok {
+			orden = append(orden, k.String())
+			opciones[k.String()] = v.Valores
+		}
+		grupos[k.String()] = append(grupos[k.String()], v.indice)
+	}
+	for _, k := range orden {
+		s.i32(3)
+		s.i32(int32(len(opciones[k])))
+		for _, o := range opciones[k] {
+			s.f64(float64(o.Value))
+			lbl := truncarUTF8(o.Label, 120)
+			if s.err == nil {
+				s.err = s.w.WriteByte(byte(len(lbl)))
+			}
+			// 1 byte de longitud + etiqueta, relleno a múltiplo de 8
+			s.txt(lbl, (len(lbl)+1+7)/8*8-1)
+		}
+		s.i32(4)
+		s.i32(int32(len(grupos[k])))
+		for _, idx := range grupos[k] {
+			s.i32(int32(idx))
+		}
+	}
+
+	// ==========================
+	// REGISTROS DE EXTENSIÓN
+	// ==========================
+	// 7.3: enteros de la máquina (versión, IEEE 754, little-endian, UTF-8)
+	s.i32(7)
+	s.i32(3)
+	s.i32(4)
+	s.i32(8)
+	for _, v := range []int32{20, 0, 0, -1, 1, 1, 2, 65001} {
+		s.i32(v)
+	}
+	// 7.4: flotantes de la máquina (sysmis, highest, lowest)
+	s.i32(7)
+	s.i32(4)
+	s.i32(8)
+	s.i32(3)
+	s.f64(savSysmis)
+	s.f64(math.MaxFloat64)
+	s.f64(-math.Nextafter(math.MaxFloat64, 0))
+
+	// 7.13: nombres largos "CORTO=largo" separados por tabulador
+	largos := make([]string, len(vars))
+	for i, v := range vars {
+		largos[i] = v.corto + "=" + truncarUTF8(v.Nombre, 64)
+	}
+	nombres := strings.Join(largos, "\t")
+	s.i32(7)
+	s.i32(13)
+	s.i32(1)
+	s.i32(int32(len(nombres)))
+	s.txt(nombres, len(nombres))
+
+	// 7.20: codificación de caracteres
+	s.i32(7)
+	s.i32(20)
+	s.i32(1)
+	s.i32(5)
+	s.txt("UTF-8", 5)
+
+	// fin del diccionario
+	s.i32(999)
+	s.i32(0)
+
+	// ==========================
+	// CASOS
+	// ==========================
+	for _, fila := range m.Filas {
+		for i, v := range vars {
+			if v.ancho > 0 {
+				str, _ := fila[i].(string)
+				s.txt(str, v.segmentos*8)
+				continue
+			}
+			switch x := fila[i].(type) {
+			case int64:
+				s.f64(float64(x))
+			case int:
+				s.f64(float64(x))
+			case float64:
+				s.f64(x)
+			default:
+				s.f64(savSysmis)
+			}
+		}
+	}
+
+	if s.err != nil {
+		return s.err
+	}
+	return s.w.Flush()
+}
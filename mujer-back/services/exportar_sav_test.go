@@ -0,0 +1,208 @@
+package services
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// lectorSAV lee un .sav little-endian registro por registro
+type lectorSAV struct {
+	r *bytes.Reader
+	t *testing.T
+}
+
+func (l *lectorSAV) i32() int32 {
+	var v int32
+	if err := binary.Read(l.r, binary.LittleEndian, &v); err != nil {
+		l.t.Fatalf("i32: %v", err)
+	}
+	return v
+}
+
+func (l *lectorSAV) f64() float64 {
+	var v float64
+	if err := binary.Read(l.r, binary.LittleEndian, &v); err != nil {
+		l.t.Fatalf("f64: %v", err)
+	}
+	return v
+}
+
+func (l *lectorSAV) txt(n int) string {
+	b := make([]byte, n)
+	if _, err := io.ReadFull(l.r, b); err != nil {
+		l.t.Fatalf("txt(%d): %v", n, err)
+	}
+	return string(b)
+}
+
+type savVarLeida struct {
+	ancho  int32
+	nombre string
+	print  int32
+	label  string
+}
+
+func TestEscribirSAV(t *testing.T) {
+	m := microdatosPrueba()
+	var buf bytes.Buffer
+	if err := EscribirSAV(&buf, m, "Microdatos de prueba"); err != nil {
+		t.Fatal(err)
+	}
+	l := &lectorSAV{r: bytes.NewReader(buf.Bytes()), t: t}
+
+	// ==========================
+	// ENCABEZADO (176 bytes)
+	// ==========================
+	if got := l.txt(4); got != "$FL2" {
+		t.Fatalf("rec_type = %q", got)
+	}
+	if got := l.txt(60); !strings.HasPrefix(got, "@(#) SPSS DATA FILE") {
+		t.Errorf("prod_name = %q", got)
+	}
+	layout, caseSize, compr, peso, ncases := l.i32(), l.i32(), l.i32(), l.i32(), l.i32()
+	// fila, centro (25 bytes → 4 segmentos), genero, P1_frecuencia, P1_gravedad
+	if layout != 2 || caseSize != 8 || compr != 0 || peso != 0 || ncases != int32(len(m.Filas)) {
+		t.Errorf("layout %d, case_size %d, compresión %d, peso %d, casos %d", layout, caseSize, compr, peso, ncases)
+	}
+	if bias := l.f64(); bias != 100 {
+		t.Errorf("bias = %v", bias)
+	}
+	l.txt(9 + 8)
+	if got := strings.TrimRight(l.txt(64), " "); got != "Microdatos de prueba" {
+		t.Errorf("file_label = %q", got)
+	}
+	l.txt(3)
+	if pos := buf.Len() - l.r.Len(); pos != 176 {
+		t.Fatalf("encabezado de %d bytes", pos)
+	}
+
+	// ==========================
+	// DICCIONARIO
+	// ==========================
+	var vars []savVarLeida
+	etiquetas := map[int32][]string{} // índice de variable -> etiquetas de valor
+	var largos, codificacion string
+	var enteros []int32
+diccionario:
+	for {
+		switch tipo := l.i32(); tipo {
+		case 2:
+			var v savVarLeida
+			v.ancho = l.i32()
+			tieneEtiqueta := l.i32()
+			if nm := l.i32(); nm != 0 {
+				t.Errorf("n_missing_values = %d", nm)
+			}
+			v.print = l.i32()
+			if w := l.i32(); w != v.print {
+				t.Errorf("write %x != print %x", w, v.print)
+			}
+			v.nombre = strings.TrimRight(l.txt(8), " ")
+			if tieneEtiqueta == 1 {
+				n := l.i32()
+				v.label = l.txt(int((n + 3) / 4 * 4))[:n]
+			}
+			vars = append(vars, v)
+		case 3:
+			n := l.i32()
+			var lbls []string
+			for i := int32(0); i < n; i++ {
+				valor := l.f64()
+				largo := l.txt(1)[0]
+				lbl := l.txt((int(largo)+1+7)/8*8 - 1)[:largo]
+				lbls = append(lbls, lbl+"="+strconv.Itoa(int(valor)))
+			}
+			if l.i32() != 4 {
+				t.Fatal("registro 3 sin registro 4")
+			}
+			for i, nv := int32(0), l.i32(); i < nv; i++ {
+				etiquetas[l.i32()] = lbls
+			}
+		case 7:
+			sub, tam, cuenta := l.i32(), l.i32(), l.i32()
+			datos := l.txt(int(tam * cuenta))
+			switch sub {
+			case 3:
+				enteros = make([]int32, cuenta)
+				_ = binary.Read(strings.NewReader(datos), binary.LittleEndian, enteros)
+			case 13:
+				largos = datos
+			case 20:
+				codificacion = datos
+			}
+		case 999:
+			if f := l.i32(); f != 0 {
+				t.Errorf("filler de 999 = %d", f)
+			}
+			break diccionario
+		default:
+			t.Fatalf("registro desconocido %d", tipo)
+		}
+	}
+
+	// variables: una por columna más las continuaciones de las cadenas
+	quiero := []savVarLeida{
+		{0, "FILA", 5<<16 | 8<<8, "Número de fila"},
+		{25, "CENTRO", 1<<16 | 25<<8, "Código de centro"},
+		{-1, "", 0, ""},
+		{-1, "", 0, ""},
+		{-1, "", 0, ""},
+		{5, "GENERO", 1<<16 | 5<<8, ""},
+		{0, "P1_FRECU", 5<<16 | 8<<8, "P1 frecuencia: ¿Con qué frecuencia…?"},
+		{0, "P1_GRAVE", 5<<16 | 8<<8, ""},
+	}
+	if len(vars) != len(quiero) {
+		t.Fatalf("%d registros de variable, quiero %d", len(vars), len(quiero))
+	}
+	for i, q := range quiero {
+		if vars[i] != q {
+			t.Errorf("variable %d = %+v, quiero %+v", i+1, vars[i], q)
+		}
+	}
+
+	// las dos escalas iguales comparten un solo par 3/4 (índices 7 y 8)
+	if len(etiquetas) != 2 || strings.Join(etiquetas[7], ";") != "Nunca=1;Muy frecuentemente=5" || len(etiquetas[8]) != 2 {
+		t.Errorf("etiquetas de valor = %v", etiquetas)
+	}
+	if len(enteros) != 8 || enteros[4] != 1 || enteros[6] != 2 || enteros[7] != 65001 {
+		t.Errorf("7.3 = %v (quiero IEEE, little-endian, UTF-8)", enteros)
+	}
+	if largos != "FILA=fila\tCENTRO=centro\tGENERO=genero\tP1_FRECU=P1_frecuencia\tP1_GRAVE=P1_gravedad" {
+		t.Errorf("7.13 = %q", largos)
+	}
+	if codificacion != "UTF-8" {
+		t.Errorf("7.20 = %q", codificacion)
+	}
+
+	// ==========================
+	// CASOS
+	// ==========================
+	for i, fila := range m.Filas {
+		got := []any{l.f64(), strings.TrimRight(l.txt(32), " "), strings.TrimRight(l.txt(8), " "), l.f64(), l.f64()}
+		for j, v := range fila {
+			var q any
+			switch x := v.(type) {
+			case int64:
+				q = float64(x)
+			case string:
+				q = x
+			case nil:
+				q = -math.MaxFloat64 // sysmis
+				if m.Variables[j].Texto {
+					q = ""
+				}
+			}
+			if got[j] != q {
+				t.Errorf("caso %d, %s = %v, quiero %v", i+1, m.Variables[j].Nombre, got[j], q)
+			}
+		}
+	}
+	if l.r.Len() != 0 {
+		t.Errorf("sobran %d bytes después de los casos", l.r.Len())
+	}
+}
@@ -0,0 +1,583 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// =======================================================
+// Geografía (claves INEGI)
+// Los centros guardan estado/ciudad como texto libre; estado_clave y
+// municipio_clave los ligan a los catálogos. El vínculo se resuelve por
+// nombre (sin acentos ni puntuación, con alias comunes) o se fija a mano
+// con municipio_clave.
+// =======================================================
+
+var (
+	ErrMunicipioNoExiste = errors.New("municipio_not_found")
+	ErrGeoJSONInvalido   = errors.New("bad_geojson")
+)
+
+// niveles geográficos
+const (
+	NivelEstados    = "estados"
+	NivelMunicipios = "municipios"
+)
+
+// aliasEstados: nombres comunes -> CVE_ENT (además del nombre oficial, que
+// se carga de la tabla estados)
+var aliasEstados = map[string]string{
+	"ags":                   "01",
+	"bc":                    "02",
+	"baja california norte": "02",
+	"bcs":                   "03",
+	"coahuila":              "05",
+	"cdmx":                  "09",
+	"ciudad de mexico":      "09",
+	"df":                    "09",
+	"distrito federal":      "09",
+	"estado de mexico":      "15",
+	"edo de mexico":         "15",
+	"edo mex":               "15",
+	"edomex":                "15",
+	"mexico":                "15",
+	"michoacan":             "16",
+	"nl":                    "19",
+	"queretaro de arteaga":  "22",
+	"qro":                   "22",
+	"q roo":                 "23",
+	"slp":                   "24",
+	"veracruz":              "30",
+	"yuc":                   "31",
+}
+
+// nombreGeo normaliza un nombre para compararlo: minúsculas, sin acentos
+// ni puntuación, espacios simples
+func nombreGeo(s string) string {
+	return strings.Join(tokens(s), " ")
+}
+
+type EstadoDTO struct {
+	Clave       string `json:"clave"`
+	Nombre      string `json:"nombre"`
+	Abreviatura string `json:"abreviatura"`
+	Geometria   bool   `json:"geometria"` // true si ya se importó su polígono
+}
+
+type MunicipioDTO struct {
+	Clave       string `json:"clave"`
+	EstadoClave string `json:"estado_clave"`
+	Nombre      string `json:"nombre"`
+	Geometria   bool   `json:"geometria"`
+}
+
+func ListarEstados(ctx context.Context, db dbtx) ([]EstadoDTO, error) {
+	rows, err := db.Query(ctx, `select clave, nombre, abreviatura, geometria is not null from estados order by clave`)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (EstadoDTO, error) {
+		var e EstadoDTO
+		err := row.Scan(&e.Clave, &e.Nombre, &e.Abreviatura, &e.Geometria)
+		return e, err
+	})
+}
+
+// ListarMunicipios regresa los municipios de un estado ("" = todos)
+func ListarMunicipios(ctx context.Context, db dbtx, estado string) ([]MunicipioDTO, error) {
+	rows, err := db.Query(ctx, `
+		select clave, estado_clave, nombre, geometria is not null
+		from municipios
+		where $1 = '' or estado_clave = $1
+		order by clave
+	`, estado)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (MunicipioDTO, error) {
+		var m MunicipioDTO
+		err := row.Scan(&m.Clave, &m.EstadoClave, &m.Nombre, &m.Geometria)
+		return m, err
+	})
+}
+
+// catalogoGeo resuelve texto libre contra los catálogos cargados
+type catalogoGeo struct {
+	estados    map[string]string            // nombre normalizado -> CVE_ENT
+	municipios map[string]map[string]string // CVE_ENT -> nombre normalizado -> CVEGEO
+	existe     map[string]string            // CVEGEO -> CVE_ENT
+}
+
+func cargarCatalogoGeo(ctx context.Context, db dbtx) (catalogoGeo, error) {
+	c := catalogoGeo{estados: map[string]string{}, municipios: map[string]map[string]string{}, existe: map[string]string{}}
+	for a, cl := range aliasEstados {
+		c.estados[a] = cl
+	}
+
+	estados, err := ListarEstados(ctx, db)
+	if err != nil {
+		return c, err
+	}
+	for _, e := range estados {
+		c.estados[nombreGeo(e.Nombre)] = e.Clave
+		c.estados[nombreGeo(e.Abreviatura)] = e.Clave
+	}
+
+	municipios, err := ListarMunicipios(ctx, db, "")
+	if err != nil {
+		return c, err
+	}
+	for _, m := range municipios {
+		if c.municipios[m.EstadoClave] == nil {
+			c.municipios[m.EstadoClave] = map[string]string{}
+		}
+		c.municipios[m.EstadoClave][nombreGeo(m.Nombre)] = m.Clave
+		c.existe[m.Clave] = m.EstadoClave
+	}
+	return c, nil
+}
+
+// resolver regresa CVE_ENT y CVEGEO ("" si no se encontró) para el texto
+// libre de un centro; municipioClave, si viene, manda sobre el texto
+func (c catalogoGeo) resolver(estado, ciudad, municipioClave string) (string, string, error) {
+	if municipioClave != "" {
+		ent, ok := c.existe[municipioClave]
+		if !ok {
+			return "", "", ErrMunicipioNoExiste
+		}
+		return ent, municipioClave, nil
+	}
+
+	ent := c.estados[nombreGeo(estado)]
+	if ent == "" {
+		return "", "", nil
+	}
+	return ent, c.municipios[ent][nombreGeo(ciudad)], nil
+}
+
+// ResolverGeografia liga estado/ciudad (texto libre) o municipio_clave a
+// los catálogos. Regresa ErrMunicipioNoExiste si municipio_clave no existe.
+func ResolverGeografia(ctx context.Context, db dbtx, estado, ciudad, municipioClave string) (string, string, error) {
+	c, err := cargarCatalogoGeo(ctx, db)
+	if err != nil {
+		return "", "", err
+	}
+	return c.resolver(estado, ciudad, municipioClave)
+}
+
+type ResultadoVinculo struct {
+	Revisados   int `json:"revisados"`
+	Estados     int `json:"estados"`      // centros que quedaron con estado
+	Municipios  int `json:"municipios"`   // centros que quedaron con municipio
+	SinVincular int `json:"sin_vincular"` // centros sin estado reconocible
+}
+
+// VincularCentros llena estado_clave/municipio_clave de los centros que aún
+// no los tienen (no toca vínculos existentes). Se corre al arrancar y después
+// de importar catálogos.
+func VincularCentros(ctx context.Context, pool *pgxpool.Pool) (ResultadoVinculo, error) {
+	var res ResultadoVinculo
+
+	c, err := cargarCatalogoGeo(ctx, pool)
+	if err != nil {
+		return res, err
+	}
+
+	rows, err := pool.Query(ctx, `
+		select id, coalesce(estado, ''), coalesce(ciudad, ''), coalesce(estado_clave, ''), coalesce(municipio_clave, '')
+		from centros
+		where estado_clave is null or municipio_clave is null
+	`)
+	if err != nil {
+		return res, err
+	}
+	type pendiente struct {
+		id                     int64
+		estado, ciudad         string
+		estadoClave, municipio string
+	}
+	var ps []pendiente
+	for rows.Next() {
+		var p pendiente
+		if err := rows.Scan(&p.id, &p.estado, &p.ciudad, &p.estadoClave, &p.municipio); err != nil {
+			rows.Close()
+			return res, err
+		}
+		ps = append(ps, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return res, err
+	}
+
+	batch := &pgx.Batch{}
+	for _, p := range ps {
+		res.Revisados++
+		ent, mun, _ := c.resolver(p.estado, p.ciudad, "")
+		if p.estadoClave != "" {
+			// estado ya fijado: solo buscar el municipio dentro de él
+			ent = p.estadoClave
+			mun = c.municipios[ent][nombreGeo(p.ciudad)]
+		}
+		if ent == "" {
+			res.SinVincular++
+			continue
+		}
+		res.Estados++
+		if mun != "" {
+			res.Municipios++
+		}
+		if ent == p.estadoClave && mun == "" {
+			continue
+		}
+		batch.Queue(`
+			update centros
+			set estado_clave = $2,
+			    municipio_clave = coalesce(nullif($3, ''), municipio_clave)
+			where id = $1
+		`, p.id, ent, mun)
+	}
+	if batch.Len() == 0 {
+		return res, nil
+	}
+	if err := pool.SendBatch(ctx, batch).Close(); err != nil {
+		return res, err
+	}
+	return res, nil
+}
+
+// ==========================
+// Importación de GeoJSON (Marco Geoestadístico de INEGI)
+// ==========================
+
+type featureGeo struct {
+	Properties map[string]any  `json:"properties"`
+	Geometry   json.RawMessage `json:"geometry"`
+}
+
+type ResultadoImportacion struct {
+	Nivel       string           `json:"nivel"`
+	Features    int              `json:"features"`
+	Importados  int              `json:"importados"`
+	Omitidos    int              `json:"omitidos"` // sin claves reconocibles o de un estado inexistente
+	Vinculacion ResultadoVinculo `json:"vinculacion"`
+}
+
+// propGeo lee una propiedad sin importar mayúsculas (CVE_ENT, cve_ent, ...);
+// INEGI a veces las publica como número
+func propGeo(props map[string]any, nombre string) string {
+	for k, v := range props {
+		if !strings.EqualFold(k, nombre) {
+			continue
+		}
+		switch x := v.(type) {
+		case string:
+			return strings.TrimSpace(x)
+		case float64:
+			return fmt.Sprintf("%.0f", x)
+		}
+	}
+	return ""
+}
+
+// rellenar completa con ceros a la izquierda ("9" -> "09")
+func rellenar(s string, n int) string {
+	if len(s) >= n {
+		return s
+	}
+	return strings.Repeat("0", n-len(s)) + s
+}
+
+// ImportarGeoJSON carga una FeatureCollection de estados (CVE_ENT) o de
+// municipios (CVEGEO, o CVE_ENT + CVE_MUN, y NOMGEO). En estados solo
+// actualiza la geometría; en municipios agrega o actualiza nombre y
+// geometría. Todo en una transacción; al final vuelve a vincular centros.
+func ImportarGeoJSON(ctx context.Context, pool *pgxpool.Pool, nivel string, r io.Reader) (ResultadoImportacion, error) {
+	res := ResultadoImportacion{Nivel: nivel}
+
+	var fc struct {
+		Type     string       `json:"type"`
+		Features []featureGeo `json:"features"`
+	}
+	if err := json.NewDecoder(r).Decode(&fc); err != nil || fc.Type != "FeatureCollection" {
+		return res, ErrGeoJSONInvalido
+	}
+	res.Features = len(fc.Features)
+
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return res, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	for _, f := range fc.Features {
+		geom := f.Geometry
+		if len(geom) == 0 || string(geom) == "null" {
+			geom = nil
+		}
+		ent := propGeo(f.Properties, "CVE_ENT")
+
+		var (
+			tag pgconn.CommandTag
+			err error
+		)
+		switch nivel {
+		case NivelEstados:
+			if ent == "" {
+				ent = propGeo(f.Properties, "CVEGEO")
+			}
+			if ent == "" {
+				res.Omitidos++
+				continue
+			}
+			tag, err = tx.Exec(ctx, `update estados set geometria = coalesce($2::jsonb, geometria) where clave = $1`, rellenar(ent, 2), geom)
+		default:
+			cvegeo := propGeo(f.Properties, "CVEGEO")
+			if mun := propGeo(f.Properties, "CVE_MUN"); cvegeo == "" && ent != "" && mun != "" {
+				cvegeo = rellenar(ent, 2) + rellenar(mun, 3)
+			}
+			nombre := propGeo(f.Properties, "NOMGEO")
+			if nombre == "" {
+				nombre = propGeo(f.Properties, "NOM_MUN")
+			}
+			if len(cvegeo) != 5 || nombre == "" {
+				res.Omitidos++
+				continue
+			}
+			tag, err = tx.Exec(ctx, `
+				insert into municipios (clave, estado_clave, nombre, geometria)
+				select $1, e.clave, $2, $3::jsonb
+				from estados e
+				where e.clave = left($1, 2)
+				on conflict (clave) do update
+				set nombre = excluded.nombre,
+				    geometria = coalesce(excluded.geometria, municipios.geometria)
+			`, cvegeo, nombre, geom)
+		}
+		if err != nil {
+			return res, err
+		}
+		if tag.RowsAffected() == 0 {
+			res.Omitidos++
+			continue
+		}
+		res.Importados++
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return res, err
+	}
+
+	v, err := VincularCentros(ctx, pool)
+	if err != nil {
+		return res, err
+	}
+	res.Vinculacion = v
+	return res, nil
+}
+
+// ==========================
+// Mapas (GeoJSON)
+// ==========================
+
+// FiltroMapa: Estado (CVE_ENT) acota los municipios a un estado; Tipo,
+// Year y Desde/Hasta filtran centros y encuestas como en los resultados.
+type FiltroMapa struct {
+	Nivel  string
+	Estado string
+	Tipo   string
+	Year   *int
+	Desde  *time.Time
+	Hasta  *time.Time
+}
+
+// FeatureCollection sigue RFC 7946; "anonimato" va como miembro adicional
+type FeatureCollection struct {
+	Type      string    `json:"type"`
+	Features  []Feature `json:"features"`
+	Anonimato Anonimato `json:"anonimato"`
+}
+
+type Feature struct {
+	Type       string          `json:"type"`
+	ID         string          `json:"id"`
+	Geometry   json.RawMessage `json:"geometry"` // null si no se ha importado
+	Properties PropiedadesGeo  `json:"properties"`
+}
+
+// PropiedadesGeo: los índices solo salen si la región tiene al menos k
+// encuestas; si no, van nulos con suprimido=true
+type PropiedadesGeo struct {
+	Clave       string   `json:"clave"`
+	Nombre      string   `json:"nombre"`
+	EstadoClave string   `json:"estado_clave"`
+	Centros     int      `json:"centros"`
+	Encuestas   int64    `json:"encuestas"`
+	Respuestas  int64    `json:"respuestas"`
+	Suprimido   bool     `json:"suprimido"`
+	Frecuencia  *float64 `json:"frecuencia"`
+	Normalidad  *float64 `json:"normalidad"`
+	Gravedad    *float64 `json:"gravedad"`
+	Total       *float64 `json:"total"`
+}
+
+// MapaRegiones agrega los índices por estado o por municipio de los centros
+// activos ya vinculados. En estados regresa las 32 entidades; en municipios,
+// los del estado pedido o, sin estado, solo los que tienen centros.
+func MapaRegiones(ctx context.Context, pool *pgxpool.Pool, f FiltroMapa, k int) (FeatureCollection, error) {
+	fc := FeatureCollection{Type: "FeatureCollection", Features: []Feature{}, Anonimato: Anonimato{K: k}}
+
+	col := "estado_clave"
+	if f.Nivel == NivelMunicipios {
+		col = "municipio_clave"
+	}
+
+	// centros activos vinculados por región
+	rows, err := pool.Query(ctx, `
+		select id, `+col+`
+		from centros
+		where activo = true
+		  and `+col+` is not null
+		  and ($1 = '' or tipo = $1)
+		  and ($2 = '' or estado_clave = $2)
+	`, f.Tipo, f.Estado)
+	if err != nil {
+		return fc, etapa("mapa_centros", err)
+	}
+	ids := []int64{}
+	centros := map[string]int{}
+	for rows.Next() {
+		var id int64
+		var region string
+		if err := rows.Scan(&id, &region); err != nil {
+			rows.Close()
+			return fc, etapa("mapa_centros", err)
+		}
+		ids = append(ids, id)
+		centros[region]++
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fc, etapa("mapa_centros", err)
+	}
+
+	type agregado struct {
+		encuestas, respuestas int64
+		valores               *Dimensiones
+	}
+	porRegion := map[string]*agregado{}
+	region := func(clave string) *agregado {
+		a := porRegion[clave]
+		if a == nil {
+			a = &agregado{}
+			porRegion[clave] = a
+		}
+		return a
+	}
+
+	if len(ids) > 0 {
+		where, args := FiltroAgregado{Centros: ids, Year: f.Year, Desde: f.Desde, Hasta: f.Hasta}.SQL()
+
+		batch := &pgx.Batch{}
+		batch.Queue(`
+			select c.`+col+`, sum(e.encuestas)::bigint, sum(e.respuestas)::bigint
+			from agg_encuestas e
+			join centros c on c.id = e.centro_id
+			where `+where+`
+			group by c.`+col+`
+		`, args...).Query(func(rows pgx.Rows) error {
+			for rows.Next() {
+				var clave string
+				var enc, resp int64
+				if err := rows.Scan(&clave, &enc, &resp); err != nil {
+					return etapa("mapa_encuestas", err)
+				}
+				a := region(clave)
+				a.encuestas, a.respuestas = enc, resp
+			}
+			return etapa("mapa_encuestas", rows.Err())
+		})
+		batch.Queue(`
+			select
+				c.`+col+`,
+				`+promedioHist("e.dimension = 'frecuencia'")+`,
+				`+promedioHist("e.dimension = 'normalidad'")+`,
+				`+promedioHist("e.dimension = 'gravedad'")+`,
+				`+promedioHist("true")+`
+			from agg_valores e
+			join centros c on c.id = e.centro_id
+			where `+where+`
+			group by c.`+col+`
+		`, args...).Query(func(rows pgx.Rows) error {
+			for rows.Next() {
+				var clave string
+				var d Dimensiones
+				if err := rows.Scan(&clave, &d.Frecuencia, &d.Normalidad, &d.Gravedad, &d.Total); err != nil {
+					return etapa("mapa_valores", err)
+				}
+				region(clave).valores = &d
+			}
+			return etapa("mapa_valores", rows.Err())
+		})
+		if err := enviarBatch(ctx, pool, batch); err != nil {
+			return fc, err
+		}
+	}
+
+	// regiones a dibujar (con su geometría)
+	var q string
+	var qargs []any
+	switch {
+	case f.Nivel != NivelMunicipios:
+		q = `select clave, nombre, clave, geometria from estados order by clave`
+	case f.Estado != "":
+		q = `select clave, nombre, estado_clave, geometria from municipios where estado_clave = $1 order by clave`
+		qargs = []any{f.Estado}
+	default:
+		q = `select clave, nombre, estado_clave, geometria from municipios where clave = any($1) order by clave`
+		claves := make([]string, 0, len(centros))
+		for c := range centros {
+			claves = append(claves, c)
+		}
+		qargs = []any{claves}
+	}
+	rows, err = pool.Query(ctx, q, qargs...)
+	if err != nil {
+		return fc, etapa("mapa_regiones", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var p PropiedadesGeo
+		var geom []byte
+		if err := rows.Scan(&p.Clave, &p.Nombre, &p.EstadoClave, &geom); err != nil {
+			return fc, etapa("mapa_regiones", err)
+		}
+		p.Centros = centros[p.Clave]
+		if a := porRegion[p.Clave]; a != nil {
+			p.Encuestas, p.Respuestas = a.encuestas, a.respuestas
+			if a.valores != nil && a.respuestas > 0 {
+				if a.encuestas < int64(k) {
+					p.Suprimido = true
+					fc.Anonimato.Marcar(CampoSuprimido(f.Nivel, p.Clave))
+				} else {
+					d := *a.valores
+					p.Frecuencia, p.Normalidad, p.Gravedad, p.Total = &d.Frecuencia, &d.Normalidad, &d.Gravedad, &d.Total
+				}
+			}
+		}
+		if len(geom) == 0 {
+			geom = []byte("null")
+		}
+		fc.Features = append(fc.Features, Feature{Type: "Feature", ID: p.Clave, Geometry: geom, Properties: p})
+	}
+	return fc, etapa("mapa_regiones", rows.Err())
+}
@@ -0,0 +1,165 @@
+package services
+
+import (
+	"math"
+	"sort"
+)
+
+// =======================================================
+// Índice compuesto de riesgo (0–100) por tipo de violencia
+//
+// Con F, N, G = promedios del tipo en frecuencia, normalidad y gravedad,
+// cada uno llevado a 0–1 con el mínimo y máximo de su escala:
+//
+//	índice = 100 × √(F' × N') × ((1 − w) + w × G')
+//
+// √(F' × N') es alto solo si la violencia es frecuente Y está normalizada;
+// la gravedad percibida pondera el resultado con peso w
+// (scoring.risk_index.gravedad_weight, default 0.5: con gravedad mínima el
+// índice queda a la mitad). El índice global es el promedio simple de los
+// tipos. El nivel (bajo/medio/alto/crítico) sale de scoring.risk_index.levels.
+// =======================================================
+
+const (
+	MetodoRiesgoFxNPorG      = "fxn_weighted_by_g"
+	RiesgoGravedadWeightDflt = 0.5
+)
+
+// ScoringRiesgo es scoring.risk_index del instrumento
+type ScoringRiesgo struct {
+	Method         string        `json:"method"`
+	Notes          string        `json:"notes,omitempty"`
+	GravedadWeight *float64      `json:"gravedad_weight,omitempty"`
+	Levels         []NivelRiesgo `json:"levels"`
+}
+
+// NivelRiesgo aplica desde Min (inclusive) hasta el Min del siguiente
+type NivelRiesgo struct {
+	Key   string  `json:"key"`
+	Label string  `json:"label"`
+	Min   float64 `json:"min"`
+}
+
+type IndiceRiesgo struct {
+	TipoNum    int32   `json:"tipo_num,omitempty"` // 0 en el global
+	TipoNombre string  `json:"tipo_nombre,omitempty"`
+	Indice     float64 `json:"indice"` // 0–100
+	Nivel      string  `json:"nivel"`
+	NivelLabel string  `json:"nivel_label"`
+}
+
+// ResumenRiesgo viaja junto a la matriz
+type ResumenRiesgo struct {
+	Metodo  string         `json:"metodo"`
+	Notas   string         `json:"notas,omitempty"`
+	Niveles []NivelRiesgo  `json:"niveles"`
+	Global  *IndiceRiesgo  `json:"global,omitempty"` // nil sin tipos completos
+	Tipos   []IndiceRiesgo `json:"tipos"`
+}
+
+func (s ScoringRiesgo) PesoGravedad() float64 {
+	if s.GravedadWeight != nil {
+		return *s.GravedadWeight
+	}
+	return RiesgoGravedadWeightDflt
+}
+
+// nivel regresa el nivel que corresponde al índice (levels ya validados: ascendentes)
+func (s ScoringRiesgo) nivel(indice float64) NivelRiesgo {
+	out := NivelRiesgo{}
+	for _, n := range s.Levels {
+		if indice >= n.Min {
+			out = n
+		}
+	}
+	return out
+}
+
+// rangoDimension regresa min y max de la escala de una dimensión
+func (i Instrumento) rangoDimension(dim string) (float64, float64, bool) {
+	for _, d := range i.Dimensions {
+		if d.Key != dim {
+			continue
+		}
+		sc, ok := i.Scales[d.ScaleID]
+		if !ok || sc.Max <= sc.Min {
+			return 0, 0, false
+		}
+		return float64(sc.Min), float64(sc.Max), true
+	}
+	return 0, 0, false
+}
+
+// IndiceCompuesto calcula el índice con promedios de frecuencia, normalidad
+// y gravedad en la escala original
+func (i Instrumento) IndiceCompuesto(f, n, g float64) (float64, bool) {
+	s := i.Scoring.RiskIndex
+	if s == nil {
+		return 0, false
+	}
+	norm := func(dim string, v float64) (float64, bool) {
+		lo, hi, ok := i.rangoDimension(dim)
+		if !ok {
+			return 0, false
+		}
+		return math.Max(0, math.Min(1, (v-lo)/(hi-lo))), true
+	}
+	fn, ok1 := norm("frecuencia", f)
+	nn, ok2 := norm("normalidad", n)
+	gn, ok3 := norm("gravedad", g)
+	if !ok1 || !ok2 || !ok3 {
+		return 0, false
+	}
+	w := s.PesoGravedad()
+	return 100 * math.Sqrt(fn*nn) * ((1 - w) + w*gn), true
+}
+
+// IndiceRiesgo arma el índice por tipo y global a partir de la matriz
+// tipo × dimensión. Regresa nil si el instrumento no define risk_index.
+func (i Instrumento) IndiceRiesgo(matriz []CeldaMatriz) *ResumenRiesgo {
+	s := i.Scoring.RiskIndex
+	if s == nil {
+		return nil
+	}
+	out := &ResumenRiesgo{Metodo: s.Method, Notas: s.Notes, Niveles: s.Levels, Tipos: []IndiceRiesgo{}}
+
+	type dims struct {
+		nombre string
+		v      map[string]float64
+	}
+	porTipo := map[int32]*dims{}
+	for _, c := range matriz {
+		d, ok := porTipo[c.TipoNum]
+		if !ok {
+			d = &dims{nombre: c.TipoNombre, v: map[string]float64{}}
+			porTipo[c.TipoNum] = d
+		}
+		d.v[c.Dimension] = c.Promedio
+	}
+
+	suma := 0.0
+	for num, d := range porTipo {
+		f, okF := d.v["frecuencia"]
+		n, okN := d.v["normalidad"]
+		g, okG := d.v["gravedad"]
+		if !okF || !okN || !okG {
+			continue
+		}
+		idx, ok := i.IndiceCompuesto(f, n, g)
+		if !ok {
+			continue
+		}
+		idx = math.Round(idx*10) / 10
+		nv := s.nivel(idx)
+		out.Tipos = append(out.Tipos, IndiceRiesgo{TipoNum: num, TipoNombre: d.nombre, Indice: idx, Nivel: nv.Key, NivelLabel: nv.Label})
+		suma += idx
+	}
+	sort.Slice(out.Tipos, func(a, b int) bool { return out.Tipos[a].TipoNum < out.Tipos[b].TipoNum })
+
+	if len(out.Tipos) > 0 {
+		g := math.Round(suma/float64(len(out.Tipos))*10) / 10
+		nv := s.nivel(g)
+		out.Global = &IndiceRiesgo{Indice: g, Nivel: nv.Key, NivelLabel: nv.Label}
+	}
+	return out
+}
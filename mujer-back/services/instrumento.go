@@ -1,6 +1,7 @@
 package services
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -8,11 +9,113 @@ import (
 
 type Instrumento struct {
 	// Campos que usa main.go
+	ID      string `json:"instrument_id"`
 	Name    string `json:"name"`
 	Version string `json:"version"`
 
+	Subtitle     string   `json:"subtitle"`
+	Language     string   `json:"language"`
+	Context      []string `json:"context"`
+	Instructions string   `json:"instructions"`
+
+	// Estructura que usan los handlers para validar y agregar
+	Dimensions      []Dimension      `json:"dimensions"`
+	Scales          map[string]Scale `json:"scales"`
+	TypesOfViolence []TipoViolencia  `json:"types_of_violence"`
+	Scoring         Scoring          `json:"scoring"`
+	Validation      Validation       `json:"validation"`
+
+	// Interpretación y recursos de apoyo para el resumen individual
+	// (ver retroalimentacion.go); opcional
+	Feedback *Feedback `json:"feedback,omitempty"`
+
 	// Contiene TODO el JSON original (sin pérdida)
 	Raw map[string]any `json:"-"`
+
+	// índice pregunta|dimension -> tarjeta (se arma al cargar)
+	cards map[string]CardRef
+
+	// keywords compiladas por tipo (se arma al cargar)
+	lexico []lexicoTipo
+}
+
+type Dimension struct {
+	Key     string `json:"key"`
+	Label   string `json:"label"`
+	Icon    string `json:"icon"`
+	ScaleID string `json:"scale_id"`
+}
+
+type Scale struct {
+	Type    string        `json:"type"`
+	Min     int           `json:"min"`
+	Max     int           `json:"max"`
+	Options []ScaleOption `json:"options"`
+}
+
+type ScaleOption struct {
+	Value int    `json:"value"`
+	Label string `json:"label"`
+}
+
+type TipoViolencia struct {
+	TypeID    string     `json:"type_id"`
+	Order     int        `json:"order"`
+	Label     string     `json:"label"`
+	Notes     string     `json:"notes"`
+	Questions []Pregunta `json:"questions"`
+
+	// Léxico para etiquetar comentarios abiertos (ver clasificador.go):
+	// palabras o frases; "*" al final de una palabra = cualquier terminación
+	Keywords []string `json:"keywords,omitempty"`
+}
+
+type Pregunta struct {
+	QuestionID string `json:"question_id"`
+	Order      int    `json:"order"`
+	Stem       string `json:"stem"`
+	Cards      []Card `json:"cards"`
+}
+
+type Card struct {
+	Dimension string `json:"dimension"`
+	Prompt    string `json:"prompt"`
+	ScaleID   string `json:"scale_id"`
+	Required  bool   `json:"required"`
+}
+
+type Scoring struct {
+	Coding                 string          `json:"coding"`
+	PerQuestion            ScoringPregunta `json:"per_question"`
+	PerTypeIndices         ScoringPorTipo  `json:"per_type_indices"`
+	TotalResponsesExpected int             `json:"total_responses_expected"`
+
+	// Índice compuesto de riesgo (ver indice_riesgo.go); opcional
+	RiskIndex *ScoringRiesgo `json:"risk_index,omitempty"`
+}
+
+type ScoringPregunta struct {
+	Method string   `json:"method"`
+	Fields []string `json:"fields"`
+}
+
+type ScoringPorTipo struct {
+	Method string `json:"method"`
+	Notes  string `json:"notes"`
+}
+
+type Validation struct {
+	RequiredAllCards bool `json:"required_all_cards"`
+	AllowSkip        bool `json:"allow_skip"`
+}
+
+// CardRef ubica una tarjeta dentro del instrumento (pregunta + tipo + escala)
+type CardRef struct {
+	PreguntaID string
+	TipoNum    int
+	TipoNombre string
+	Card       Card
+	Scale      Scale
 }
 
 // MarshalJSON hace que al responder la API
@@ -32,17 +135,109 @@ func LoadInstrumento(path string) (Instrumento, error) {
 		return Instrumento{}, fmt.Errorf("unmarshal instrumento: %w", err)
 	}
 
-	inst := Instrumento{
-		Raw: raw,
+	// Decodificación estricta: una llave mal escrita en el JSON es un error de arranque
+	var inst Instrumento
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&inst); err != nil {
+		return Instrumento{}, fmt.Errorf("unmarshal instrumento %s: %w", path, err)
 	}
+	inst.Raw = raw
 
-	// Extraemos solo lo que necesita main.go
-	if v, ok := raw["name"].(string); ok {
-		inst.Name = v
+	if err := inst.Validate(); err != nil {
+		return Instrumento{}, fmt.Errorf("instrumento %s: %w", path, err)
 	}
-	if v, ok := raw["version"].(string); ok {
-		inst.Version = v
+
+	// Índice de tarjetas (Validate ya garantizó que cada escala existe)
+	inst.cards = make(map[string]CardRef, 64)
+	for _, t := range inst.TypesOfViolence {
+		for _, q := range t.Questions {
+			for _, c := range q.Cards {
+				inst.cards[q.QuestionID+"|"+c.Dimension] = CardRef{
+					PreguntaID: q.QuestionID,
+					TipoNum:    t.Order,
+					TipoNombre: t.Label,
+					Card:       c,
+					Scale:      inst.Scales[c.ScaleID],
+				}
+			}
+		}
 	}
+	inst.lexico = compilarLexico(inst.TypesOfViolence)
 
 	return inst, nil
 }
+
+// CardFor regresa la tarjeta de una pregunta en una dimensión (ej. "P3", "gravedad")
+func (i Instrumento) CardFor(preguntaID, dimension string) (CardRef, bool) {
+	c, ok := i.cards[preguntaID+"|"+dimension]
+	return c, ok
+}
+
+// HasPregunta indica si el question_id existe en el instrumento
+func (i Instrumento) HasPregunta(preguntaID string) bool {
+	for _, t := range i.TypesOfViolence {
+		for _, q := range t.Questions {
+			if q.QuestionID == preguntaID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// HasDimension indica si la dimensión está declarada en "dimensions"
+func (i Instrumento) HasDimension(key string) bool {
+	for _, d := range i.Dimensions {
+		if d.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// TotalRespuestasEsperadas usa scoring.total_responses_expected y,
+// si no viene, el número de tarjetas del instrumento.
+func (i Instrumento) TotalRespuestasEsperadas() int {
+	if i.Scoring.TotalResponsesExpected > 0 {
+		return i.Scoring.TotalResponsesExpected
+	}
+	return len(i.cards)
+}
+
+// MapaPreguntas regresa pregunta -> tipo en el orden del instrumento
+func (i Instrumento) MapaPreguntas() []CardRef {
+	out := make([]CardRef, 0, 16)
+	for _, t := range i.TypesOfViolence {
+		for _, q := range t.Questions {
+			out = append(out, CardRef{PreguntaID: q.QuestionID, TipoNum: t.Order, TipoNombre: t.Label})
+		}
+	}
+	return out
+}
+
+// Faltantes regresa las tarjetas ("P3|gravedad") que faltan para poder
+// finalizar, según validation.required_all_cards / allow_skip:
+//   - required_all_cards: todas las tarjetas son obligatorias
+//   - allow_skip=false:    solo las tarjetas con "required": true
+//   - allow_skip=true:     ninguna (se puede finalizar con lo que haya)
+func (i Instrumento) Faltantes(respondidas map[string]bool) []string {
+	out := []string{}
+	if i.Validation.AllowSkip && !i.Validation.RequiredAllCards {
+		return out
+	}
+	for _, t := range i.TypesOfViolence {
+		for _, q := range t.Questions {
+			for _, c := range q.Cards {
+				if !i.Validation.RequiredAllCards && !c.Required {
+					continue
+				}
+				key := q.QuestionID + "|" + c.Dimension
+				if !respondidas[key] {
+					out = append(out, key)
+				}
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,37 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// SyncCatalogo copia el mapeo pregunta -> tipo del instrumento a la tabla
+// instrumento_preguntas. Las vistas y los reportes hacen JOIN contra esa
+// tabla, así que el JSON del instrumento es la única fuente de verdad.
+func SyncCatalogo(ctx context.Context, pool *pgxpool.Pool, inst Instrumento) error {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("sync catalogo: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if _, err := tx.Exec(ctx, `delete from instrumento_preguntas where instrumento_id = $1`, inst.ID); err != nil {
+		return fmt.Errorf("sync catalogo: %w", err)
+	}
+
+	for _, p := range inst.MapaPreguntas() {
+		if _, err := tx.Exec(ctx, `
+			insert into instrumento_preguntas (instrumento_id, pregunta_id, tipo_num, tipo_nombre)
+			values ($1, $2, $3, $4)
+		`, inst.ID, p.PreguntaID, p.TipoNum, p.TipoNombre); err != nil {
+			return fmt.Errorf("sync catalogo %s: %w", p.PreguntaID, err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("sync catalogo: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,325 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Rango que acepta respuestas.valor (CHECK de la migración 012); toda
+// escala del instrumento debe caber en él
+const (
+	EscalaValorMin = 0
+	EscalaValorMax = 10
+)
+
+// DimensionesReportes son las dimensiones que leen los reportes por clave
+// (Dimensiones, promedioHist, queueGrupos, DatosBenchmark, alertas e índice
+// de riesgo). Validate exige que todo instrumento declare exactamente
+// estas: una dimensión de más no saldría en ningún reporte y una de menos
+// los dejaría en cero sin aviso.
+var DimensionesReportes = []string{"frecuencia", "normalidad", "gravedad"}
+
+// InstrumentoInvalido junta todos los problemas encontrados en el JSON
+// para que se corrijan de una sola vez y no uno por arranque.
+type InstrumentoInvalido struct {
+	Problemas []string
+}
+
+func (e *InstrumentoInvalido) Error() string {
+	return "instrumento inválido:\n  - " + strings.Join(e.Problemas, "\n  - ")
+}
+
+// Validate revisa la consistencia interna del instrumento:
+// ids únicos, referencias a escalas/dimensiones existentes y el conteo esperado de respuestas.
+func (i Instrumento) Validate() error {
+	var p []string
+	add := func(format string, args ...any) {
+		p = append(p, fmt.Sprintf(format, args...))
+	}
+
+	if strings.TrimSpace(i.ID) == "" {
+		add("instrument_id vacío")
+	}
+	if strings.TrimSpace(i.Name) == "" {
+		add("name vacío")
+	}
+	if strings.TrimSpace(i.Version) == "" {
+		add("version vacío")
+	}
+
+	// ==========================
+	// Escalas
+	// ==========================
+	if len(i.Scales) == 0 {
+		add("scales vacío")
+	}
+	scaleIDs := make([]string, 0, len(i.Scales))
+	for id := range i.Scales {
+		scaleIDs = append(scaleIDs, id)
+	}
+	sort.Strings(scaleIDs)
+	for _, id := range scaleIDs {
+		sc := i.Scales[id]
+		if sc.Type != "likert" {
+			add("scale %s: type %q no soportado", id, sc.Type)
+		}
+		if sc.Min >= sc.Max {
+			add("scale %s: min (%d) debe ser menor que max (%d)", id, sc.Min, sc.Max)
+		}
+		if sc.Min < EscalaValorMin || sc.Max > EscalaValorMax {
+			add("scale %s: [%d,%d] fuera del rango que acepta respuestas.valor [%d,%d]", id, sc.Min, sc.Max, EscalaValorMin, EscalaValorMax)
+		}
+		seen := map[int]bool{}
+		for _, o := range sc.Options {
+			if o.Value < sc.Min || o.Value > sc.Max {
+				add("scale %s: opción %d fuera de [%d,%d]", id, o.Value, sc.Min, sc.Max)
+			}
+			if seen[o.Value] {
+				add("scale %s: opción %d duplicada", id, o.Value)
+			}
+			seen[o.Value] = true
+			if strings.TrimSpace(o.Label) == "" {
+				add("scale %s: opción %d sin label", id, o.Value)
+			}
+		}
+	}
+
+	// ==========================
+	// Dimensiones
+	// ==========================
+	if len(i.Dimensions) == 0 {
+		add("dimensions vacío")
+	}
+	dims := map[string]bool{}
+	for _, d := range i.Dimensions {
+		if d.Key == "" {
+			add("dimensión sin key")
+			continue
+		}
+		if dims[d.Key] {
+			add("dimensión %s duplicada", d.Key)
+		}
+		dims[d.Key] = true
+		if _, ok := i.Scales[d.ScaleID]; !ok {
+			add("dimensión %s: scale_id %q no existe", d.Key, d.ScaleID)
+		}
+	}
+	reportes := map[string]bool{}
+	for _, d := range DimensionesReportes {
+		reportes[d] = true
+		if !dims[d] {
+			add("dimensions: falta %s (los reportes usan %s)", d, strings.Join(DimensionesReportes, ", "))
+		}
+	}
+	for _, d := range i.Dimensions {
+		if d.Key != "" && !reportes[d.Key] {
+			add("dimensión %s no soportada: los reportes solo usan %s", d.Key, strings.Join(DimensionesReportes, ", "))
+		}
+	}
+
+	// ==========================
+	// Tipos, preguntas y tarjetas
+	// ==========================
+	if len(i.TypesOfViolence) == 0 {
+		add("types_of_violence vacío")
+	}
+	typeIDs := map[string]bool{}
+	typeOrders := map[int]bool{}
+	questionIDs := map[string]bool{}
+	questionOrders := map[int]bool{}
+	totalCards := 0
+
+	for _, t := range i.TypesOfViolence {
+		if t.TypeID == "" {
+			add("tipo (order %d) sin type_id", t.Order)
+		} else if typeIDs[t.TypeID] {
+			add("type_id %s duplicado", t.TypeID)
+		}
+		typeIDs[t.TypeID] = true
+
+		if t.Order <= 0 {
+			add("tipo %s: order debe ser > 0", t.TypeID)
+		} else if typeOrders[t.Order] {
+			add("tipo %s: order %d duplicado", t.TypeID, t.Order)
+		}
+		typeOrders[t.Order] = true
+
+		if strings.TrimSpace(t.Label) == "" {
+			add("tipo %s sin label", t.TypeID)
+		}
+		if len(t.Questions) == 0 {
+			add("tipo %s sin preguntas", t.TypeID)
+		}
+
+		// léxico para comentarios (opcional)
+		kws := map[string]bool{}
+		for _, k := range t.Keywords {
+			if len(compilarTermino(k)) == 0 {
+				add("tipo %s: keyword %q vacía", t.TypeID, k)
+				continue
+			}
+			for _, w := range strings.Fields(k) {
+				if strings.Contains(strings.TrimSuffix(w, "*"), "*") || w == "*" {
+					add("tipo %s: keyword %q: \"*\" solo va al final de una palabra", t.TypeID, k)
+					break
+				}
+			}
+			n := normalizarTexto(strings.Join(strings.Fields(k), " "))
+			if kws[n] {
+				add("tipo %s: keyword %q duplicada", t.TypeID, k)
+			}
+			kws[n] = true
+		}
+
+		for _, q := range t.Questions {
+			if q.QuestionID == "" {
+				add("tipo %s: pregunta sin question_id", t.TypeID)
+				continue
+			}
+			if questionIDs[q.QuestionID] {
+				add("question_id %s duplicado", q.QuestionID)
+			}
+			questionIDs[q.QuestionID] = true
+
+			if questionOrders[q.Order] {
+				add("pregunta %s: order %d duplicado", q.QuestionID, q.Order)
+			}
+			questionOrders[q.Order] = true
+
+			if strings.TrimSpace(q.Stem) == "" {
+				add("pregunta %s sin stem", q.QuestionID)
+			}
+			if len(q.Cards) == 0 {
+				add("pregunta %s sin tarjetas", q.QuestionID)
+			}
+
+			cardDims := map[string]bool{}
+			for _, c := range q.Cards {
+				totalCards++
+				if !dims[c.Dimension] {
+					add("pregunta %s: dimensión %q no declarada", q.QuestionID, c.Dimension)
+				}
+				if cardDims[c.Dimension] {
+					add("pregunta %s: dimensión %s repetida", q.QuestionID, c.Dimension)
+				}
+				cardDims[c.Dimension] = true
+				if _, ok := i.Scales[c.ScaleID]; !ok {
+					add("pregunta %s/%s: scale_id %q no existe", q.QuestionID, c.Dimension, c.ScaleID)
+				}
+				if strings.TrimSpace(c.Prompt) == "" {
+					add("pregunta %s/%s sin prompt", q.QuestionID, c.Dimension)
+				}
+			}
+		}
+	}
+
+	// ==========================
+	// Scoring y validación
+	// ==========================
+	if i.Scoring.TotalResponsesExpected != totalCards {
+		add("scoring.total_responses_expected = %d, pero el instrumento tiene %d tarjetas", i.Scoring.TotalResponsesExpected, totalCards)
+	}
+	for _, f := range i.Scoring.PerQuestion.Fields {
+		if !dims[f] {
+			add("scoring.per_question.fields: dimensión %q no declarada", f)
+		}
+	}
+	if ri := i.Scoring.RiskIndex; ri != nil {
+		if ri.Method != MetodoRiesgoFxNPorG {
+			add("scoring.risk_index.method %q no soportado (%s)", ri.Method, MetodoRiesgoFxNPorG)
+		}
+		if w := ri.PesoGravedad(); w < 0 || w > 1 {
+			add("scoring.risk_index.gravedad_weight %.2f fuera de [0,1]", w)
+		}
+		if len(ri.Levels) == 0 {
+			add("scoring.risk_index.levels vacío")
+		}
+		keys := map[string]bool{}
+		for n, l := range ri.Levels {
+			if l.Key == "" || strings.TrimSpace(l.Label) == "" {
+				add("scoring.risk_index.levels[%d] sin key o label", n)
+			}
+			if keys[l.Key] {
+				add("scoring.risk_index.levels: key %s duplicada", l.Key)
+			}
+			keys[l.Key] = true
+			switch {
+			case n == 0 && l.Min != 0:
+				add("scoring.risk_index.levels: el primer nivel debe empezar en 0")
+			case n > 0 && l.Min <= ri.Levels[n-1].Min:
+				add("scoring.risk_index.levels: %s debe empezar después de %s", l.Key, ri.Levels[n-1].Key)
+			}
+			if l.Min < 0 || l.Min >= 100 {
+				add("scoring.risk_index.levels: min %.1f de %s fuera de [0,100)", l.Min, l.Key)
+			}
+		}
+	}
+	if i.Validation.RequiredAllCards && i.Validation.AllowSkip {
+		add("validation: required_all_cards y allow_skip no pueden ser ambos true")
+	}
+
+	// ==========================
+	// Retroalimentación (opcional)
+	// ==========================
+	if fb := i.Feedback; fb != nil {
+		recursos := map[string]bool{}
+		for n, rec := range fb.Resources {
+			if rec.ID == "" {
+				add("feedback.resources[%d] sin id", n)
+				continue
+			}
+			if recursos[rec.ID] {
+				add("feedback.resources: id %s duplicado", rec.ID)
+			}
+			recursos[rec.ID] = true
+			switch rec.Kind {
+			case RecursoLinea, RecursoProtocolo, RecursoServicio:
+			default:
+				add("feedback.resources %s: kind %q no soportado", rec.ID, rec.Kind)
+			}
+			if strings.TrimSpace(rec.Name) == "" {
+				add("feedback.resources %s sin name", rec.ID)
+			}
+		}
+
+		if len(fb.Rules) == 0 {
+			add("feedback.rules vacío")
+		}
+		reglas := map[string]bool{}
+		for n, r := range fb.Rules {
+			if r.ID == "" {
+				add("feedback.rules[%d] sin id", n)
+				continue
+			}
+			if reglas[r.ID] {
+				add("feedback.rules: id %s duplicado", r.ID)
+			}
+			reglas[r.ID] = true
+			if r.TypeID != "" && r.TypeID != TipoFeedbackCadaTipo && !typeIDs[r.TypeID] {
+				add("feedback.rules %s: type_id %q no existe", r.ID, r.TypeID)
+			}
+			if !dims[r.Dimension] {
+				add("feedback.rules %s: dimensión %q no declarada", r.ID, r.Dimension)
+			} else if lo, hi, ok := i.rangoDimension(r.Dimension); ok && (r.Min < lo || r.Max > hi) {
+				add("feedback.rules %s: rango [%.2f, %.2f] fuera de la escala [%.0f, %.0f]", r.ID, r.Min, r.Max, lo, hi)
+			}
+			if r.Min > r.Max {
+				add("feedback.rules %s: min mayor que max", r.ID)
+			}
+			if strings.TrimSpace(r.Title) == "" || strings.TrimSpace(r.Message) == "" {
+				add("feedback.rules %s sin title o message", r.ID)
+			}
+			for _, id := range r.Resources {
+				if !recursos[id] {
+					add("feedback.rules %s: recurso %q no existe", r.ID, id)
+				}
+			}
+		}
+	}
+
+	if len(p) > 0 {
+		return &InstrumentoInvalido{Problemas: p}
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValidateDimensionesReportes(t *testing.T) {
+	if err := instrumentoPrueba(t).Validate(); err != nil {
+		t.Fatalf("instrumento de prueba: %v", err)
+	}
+
+	casos := []struct {
+		nombre string
+		cambia func(inst *Instrumento)
+		quiero string
+	}{
+		{
+			nombre: "falta una dimensión",
+			cambia: func(inst *Instrumento) { inst.Dimensions = inst.Dimensions[:2] },
+			quiero: "dimensions: falta gravedad",
+		},
+		{
+			nombre: "dimensión de más",
+			cambia: func(inst *Instrumento) {
+				d := inst.Dimensions[0]
+				d.Key = "intensidad"
+				inst.Dimensions = append(inst.Dimensions, d)
+			},
+			quiero: "dimensión intensidad no soportada",
+		},
+	}
+	for _, c := range casos {
+		t.Run(c.nombre, func(t *testing.T) {
+			inst := instrumentoPrueba(t)
+			c.cambia(&inst)
+			var inv *InstrumentoInvalido
+			if err := inst.Validate(); !errors.As(err, &inv) {
+				t.Fatalf("err = %v, quiero InstrumentoInvalido", err)
+			}
+			if !strings.Contains(strings.Join(inv.Problemas, "\n"), c.quiero) {
+				t.Errorf("problemas %q, quiero %q", inv.Problemas, c.quiero)
+			}
+		})
+	}
+}
@@ -0,0 +1,309 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// =======================================================
+// Microdatos anonimizados para investigación (solo admin)
+// Una fila por encuesta finalizada: código de centro (seudónimo por
+// exportación), tipo de centro, año, género, rango de edad y una columna
+// por tarjeta del instrumento. Nunca salen email, fechas exactas,
+// edad exacta, comentarios ni el id de la encuesta.
+// =======================================================
+
+// FiltroMicrodatos delimita la exportación
+type FiltroMicrodatos struct {
+	Instrumento Instrumento // define las columnas de respuestas
+	Year        *int
+	CentroTipo  string      // escolar|laboral|"" (todos)
+	Rangos      []RangoEdad // nil = conjunto default de cada tipo de centro
+	K           int         // centros con menos de K encuestas se omiten
+}
+
+// VariableMicro describe una columna (nombre, etiqueta y tipo para SPSS/Parquet)
+type VariableMicro struct {
+	Nombre   string        // identificador corto, sin espacios (ej. "P1_frecuencia")
+	Etiqueta string        // descripción larga (ej. el texto de la tarjeta)
+	Texto    bool          // true = cadena; false = entero
+	Valores  []ScaleOption // etiquetas de valor (escala likert)
+}
+
+// Microdatos es la tabla ya anonimizada. Las celdas son int64, string o nil.
+type Microdatos struct {
+	Variables []VariableMicro
+	Filas     [][]any
+
+	CentrosSuprimidos   int // centros omitidos por tener menos de K encuestas
+	EncuestasSuprimidas int // encuestas de esos centros
+	CeldasGeneralizadas int // filas a las que se les quitó género/rango por ser poco frecuentes
+}
+
+// Hoja convierte los microdatos a una hoja para CSV
+func (m Microdatos) Hoja() Hoja {
+	cols := make([]string, len(m.Variables))
+	for i, v := range m.Variables {
+		cols[i] = v.Nombre
+	}
+	return Hoja{Clave: "microdatos", Nombre: "Microdatos", Columnas: cols, Filas: m.Filas}
+}
+
+// columnas fijas antes de las respuestas
+const (
+	microFila = iota
+	microCentro
+	microCentroTipo
+	microYear
+	microGenero
+	microRangoEdad
+)
+
+// ExtraerMicrodatos lee encuestas y respuestas en una transacción de solo
+// lectura (misma foto de la base) y aplica la anonimización.
+func ExtraerMicrodatos(ctx context.Context, pool *pgxpool.Pool, f FiltroMicrodatos) (Microdatos, error) {
+	if f.K <= 0 {
+		f.K = AnonimatoKDefault
+	}
+	inst := f.Instrumento
+	var m Microdatos
+
+	// ==========================
+	// VARIABLES
+	// ==========================
+	m.Variables = []VariableMicro{
+		{Nombre: "fila", Etiqueta: "Número de fila (orden aleatorio)"},
+		{Nombre: "centro", Etiqueta: "Código de centro (seudónimo, cambia en cada exportación)", Texto: true},
+		{Nombre: "centro_tipo", Etiqueta: "Tipo de centro (escolar / laboral)", Texto: true},
+		{Nombre: "year", Etiqueta: "Año de finalización"},
+		{Nombre: "genero", Etiqueta: "Género (clave); vacío si la combinación es poco frecuente", Texto: true},
+		{Nombre: "rango_edad", Etiqueta: "Rango de edad; vacío si la combinación es poco frecuente", Texto: true},
+	}
+
+	colDe := map[string]int{}
+	for _, t := range inst.TypesOfViolence {
+		for _, q := range t.Questions {
+			for _, c := range q.Cards {
+				colDe[q.QuestionID+"|"+c.Dimension] = len(m.Variables)
+				sc := inst.Scales[c.ScaleID]
+				m.Variables = append(m.Variables, VariableMicro{
+					Nombre:   q.QuestionID + "_" + c.Dimension,
+					Etiqueta: q.QuestionID + " " + c.Dimension + ": " + c.Prompt,
+					Valores:  sc.Options,
+				})
+			}
+		}
+	}
+
+	tx, err := pool.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return m, fmt.Errorf("microdatos: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	// ==========================
+	// RANGOS DE EDAD (uno para todos o el default de cada tipo)
+	// ==========================
+	rangosDe := func(string) []RangoEdad { return f.Rangos }
+	if f.Rangos == nil {
+		porTipo, err := rangosPorTipo(ctx, tx)
+		if err != nil {
+			return m, err
+		}
+		rangosDe = func(tipo string) []RangoEdad {
+			if rs, ok := porTipo[tipo]; ok {
+				return rs
+			}
+			return RangosEdadDefault
+		}
+	}
+
+	// ==========================
+	// ENCUESTAS (orden aleatorio: el orden no debe delatar la fecha)
+	// ==========================
+	where := `
+		e.finished_at is not null
+		and e.instrumento_id = $1
+		and ($2::int is null or extract(year from e.finished_at)::int = $2)
+		and ($3 = '' or c.tipo = $3)`
+	args := []any{inst.ID, f.Year, f.CentroTipo}
+
+	var encuestas []encuestaMicro
+	indice := map[string]int{}
+
+	rows, err := tx.Query(ctx, `
+		select e.id::text, e.centro_id, c.tipo, extract(year from e.finished_at)::int, coalesce(g.clave, ''), e.edad
+		from encuestas e
+		join centros c on c.id = e.centro_id
+		left join generos g on g.id = e.genero_id
+		where `+where+`
+		order by random()
+	`, args...)
+	if err != nil {
+		return m, fmt.Errorf("microdatos encuestas: %w", err)
+	}
+	for rows.Next() {
+		var id, tipo, genero string
+		var centroID int64
+		var year, edad int
+		if err := rows.Scan(&id, &centroID, &tipo, &year, &genero, &edad); err != nil {
+			rows.Close()
+			return m, fmt.Errorf("microdatos encuestas: %w", err)
+		}
+		fila := make([]any, len(m.Variables))
+		fila[microCentroTipo] = tipo
+		fila[microYear] = int64(year)
+		if genero != "" {
+			fila[microGenero] = genero
+		}
+		if rg := EtiquetaRango(rangosDe(tipo), edad); rg != RangoEdadSinDato {
+			fila[microRangoEdad] = rg
+		}
+		indice[id] = len(encuestas)
+		encuestas = append(encuestas, encuestaMicro{centroID: centroID, fila: fila})
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return m, fmt.Errorf("microdatos encuestas: %w", err)
+	}
+
+	// ==========================
+	// RESPUESTAS
+	// ==========================
+	rows, err = tx.Query(ctx, `
+		select r.encuesta_id::text, r.pregunta_id, r.dimension::text, r.valor
+		from respuestas r
+		join encuestas e on e.id = r.encuesta_id
+		join centros c on c.id = e.centro_id
+		where `+where+`
+	`, args...)
+	if err != nil {
+		return m, fmt.Errorf("microdatos respuestas: %w", err)
+	}
+	for rows.Next() {
+		var id, pid, dim string
+		var valor int16
+		if err := rows.Scan(&id, &pid, &dim, &valor); err != nil {
+			rows.Close()
+			return m, fmt.Errorf("microdatos respuestas: %w", err)
+		}
+		i, ok := indice[id]
+		if !ok {
+			continue
+		}
+		if col, ok := colDe[pid+"|"+dim]; ok {
+			encuestas[i].fila[col] = int64(valor)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return m, fmt.Errorf("microdatos respuestas: %w", err)
+	}
+
+	anonimizarMicrodatos(&m, encuestas, f.K)
+	return m, nil
+}
+
+// encuestaMicro es una fila antes de anonimizar (con el centro real)
+type encuestaMicro struct {
+	centroID int64
+	fila     []any
+}
+
+// anonimizarMicrodatos llena m.Filas con las encuestas (ya en orden
+// aleatorio) y aplica la anonimización con umbral k
+func anonimizarMicrodatos(m *Microdatos, encuestas []encuestaMicro, k int) {
+	// 1) centros con menos de k encuestas fuera; los demás con seudónimo
+	porCentro := map[int64]int{}
+	for _, e := range encuestas {
+		porCentro[e.centroID]++
+	}
+	codigo := map[int64]string{}
+	for _, n := range porCentro {
+		if n < k {
+			m.CentrosSuprimidos++
+			m.EncuestasSuprimidas += n
+		}
+	}
+	m.Filas = make([][]any, 0, len(encuestas)-m.EncuestasSuprimidas)
+	for _, e := range encuestas {
+		if porCentro[e.centroID] < k {
+			continue
+		}
+		c, ok := codigo[e.centroID]
+		if !ok {
+			c = fmt.Sprintf("C%03d", len(codigo)+1)
+			codigo[e.centroID] = c
+		}
+		e.fila[microCentro] = c
+		e.fila[microFila] = int64(len(m.Filas) + 1)
+		m.Filas = append(m.Filas, e.fila)
+	}
+
+	// 2) combinaciones centro × año × género × rango con menos de k filas
+	//    pierden género y rango (se vuelven indistinguibles dentro del centro)
+	clave := func(fila []any) string {
+		partes := make([]string, 0, 4)
+		for _, i := range []int{microCentro, microYear, microGenero, microRangoEdad} {
+			partes = append(partes, celdaTexto(fila[i]))
+		}
+		return strings.Join(partes, "|")
+	}
+	celdas := map[string]int{}
+	for _, fila := range m.Filas {
+		celdas[clave(fila)]++
+	}
+	for _, fila := range m.Filas {
+		if celdas[clave(fila)] < k {
+			fila[microGenero] = nil
+			fila[microRangoEdad] = nil
+			m.CeldasGeneralizadas++
+		}
+	}
+}
+
+// rangosPorTipo carga el conjunto default de cada tipo de centro
+func rangosPorTipo(ctx context.Context, tx pgx.Tx) (map[string][]RangoEdad, error) {
+	rows, err := tx.Query(ctx, `
+		select s.centro_tipo, re.edad_min, coalesce(re.edad_max, 0), re.etiqueta
+		from rangos_edad_sets s
+		join rangos_edad re on re.set_id = s.id
+		where s.centro_tipo is not null
+		order by s.centro_tipo, re.orden
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("microdatos rangos: %w", err)
+	}
+	defer rows.Close()
+
+	out := map[string][]RangoEdad{}
+	for rows.Next() {
+		var tipo string
+		var rg RangoEdad
+		if err := rows.Scan(&tipo, &rg.Min, &rg.Max, &rg.Label); err != nil {
+			return nil, fmt.Errorf("microdatos rangos: %w", err)
+		}
+		out[tipo] = append(out[tipo], rg)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("microdatos rangos: %w", err)
+	}
+	return out, nil
+}
+
+// NombreArchivo es el nombre base de la descarga (sin extensión)
+func (f FiltroMicrodatos) NombreArchivo() string {
+	s := "microdatos_" + f.Instrumento.ID
+	if f.Year != nil {
+		s += "_" + strconv.Itoa(*f.Year)
+	}
+	if f.CentroTipo != "" {
+		s += "_" + f.CentroTipo
+	}
+	return s
+}
@@ -0,0 +1,169 @@
+package services
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestAnonimizarMicrodatos(t *testing.T) {
+	type enc struct {
+		centro        int64
+		year          int64
+		genero, rango string // "" = sin dato
+	}
+	mujer := func(c int64) enc { return enc{c, 2024, "mujer", "18-24"} }
+
+	casos := []struct {
+		nombre       string
+		k            int
+		encuestas    []enc
+		fuera        []int // índices de encuestas omitidas (su centro tiene < k)
+		generalizada []int // índices que pierden género y rango
+		centrosFuera int
+	}{
+		{
+			nombre:    "todo en celdas de k o más",
+			k:         3,
+			encuestas: []enc{mujer(1), mujer(1), mujer(1)},
+		},
+		{
+			nombre:       "centro con menos de k se omite",
+			k:            3,
+			encuestas:    []enc{mujer(1), mujer(1), mujer(1), mujer(2), mujer(2)},
+			fuera:        []int{3, 4},
+			centrosFuera: 1,
+		},
+		{
+			nombre: "celda con menos de k pierde género y rango",
+			k:      3,
+			encuestas: []enc{
+				mujer(1), mujer(1), mujer(1),
+				{1, 2024, "hombre", "25-34"},
+			},
+			generalizada: []int{3},
+		},
+		{
+			nombre: "mismo género y rango en otro año es otra celda",
+			k:      3,
+			encuestas: []enc{
+				mujer(1), mujer(1), mujer(1),
+				{1, 2023, "mujer", "18-24"}, {1, 2023, "mujer", "18-24"},
+			},
+			generalizada: []int{3, 4},
+		},
+		{
+			nombre: "celda con menos de k en un centro, completa en otro",
+			k:      2,
+			encuestas: []enc{
+				mujer(1), mujer(1),
+				mujer(2), {2, 2024, "", "18-24"},
+			},
+			generalizada: []int{2, 3},
+		},
+		{
+			nombre: "sin dato también forma celda",
+			k:      2,
+			encuestas: []enc{
+				{1, 2024, "", ""}, {1, 2024, "", ""}, mujer(1),
+			},
+			generalizada: []int{2},
+		},
+	}
+
+	for _, c := range casos {
+		t.Run(c.nombre, func(t *testing.T) {
+			// la columna después de rango_edad guarda el índice de la encuesta
+			const colIndice = microRangoEdad + 1
+			var encuestas []encuestaMicro
+			for i, e := range c.encuestas {
+				fila := make([]any, colIndice+1)
+				fila[microCentroTipo] = "escolar"
+				fila[microYear] = e.year
+				if e.genero != "" {
+					fila[microGenero] = e.genero
+				}
+				if e.rango != "" {
+					fila[microRangoEdad] = e.rango
+				}
+				fila[colIndice] = int64(i)
+				encuestas = append(encuestas, encuestaMicro{centroID: e.centro, fila: fila})
+			}
+
+			var m Microdatos
+			anonimizarMicrodatos(&m, encuestas, c.k)
+
+			if m.CentrosSuprimidos != c.centrosFuera || m.EncuestasSuprimidas != len(c.fuera) {
+				t.Errorf("suprimidos: %d centros, %d encuestas; quiero %d, %d",
+					m.CentrosSuprimidos, m.EncuestasSuprimidas, c.centrosFuera, len(c.fuera))
+			}
+			if m.CeldasGeneralizadas != len(c.generalizada) {
+				t.Errorf("%d filas generalizadas, quiero %d", m.CeldasGeneralizadas, len(c.generalizada))
+			}
+
+			fuera := map[int]bool{}
+			for _, i := range c.fuera {
+				fuera[i] = true
+			}
+			generalizada := map[int]bool{}
+			for _, i := range c.generalizada {
+				generalizada[i] = true
+			}
+
+			var vistas []int
+			codigoDe := map[int64]string{}
+			for n, fila := range m.Filas {
+				i := int(fila[colIndice].(int64))
+				e := c.encuestas[i]
+				vistas = append(vistas, i)
+				if fuera[i] {
+					t.Errorf("encuesta %d de un centro con menos de k sigue en la salida", i)
+				}
+				if fila[microFila] != int64(n+1) {
+					t.Errorf("fila %d numerada %v", n, fila[microFila])
+				}
+
+				// seudónimo: mismo centro, mismo código; nunca el id real
+				cod, _ := fila[microCentro].(string)
+				if !strings.HasPrefix(cod, "C") || len(cod) != 4 {
+					t.Errorf("código de centro %q", cod)
+				}
+				if prev, ok := codigoDe[e.centro]; ok && prev != cod {
+					t.Errorf("centro %d con dos códigos: %s y %s", e.centro, prev, cod)
+				}
+				codigoDe[e.centro] = cod
+
+				if generalizada[i] {
+					if fila[microGenero] != nil || fila[microRangoEdad] != nil {
+						t.Errorf("encuesta %d: género %v, rango %v; quiero vacíos", i, fila[microGenero], fila[microRangoEdad])
+					}
+				} else if celdaTexto(fila[microGenero]) != e.genero || celdaTexto(fila[microRangoEdad]) != e.rango {
+					t.Errorf("encuesta %d: género %v, rango %v; quiero %q, %q", i, fila[microGenero], fila[microRangoEdad], e.genero, e.rango)
+				}
+				if fila[microYear] != e.year {
+					t.Errorf("encuesta %d: año %v, quiero %d", i, fila[microYear], e.year)
+				}
+			}
+
+			var quiero []int
+			for i := range c.encuestas {
+				if !fuera[i] {
+					quiero = append(quiero, i)
+				}
+			}
+			sort.Ints(vistas)
+			if !reflect.DeepEqual(vistas, quiero) {
+				t.Errorf("encuestas en la salida %v, quiero %v", vistas, quiero)
+			}
+
+			codigos := map[string]bool{}
+			for _, cod := range codigoDe {
+				codigos[cod] = true
+			}
+			if len(codigos) != len(codigoDe) {
+				t.Errorf("dos centros comparten código: %v", codigoDe)
+			}
+		})
+	}
+}
@@ -0,0 +1,372 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// =======================================================
+// Moderación de comentarios abiertos
+// Todo comentario nuevo (o modificado) entra como pendiente. La detección de
+// datos personales es heurística: solo sugiere qué tachar, la decisión la
+// toma una persona admin (aprobar, publicar redactado u ocultar).
+// =======================================================
+
+const (
+	ModeracionPendiente = "pendiente"
+	ModeracionAprobado  = "aprobado"
+	ModeracionRedactado = "redactado"
+	ModeracionOculto    = "oculto"
+)
+
+// EstadoModeracionValido dice si s es uno de los estados de la cola
+func EstadoModeracionValido(s string) bool {
+	switch s {
+	case ModeracionPendiente, ModeracionAprobado, ModeracionRedactado, ModeracionOculto:
+		return true
+	}
+	return false
+}
+
+// EncolarComentario (re)pone en pendiente el comentario de la encuesta si su
+// texto cambió. Va dentro de la misma transacción que guarda encuestas.comentario.
+func EncolarComentario(ctx context.Context, tx dbtx, encuestaID string) error {
+	_, err := tx.Exec(ctx, `
+		insert into comentarios_moderacion (encuesta_id, original_md5)
+		select e.id, md5(e.comentario)
+		from encuestas e
+		where e.id = $1
+		  and e.comentario is not null
+		  and btrim(e.comentario) <> ''
+		on conflict (encuesta_id) do update set
+			estado = 'pendiente',
+			original_md5 = excluded.original_md5,
+			texto_publicado = null,
+			revisado_por = null,
+			revisado_at = null,
+			updated_at = now()
+		where comentarios_moderacion.original_md5 <> excluded.original_md5
+	`, encuestaID)
+	if err != nil {
+		return fmt.Errorf("moderacion encolar: %w", err)
+	}
+	return nil
+}
+
+// ==========================
+// DETECCIÓN DE DATOS PERSONALES
+// ==========================
+
+// Hallazgo es un fragmento del comentario que parece dato personal.
+// Inicio/Fin son posiciones en runas (para resaltar en la UI).
+type Hallazgo struct {
+	Tipo   string `json:"tipo"` // email|telefono|curp|rfc|nombre
+	Texto  string `json:"texto"`
+	Inicio int    `json:"inicio"`
+	Fin    int    `json:"fin"`
+}
+
+// reemplazo de cada tipo en el texto redactado
+var etiquetaHallazgo = map[string]string{
+	"email":    "[CORREO]",
+	"telefono": "[TELÉFONO]",
+	"curp":     "[CURP]",
+	"rfc":      "[RFC]",
+	"nombre":   "[NOMBRE]",
+}
+
+var (
+	reEmail    = regexp.MustCompile(`(?i)[a-z0-9._%+\-]+@[a-z0-9.\-]+\.[a-z]{2,}`)
+	reCURP     = regexp.MustCompile(`(?i)[a-z][aeioux][a-z]{2}\d{6}[hmx][a-z]{5}[a-z0-9]\d`)
+	reRFC      = regexp.MustCompile(`(?i)[a-zñ&]{3,4}\d{6}(?:[a-z0-9]{3})?`)
+	reTelefono = regexp.MustCompile(`\+?\(?\d[\d \t().\-]{6,18}\d`)
+	reOchoDig  = regexp.MustCompile(`^\d{4}-\d{4}$`)
+	reAnios    = regexp.MustCompile(`^(?:19|20)\d{2}-(?:19|20)\d{2}$`)
+)
+
+// telefonoValido: 10 a 13 dígitos (con lada / +52) o un local de 8 con
+// formato 5555-1234; descarta fechas, años, rangos de años (2023-2024) y
+// folios cortos
+func telefonoValido(s string) bool {
+	n := 0
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			n++
+		}
+	}
+	return (n >= 10 && n <= 13) || (n == 8 && reOchoDig.MatchString(s) && !reAnios.MatchString(s))
+}
+
+type detectorRegex struct {
+	tipo   string
+	re     *regexp.Regexp
+	valido func(string) bool
+}
+
+// el orden importa: ante traslapes gana el primero
+var detectoresRegex = []detectorRegex{
+	{"email", reEmail, nil},
+	{"curp", reCURP, nil},
+	{"rfc", reRFC, nil},
+	{"telefono", reTelefono, telefonoValido},
+}
+
+// DetectarPII busca correos, teléfonos, CURP, RFC y nombres propios.
+// Los hallazgos salen ordenados y sin traslapes.
+func DetectarPII(texto string) []Hallazgo {
+	type tramo struct {
+		tipo string
+		i, j int // bytes
+	}
+	var tramos []tramo
+	ocupado := func(i, j int) bool {
+		for _, t := range tramos {
+			if i < t.j && t.i < j {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, d := range detectoresRegex {
+		for _, m := range d.re.FindAllStringIndex(texto, -1) {
+			i, j := m[0], m[1]
+			if !aislado(texto, i, j) || ocupado(i, j) {
+				continue
+			}
+			if d.valido != nil && !d.valido(texto[i:j]) {
+				continue
+			}
+			tramos = append(tramos, tramo{d.tipo, i, j})
+		}
+	}
+	for _, m := range detectarNombres(texto) {
+		if !ocupado(m[0], m[1]) {
+			tramos = append(tramos, tramo{"nombre", m[0], m[1]})
+		}
+	}
+
+	sort.Slice(tramos, func(a, b int) bool { return tramos[a].i < tramos[b].i })
+	out := make([]Hallazgo, 0, len(tramos))
+	for _, t := range tramos {
+		out = append(out, Hallazgo{
+			Tipo:   t.tipo,
+			Texto:  texto[t.i:t.j],
+			Inicio: utf8.RuneCountInString(texto[:t.i]),
+			Fin:    utf8.RuneCountInString(texto[:t.j]),
+		})
+	}
+	return out
+}
+
+// RedactarPII reemplaza cada hallazgo por su etiqueta ([CORREO], [NOMBRE], ...)
+func RedactarPII(texto string, hs []Hallazgo) string {
+	runas := []rune(texto)
+	var b strings.Builder
+	pos := 0
+	for _, h := range hs {
+		if h.Inicio < pos || h.Fin > len(runas) {
+			continue
+		}
+		b.WriteString(string(runas[pos:h.Inicio]))
+		b.WriteString(etiquetaHallazgo[h.Tipo])
+		pos = h.Fin
+	}
+	b.WriteString(string(runas[pos:]))
+	return b.String()
+}
+
+// aislado: el tramo [i, j) no está pegado a otra letra o dígito
+// (regexp de Go no tiene \b para letras acentuadas)
+func aislado(s string, i, j int) bool {
+	if i > 0 {
+		r, _ := utf8.DecodeLastRuneInString(s[:i])
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return false
+		}
+	}
+	if j < len(s) {
+		r, _ := utf8.DecodeRuneInString(s[j:])
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// ==========================
+// NOMBRES PROPIOS
+// ==========================
+
+// palabras que van con mayúscula por iniciar frase, nunca nombres
+var inicioFrase = conjunto(`el la los las un una unos unas en mi mis yo me nos se que qué por para
+	con no si sí pero y o cuando hay es son fue muy también tambien ella él ellos ellas a al de del
+	lo le les su sus este esta esto ese esa eso aquí aqui ahí ahi todo todos todas nunca siempre
+	hola gracias ojalá ojala creo considero pienso ya porque cuál cual como cómo mejor falta
+	ayer hoy luego después despues antes ahora entonces aunque además ademas desde hasta durante
+	sin solo sólo más mas casi bueno pues igual nadie alguien algunos algunas otro otra otros otras
+	varios varias muchos muchas cada tú tu usted ustedes nosotras nosotros hace estoy tengo siento`)
+
+// palabras que, justo después de una palabra capitalizada al inicio de una
+// frase, indican que esa palabra es el sujeto ("Juan me pegó", "Pedro siempre...")
+var marcadoresSujeto = conjunto(`me te le les nos lo la los las se no siempre nunca también tambien ya
+	es era fue está esta estaba dijo dice hizo hace tiene tenía tenia`)
+
+// palabras con mayúscula que no son nombres de personas (instituciones,
+// lugares, meses, marcas); si aparecen, el tramo completo se descarta
+var noNombres = conjunto(`dios méxico mexico cdmx escuela instituto universidad secundaria
+	preparatoria primaria bachillerato departamento recursos humanos dirección direccion centro
+	nacional politécnico politecnico ipn unam empresa área area comité comite protocolo
+	lunes martes miércoles miercoles jueves viernes sábado sabado domingo
+	enero febrero marzo abril mayo junio julio agosto septiembre octubre noviembre diciembre
+	whatsapp facebook instagram tiktok google internet covid
+	profesores maestros maestras compañeros compañeras alumnos alumnas jefes hombres mujeres
+	chicos chicas estudiantes personas gente`)
+
+// títulos o roles que suelen anteceder un nombre ("el profe Juan")
+var titulos = conjunto(`profe profesor profesora maestro maestra mtro mtra miss licenciado licenciada
+	lic ingeniero ingeniera ing doctor doctora dr dra señor señora sr sra srita don doña jefe jefa
+	director directora coordinador coordinadora compañero compañera supervisor supervisora
+	prefecto prefecta tutor tutora gerente encargado encargada`)
+
+// conectores dentro de un nombre compuesto ("María de la Luz")
+var conectores = conjunto(`de del la las los`)
+
+func conjunto(s string) map[string]bool {
+	m := map[string]bool{}
+	for _, w := range strings.Fields(s) {
+		m[w] = true
+	}
+	return m
+}
+
+type palabra struct {
+	s    string
+	i, j int // bytes
+}
+
+func partirPalabras(s string) []palabra {
+	var out []palabra
+	inicio := -1
+	for i, r := range s {
+		letra := unicode.IsLetter(r)
+		if letra && inicio < 0 {
+			inicio = i
+		}
+		if !letra && inicio >= 0 {
+			out = append(out, palabra{s[inicio:i], inicio, i})
+			inicio = -1
+		}
+	}
+	if inicio >= 0 {
+		out = append(out, palabra{s[inicio:], inicio, len(s)})
+	}
+	return out
+}
+
+// capitalizada: Mayúscula seguida de minúsculas (las siglas no cuentan)
+func capitalizada(w string) bool {
+	r, n := utf8.DecodeRuneInString(w)
+	if !unicode.IsUpper(r) || n == len(w) {
+		return false
+	}
+	for _, r := range w[n:] {
+		if !unicode.IsLower(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// soloEspacios: entre dos palabras solo hay espacios (sin puntuación)
+func soloEspacios(s string) bool {
+	return strings.TrimSpace(s) == "" && s != ""
+}
+
+// detectarNombres regresa tramos [i, j) en bytes que parecen nombres:
+// - dos o más palabras capitalizadas seguidas ("Juan Pérez")
+// - una palabra capitalizada después de un título ("la maestra Rosa")
+// - una palabra capitalizada a media frase ("le dije a Pedro")
+// - una palabra capitalizada al inicio de frase antes de "me", "no", "dijo"... ("Juan me pegó")
+func detectarNombres(s string) [][2]int {
+	ws := partirPalabras(s)
+	var out [][2]int
+
+	for a := 0; a < len(ws); a++ {
+		if !capitalizada(ws[a].s) {
+			continue
+		}
+
+		// extender: capitalizadas o conectores seguidos de capitalizada
+		b := a
+		for b+1 < len(ws) && soloEspacios(s[ws[b].j:ws[b+1].i]) {
+			sig := ws[b+1]
+			if titulos[strings.ToLower(sig.s)] {
+				break
+			}
+			if capitalizada(sig.s) {
+				b++
+				continue
+			}
+			if conectores[strings.ToLower(sig.s)] {
+				c := b + 1
+				for c+1 < len(ws) && conectores[strings.ToLower(ws[c+1].s)] && soloEspacios(s[ws[c].j:ws[c+1].i]) {
+					c++
+				}
+				if c+1 < len(ws) && capitalizada(ws[c+1].s) && soloEspacios(s[ws[c].j:ws[c+1].i]) {
+					b = c + 1
+					continue
+				}
+			}
+			break
+		}
+		siguiente := b
+
+		// quitar al inicio palabras de frase, conectores y títulos
+		for a <= b && (inicioFrase[strings.ToLower(ws[a].s)] || conectores[strings.ToLower(ws[a].s)] || titulos[strings.ToLower(ws[a].s)]) {
+			a++
+		}
+		if a > b {
+			a = siguiente
+			continue
+		}
+
+		descartar := false
+		caps := 0
+		for _, w := range ws[a : b+1] {
+			if noNombres[strings.ToLower(w.s)] {
+				descartar = true
+			}
+			if capitalizada(w.s) {
+				caps++
+			}
+		}
+
+		previa := ""
+		if a > 0 {
+			previa = strings.ToLower(ws[a-1].s)
+		}
+		conTitulo := titulos[previa] && strings.TrimSpace(strings.Trim(s[ws[a-1].j:ws[a].i], ".")) == ""
+		sujeto := b+1 < len(ws) && marcadoresSujeto[strings.ToLower(ws[b+1].s)] && soloEspacios(s[ws[b].j:ws[b+1].i])
+		if !descartar && (caps >= 2 || conTitulo || aMediaFrase(s, ws[a].i) || sujeto) {
+			out = append(out, [2]int{ws[a].i, ws[b].j})
+		}
+		a = siguiente
+	}
+	return out
+}
+
+// aMediaFrase: antes de la posición i hay texto y no termina en . ! ? ¿ ¡ : ni salto de línea
+func aMediaFrase(s string, i int) bool {
+	antes := strings.TrimRight(s[:i], " \t\"'«(")
+	if antes == "" {
+		return false
+	}
+	r, _ := utf8.DecodeLastRuneInString(antes)
+	return !strings.ContainsRune(".!?¿¡:\n\r", r)
+}
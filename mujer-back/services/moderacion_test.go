@@ -0,0 +1,84 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDetectarPII(t *testing.T) {
+	type h struct{ tipo, texto string }
+	casos := []struct {
+		nombre string
+		in     string
+		want   []h
+	}{
+		// correos
+		{"email", "escríbeme a ana.lopez@gmail.com por favor", []h{{"email", "ana.lopez@gmail.com"}}},
+		{"email mayúsculas", "Correo: Juan_P+1@Empresa.COM.mx", []h{{"email", "Juan_P+1@Empresa.COM.mx"}}},
+
+		// CURP y RFC
+		{"curp", "mi curp es GODE561231HDFRRN09 y ya", []h{{"curp", "GODE561231HDFRRN09"}}},
+		{"rfc con homoclave", "su rfc GODE561231AB1 sale en el recibo", []h{{"rfc", "GODE561231AB1"}}},
+		{"rfc sin homoclave", "rfc gode561231", []h{{"rfc", "gode561231"}}},
+
+		// teléfonos
+		{"celular con espacios", "llámame al 55 1234 5678", []h{{"telefono", "55 1234 5678"}}},
+		{"lada internacional", "su número es +52 (55) 1234-5678.", []h{{"telefono", "+52 (55) 1234-5678"}}},
+		{"local de ocho", "marca al 5555-1234 en la tarde", []h{{"telefono", "5555-1234"}}},
+		{"rango de años", "Desde 2023-2024 pasa esto.", nil},
+		{"rango de años largo", "entre 1999-2001 y ahora", nil},
+		{"folio corto", "el folio 12345 no sirve", nil},
+
+		// fechas
+		{"fecha iso", "el 2024-03-12 pasó otra vez", nil},
+		{"fecha con diagonales", "el 12/03/2024 pasó otra vez", nil},
+		{"fecha con guiones", "el 12-03-2024 pasó otra vez", nil},
+		{"mes con mayúscula", "en Marzo me cambiaron de grupo", nil},
+
+		// nombres
+		{"nombre al inicio de frase", "Juan me pegó en el salón.", []h{{"nombre", "Juan"}}},
+		{"nombre al inicio tras punto", "Ya no aguanto. Pedro siempre grita.", []h{{"nombre", "Pedro"}}},
+		{"nombre a media frase", "le dije a Pedro que parara", []h{{"nombre", "Pedro"}}},
+		{"nombre con título", "La maestra Rosa nos grita.", []h{{"nombre", "Rosa"}}},
+		{"nombre y apellido", "Juan Pérez me molesta", []h{{"nombre", "Juan Pérez"}}},
+		{"nombre compuesto", "fue con María de la Luz García", []h{{"nombre", "María de la Luz García"}}},
+		{"nombre al final", "quien me acosa es Roberto", []h{{"nombre", "Roberto"}}},
+		{"inicio de frase común", "Ayer me gritaron otra vez.", nil},
+		{"institución", "En la Escuela Nacional hay acoso", nil},
+		{"plural", "Profesores me gritan.", nil},
+		{"siglas", "en la UNAM y el IPN pasa", nil},
+		{"sin datos", "Hola, gracias por preguntar.", nil},
+
+		// varios en orden
+		{"varios", "Juan Pérez (juan@x.mx) 5512345678", []h{
+			{"nombre", "Juan Pérez"}, {"email", "juan@x.mx"}, {"telefono", "5512345678"},
+		}},
+	}
+	for _, c := range casos {
+		t.Run(c.nombre, func(t *testing.T) {
+			var got []h
+			for _, x := range DetectarPII(c.in) {
+				got = append(got, h{x.Tipo, x.Texto})
+			}
+			if !reflect.DeepEqual(got, c.want) {
+				t.Errorf("DetectarPII(%q) = %v, want %v", c.in, got, c.want)
+			}
+		})
+	}
+}
+
+func TestRedactarPII(t *testing.T) {
+	casos := []struct {
+		in   string
+		want string
+	}{
+		{"Juan me pegó, su cel es 55 1234 5678.", "[NOMBRE] me pegó, su cel es [TELÉFONO]."},
+		{"la maestra Rosa (rosa@escuela.mx)", "la maestra [NOMBRE] ([CORREO])"},
+		{"Desde 2023-2024 pasa esto.", "Desde 2023-2024 pasa esto."},
+	}
+	for _, c := range casos {
+		if got := RedactarPII(c.in, DetectarPII(c.in)); got != c.want {
+			t.Errorf("RedactarPII(%q) = %q, want %q", c.in, got, c.want)
+		}
+	}
+}
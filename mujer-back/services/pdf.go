@@ -0,0 +1,282 @@
+package services
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// =======================================================
+// Escritor PDF mínimo (PDF 1.4) sin dependencias externas.
+// Solo usa las fuentes estándar Helvetica / Helvetica-Bold con
+// WinAnsiEncoding (acentos, ñ, ¿¡), líneas y rectángulos: suficiente
+// para informes con tablas y gráficas de barras / líneas.
+// Las coordenadas son en puntos desde la esquina SUPERIOR izquierda.
+// =======================================================
+
+// Color RGB con componentes 0..1
+type Color struct {
+	R, G, B float64
+}
+
+// ColorHex convierte "#7F017F" a Color (negro si viene mal)
+func ColorHex(hex string) Color {
+	v, err := strconv.ParseUint(strings.TrimPrefix(hex, "#"), 16, 32)
+	if err != nil {
+		return Color{}
+	}
+	return Color{
+		R: float64(v>>16&0xff) / 255,
+		G: float64(v>>8&0xff) / 255,
+		B: float64(v&0xff) / 255,
+	}
+}
+
+func (c Color) op(stroke bool) string {
+	if stroke {
+		return fmt.Sprintf("%.3f %.3f %.3f RG", c.R, c.G, c.B)
+	}
+	return fmt.Sprintf("%.3f %.3f %.3f rg", c.R, c.G, c.B)
+}
+
+// PDF acumula páginas; cada página es un content stream
+type PDF struct {
+	W, H   float64 // tamaño de página en puntos (carta: 612 × 792)
+	Titulo string  // /Title del documento
+
+	pages []*bytes.Buffer
+	cur   *bytes.Buffer
+}
+
+// NuevoPDF crea un documento tamaño carta
+func NuevoPDF(titulo string) *PDF {
+	return &PDF{W: 612, H: 792, Titulo: titulo}
+}
+
+// AgregarPagina abre una página nueva y la deja como actual
+func (p *PDF) AgregarPagina() {
+	p.cur = &bytes.Buffer{}
+	p.pages = append(p.pages, p.cur)
+}
+
+// Paginas regresa cuántas páginas lleva el documento
+func (p *PDF) Paginas() int {
+	return len(p.pages)
+}
+
+// IrAPagina cambia la página actual (ej. para dibujar pies de página al final)
+func (p *PDF) IrAPagina(i int) {
+	p.cur = p.pages[i]
+}
+
+// Texto dibuja s con la línea base en (x, y)
+func (p *PDF) Texto(x, y, size float64, bold bool, c Color, s string) {
+	font := "F1"
+	if bold {
+		font = "F2"
+	}
+	fmt.Fprintf(p.cur, "BT /%s %.2f Tf %s %.2f %.2f Td (%s) Tj ET\n", font, size, c.op(false), x, p.H-y, pdfCadena(s))
+}
+
+// Linea dibuja un segmento de (x1, y1) a (x2, y2)
+func (p *PDF) Linea(x1, y1, x2, y2, grosor float64, c Color) {
+	fmt.Fprintf(p.cur, "%s %.2f w %.2f %.2f m %.2f %.2f l S\n", c.op(true), grosor, x1, p.H-y1, x2, p.H-y2)
+}
+
+// Rect rellena un rectángulo con esquina superior izquierda en (x, y)
+func (p *PDF) Rect(x, y, w, h float64, c Color) {
+	fmt.Fprintf(p.cur, "%s %.2f %.2f %.2f %.2f re f\n", c.op(false), x, p.H-y-h, w, h)
+}
+
+// Marco dibuja el contorno de un rectángulo
+func (p *PDF) Marco(x, y, w, h, grosor float64, c Color) {
+	fmt.Fprintf(p.cur, "%s %.2f w %.2f %.2f %.2f %.2f re S\n", c.op(true), grosor, x, p.H-y-h, w, h)
+}
+
+// AnchoTexto mide s en puntos con las métricas de Helvetica
+func (p *PDF) AnchoTexto(s string, size float64, bold bool) float64 {
+	tabla := &anchoHelvetica
+	if bold {
+		tabla = &anchoHelveticaBold
+	}
+	var total int
+	for _, b := range winAnsi(s) {
+		total += anchoByte(tabla, b)
+	}
+	return float64(total) * size / 1000
+}
+
+// Escribir serializa el documento (objetos, xref y trailer)
+func (p *PDF) Escribir(w io.Writer) error {
+	if len(p.pages) == 0 {
+		p.AgregarPagina()
+	}
+
+	var out bytes.Buffer
+	offsets := []int{0} // objeto 0 es el libre
+	obj := func(body string) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", len(offsets)-1, body)
+	}
+
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	// 1 catálogo, 2 árbol de páginas, 3-4 fuentes, 5 info; luego página + contenido
+	const primeraPagina = 6
+	kids := make([]string, len(p.pages))
+	for i := range p.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", primeraPagina+2*i)
+	}
+
+	obj("<< /Type /Catalog /Pages 2 0 R >>")
+	obj(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(p.pages)))
+	obj("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	obj("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	obj(fmt.Sprintf("<< /Title (%s) /Producer (mujer-back) >>", pdfCadena(p.Titulo)))
+
+	for i, page := range p.pages {
+		var z bytes.Buffer
+		zw := zlib.NewWriter(&z)
+		if _, err := zw.Write(page.Bytes()); err != nil {
+			return err
+		}
+		if err := zw.Close(); err != nil {
+			return err
+		}
+
+		obj(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.0f %.0f] "+
+			"/Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+			p.W, p.H, primeraPagina+2*i+1))
+
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n<< /Length %d /Filter /FlateDecode >>\nstream\n", len(offsets)-1, z.Len())
+		out.Write(z.Bytes())
+		out.WriteString("\nendstream\nendobj\n")
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets))
+	for _, off := range offsets[1:] {
+		fmt.Fprintf(&out, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R /Info 5 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets), xref)
+
+	_, err := out.WriteTo(w)
+	return err
+}
+
+// pdfCadena convierte a WinAnsi y escapa para un literal (...)
+func pdfCadena(s string) string {
+	var b strings.Builder
+	for _, c := range winAnsi(s) {
+		switch c {
+		case '(', ')', '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		default:
+			if c < 32 {
+				b.WriteByte(' ')
+				continue
+			}
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// winAnsi pasa UTF-8 a Windows-1252. Los símbolos comunes que no existen
+// ahí se transliteran (√ → raíz, − → -, ≥ → >=); lo demás queda como '?'
+func winAnsi(s string) []byte {
+	out := make([]byte, 0, len(s))
+	for _, r := range s {
+		switch {
+		case r < 0x80:
+			out = append(out, byte(r))
+		case r >= 0xA0 && r <= 0xFF:
+			out = append(out, byte(r))
+		default:
+			if b, ok := cp1252Extra[r]; ok {
+				out = append(out, b)
+			} else if t, ok := transliteracion[r]; ok {
+				out = append(out, winAnsi(t)...)
+			} else {
+				out = append(out, '?')
+			}
+		}
+	}
+	return out
+}
+
+// caracteres de Windows-1252 fuera de Latin-1 (0x80–0x9F)
+var cp1252Extra = map[rune]byte{
+	'€': 0x80, '‚': 0x82, 'ƒ': 0x83, '„': 0x84, '…': 0x85, '†': 0x86, '‡': 0x87,
+	'ˆ': 0x88, '‰': 0x89, 'Š': 0x8A, '‹': 0x8B, 'Œ': 0x8C, 'Ž': 0x8E,
+	'‘': 0x91, '’': 0x92, '“': 0x93, '”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97,
+	'˜': 0x98, '™': 0x99, 'š': 0x9A, '›': 0x9B, 'œ': 0x9C, 'ž': 0x9E, 'Ÿ': 0x9F,
+}
+
+// equivalentes en WinAnsi de símbolos que aparecen en notas del instrumento,
+// comentarios y nombres de centros
+var transliteracion = map[rune]string{
+	'√': "raíz", '−': "-", '‐': "-", '‑': "-", '‒': "-", '―': "—",
+	'≥': ">=", '≤': "<=", '≠': "!=", '≈': "~", '∞': "inf",
+	'′': "'", '″': "\"", '→': "->", '←': "<-", '⇒': "=>",
+	'∑': "suma", 'Σ': "suma", 'σ': "sigma", 'α': "alfa", 'μ': "mu",
+	'\u2009': " ", '\u200a': " ", '\u202f': " ", '\u2007': " ", '\u2002': " ", '\u2003': " ",
+	'\u200b': "", '\ufeff': "",
+}
+
+// anchoByte busca el ancho (milésimas de em) de un byte WinAnsi
+func anchoByte(tabla *[95]int, b byte) int {
+	switch {
+	case b >= 32 && b <= 126:
+		return tabla[b-32]
+	case b >= 0xC0:
+		// letras acentuadas: mismo ancho que la letra base
+		if base, ok := baseLatin1[b]; ok {
+			return tabla[base-32]
+		}
+		return 556
+	case b == 0x85:
+		return 1000 // …
+	case b == 0x96, b == 0x95:
+		return 556
+	case b == 0x97:
+		return 1000
+	default:
+		return 333 // ¡ ¿ ° comillas y demás signos: aproximado
+	}
+}
+
+var baseLatin1 = map[byte]byte{
+	0xC0: 'A', 0xC1: 'A', 0xC2: 'A', 0xC3: 'A', 0xC4: 'A', 0xC7: 'C',
+	0xC8: 'E', 0xC9: 'E', 0xCA: 'E', 0xCB: 'E', 0xCC: 'I', 0xCD: 'I', 0xCE: 'I', 0xCF: 'I',
+	0xD1: 'N', 0xD2: 'O', 0xD3: 'O', 0xD4: 'O', 0xD5: 'O', 0xD6: 'O', 0xD7: '+',
+	0xD9: 'U', 0xDA: 'U', 0xDB: 'U', 0xDC: 'U',
+	0xE0: 'a', 0xE1: 'a', 0xE2: 'a', 0xE3: 'a', 0xE4: 'a', 0xE7: 'c',
+	0xE8: 'e', 0xE9: 'e', 0xEA: 'e', 0xEB: 'e', 0xEC: 'i', 0xED: 'i', 0xEE: 'i', 0xEF: 'i',
+	0xF1: 'n', 0xF2: 'o', 0xF3: 'o', 0xF4: 'o', 0xF5: 'o', 0xF6: 'o', 0xF7: '+',
+	0xF9: 'u', 0xFA: 'u', 0xFB: 'u', 0xFC: 'u',
+}
+
+// anchos AFM de Helvetica y Helvetica-Bold para los caracteres 32..126
+var anchoHelvetica = [95]int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+}
+
+var anchoHelveticaBold = [95]int{
+	278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 333, 333, 584, 584, 584, 611,
+	975, 722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722, 611, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 333, 278, 333, 584, 556,
+	333, 556, 611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889, 611, 611,
+	611, 611, 389, 556, 333, 611, 556, 778, 556, 556, 500, 389, 280, 389, 584,
+}
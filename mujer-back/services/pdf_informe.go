@@ -0,0 +1,323 @@
+package services
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// =======================================================
+// Maquetación de informes sobre PDF: cursor vertical con salto de
+// página automático, títulos, párrafos, tablas y gráficas simples.
+// =======================================================
+
+var (
+	ColorTexto   = ColorHex("#0F172A")
+	ColorSuave   = ColorHex("#64748B")
+	ColorLinea   = ColorHex("#CBD5E1")
+	ColorFondo   = ColorHex("#F1F5F9")
+	ColorMarca   = ColorHex("#7F017F")
+	ColorBlanco  = Color{R: 1, G: 1, B: 1}
+	ColoresSerie = []Color{ColorHex("#2563EB"), ColorHex("#EAB308"), ColorHex("#DC2626"), ColorHex("#7F017F"), ColorHex("#059669")}
+)
+
+// Informe es un PDF con cursor (Y desde arriba) y márgenes
+type Informe struct {
+	*PDF
+	Margen     float64
+	Y          float64
+	Encabezado string // texto que se repite arriba de cada página
+}
+
+// NuevoInforme crea el documento con la primera página abierta
+func NuevoInforme(titulo, encabezado string) *Informe {
+	in := &Informe{PDF: NuevoPDF(titulo), Margen: 50, Encabezado: encabezado}
+	in.nuevaPagina()
+	return in
+}
+
+// Ancho útil entre márgenes
+func (in *Informe) Ancho() float64 {
+	return in.W - 2*in.Margen
+}
+
+func (in *Informe) nuevaPagina() {
+	in.AgregarPagina()
+	in.Y = in.Margen
+	if in.Encabezado != "" {
+		in.Texto(in.Margen, in.Margen-18, 8, false, ColorSuave, in.Encabezado)
+		in.Linea(in.Margen, in.Margen-12, in.W-in.Margen, in.Margen-12, 0.5, ColorLinea)
+	}
+}
+
+// Espacio asegura h puntos libres en la página (si no, salta a la siguiente)
+func (in *Informe) Espacio(h float64) {
+	if in.Y+h > in.H-in.Margen-20 {
+		in.nuevaPagina()
+	}
+}
+
+// SaltoPagina fuerza una página nueva
+func (in *Informe) SaltoPagina() {
+	in.nuevaPagina()
+}
+
+// Titulo escribe un título de sección
+func (in *Informe) Titulo(s string, size float64) {
+	in.Espacio(size*2 + 20)
+	in.Y += size
+	in.Texto(in.Margen, in.Y, size, true, ColorMarca, s)
+	in.Y += size * 0.6
+}
+
+// Parrafo escribe texto con ajuste de línea
+func (in *Informe) Parrafo(s string, size float64, c Color) {
+	for _, linea := range in.Ajustar(s, size, false, in.Ancho()) {
+		in.Espacio(size * 1.4)
+		in.Y += size * 1.4
+		in.Texto(in.Margen, in.Y, size, false, c, linea)
+	}
+	in.Y += size * 0.6
+}
+
+// Vineta escribe un párrafo con viñeta
+func (in *Informe) Vineta(s string, size float64) {
+	lineas := in.Ajustar(s, size, false, in.Ancho()-12)
+	for i, linea := range lineas {
+		in.Espacio(size * 1.4)
+		in.Y += size * 1.4
+		if i == 0 {
+			in.Texto(in.Margen, in.Y, size, false, ColorMarca, "•")
+		}
+		in.Texto(in.Margen+12, in.Y, size, false, ColorTexto, linea)
+	}
+	in.Y += size * 0.3
+}
+
+// Ajustar parte s en líneas de a lo más ancho puntos (respeta saltos \n)
+func (in *Informe) Ajustar(s string, size float64, bold bool, ancho float64) []string {
+	var out []string
+	for _, parrafo := range strings.Split(s, "\n") {
+		linea := ""
+		for _, palabra := range strings.Fields(parrafo) {
+			prueba := palabra
+			if linea != "" {
+				prueba = linea + " " + palabra
+			}
+			if linea != "" && in.AnchoTexto(prueba, size, bold) > ancho {
+				out = append(out, linea)
+				linea = palabra
+				continue
+			}
+			linea = prueba
+		}
+		out = append(out, linea)
+	}
+	return out
+}
+
+// recortar acorta s con "…" para que quepa en ancho
+func (in *Informe) recortar(s string, size float64, bold bool, ancho float64) string {
+	if in.AnchoTexto(s, size, bold) <= ancho {
+		return s
+	}
+	rs := []rune(s)
+	for len(rs) > 0 && in.AnchoTexto(string(rs)+"…", size, bold) > ancho {
+		rs = rs[:len(rs)-1]
+	}
+	return string(rs) + "…"
+}
+
+// Tabla dibuja una tabla con encabezado; anchos son proporciones del ancho
+// útil. relleno (opcional) da color de fondo por celda (ej. mapa de calor).
+func (in *Informe) Tabla(cols []string, anchos []float64, filas [][]string, relleno func(fila, col int) (Color, bool)) {
+	const size = 8.5
+	const pad = 4.0
+
+	var suma float64
+	for _, a := range anchos {
+		suma += a
+	}
+	ws := make([]float64, len(anchos))
+	for i, a := range anchos {
+		ws[i] = in.Ancho() * a / suma
+	}
+
+	encabezado := func() {
+		alto := 0.0
+		celdas := make([][]string, len(cols))
+		for i, c := range cols {
+			celdas[i] = in.Ajustar(c, size, true, ws[i]-2*pad)
+			alto = math.Max(alto, float64(len(celdas[i]))*size*1.25+2*pad)
+		}
+		in.Espacio(alto + size*2)
+		in.Rect(in.Margen, in.Y, in.Ancho(), alto, ColorMarca)
+		x := in.Margen
+		for i := range cols {
+			for j, l := range celdas[i] {
+				in.Texto(x+pad, in.Y+pad+size+float64(j)*size*1.25, size, true, ColorBlanco, l)
+			}
+			x += ws[i]
+		}
+		in.Y += alto
+	}
+	encabezado()
+
+	for fi, fila := range filas {
+		alto := 0.0
+		celdas := make([][]string, len(fila))
+		for i, c := range fila {
+			if i >= len(ws) {
+				break
+			}
+			celdas[i] = in.Ajustar(c, size, false, ws[i]-2*pad)
+			alto = math.Max(alto, float64(len(celdas[i]))*size*1.25+2*pad)
+		}
+		if in.Y+alto > in.H-in.Margen-20 {
+			in.nuevaPagina()
+			encabezado()
+		}
+		if fi%2 == 1 {
+			in.Rect(in.Margen, in.Y, in.Ancho(), alto, ColorFondo)
+		}
+		x := in.Margen
+		for i := range celdas {
+			if relleno != nil {
+				if c, ok := relleno(fi, i); ok {
+					in.Rect(x, in.Y, ws[i], alto, c)
+				}
+			}
+			for j, l := range celdas[i] {
+				in.Texto(x+pad, in.Y+pad+size+float64(j)*size*1.25, size, false, ColorTexto, l)
+			}
+			x += ws[i]
+		}
+		in.Y += alto
+		in.Linea(in.Margen, in.Y, in.Margen+in.Ancho(), in.Y, 0.3, ColorLinea)
+	}
+	in.Y += 10
+}
+
+// Serie es una serie de valores para gráficas (NaN = sin dato)
+type Serie struct {
+	Nombre  string
+	Valores []float64
+}
+
+// ejes dibuja la cuadrícula horizontal de 0..max y regresa la función valor → y
+func (in *Informe) ejes(x, y, w, h, max float64, paso float64) func(float64) float64 {
+	aY := func(v float64) float64 { return y + h - (v/max)*h }
+	for v := 0.0; v <= max+1e-9; v += paso {
+		yy := aY(v)
+		in.Linea(x, yy, x+w, yy, 0.3, ColorLinea)
+		in.Texto(x-18, yy+3, 7, false, ColorSuave, strconv.FormatFloat(v, 'f', -1, 64))
+	}
+	return aY
+}
+
+// leyenda dibuja los nombres de las series con su color
+func (in *Informe) leyenda(series []Serie) {
+	x := in.Margen + 20
+	in.Y += 14
+	for i, s := range series {
+		c := ColoresSerie[i%len(ColoresSerie)]
+		in.Rect(x, in.Y-7, 8, 8, c)
+		in.Texto(x+12, in.Y, 8, false, ColorTexto, s.Nombre)
+		x += 24 + in.AnchoTexto(s.Nombre, 8, false)
+	}
+	in.Y += 10
+}
+
+// Barras dibuja barras agrupadas: una categoría por grupo, una barra por serie
+func (in *Informe) Barras(grupos []string, series []Serie, max float64) {
+	const alto = 170.0
+	in.Espacio(alto + 60)
+
+	x0 := in.Margen + 20
+	w := in.Ancho() - 20
+	top := in.Y + 10
+	aY := in.ejes(x0, top, w, alto, max, 1)
+
+	if len(grupos) > 0 && len(series) > 0 {
+		anchoGrupo := w / float64(len(grupos))
+		anchoBarra := math.Min(28, (anchoGrupo-12)/float64(len(series)))
+		for gi, g := range grupos {
+			gx := x0 + float64(gi)*anchoGrupo + (anchoGrupo-anchoBarra*float64(len(series)))/2
+			for si, s := range series {
+				if gi >= len(s.Valores) || math.IsNaN(s.Valores[gi]) {
+					continue
+				}
+				v := math.Min(s.Valores[gi], max)
+				bx := gx + float64(si)*anchoBarra
+				in.Rect(bx+1, aY(v), anchoBarra-2, top+alto-aY(v), ColoresSerie[si%len(ColoresSerie)])
+				if anchoBarra >= 16 {
+					in.Texto(bx+1, aY(v)-3, 6.5, false, ColorTexto, strconv.FormatFloat(s.Valores[gi], 'f', 2, 64))
+				}
+			}
+			etiqueta := in.recortar(g, 7.5, false, anchoGrupo-4)
+			in.Texto(x0+float64(gi)*anchoGrupo+(anchoGrupo-in.AnchoTexto(etiqueta, 7.5, false))/2, top+alto+12, 7.5, false, ColorTexto, etiqueta)
+		}
+	}
+	in.Linea(x0, top+alto, x0+w, top+alto, 0.8, ColorSuave)
+
+	in.Y = top + alto + 18
+	if len(series) > 1 {
+		in.leyenda(series)
+	}
+	in.Y += 8
+}
+
+// Lineas dibuja una serie por línea sobre categorías en el eje x (ej. años)
+func (in *Informe) Lineas(xs []string, series []Serie, max float64) {
+	const alto = 170.0
+	in.Espacio(alto + 60)
+
+	x0 := in.Margen + 20
+	w := in.Ancho() - 20
+	top := in.Y + 10
+	aY := in.ejes(x0, top, w, alto, max, 1)
+
+	if len(xs) > 0 {
+		paso := w / float64(len(xs))
+		aX := func(i int) float64 { return x0 + paso*(float64(i)+0.5) }
+
+		for i, x := range xs {
+			in.Texto(aX(i)-in.AnchoTexto(x, 7.5, false)/2, top+alto+12, 7.5, false, ColorTexto, x)
+		}
+		for si, s := range series {
+			c := ColoresSerie[si%len(ColoresSerie)]
+			prev := -1
+			for i, v := range s.Valores {
+				if math.IsNaN(v) {
+					prev = -1
+					continue
+				}
+				if prev >= 0 {
+					in.Linea(aX(prev), aY(s.Valores[prev]), aX(i), aY(v), 1.5, c)
+				}
+				in.Rect(aX(i)-2, aY(v)-2, 4, 4, c)
+				prev = i
+			}
+		}
+	}
+	in.Linea(x0, top+alto, x0+w, top+alto, 0.8, ColorSuave)
+
+	in.Y = top + alto + 18
+	in.leyenda(series)
+	in.Y += 8
+}
+
+// Cerrar dibuja el pie "Página i de n" en todas las páginas
+func (in *Informe) Cerrar(pie string) {
+	n := in.Paginas()
+	for i := 0; i < n; i++ {
+		in.IrAPagina(i)
+		y := in.H - in.Margen + 20
+		in.Linea(in.Margen, y-10, in.W-in.Margen, y-10, 0.5, ColorLinea)
+		if pie != "" {
+			in.Texto(in.Margen, y, 7.5, false, ColorSuave, pie)
+		}
+		num := "Página " + strconv.Itoa(i+1) + " de " + strconv.Itoa(n)
+		in.Texto(in.W-in.Margen-in.AnchoTexto(num, 7.5, false), y, 7.5, false, ColorSuave, num)
+	}
+}
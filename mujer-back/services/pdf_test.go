@@ -0,0 +1,45 @@
+package services
+
+import "testing"
+
+func TestWinAnsi(t *testing.T) {
+	casos := []struct {
+		nombre string
+		in     string
+		want   string
+	}{
+		{"ascii", "Centro 12 (norte)", "Centro 12 (norte)"},
+		{"latin1", "Señora Núñez, ¿sí?", "Se\xf1ora N\xfa\xf1ez, \xbfs\xed?"},
+		{"cp1252", "“hola” – … €", "\x93hola\x94 \x96 \x85 \x80"},
+		{"raíz", "100 × √(F' × N')", "100 \xd7 ra\xedz(F' \xd7 N')"},
+		{"menos", "(x − 1) / 4", "(x - 1) / 4"},
+		{"comparaciones", "G ≥ 3 y F ≤ 2, N ≠ 1", "G >= 3 y F <= 2, N != 1"},
+		{"prima", "F′ → N″", "F' -> N\""},
+		{"espacios", "10\u202f000\u200b", "10 000"},
+		{"sin equivalente", "gracias 🙏", "gracias ?"},
+		{"cjk", "中", "?"},
+	}
+	for _, c := range casos {
+		t.Run(c.nombre, func(t *testing.T) {
+			if got := string(winAnsi(c.in)); got != c.want {
+				t.Errorf("winAnsi(%q) = %q, want %q", c.in, got, c.want)
+			}
+		})
+	}
+}
+
+func TestPdfCadena(t *testing.T) {
+	casos := []struct {
+		in   string
+		want string
+	}{
+		{`a(b)\c`, `a\(b\)\\c`},
+		{"línea\nnueva", "l\xednea nueva"},
+		{"√(x)", `ra` + "\xed" + `z\(x\)`},
+	}
+	for _, c := range casos {
+		if got := pdfCadena(c.in); got != c.want {
+			t.Errorf("pdfCadena(%q) = %q, want %q", c.in, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,355 @@
+package services
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"mujer-back/stats"
+)
+
+// =======================================================
+// Análisis psicométrico del instrumento (solo admin)
+// Sobre todas las encuestas finalizadas de una versión del instrumento:
+// por tarjeta (pregunta × dimensión) media, varianza y efectos piso/techo;
+// por escala (cada dimensión y cada tipo de violencia dentro de cada
+// dimensión) alpha con su IC, correlación ítem-total corregida, alpha si se
+// elimina y la matriz de correlaciones entre ítems.
+// Todo sale de las tablas agg_* (histogramas y sumas por par de
+// agg_items_cruzados), igual que la estadística avanzada de los centros.
+// Las escalas usan solo las encuestas que respondieron todas las tarjetas
+// de la dimensión; como agg_items_cruzados solo cruza ítems de una misma
+// dimensión, los tipos son subescalas por dimensión.
+// =======================================================
+
+// UmbralPisoTecho es la proporción de respuestas en el mínimo (o máximo) de
+// la escala a partir de la cual se marca efecto piso (o techo); 15% es el
+// criterio habitual (Terwee et al., 2007)
+const UmbralPisoTecho = 0.15
+
+// ItemPsicometrico es una tarjeta del instrumento (ej. P3 gravedad)
+type ItemPsicometrico struct {
+	PreguntaID string `json:"pregunta_id"`
+	Dimension  string `json:"dimension"`
+	TipoNum    int    `json:"tipo_num"`
+	TipoNombre string `json:"tipo_nombre"`
+
+	N        int64   `json:"n"` // respuestas de la tarjeta
+	Promedio float64 `json:"promedio"`
+	Varianza float64 `json:"varianza"`
+
+	// proporción de respuestas en el mínimo / máximo de la escala
+	EscalaMin   int     `json:"escala_min"`
+	EscalaMax   int     `json:"escala_max"`
+	Piso        float64 `json:"piso"`
+	Techo       float64 `json:"techo"`
+	EfectoPiso  bool    `json:"efecto_piso"`
+	EfectoTecho bool    `json:"efecto_techo"`
+
+	// dentro de su dimensión (nil = indefinido)
+	CorrelacionItemTotal *float64 `json:"correlacion_item_total"`
+	AlphaSiSeElimina     *float64 `json:"alpha_si_se_elimina"`
+}
+
+// ItemEscala es un ítem dentro de una escala
+type ItemEscala struct {
+	PreguntaID           string   `json:"pregunta_id"`
+	Dimension            string   `json:"dimension"`
+	CorrelacionItemTotal *float64 `json:"correlacion_item_total"`
+	AlphaSiSeElimina     *float64 `json:"alpha_si_se_elimina"`
+}
+
+// EscalaPsicometrica es una dimensión o un tipo de violencia como escala
+type EscalaPsicometrica struct {
+	Clave  string `json:"clave"` // key de la dimensión o "tipo|dimensión"
+	Nombre string `json:"nombre"`
+
+	NEncuestasCompletas int64 `json:"n_encuestas_completas"`
+
+	Alpha             *float64 `json:"alpha"`
+	AlphaIC95Inferior *float64 `json:"alpha_ic95_inferior"`
+	AlphaIC95Superior *float64 `json:"alpha_ic95_superior"`
+
+	// promedio de las correlaciones entre pares de ítems
+	CorrelacionMedia *float64 `json:"correlacion_media"`
+
+	Items []ItemEscala `json:"items"`
+
+	// matriz de correlaciones en el orden de Items (nil = indefinida)
+	Correlaciones [][]*float64 `json:"correlaciones"`
+}
+
+// AnalisisPsicometrico es la respuesta de /api/admin/psicometria
+type AnalisisPsicometrico struct {
+	InstrumentoID string `json:"instrumento_id"`
+	Version       string `json:"version"`
+	NEncuestas    int64  `json:"n_encuestas"` // finalizadas con al menos una respuesta
+
+	Items       []ItemPsicometrico   `json:"items"`
+	Dimensiones []EscalaPsicometrica `json:"dimensiones"`
+	Tipos       []EscalaPsicometrica `json:"tipos"`
+
+	// menos de k encuestas: solo se reporta n_encuestas
+	Suprimido bool `json:"suprimido,omitempty"`
+	K         int  `json:"k"`
+}
+
+// datosPsicometria son los agregados de un instrumento que usa el análisis
+type datosPsicometria struct {
+	encuestas int64                        // finalizadas con al menos una respuesta
+	valores   map[string]*stats.Histograma // "P3|gravedad" -> valores
+	porNItems map[string]map[int]int64     // dimensión -> n_items -> encuestas
+	cruzados  map[string][]cruzadoItems    // dimensión -> pares de ítems
+}
+
+// AnalizarInstrumento calcula el análisis psicométrico de una versión del
+// instrumento. Regresa ErrSinDatos si no hay encuestas con respuestas.
+func AnalizarInstrumento(ctx context.Context, pool *pgxpool.Pool, inst Instrumento, k int) (AnalisisPsicometrico, error) {
+	d := datosPsicometria{
+		valores:   map[string]*stats.Histograma{},
+		porNItems: map[string]map[int]int64{},
+		cruzados:  map[string][]cruzadoItems{},
+	}
+
+	batch := &pgx.Batch{}
+	batch.Queue(`
+		select coalesce(sum(e.encuestas), 0)::bigint
+		from agg_encuestas e
+		where e.instrumento_id = $1
+	`, inst.ID).QueryRow(func(row pgx.Row) error {
+		return etapa("psicometria_encuestas", row.Scan(&d.encuestas))
+	})
+	batch.Queue(`
+		select e.pregunta_id, e.dimension, e.valor::float8, sum(e.n)::bigint
+		from agg_valores e
+		where e.instrumento_id = $1
+		group by 1, 2, 3
+	`, inst.ID).Query(func(rows pgx.Rows) error {
+		for rows.Next() {
+			var pid, dim string
+			var v float64
+			var n int64
+			if err := rows.Scan(&pid, &dim, &v, &n); err != nil {
+				return etapa("psicometria_valores", err)
+			}
+			h := d.valores[pid+"|"+dim]
+			if h == nil {
+				h = &stats.Histograma{}
+				d.valores[pid+"|"+dim] = h
+			}
+			h.Add(v, n)
+		}
+		return etapa("psicometria_valores", rows.Err())
+	})
+	batch.Queue(`
+		select e.dimension, e.n_items, sum(e.n)::bigint
+		from agg_encuestas_dim e
+		where e.instrumento_id = $1
+		group by 1, 2
+	`, inst.ID).Query(func(rows pgx.Rows) error {
+		for rows.Next() {
+			var dim string
+			var nItems int
+			var n int64
+			if err := rows.Scan(&dim, &nItems, &n); err != nil {
+				return etapa("psicometria_encuestas_dim", err)
+			}
+			if d.porNItems[dim] == nil {
+				d.porNItems[dim] = map[int]int64{}
+			}
+			d.porNItems[dim][nItems] += n
+		}
+		return etapa("psicometria_encuestas_dim", rows.Err())
+	})
+	batch.Queue(`
+		select e.dimension, e.n_items, e.pregunta_a, e.pregunta_b, sum(e.suma_a)::float8, sum(e.suma_ab)::float8
+		from agg_items_cruzados e
+		where e.instrumento_id = $1
+		group by 1, 2, 3, 4
+	`, inst.ID).Query(func(rows pgx.Rows) error {
+		for rows.Next() {
+			var dim string
+			var c cruzadoItems
+			if err := rows.Scan(&dim, &c.nItems, &c.a, &c.b, &c.sumaA, &c.sumaAB); err != nil {
+				return etapa("psicometria_items", err)
+			}
+			d.cruzados[dim] = append(d.cruzados[dim], c)
+		}
+		return etapa("psicometria_items", rows.Err())
+	})
+
+	if err := enviarBatch(ctx, pool, batch); err != nil {
+		return AnalisisPsicometrico{InstrumentoID: inst.ID, Version: inst.Version}, err
+	}
+	return analizarPsicometria(inst, k, d)
+}
+
+// analizarPsicometria arma el análisis a partir de los agregados
+func analizarPsicometria(inst Instrumento, k int, d datosPsicometria) (AnalisisPsicometrico, error) {
+	if k <= 0 {
+		k = AnonimatoKDefault
+	}
+	out := AnalisisPsicometrico{InstrumentoID: inst.ID, Version: inst.Version, K: k}
+
+	out.NEncuestas = d.encuestas
+	if out.NEncuestas == 0 {
+		return out, ErrSinDatos
+	}
+	if out.NEncuestas < int64(k) {
+		out.Suprimido = true
+		return out, nil
+	}
+
+	// tarjetas en el orden del instrumento
+	var tarjetas []CardRef
+	for _, t := range inst.TypesOfViolence {
+		for _, q := range t.Questions {
+			for _, c := range q.Cards {
+				if ref, ok := inst.CardFor(q.QuestionID, c.Dimension); ok {
+					tarjetas = append(tarjetas, ref)
+				}
+			}
+		}
+	}
+	llave := func(c CardRef) string { return c.PreguntaID + "|" + c.Card.Dimension }
+
+	// ==========================
+	// ESCALAS (dimensiones y tipos dentro de cada dimensión)
+	// ==========================
+	escala := func(clave, nombre, dim string, preguntas []string, sumas stats.SumasItems) EscalaPsicometrica {
+		e := EscalaPsicometrica{Clave: clave, Nombre: nombre, NEncuestasCompletas: sumas.N}
+
+		if alpha, ok := sumas.Alpha(); ok {
+			e.Alpha = finito(alpha)
+			if lo, hi, ok := stats.IntervaloAlphaFeldt(alpha, int(sumas.N), len(preguntas), ConfianzaIC); ok {
+				e.AlphaIC95Inferior, e.AlphaIC95Superior = finito(lo), finito(hi)
+			}
+		}
+
+		var analisis []stats.Item
+		if sumas.N >= 2 {
+			analisis = sumas.AnalisisItems()
+		}
+		for j, pid := range preguntas {
+			it := ItemEscala{PreguntaID: pid, Dimension: dim}
+			if analisis != nil {
+				it.CorrelacionItemTotal = finito(analisis[j].CorrelacionItemTotal)
+				it.AlphaSiSeElimina = finito(analisis[j].AlphaSiSeElimina)
+			}
+			e.Items = append(e.Items, it)
+		}
+
+		if sumas.N >= 2 {
+			var suma float64
+			var pares int
+			for i, fila := range sumas.Correlaciones() {
+				celdas := make([]*float64, len(fila))
+				for j, r := range fila {
+					celdas[j] = finito(r)
+					if j > i && celdas[j] != nil {
+						suma += r
+						pares++
+					}
+				}
+				e.Correlaciones = append(e.Correlaciones, celdas)
+			}
+			if pares > 0 {
+				e.CorrelacionMedia = finito(suma / float64(pares))
+			}
+		}
+		return e
+	}
+
+	// ítem-total y alpha si se elimina de cada tarjeta dentro de su dimensión
+	type dimEscala struct {
+		cards []CardRef
+		sumas stats.SumasItems
+	}
+	porDim := map[string]dimEscala{}
+	enDimension := map[string]ItemEscala{}
+	for _, dim := range inst.Dimensions {
+		var cards []CardRef
+		var preguntas []string
+		for _, c := range tarjetas {
+			if c.Card.Dimension == dim.Key {
+				cards = append(cards, c)
+				preguntas = append(preguntas, c.PreguntaID)
+			}
+		}
+		if len(cards) == 0 {
+			continue
+		}
+		sumas := sumasCompletas(preguntas, d.porNItems[dim.Key], d.cruzados[dim.Key])
+		porDim[dim.Key] = dimEscala{cards: cards, sumas: sumas}
+
+		e := escala(dim.Key, dim.Label, dim.Key, preguntas, sumas)
+		for _, it := range e.Items {
+			enDimension[it.PreguntaID+"|"+it.Dimension] = it
+		}
+		out.Dimensiones = append(out.Dimensiones, e)
+	}
+
+	// tipos: las columnas de cada tipo dentro de cada dimensión
+	for _, t := range inst.TypesOfViolence {
+		for _, dim := range inst.Dimensions {
+			de, ok := porDim[dim.Key]
+			if !ok {
+				continue
+			}
+			var cols []int
+			var preguntas []string
+			for j, c := range de.cards {
+				if c.TipoNum == t.Order {
+					cols = append(cols, j)
+					preguntas = append(preguntas, c.PreguntaID)
+				}
+			}
+			if len(cols) == 0 {
+				continue
+			}
+			clave := strconv.Itoa(t.Order) + "|" + dim.Key
+			out.Tipos = append(out.Tipos, escala(clave, t.Label+" · "+dim.Label, dim.Key, preguntas, de.sumas.Sub(cols)))
+		}
+	}
+
+	// ==========================
+	// ÍTEMS (todas las respuestas de cada tarjeta, no solo casos completos)
+	// ==========================
+	for _, c := range tarjetas {
+		it := ItemPsicometrico{
+			PreguntaID: c.PreguntaID,
+			Dimension:  c.Card.Dimension,
+			TipoNum:    c.TipoNum,
+			TipoNombre: c.TipoNombre,
+			EscalaMin:  c.Scale.Min,
+			EscalaMax:  c.Scale.Max,
+		}
+		if h := d.valores[llave(c)]; h != nil && h.N() > 0 {
+			var piso, techo int64
+			for _, b := range h.Bins {
+				if b.V <= float64(c.Scale.Min) {
+					piso += b.N
+				}
+				if b.V >= float64(c.Scale.Max) {
+					techo += b.N
+				}
+			}
+			it.N = h.N()
+			it.Promedio = h.Media()
+			it.Varianza, _ = h.VarSamp()
+			it.Piso = float64(piso) / float64(it.N)
+			it.Techo = float64(techo) / float64(it.N)
+			it.EfectoPiso = it.Piso >= UmbralPisoTecho
+			it.EfectoTecho = it.Techo >= UmbralPisoTecho
+		}
+		if en, ok := enDimension[llave(c)]; ok {
+			it.CorrelacionItemTotal = en.CorrelacionItemTotal
+			it.AlphaSiSeElimina = en.AlphaSiSeElimina
+		}
+		out.Items = append(out.Items, it)
+	}
+
+	return out, nil
+}
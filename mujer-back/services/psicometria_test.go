@@ -0,0 +1,189 @@
+package services
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"mujer-back/stats"
+)
+
+func instrumentoPrueba(t *testing.T) Instrumento {
+	t.Helper()
+	inst, err := LoadInstrumento("../config/instrumentos/mujer_alerta_v1.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return inst
+}
+
+// datosDe agrega encuestas ("P3|gravedad" -> valor) igual que los inserts
+// de agg_valores, agg_encuestas_dim y agg_items_cruzados
+func datosDe(encuestas []map[string]float64) datosPsicometria {
+	d := datosPsicometria{
+		encuestas: int64(len(encuestas)),
+		valores:   map[string]*stats.Histograma{},
+		porNItems: map[string]map[int]int64{},
+		cruzados:  map[string][]cruzadoItems{},
+	}
+	for _, resp := range encuestas {
+		porDim := map[string]map[string]float64{}
+		for llave, v := range resp {
+			pid, dim, _ := strings.Cut(llave, "|")
+			if d.valores[llave] == nil {
+				d.valores[llave] = &stats.Histograma{}
+			}
+			d.valores[llave].Add(v, 1)
+			if porDim[dim] == nil {
+				porDim[dim] = map[string]float64{}
+			}
+			porDim[dim][pid] = v
+		}
+		for dim, vals := range porDim {
+			if d.porNItems[dim] == nil {
+				d.porNItems[dim] = map[int]int64{}
+			}
+			d.porNItems[dim][len(vals)]++
+			for a, va := range vals {
+				for b, vb := range vals {
+					if b < a {
+						continue
+					}
+					d.cruzados[dim] = append(d.cruzados[dim], cruzadoItems{
+						nItems: len(vals), a: a, b: b, sumaA: va, sumaAB: va * vb,
+					})
+				}
+			}
+		}
+	}
+	return d
+}
+
+// encuestasPrueba genera n encuestas completas con un rasgo común por
+// encuesta (para que alpha sea positivo)
+func encuestasPrueba(inst Instrumento, n int) []map[string]float64 {
+	rng := rand.New(rand.NewSource(7))
+	var out []map[string]float64
+	for i := 0; i < n; i++ {
+		rasgo := rng.Float64() * 4
+		resp := map[string]float64{}
+		for _, t := range inst.TypesOfViolence {
+			for _, q := range t.Questions {
+				for _, c := range q.Cards {
+					v := math.Round(1 + rasgo + rng.NormFloat64()*0.7)
+					resp[q.QuestionID+"|"+c.Dimension] = math.Max(1, math.Min(5, v))
+				}
+			}
+		}
+		out = append(out, resp)
+	}
+	return out
+}
+
+func TestAnalizarPsicometriaSupresion(t *testing.T) {
+	inst := instrumentoPrueba(t)
+
+	if _, err := analizarPsicometria(inst, 5, datosDe(nil)); !errors.Is(err, ErrSinDatos) {
+		t.Errorf("sin encuestas: err = %v, quiero ErrSinDatos", err)
+	}
+
+	out, err := analizarPsicometria(inst, 5, datosDe(encuestasPrueba(inst, 4)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !out.Suprimido || out.K != 5 || out.NEncuestas != 4 {
+		t.Errorf("4 encuestas con k=5: suprimido=%v k=%d n=%d", out.Suprimido, out.K, out.NEncuestas)
+	}
+	if out.Items != nil || out.Dimensiones != nil || out.Tipos != nil {
+		t.Errorf("suprimido con datos: %d ítems, %d dimensiones, %d tipos", len(out.Items), len(out.Dimensiones), len(out.Tipos))
+	}
+
+	out, err = analizarPsicometria(inst, 5, datosDe(encuestasPrueba(inst, 5)))
+	if err != nil || out.Suprimido {
+		t.Errorf("5 encuestas con k=5: suprimido=%v err=%v", out.Suprimido, err)
+	}
+}
+
+func TestAnalizarPsicometriaForma(t *testing.T) {
+	inst := instrumentoPrueba(t)
+
+	// 30 completas y 2 que no respondieron P1 en frecuencia
+	encuestas := encuestasPrueba(inst, 32)
+	for _, resp := range encuestas[30:] {
+		delete(resp, "P1|frecuencia")
+	}
+
+	out, err := analizarPsicometria(inst, 5, datosDe(encuestas))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Suprimido || out.NEncuestas != 32 {
+		t.Fatalf("suprimido=%v n=%d", out.Suprimido, out.NEncuestas)
+	}
+	if len(out.Items) != 48 {
+		t.Errorf("%d ítems, quiero 48", len(out.Items))
+	}
+	for _, it := range out.Items {
+		quiero := int64(32)
+		if it.PreguntaID == "P1" && it.Dimension == "frecuencia" {
+			quiero = 30
+		}
+		if it.N != quiero || it.CorrelacionItemTotal == nil {
+			t.Errorf("ítem %s %s: n=%d r=%v", it.PreguntaID, it.Dimension, it.N, it.CorrelacionItemTotal)
+		}
+	}
+
+	if len(out.Dimensiones) != 3 {
+		t.Fatalf("%d dimensiones, quiero 3", len(out.Dimensiones))
+	}
+	for _, e := range out.Dimensiones {
+		quiero := int64(32)
+		if e.Clave == "frecuencia" {
+			quiero = 30
+		}
+		if e.NEncuestasCompletas != quiero {
+			t.Errorf("%s: %d completas, quiero %d", e.Clave, e.NEncuestasCompletas, quiero)
+		}
+		if len(e.Items) != 16 || len(e.Correlaciones) != 16 {
+			t.Fatalf("%s: %d ítems, %d filas de correlaciones", e.Clave, len(e.Items), len(e.Correlaciones))
+		}
+		for i, fila := range e.Correlaciones {
+			if len(fila) != 16 || fila[i] == nil || *fila[i] != 1 {
+				t.Errorf("%s: fila %d de correlaciones mal formada", e.Clave, i)
+			}
+		}
+
+		// alpha igual que sobre la matriz de encuestas completas
+		var filas [][]float64
+		for _, resp := range encuestas {
+			fila := make([]float64, 0, 16)
+			for _, it := range e.Items {
+				if v, ok := resp[it.PreguntaID+"|"+e.Clave]; ok {
+					fila = append(fila, v)
+				}
+			}
+			if len(fila) == 16 {
+				filas = append(filas, fila)
+			}
+		}
+		alpha, _ := stats.AlphaCronbach(filas)
+		if e.Alpha == nil || math.Abs(*e.Alpha-alpha) > 1e-9 {
+			t.Errorf("%s: alpha = %v, quiero %v", e.Clave, e.Alpha, alpha)
+		}
+	}
+
+	// 8 tipos × 3 dimensiones, con 2 preguntas cada uno, por tipo y dimensión
+	if len(out.Tipos) != 24 {
+		t.Fatalf("%d tipos, quiero 24", len(out.Tipos))
+	}
+	if out.Tipos[0].Clave != "1|frecuencia" || out.Tipos[1].Clave != "1|normalidad" || out.Tipos[3].Clave != "2|frecuencia" {
+		t.Errorf("orden de tipos: %s, %s, %s", out.Tipos[0].Clave, out.Tipos[1].Clave, out.Tipos[3].Clave)
+	}
+	for _, e := range out.Tipos {
+		if len(e.Items) != 2 || len(e.Correlaciones) != 2 || e.Alpha == nil {
+			t.Errorf("tipo %s: %d ítems, alpha %v", e.Clave, len(e.Items), e.Alpha)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// RangoEdad es un rango de un conjunto de rangos_edad_sets.
+// Los reportes nunca publican la edad exacta, solo la etiqueta del rango.
+type RangoEdad struct {
+	Min   int
+	Max   int // 0 = sin tope
+	Label string
+}
+
+// RangosEdadDefault se usa si no hay conjunto en rangos_edad_sets para el centro
+var RangosEdadDefault = []RangoEdad{
+	{Min: 10, Max: 17, Label: "10-17"},
+	{Min: 18, Max: 24, Label: "18-24"},
+	{Min: 25, Max: 34, Label: "25-34"},
+	{Min: 35, Max: 44, Label: "35-44"},
+	{Min: 45, Max: 59, Label: "45-59"},
+	{Min: 60, Max: 0, Label: "60+"},
+}
+
+// RangoEdadSinDato es la etiqueta para edades que no caen en ningún rango
+const RangoEdadSinDato = "sin_dato"
+
+var ErrRangosEdadNoExiste = errors.New("rangos_edad_not_found")
+
+// RangoEdadSQL arma el CASE que convierte e.edad en la etiqueta del rango
+func RangoEdadSQL(rangos []RangoEdad) string {
+	var sb strings.Builder
+	sb.WriteString("(case")
+	for _, rg := range rangos {
+		label := strings.ReplaceAll(rg.Label, "'", "''")
+		if rg.Max > 0 {
+			fmt.Fprintf(&sb, " when e.edad between %d and %d then '%s'", rg.Min, rg.Max, label)
+		} else {
+			fmt.Fprintf(&sb, " when e.edad >= %d then '%s'", rg.Min, label)
+		}
+	}
+	sb.WriteString(" else '" + RangoEdadSinDato + "' end)")
+	return sb.String()
+}
+
+// EtiquetaRango es el equivalente en Go de RangoEdadSQL para una edad
+func EtiquetaRango(rangos []RangoEdad, edad int) string {
+	for _, rg := range rangos {
+		if edad >= rg.Min && (rg.Max == 0 || edad <= rg.Max) {
+			return rg.Label
+		}
+	}
+	return RangoEdadSinDato
+}
+
+// CargarRangosEdad resuelve los rangos para un reporte:
+// el conjunto con esa clave si viene; si no, el del tipo de los centros (si
+// todos son del mismo tipo); si no hay ninguno, RangosEdadDefault.
+// Regresa la clave del conjunto usado ("" si es el default).
+func CargarRangosEdad(ctx context.Context, pool *pgxpool.Pool, centros []int64, clave string) (string, []RangoEdad, error) {
+	clave = strings.ToLower(strings.TrimSpace(clave))
+
+	rows, err := pool.Query(ctx, `
+		select s.clave, re.edad_min, coalesce(re.edad_max, 0), re.etiqueta
+		from rangos_edad_sets s
+		join rangos_edad re on re.set_id = s.id
+		where case
+			when $1 <> '' then s.clave = $1
+			else s.centro_tipo = (
+				select min(c.tipo)
+				from centros c
+				where c.id = any($2::bigint[])
+				having count(distinct c.tipo) = 1
+			)
+		end
+		order by re.orden
+	`, clave, centros)
+	if err != nil {
+		return "", nil, fmt.Errorf("rangos edad: %w", err)
+	}
+	defer rows.Close()
+
+	var usada string
+	out := make([]RangoEdad, 0, 8)
+	for rows.Next() {
+		var rg RangoEdad
+		if err := rows.Scan(&usada, &rg.Min, &rg.Max, &rg.Label); err != nil {
+			return "", nil, fmt.Errorf("rangos edad: %w", err)
+		}
+		out = append(out, rg)
+	}
+	if err := rows.Err(); err != nil {
+		return "", nil, fmt.Errorf("rangos edad: %w", err)
+	}
+
+	if len(out) == 0 {
+		if clave != "" {
+			return "", nil, ErrRangosEdadNoExiste
+		}
+		return "", RangosEdadDefault, nil
+	}
+	return usada, out, nil
+}
@@ -0,0 +1,107 @@
+package services
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Registro guarda todas las versiones del instrumento cargadas al arrancar.
+// Cada encuesta queda amarrada (encuestas.instrumento_id) a una de ellas.
+type Registro struct {
+	porID     map[string]Instrumento
+	orden     []string
+	defaultID string
+}
+
+// InstrumentoInfo es el resumen que se lista en /api/instrumentos
+type InstrumentoInfo struct {
+	ID       string   `json:"instrument_id"`
+	Name     string   `json:"name"`
+	Version  string   `json:"version"`
+	Language string   `json:"language,omitempty"`
+	Context  []string `json:"context,omitempty"`
+	Default  bool     `json:"default"`
+}
+
+// LoadRegistro carga todos los *.json del directorio (un archivo por versión).
+// defaultID es el instrumento que se usa cuando el centro no tiene uno fijado.
+func LoadRegistro(dir, defaultID string) (*Registro, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("read instrumentos: %w", err)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no hay instrumentos en %s", dir)
+	}
+	sort.Strings(files)
+
+	reg := &Registro{porID: make(map[string]Instrumento, len(files))}
+	for _, f := range files {
+		inst, err := LoadInstrumento(f)
+		if err != nil {
+			return nil, err
+		}
+		if _, dup := reg.porID[inst.ID]; dup {
+			return nil, fmt.Errorf("instrument_id %s duplicado (%s)", inst.ID, f)
+		}
+		reg.porID[inst.ID] = inst
+		reg.orden = append(reg.orden, inst.ID)
+	}
+
+	defaultID = strings.TrimSpace(defaultID)
+	if defaultID == "" && len(reg.orden) == 1 {
+		defaultID = reg.orden[0]
+	}
+	if _, ok := reg.porID[defaultID]; !ok {
+		return nil, fmt.Errorf("instrumento default %q no existe en %s", defaultID, dir)
+	}
+	reg.defaultID = defaultID
+
+	return reg, nil
+}
+
+// Get regresa el instrumento por instrument_id
+func (r *Registro) Get(id string) (Instrumento, bool) {
+	inst, ok := r.porID[id]
+	return inst, ok
+}
+
+// Default regresa el instrumento que se usa si el centro no tiene versión fijada
+func (r *Registro) Default() Instrumento {
+	return r.porID[r.defaultID]
+}
+
+// Resolve regresa el instrumento fijado (si viene) o el default
+func (r *Registro) Resolve(id string) (Instrumento, bool) {
+	if strings.TrimSpace(id) == "" {
+		return r.Default(), true
+	}
+	return r.Get(id)
+}
+
+// All regresa los instrumentos en orden de carga
+func (r *Registro) All() []Instrumento {
+	out := make([]Instrumento, 0, len(r.orden))
+	for _, id := range r.orden {
+		out = append(out, r.porID[id])
+	}
+	return out
+}
+
+// List regresa el resumen de cada versión cargada
+func (r *Registro) List() []InstrumentoInfo {
+	out := make([]InstrumentoInfo, 0, len(r.orden))
+	for _, inst := range r.All() {
+		out = append(out, InstrumentoInfo{
+			ID:       inst.ID,
+			Name:     inst.Name,
+			Version:  inst.Version,
+			Language: inst.Language,
+			Context:  inst.Context,
+			Default:  inst.ID == r.defaultID,
+		})
+	}
+	return out
+}
@@ -0,0 +1,166 @@
+package services
+
+import (
+	"math"
+	"strings"
+)
+
+// =======================================================
+// Retroalimentación para la persona que respondió
+//
+// La sección "feedback" del instrumento define reglas de interpretación
+// (rango [min, max] del promedio de una dimensión, global o por tipo) con
+// un mensaje reflexivo, y los recursos de apoyo (líneas, protocolos) que
+// cada regla sugiere. El resumen individual regresa las tarjetas cuyas
+// reglas coinciden, en el orden en que están declaradas.
+// =======================================================
+
+// tipos de recurso de apoyo
+const (
+	RecursoLinea     = "hotline"
+	RecursoProtocolo = "protocol"
+	RecursoServicio  = "service"
+)
+
+// TipoFeedbackCadaTipo en type_id aplica la regla a cada tipo por separado
+const TipoFeedbackCadaTipo = "*"
+
+// Feedback es la sección "feedback" del instrumento
+type Feedback struct {
+	Intro     string          `json:"intro,omitempty"`
+	Rules     []ReglaFeedback `json:"rules"`
+	Resources []RecursoApoyo  `json:"resources"`
+}
+
+// ReglaFeedback: sin type_id evalúa el promedio global de la dimensión,
+// con "*" cada tipo y con un type_id solo ese tipo. En title/message,
+// "{tipo}" se reemplaza por el nombre del tipo.
+type ReglaFeedback struct {
+	ID        string   `json:"id"`
+	TypeID    string   `json:"type_id,omitempty"`
+	Dimension string   `json:"dimension"`
+	Min       float64  `json:"min"`
+	Max       float64  `json:"max"`
+	Title     string   `json:"title"`
+	Message   string   `json:"message"`
+	Resources []string `json:"resources,omitempty"`
+}
+
+type RecursoApoyo struct {
+	ID          string `json:"id"`
+	Kind        string `json:"kind"` // hotline | protocol | service
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Phone       string `json:"phone,omitempty"`
+	URL         string `json:"url,omitempty"`
+	Hours       string `json:"hours,omitempty"`
+
+	// se incluye siempre, aunque ninguna regla lo sugiera
+	Always bool `json:"always,omitempty"`
+}
+
+type TarjetaRetro struct {
+	ReglaID        string   `json:"regla_id"`
+	TipoNum        int32    `json:"tipo_num,omitempty"` // 0 en reglas globales
+	TipoNombre     string   `json:"tipo_nombre,omitempty"`
+	Dimension      string   `json:"dimension"`
+	DimensionLabel string   `json:"dimension_label"`
+	Valor          float64  `json:"valor"`
+	Nivel          string   `json:"nivel"` // opción de la escala más cercana
+	Titulo         string   `json:"titulo"`
+	Mensaje        string   `json:"mensaje"`
+	Recursos       []string `json:"recursos"`
+}
+
+// Retroalimentacion viaja en el resumen individual
+type Retroalimentacion struct {
+	Intro    string         `json:"intro,omitempty"`
+	Tarjetas []TarjetaRetro `json:"tarjetas"`
+	Recursos []RecursoApoyo `json:"recursos"` // sugeridos + "always", en el orden del instrumento
+}
+
+// etiquetaDimension regresa el label de la dimensión y la opción de su
+// escala más cercana al valor
+func (i Instrumento) etiquetaDimension(dim string, v float64) (string, string) {
+	for _, d := range i.Dimensions {
+		if d.Key != dim {
+			continue
+		}
+		best, dist := "", math.Inf(1)
+		for _, o := range i.Scales[d.ScaleID].Options {
+			if dd := math.Abs(float64(o.Value) - v); dd < dist {
+				best, dist = o.Label, dd
+			}
+		}
+		return d.Label, best
+	}
+	return dim, ""
+}
+
+// Retroalimentar arma las tarjetas con el promedio global por dimensión y la
+// matriz tipo × dimensión de una encuesta. Regresa nil si el instrumento no
+// define feedback.
+func (i Instrumento) Retroalimentar(global map[string]float64, matriz []CeldaMatriz) *Retroalimentacion {
+	fb := i.Feedback
+	if fb == nil {
+		return nil
+	}
+	out := &Retroalimentacion{Intro: fb.Intro, Tarjetas: []TarjetaRetro{}, Recursos: []RecursoApoyo{}}
+
+	celda := map[int32]map[string]float64{}
+	for _, c := range matriz {
+		if celda[c.TipoNum] == nil {
+			celda[c.TipoNum] = map[string]float64{}
+		}
+		celda[c.TipoNum][c.Dimension] = c.Promedio
+	}
+
+	sugeridos := map[string]bool{}
+	agregar := func(r ReglaFeedback, tipoNum int32, tipoNombre string, v float64) {
+		if v < r.Min || v > r.Max {
+			return
+		}
+		label, nivel := i.etiquetaDimension(r.Dimension, v)
+		rep := strings.NewReplacer("{tipo}", tipoNombre)
+		t := TarjetaRetro{
+			ReglaID:        r.ID,
+			TipoNum:        tipoNum,
+			TipoNombre:     tipoNombre,
+			Dimension:      r.Dimension,
+			DimensionLabel: label,
+			Valor:          math.Round(v*100) / 100,
+			Nivel:          nivel,
+			Titulo:         rep.Replace(r.Title),
+			Mensaje:        rep.Replace(r.Message),
+			Recursos:       append([]string{}, r.Resources...),
+		}
+		out.Tarjetas = append(out.Tarjetas, t)
+		for _, id := range r.Resources {
+			sugeridos[id] = true
+		}
+	}
+
+	for _, r := range fb.Rules {
+		if r.TypeID == "" {
+			if v, ok := global[r.Dimension]; ok {
+				agregar(r, 0, "", v)
+			}
+			continue
+		}
+		for _, t := range i.TypesOfViolence {
+			if r.TypeID != TipoFeedbackCadaTipo && r.TypeID != t.TypeID {
+				continue
+			}
+			if v, ok := celda[int32(t.Order)][r.Dimension]; ok {
+				agregar(r, int32(t.Order), t.Label, v)
+			}
+		}
+	}
+
+	for _, rec := range fb.Resources {
+		if rec.Always || sugeridos[rec.ID] {
+			out.Recursos = append(out.Recursos, rec)
+		}
+	}
+	return out
+}
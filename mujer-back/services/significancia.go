@@ -0,0 +1,359 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"mujer-back/stats"
+)
+
+// =======================================================
+// Significancia de diferencias entre dos grupos (dos años, dos géneros o
+// dos rangos de edad). La unidad es la encuesta: su puntaje en una
+// dimensión o tipo es el promedio de sus respuestas ahí. Por cada métrica
+// se reporta Welch t, Mann–Whitney U, d de Cohen y delta de Cliff (paquete
+// stats); los p se corrigen con Holm sobre todas las métricas de la respuesta.
+// =======================================================
+
+// criterios de comparación
+const (
+	CompararPorYear      = "year"
+	CompararPorGenero    = "genero"
+	CompararPorRangoEdad = "rango_edad"
+)
+
+// AlphaSignificancia es el nivel con el que se marca "significativa" (p Holm)
+const AlphaSignificancia = 0.05
+
+var ErrGrupoInvalido = errors.New("bad_grupo")
+
+// GruposComparacion: A es la referencia y B se compara contra ella
+// (diferencias positivas = B mayor que A)
+type GruposComparacion struct {
+	Por string
+	A   string
+	B   string
+}
+
+type MuestraGrupo struct {
+	N       int     `json:"n"` // encuestas
+	Media   float64 `json:"media"`
+	DE      float64 `json:"de"`
+	Mediana float64 `json:"mediana"`
+}
+
+type PruebaWelch struct {
+	T  float64 `json:"t"`
+	GL float64 `json:"gl"` // grados de libertad de Welch–Satterthwaite
+	P  float64 `json:"p"`
+}
+
+type PruebaMannWhitney struct {
+	U float64 `json:"u"` // U del grupo B
+	Z float64 `json:"z"` // aproximación normal con corrección por empates y continuidad
+	P float64 `json:"p"`
+}
+
+type TamanoEfecto struct {
+	Valor    float64 `json:"valor"`
+	Magnitud string  `json:"magnitud"` // despreciable, pequeño, mediano, grande
+}
+
+// ComparacionMetrica es el contraste A vs B en una dimensión o un tipo
+type ComparacionMetrica struct {
+	Ambito     string `json:"ambito"` // "dimension" o "tipo"
+	Dimension  string `json:"dimension,omitempty"`
+	TipoNum    int32  `json:"tipo_num,omitempty"`
+	TipoNombre string `json:"tipo_nombre,omitempty"`
+
+	A MuestraGrupo `json:"a"`
+	B MuestraGrupo `json:"b"`
+
+	Diferencia float64 `json:"diferencia"` // media B - media A
+
+	Welch       *PruebaWelch       `json:"welch,omitempty"` // nil si no hay varianza o n < 2
+	MannWhitney *PruebaMannWhitney `json:"mann_whitney,omitempty"`
+	CohenD      *TamanoEfecto      `json:"cohen_d,omitempty"`
+	CliffDelta  *TamanoEfecto      `json:"cliff_delta,omitempty"`
+
+	// p ajustados por Holm (familia = todas las métricas de la respuesta)
+	PWelchHolm       *float64 `json:"p_welch_holm,omitempty"`
+	PMannWhitneyHolm *float64 `json:"p_mann_whitney_holm,omitempty"`
+	Significativa    bool     `json:"significativa"` // ambos p Holm < AlphaSignificancia
+}
+
+type Comparacion struct {
+	Por        string               `json:"por"`
+	A          string               `json:"a"`
+	B          string               `json:"b"`
+	EncuestasA int                  `json:"encuestas_a"`
+	EncuestasB int                  `json:"encuestas_b"`
+	Alpha      float64              `json:"alpha"`
+	Correccion string               `json:"correccion"`
+	Metricas   []ComparacionMetrica `json:"metricas"`
+	Anonimato  Anonimato            `json:"anonimato"`
+}
+
+// puntajesEncuesta acumula suma y conteo por dimensión y por tipo
+type puntajesEncuesta struct {
+	grupo int // 0 = A, 1 = B
+	dim   map[string][2]float64
+	tipo  map[int32][2]float64
+}
+
+func (p *puntajesEncuesta) add(dim string, tipo int32, suma float64, n int) {
+	for _, k := range []string{dim, DimensionTotal} {
+		v := p.dim[k]
+		p.dim[k] = [2]float64{v[0] + suma, v[1] + float64(n)}
+	}
+	v := p.tipo[tipo]
+	p.tipo[tipo] = [2]float64{v[0] + suma, v[1] + float64(n)}
+}
+
+// DimensionTotal es la clave del puntaje sobre todas las respuestas
+const DimensionTotal = "total"
+
+// CompararGruposSignificancia compara los grupos g.A y g.B dentro del
+// filtro f. Si alguno tiene menos de k encuestas no se calcula nada y se
+// marca en Anonimato. Regresa ErrSinDatos si ninguno tiene encuestas.
+func CompararGruposSignificancia(ctx context.Context, pool *pgxpool.Pool, f FiltroAgregado, g GruposComparacion, k int) (Comparacion, error) {
+	res := Comparacion{
+		Por: g.Por, A: g.A, B: g.B,
+		Alpha:      AlphaSignificancia,
+		Correccion: "holm",
+		Metricas:   []ComparacionMetrica{},
+		Anonimato:  Anonimato{K: k},
+	}
+
+	// el filtro no puede fijar el mismo criterio que se compara
+	var expr string
+	var a, b any = g.A, g.B
+	switch g.Por {
+	case CompararPorYear:
+		ya, errA := strconv.Atoi(g.A)
+		yb, errB := strconv.Atoi(g.B)
+		if errA != nil || errB != nil {
+			return res, ErrGrupoInvalido
+		}
+		f.Year, f.Years = nil, nil
+		expr, a, b = "extract(year from e.finished_at)::int", ya, yb
+	case CompararPorGenero:
+		f.Genero = ""
+		expr = "g.clave"
+	case CompararPorRangoEdad:
+		f.RangoEdad = ""
+		expr = RangoEdadSQL(f.RangosEdad())
+	default:
+		return res, ErrGrupoInvalido
+	}
+	if g.A == g.B {
+		return res, ErrGrupoInvalido
+	}
+
+	where, args := f.SQL()
+	args = append(args, a, b)
+	pa, pb := "$"+strconv.Itoa(len(args)-1), "$"+strconv.Itoa(len(args))
+
+	rows, err := pool.Query(ctx, `
+		select
+			case when `+expr+` = `+pa+` then 0 else 1 end,
+			e.id::text,
+			r.dimension::text,
+			ip.tipo_num,
+			ip.tipo_nombre,
+			sum(r.valor)::float8,
+			count(*)::int
+		from encuestas e
+		join respuestas r on r.encuesta_id = e.id
+		join instrumento_preguntas ip
+		  on ip.instrumento_id = e.instrumento_id
+		 and ip.pregunta_id = r.pregunta_id
+		left join generos g on g.id = e.genero_id
+		where `+where+`
+		  and `+expr+` in (`+pa+`, `+pb+`)
+		group by 1, 2, 3, 4, 5
+	`, args...)
+	if err != nil {
+		return res, etapa("significancia", err)
+	}
+	encuestas := map[string]*puntajesEncuesta{}
+	tipos := map[int32]string{}
+	dims := map[string]bool{}
+	for rows.Next() {
+		var grupo, n int
+		var id, dim, tipoNombre string
+		var tipo int32
+		var suma float64
+		if err := rows.Scan(&grupo, &id, &dim, &tipo, &tipoNombre, &suma, &n); err != nil {
+			rows.Close()
+			return res, etapa("significancia", err)
+		}
+		p := encuestas[id]
+		if p == nil {
+			p = &puntajesEncuesta{grupo: grupo, dim: map[string][2]float64{}, tipo: map[int32][2]float64{}}
+			encuestas[id] = p
+			if grupo == 0 {
+				res.EncuestasA++
+			} else {
+				res.EncuestasB++
+			}
+		}
+		p.add(dim, tipo, suma, n)
+		tipos[tipo] = tipoNombre
+		dims[dim] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return res, etapa("significancia", err)
+	}
+
+	if res.EncuestasA+res.EncuestasB == 0 {
+		return res, ErrSinDatos
+	}
+	if res.EncuestasA < k {
+		res.Anonimato.Marcar(CampoSuprimido("a", g.A))
+	}
+	if res.EncuestasB < k {
+		res.Anonimato.Marcar(CampoSuprimido("b", g.B))
+	}
+	if res.Anonimato.Suprimido {
+		return res, nil
+	}
+
+	// muestras por métrica
+	muestras := func(valor func(p *puntajesEncuesta) ([2]float64, bool)) ([]float64, []float64) {
+		var xa, xb []float64
+		for _, p := range encuestas {
+			v, ok := valor(p)
+			if !ok || v[1] == 0 {
+				continue
+			}
+			if p.grupo == 0 {
+				xa = append(xa, v[0]/v[1])
+			} else {
+				xb = append(xb, v[0]/v[1])
+			}
+		}
+		return xa, xb
+	}
+
+	nombresDim := make([]string, 0, len(dims))
+	for d := range dims {
+		nombresDim = append(nombresDim, d)
+	}
+	sort.Strings(nombresDim)
+	nombresDim = append(nombresDim, DimensionTotal)
+	for _, d := range nombresDim {
+		xa, xb := muestras(func(p *puntajesEncuesta) ([2]float64, bool) { v, ok := p.dim[d]; return v, ok })
+		m := compararMuestras(xa, xb)
+		m.Ambito, m.Dimension = "dimension", d
+		res.Metricas = append(res.Metricas, m)
+	}
+
+	nums := make([]int32, 0, len(tipos))
+	for t := range tipos {
+		nums = append(nums, t)
+	}
+	sort.Slice(nums, func(i, j int) bool { return nums[i] < nums[j] })
+	for _, t := range nums {
+		xa, xb := muestras(func(p *puntajesEncuesta) ([2]float64, bool) { v, ok := p.tipo[t]; return v, ok })
+		m := compararMuestras(xa, xb)
+		m.Ambito, m.TipoNum, m.TipoNombre = "tipo", t, tipos[t]
+		res.Metricas = append(res.Metricas, m)
+	}
+
+	corregirHolm(res.Metricas)
+	return res, nil
+}
+
+// compararMuestras corre las pruebas y tamaños de efecto para A vs B
+func compararMuestras(xa, xb []float64) ComparacionMetrica {
+	m := ComparacionMetrica{A: describirMuestra(xa), B: describirMuestra(xb)}
+	m.Diferencia = m.B.Media - m.A.Media
+	if len(xa) == 0 || len(xb) == 0 {
+		return m
+	}
+
+	if t, ok := stats.Welch(xa, xb); ok {
+		m.Welch = &PruebaWelch{T: t.T, GL: t.GL, P: t.P}
+	}
+	u := stats.MannWhitney(xa, xb)
+	m.MannWhitney = &PruebaMannWhitney{U: u.U, Z: u.Z, P: u.P}
+
+	if d, ok := stats.CohenD(xa, xb); ok {
+		m.CohenD = &TamanoEfecto{Valor: d, Magnitud: magnitudCohen(d)}
+	}
+	delta := stats.CliffDelta(xa, xb)
+	m.CliffDelta = &TamanoEfecto{Valor: delta, Magnitud: magnitudCliff(delta)}
+	return m
+}
+
+func describirMuestra(x []float64) MuestraGrupo {
+	m := MuestraGrupo{N: len(x)}
+	if len(x) == 0 {
+		return m
+	}
+	h := stats.NuevoHistograma(x)
+	m.Media, m.DE, m.Mediana = h.Media(), h.DesvEst(), h.Percentil(0.5)
+	return m
+}
+
+// corregirHolm ajusta los p de Welch y de Mann–Whitney por separado
+func corregirHolm(ms []ComparacionMetrica) {
+	var pw, pm []float64
+	var iw, im []int
+	for i, m := range ms {
+		if m.Welch != nil {
+			pw = append(pw, m.Welch.P)
+			iw = append(iw, i)
+		}
+		if m.MannWhitney != nil {
+			pm = append(pm, m.MannWhitney.P)
+			im = append(im, i)
+		}
+	}
+	for j, p := range stats.Holm(pw) {
+		p := p
+		ms[iw[j]].PWelchHolm = &p
+	}
+	for j, p := range stats.Holm(pm) {
+		p := p
+		ms[im[j]].PMannWhitneyHolm = &p
+	}
+	for i := range ms {
+		m := &ms[i]
+		m.Significativa = m.PWelchHolm != nil && *m.PWelchHolm < AlphaSignificancia &&
+			m.PMannWhitneyHolm != nil && *m.PMannWhitneyHolm < AlphaSignificancia
+	}
+}
+
+// umbrales de Cohen (1988)
+func magnitudCohen(d float64) string {
+	switch d = math.Abs(d); {
+	case d < 0.2:
+		return "despreciable"
+	case d < 0.5:
+		return "pequeño"
+	case d < 0.8:
+		return "mediano"
+	}
+	return "grande"
+}
+
+// umbrales de Romano et al. (2006)
+func magnitudCliff(d float64) string {
+	switch d = math.Abs(d); {
+	case d < 0.147:
+		return "despreciable"
+	case d < 0.33:
+		return "pequeño"
+	case d < 0.474:
+		return "mediano"
+	}
+	return "grande"
+}
@@ -0,0 +1,129 @@
+// Package stats reúne los cálculos estadísticos de los reportes:
+// descriptivos, distribuciones t y F, intervalos de confianza, fiabilidad
+// (alpha de Cronbach y análisis de ítems) y pruebas entre dos grupos.
+// No depende de la base de datos; services arma las muestras y llama aquí.
+package stats
+
+import (
+	"math"
+	"sort"
+)
+
+// Bin es un valor con su frecuencia
+type Bin struct {
+	V float64
+	N int64
+}
+
+// Histograma acumula valores con frecuencia; da lo mismo que avg,
+// stddev_samp y percentile_cont sobre las filas expandidas
+type Histograma struct {
+	Bins []Bin
+}
+
+// NuevoHistograma arma un histograma con frecuencia 1 por valor
+func NuevoHistograma(x []float64) Histograma {
+	h := Histograma{Bins: make([]Bin, 0, len(x))}
+	for _, v := range x {
+		h.Add(v, 1)
+	}
+	return h
+}
+
+func (h *Histograma) Add(v float64, n int64) {
+	h.Bins = append(h.Bins, Bin{V: v, N: n})
+}
+
+func (h Histograma) N() int64 {
+	var n int64
+	for _, b := range h.Bins {
+		n += b.N
+	}
+	return n
+}
+
+func (h Histograma) Media() float64 {
+	n := h.N()
+	if n == 0 {
+		return 0
+	}
+	var s float64
+	for _, b := range h.Bins {
+		s += b.V * float64(b.N)
+	}
+	return s / float64(n)
+}
+
+// VarSamp es la varianza muestral; ok=false con menos de 2 observaciones
+// (null en SQL)
+func (h Histograma) VarSamp() (float64, bool) {
+	n := h.N()
+	if n < 2 {
+		return 0, false
+	}
+	m := h.Media()
+	var ss float64
+	for _, b := range h.Bins {
+		d := b.V - m
+		ss += d * d * float64(b.N)
+	}
+	return ss / float64(n-1), true
+}
+
+// DesvEst es la desviación estándar muestral (0 con menos de 2 observaciones)
+func (h Histograma) DesvEst() float64 {
+	v, ok := h.VarSamp()
+	if !ok {
+		return 0
+	}
+	return math.Sqrt(v)
+}
+
+// Percentil replica percentile_cont(p) within group (order by v)
+// (interpolación lineal, tipo 7 de Hyndman y Fan)
+func (h Histograma) Percentil(p float64) float64 {
+	n := h.N()
+	if n == 0 {
+		return 0
+	}
+	bins := append([]Bin(nil), h.Bins...)
+	sort.Slice(bins, func(i, j int) bool { return bins[i].V < bins[j].V })
+
+	pos := p * float64(n-1)
+	lo := int64(math.Floor(pos))
+	hi := int64(math.Ceil(pos))
+
+	valor := func(idx int64) float64 {
+		var acc int64
+		for _, b := range bins {
+			acc += b.N
+			if idx < acc {
+				return b.V
+			}
+		}
+		return bins[len(bins)-1].V
+	}
+
+	vlo, vhi := valor(lo), valor(hi)
+	return vlo + (pos-float64(lo))*(vhi-vlo)
+}
+
+// Media de una muestra (0 si está vacía)
+func Media(x []float64) float64 {
+	return NuevoHistograma(x).Media()
+}
+
+// Varianza muestral (n-1); ok=false con menos de 2 observaciones
+func Varianza(x []float64) (float64, bool) {
+	return NuevoHistograma(x).VarSamp()
+}
+
+// DesvEst muestral (0 con menos de 2 observaciones)
+func DesvEst(x []float64) float64 {
+	return NuevoHistograma(x).DesvEst()
+}
+
+// Percentil con interpolación lineal (p entre 0 y 1)
+func Percentil(x []float64, p float64) float64 {
+	return NuevoHistograma(x).Percentil(p)
+}
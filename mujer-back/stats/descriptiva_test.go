@@ -0,0 +1,69 @@
+package stats
+
+import (
+	"math"
+	"testing"
+)
+
+func cerca(a, b, tol float64) bool {
+	return math.Abs(a-b) <= tol
+}
+
+func TestDescriptivos(t *testing.T) {
+	casos := []struct {
+		nombre   string
+		x        []float64
+		media    float64
+		varianza float64
+		varOK    bool
+		p25      float64
+		mediana  float64
+		p75      float64
+	}{
+		{"vacía", nil, 0, 0, false, 0, 0, 0},
+		{"un valor", []float64{3}, 3, 0, false, 3, 3, 3},
+		{"par", []float64{1, 2, 3, 4}, 2.5, 1.6666666666666667, true, 1.75, 2.5, 3.25},
+		{"clásico σ=2", []float64{2, 4, 4, 4, 5, 5, 7, 9}, 5, 32.0 / 7, true, 4, 4.5, 5.5},
+		{"desordenado", []float64{5, 1, 4, 2, 3}, 3, 2.5, true, 2, 3, 4},
+	}
+	for _, c := range casos {
+		t.Run(c.nombre, func(t *testing.T) {
+			if got := Media(c.x); !cerca(got, c.media, 1e-12) {
+				t.Errorf("Media = %v, quiero %v", got, c.media)
+			}
+			v, ok := Varianza(c.x)
+			if ok != c.varOK || !cerca(v, c.varianza, 1e-12) {
+				t.Errorf("Varianza = %v, %v; quiero %v, %v", v, ok, c.varianza, c.varOK)
+			}
+			for _, p := range []struct{ p, quiero float64 }{{0.25, c.p25}, {0.5, c.mediana}, {0.75, c.p75}} {
+				if got := Percentil(c.x, p.p); !cerca(got, p.quiero, 1e-12) {
+					t.Errorf("Percentil(%v) = %v, quiero %v", p.p, got, p.quiero)
+				}
+			}
+		})
+	}
+}
+
+func TestHistogramaIgualAFilas(t *testing.T) {
+	// 1×3, 2×1, 5×2 expandido es {1, 1, 1, 2, 5, 5}
+	var h Histograma
+	h.Add(5, 2)
+	h.Add(1, 3)
+	h.Add(2, 1)
+	filas := []float64{1, 1, 1, 2, 5, 5}
+
+	if h.N() != 6 {
+		t.Fatalf("N = %d, quiero 6", h.N())
+	}
+	if !cerca(h.Media(), Media(filas), 1e-12) {
+		t.Errorf("Media = %v, quiero %v", h.Media(), Media(filas))
+	}
+	if !cerca(h.DesvEst(), DesvEst(filas), 1e-12) {
+		t.Errorf("DesvEst = %v, quiero %v", h.DesvEst(), DesvEst(filas))
+	}
+	for _, p := range []float64{0, 0.1, 0.25, 0.5, 0.9, 1} {
+		if got, quiero := h.Percentil(p), Percentil(filas, p); !cerca(got, quiero, 1e-12) {
+			t.Errorf("Percentil(%v) = %v, quiero %v", p, got, quiero)
+		}
+	}
+}
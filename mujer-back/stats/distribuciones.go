@@ -0,0 +1,127 @@
+package stats
+
+import "math"
+
+// BetaIncompleta es la función beta incompleta regularizada I_x(a, b)
+// (fracción continua de Lentz, como en Numerical Recipes)
+func BetaIncompleta(a, b, x float64) float64 {
+	if x <= 0 {
+		return 0
+	}
+	if x >= 1 {
+		return 1
+	}
+	la, _ := math.Lgamma(a + b)
+	lb, _ := math.Lgamma(a)
+	lc, _ := math.Lgamma(b)
+	frente := math.Exp(la - lb - lc + a*math.Log(x) + b*math.Log(1-x))
+	if x < (a+1)/(a+b+2) {
+		return frente * fraccionBeta(a, b, x) / a
+	}
+	return 1 - frente*fraccionBeta(b, a, 1-x)/b
+}
+
+func fraccionBeta(a, b, x float64) float64 {
+	const (
+		maxIter = 300
+		eps     = 3e-14
+		minimo  = 1e-300
+	)
+	acotar := func(v float64) float64 {
+		if math.Abs(v) < minimo {
+			return minimo
+		}
+		return v
+	}
+	qab, qap, qam := a+b, a+1, a-1
+	c, d := 1.0, 1/acotar(1-qab*x/qap)
+	h := d
+	for m := 1; m <= maxIter; m++ {
+		fm := float64(m)
+		m2 := 2 * fm
+		aa := fm * (b - fm) * x / ((qam + m2) * (a + m2))
+		d = 1 / acotar(1+aa*d)
+		c = acotar(1 + aa/c)
+		h *= d * c
+		aa = -(a + fm) * (qab + fm) * x / ((a + m2) * (qap + m2))
+		d = 1 / acotar(1+aa*d)
+		c = acotar(1 + aa/c)
+		del := d * c
+		h *= del
+		if math.Abs(del-1) < eps {
+			break
+		}
+	}
+	return h
+}
+
+// CDFNormal es Φ(z) de la normal estándar
+func CDFNormal(z float64) float64 {
+	return 0.5 * math.Erfc(-z/math.Sqrt2)
+}
+
+// CDFT es P(T ≤ t) para la t de Student con gl grados de libertad
+func CDFT(t, gl float64) float64 {
+	cola := 0.5 * BetaIncompleta(gl/2, 0.5, gl/(gl+t*t))
+	if t >= 0 {
+		return 1 - cola
+	}
+	return cola
+}
+
+// PValorT es el p bilateral P(|T| ≥ |t|)
+func PValorT(t, gl float64) float64 {
+	return BetaIncompleta(gl/2, 0.5, gl/(gl+t*t))
+}
+
+// CuantilT regresa t tal que CDFT(t, gl) = p
+func CuantilT(p, gl float64) float64 {
+	if p == 0.5 {
+		return 0
+	}
+	return invertir(func(x float64) float64 { return CDFT(x, gl) }, p, math.Inf(-1))
+}
+
+// CDFF es P(X ≤ x) para la F de Snedecor con (d1, d2) grados de libertad
+func CDFF(x, d1, d2 float64) float64 {
+	if x <= 0 {
+		return 0
+	}
+	return BetaIncompleta(d1/2, d2/2, d1*x/(d1*x+d2))
+}
+
+// CuantilF regresa x ≥ 0 tal que CDFF(x, d1, d2) = p
+func CuantilF(p, d1, d2 float64) float64 {
+	return invertir(func(x float64) float64 { return CDFF(x, d1, d2) }, p, 0)
+}
+
+// invertir busca por bisección x con cdf(x) = p; piso es el mínimo del
+// soporte (0 o -Inf)
+func invertir(cdf func(float64) float64, p, piso float64) float64 {
+	if p <= 0 {
+		return piso
+	}
+	if p >= 1 {
+		return math.Inf(1)
+	}
+	lo, hi := -1.0, 1.0
+	if math.IsInf(piso, -1) {
+		for cdf(lo) > p {
+			lo *= 2
+		}
+	} else {
+		lo = piso
+	}
+	for cdf(hi) < p {
+		hi *= 2
+	}
+	for i := 0; i < 200 && hi-lo > 1e-12*math.Max(1, math.Abs(hi)); i++ {
+		mid := (lo + hi) / 2
+		if cdf(mid) < p {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return (lo + hi) / 2
+}
@@ -0,0 +1,94 @@
+package stats
+
+import "testing"
+
+// valores de referencia de tablas estándar (R: qt, qf, pnorm, pbeta)
+
+func TestCuantilT(t *testing.T) {
+	casos := []struct {
+		p, gl, quiero float64
+	}{
+		{0.975, 1, 12.706205},
+		{0.975, 6, 2.446912},
+		{0.975, 10, 2.228139},
+		{0.975, 30, 2.042272},
+		{0.95, 10, 1.812461},
+		{0.995, 20, 2.845340},
+		{0.025, 10, -2.228139},
+		{0.5, 7, 0},
+		{0.975, 1e7, 1.959964},
+	}
+	for _, c := range casos {
+		if got := CuantilT(c.p, c.gl); !cerca(got, c.quiero, 1e-5) {
+			t.Errorf("CuantilT(%v, %v) = %v, quiero %v", c.p, c.gl, got, c.quiero)
+		}
+	}
+}
+
+func TestCDFTyPValor(t *testing.T) {
+	casos := []struct {
+		t, gl, cdf, p float64
+	}{
+		{0, 5, 0.5, 1},
+		{2.228139, 10, 0.975, 0.05},
+		{-2.228139, 10, 0.025, 0.05},
+		{2, 5, 0.9490303, 0.1019395},
+		{1, 1, 0.75, 0.5},
+	}
+	for _, c := range casos {
+		if got := CDFT(c.t, c.gl); !cerca(got, c.cdf, 1e-6) {
+			t.Errorf("CDFT(%v, %v) = %v, quiero %v", c.t, c.gl, got, c.cdf)
+		}
+		if got := PValorT(c.t, c.gl); !cerca(got, c.p, 1e-6) {
+			t.Errorf("PValorT(%v, %v) = %v, quiero %v", c.t, c.gl, got, c.p)
+		}
+	}
+}
+
+func TestCuantilF(t *testing.T) {
+	casos := []struct {
+		p, d1, d2, quiero float64
+	}{
+		{0.95, 1, 10, 4.964603}, // = t(0.975, 10)²
+		{0.95, 2, 10, 4.102821},
+		{0.95, 5, 10, 3.325835},
+		{0.5, 10, 10, 1},
+	}
+	for _, c := range casos {
+		if got := CuantilF(c.p, c.d1, c.d2); !cerca(got, c.quiero, 1e-5) {
+			t.Errorf("CuantilF(%v, %v, %v) = %v, quiero %v", c.p, c.d1, c.d2, got, c.quiero)
+		}
+		if got := CDFF(c.quiero, c.d1, c.d2); !cerca(got, c.p, 1e-6) {
+			t.Errorf("CDFF(%v, %v, %v) = %v, quiero %v", c.quiero, c.d1, c.d2, got, c.p)
+		}
+	}
+}
+
+func TestBetaIncompletaYNormal(t *testing.T) {
+	beta := []struct {
+		a, b, x, quiero float64
+	}{
+		{2, 2, 0.5, 0.5},
+		{1, 1, 0.3, 0.3},    // uniforme
+		{2, 3, 0.4, 0.5248}, // 1 - (1-x)^3·(1+3x)
+		{0.5, 0.5, 0, 0},
+		{0.5, 0.5, 1, 1},
+	}
+	for _, c := range beta {
+		if got := BetaIncompleta(c.a, c.b, c.x); !cerca(got, c.quiero, 1e-10) {
+			t.Errorf("BetaIncompleta(%v, %v, %v) = %v, quiero %v", c.a, c.b, c.x, got, c.quiero)
+		}
+	}
+
+	normal := []struct{ z, quiero float64 }{
+		{0, 0.5},
+		{1.959964, 0.975},
+		{-1, 0.1586553},
+		{3, 0.9986501},
+	}
+	for _, c := range normal {
+		if got := CDFNormal(c.z); !cerca(got, c.quiero, 1e-7) {
+			t.Errorf("CDFNormal(%v) = %v, quiero %v", c.z, got, c.quiero)
+		}
+	}
+}
@@ -0,0 +1,323 @@
+package stats
+
+import "math"
+
+// Las matrices de ítems son filas = personas (encuestas) y columnas = ítems,
+// sin faltantes.
+
+// columna regresa los valores del ítem j
+func columna(m [][]float64, j int) []float64 {
+	out := make([]float64, len(m))
+	for i, fila := range m {
+		out[i] = fila[j]
+	}
+	return out
+}
+
+func sumaFilas(m [][]float64, sin int) []float64 {
+	out := make([]float64, len(m))
+	for i, fila := range m {
+		for j, v := range fila {
+			if j != sin {
+				out[i] += v
+			}
+		}
+	}
+	return out
+}
+
+func numItems(m [][]float64) int {
+	if len(m) == 0 {
+		return 0
+	}
+	return len(m[0])
+}
+
+// AlphaCronbach = k/(k-1) · (1 - Σσ²ítem / σ²total). ok=false con menos de
+// 2 ítems, menos de 2 personas o puntaje total sin varianza.
+func AlphaCronbach(m [][]float64) (float64, bool) {
+	return alphaSin(m, -1)
+}
+
+// alphaSin calcula alpha ignorando el ítem `sin` (-1 = ninguno)
+func alphaSin(m [][]float64, sin int) (float64, bool) {
+	k := numItems(m)
+	if sin >= 0 {
+		k--
+	}
+	if k < 2 || len(m) < 2 {
+		return 0, false
+	}
+	var sumVar float64
+	for j := 0; j < numItems(m); j++ {
+		if j == sin {
+			continue
+		}
+		v, _ := Varianza(columna(m, j))
+		sumVar += v
+	}
+	varTotal, _ := Varianza(sumaFilas(m, sin))
+	if varTotal == 0 {
+		return 0, false
+	}
+	fk := float64(k)
+	return fk / (fk - 1) * (1 - sumVar/varTotal), true
+}
+
+// AlphaDesdeVarianzas es alpha a partir de las varianzas de cada ítem y la
+// del puntaje total (cuando solo se tienen marginales, p. ej. histogramas)
+func AlphaDesdeVarianzas(varItems []float64, varTotal float64) (float64, bool) {
+	k := float64(len(varItems))
+	if k < 2 || varTotal <= 0 {
+		return 0, false
+	}
+	var s float64
+	for _, v := range varItems {
+		s += v
+	}
+	return k / (k - 1) * (1 - s/varTotal), true
+}
+
+// IntervaloAlphaFeldt es el IC de alpha de Feldt (1965):
+// (1-ρ)/(1-α) ~ F(n-1, (n-1)(k-1))
+func IntervaloAlphaFeldt(alpha float64, n, k int, confianza float64) (float64, float64, bool) {
+	if n < 2 || k < 2 {
+		return 0, 0, false
+	}
+	d1 := float64(n - 1)
+	d2 := d1 * float64(k-1)
+	g := (1 - confianza) / 2
+	lo := 1 - (1-alpha)*CuantilF(1-g, d1, d2)
+	hi := 1 - (1-alpha)*CuantilF(g, d1, d2)
+	return lo, hi, true
+}
+
+// Correlacion de Pearson; NaN si alguna variable no tiene varianza
+func Correlacion(x, y []float64) float64 {
+	n := len(x)
+	if n != len(y) || n < 2 {
+		return math.NaN()
+	}
+	mx, my := Media(x), Media(y)
+	var sxy, sxx, syy float64
+	for i := range x {
+		dx, dy := x[i]-mx, y[i]-my
+		sxy += dx * dy
+		sxx += dx * dx
+		syy += dy * dy
+	}
+	if sxx == 0 || syy == 0 {
+		return math.NaN()
+	}
+	return sxy / math.Sqrt(sxx*syy)
+}
+
+// MatrizCorrelaciones regresa la matriz k×k de correlaciones entre ítems
+func MatrizCorrelaciones(m [][]float64) [][]float64 {
+	k := numItems(m)
+	cols := make([][]float64, k)
+	for j := range cols {
+		cols[j] = columna(m, j)
+	}
+	out := make([][]float64, k)
+	for i := range out {
+		out[i] = make([]float64, k)
+		for j := range out[i] {
+			switch {
+			case i == j:
+				out[i][j] = 1
+			case j < i:
+				out[i][j] = out[j][i]
+			default:
+				out[i][j] = Correlacion(cols[i], cols[j])
+			}
+		}
+	}
+	return out
+}
+
+// Item es el análisis de un ítem dentro de su escala
+type Item struct {
+	Media    float64
+	Varianza float64
+
+	// correlación del ítem con la suma de los demás (corregida); NaN si no hay varianza
+	CorrelacionItemTotal float64
+
+	// alpha de la escala sin el ítem; NaN con menos de 3 ítems
+	AlphaSiSeElimina float64
+}
+
+// AnalisisItems regresa, por columna, media, varianza, correlación
+// ítem-total corregida y alpha si se elimina el ítem
+func AnalisisItems(m [][]float64) []Item {
+	k := numItems(m)
+	out := make([]Item, k)
+	for j := range out {
+		col := columna(m, j)
+		v, _ := Varianza(col)
+		it := Item{
+			Media:                Media(col),
+			Varianza:             v,
+			CorrelacionItemTotal: Correlacion(col, sumaFilas(m, j)),
+			AlphaSiSeElimina:     math.NaN(),
+		}
+		if a, ok := alphaSin(m, j); ok {
+			it.AlphaSiSeElimina = a
+		}
+		out[j] = it
+	}
+	return out
+}
+
+// SumasItems son las sumas suficientes de una matriz de ítems: personas,
+// Σx por ítem y Σx·y por par. Se acumulan por partes (p. ej. por celda de
+// agregados) y dan lo mismo que AlphaCronbach y AnalisisItems sobre las filas.
+type SumasItems struct {
+	N        int64
+	Suma     []float64   // Σx por ítem
+	Cruzados [][]float64 // Σx·y por par, k×k simétrica (la diagonal es Σx²)
+}
+
+// NuevasSumasItems regresa sumas en cero para k ítems
+func NuevasSumasItems(k int) SumasItems {
+	s := SumasItems{Suma: make([]float64, k), Cruzados: make([][]float64, k)}
+	for j := range s.Cruzados {
+		s.Cruzados[j] = make([]float64, k)
+	}
+	return s
+}
+
+// AddFila suma una persona
+func (s *SumasItems) AddFila(fila []float64) {
+	s.N++
+	for j, x := range fila {
+		s.Suma[j] += x
+		for l, y := range fila {
+			s.Cruzados[j][l] += x * y
+		}
+	}
+}
+
+// comomentos regresa n·Σxy - Σx·Σy, que es n(n-1) veces la covarianza.
+// Alpha y las correlaciones no cambian de escala, y con valores enteros la
+// cuenta es exacta: un total sin varianza da 0 y no un residuo.
+func (s SumasItems) comomentos() [][]float64 {
+	n := float64(s.N)
+	c := make([][]float64, len(s.Suma))
+	for j := range c {
+		c[j] = make([]float64, len(s.Suma))
+		for l := range c[j] {
+			c[j][l] = n*s.Cruzados[j][l] - s.Suma[j]*s.Suma[l]
+		}
+	}
+	return c
+}
+
+// sumaComomentos suma la submatriz sin el ítem `sin` (-1 = ninguno):
+// la traza (Σσ²ítem) y el total (σ²total), ambos escalados
+func sumaComomentos(c [][]float64, sin int) (traza, total float64) {
+	for j := range c {
+		if j == sin {
+			continue
+		}
+		traza += c[j][j]
+		for l := range c[j] {
+			if l != sin {
+				total += c[j][l]
+			}
+		}
+	}
+	return traza, total
+}
+
+// alphaComomentos es alphaSin sobre la matriz de comomentos
+func alphaComomentos(c [][]float64, n int64, sin int) (float64, bool) {
+	k := len(c)
+	if sin >= 0 {
+		k--
+	}
+	if k < 2 || n < 2 {
+		return 0, false
+	}
+	traza, total := sumaComomentos(c, sin)
+	if total <= 0 {
+		return 0, false
+	}
+	fk := float64(k)
+	return fk / (fk - 1) * (1 - traza/total), true
+}
+
+// Alpha es AlphaCronbach de la matriz que originó las sumas
+func (s SumasItems) Alpha() (float64, bool) {
+	return alphaComomentos(s.comomentos(), s.N, -1)
+}
+
+// AnalisisItems es AnalisisItems de la matriz que originó las sumas
+func (s SumasItems) AnalisisItems() []Item {
+	c := s.comomentos()
+	out := make([]Item, len(c))
+	for j := range out {
+		it := Item{
+			CorrelacionItemTotal: math.NaN(),
+			AlphaSiSeElimina:     math.NaN(),
+		}
+		if s.N > 0 {
+			it.Media = s.Suma[j] / float64(s.N)
+		}
+		if s.N >= 2 {
+			it.Varianza = c[j][j] / float64(s.N*(s.N-1))
+
+			// cov(x, resto) = Σ c[j][l] (l ≠ j); σ²resto = total sin j
+			var cov float64
+			for l, v := range c[j] {
+				if l != j {
+					cov += v
+				}
+			}
+			_, resto := sumaComomentos(c, j)
+			if c[j][j] > 0 && resto > 0 {
+				it.CorrelacionItemTotal = cov / math.Sqrt(c[j][j]*resto)
+			}
+		}
+		if a, ok := alphaComomentos(c, s.N, j); ok {
+			it.AlphaSiSeElimina = a
+		}
+		out[j] = it
+	}
+	return out
+}
+
+// Sub regresa las sumas de las columnas cols (en ese orden), p. ej. una
+// subescala; son las mismas personas
+func (s SumasItems) Sub(cols []int) SumasItems {
+	out := NuevasSumasItems(len(cols))
+	out.N = s.N
+	for j, cj := range cols {
+		out.Suma[j] = s.Suma[cj]
+		for l, cl := range cols {
+			out.Cruzados[j][l] = s.Cruzados[cj][cl]
+		}
+	}
+	return out
+}
+
+// Correlaciones es MatrizCorrelaciones de la matriz que originó las sumas
+func (s SumasItems) Correlaciones() [][]float64 {
+	c := s.comomentos()
+	out := make([][]float64, len(c))
+	for j := range out {
+		out[j] = make([]float64, len(c))
+		for l := range out[j] {
+			switch {
+			case j == l:
+				out[j][l] = 1
+			case s.N < 2 || c[j][j] <= 0 || c[l][l] <= 0:
+				out[j][l] = math.NaN()
+			default:
+				out[j][l] = c[j][l] / math.Sqrt(c[j][j]*c[l][l])
+			}
+		}
+	}
+	return out
+}
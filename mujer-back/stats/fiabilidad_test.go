@@ -0,0 +1,209 @@
+package stats
+
+import (
+	"math"
+	"testing"
+)
+
+// 8 personas × 4 ítems; referencias calculadas aparte con las fórmulas
+// de libro (Cronbach 1951, Feldt 1965)
+var matrizEjemplo = [][]float64{
+	{4, 5, 4, 3},
+	{2, 3, 3, 2},
+	{5, 5, 4, 5},
+	{3, 4, 2, 3},
+	{1, 2, 2, 1},
+	{4, 4, 5, 4},
+	{2, 2, 3, 3},
+	{5, 4, 5, 5},
+}
+
+func TestAlphaCronbach(t *testing.T) {
+	casos := []struct {
+		nombre string
+		m      [][]float64
+		quiero float64
+		ok     bool
+	}{
+		{"ejemplo", matrizEjemplo, 0.9307593307593307, true},
+		{"ítems idénticos", [][]float64{{1, 1, 1}, {3, 3, 3}, {5, 5, 5}}, 1, true},
+		// ítems con correlación negativa (r = -0.6) y total con varianza:
+		// σ²ítems = 5/2 y 5/2, σ²total = 2 → alpha = 2 · (1 - 5/2) = -3
+		{"ítems con correlación negativa", [][]float64{{1, 4}, {2, 5}, {3, 1}, {4, 3}, {5, 2}}, -3, true},
+		// total constante: alpha indefinido
+		{"ítems opuestos", [][]float64{{1, 5}, {2, 4}, {3, 3}, {4, 2}}, 0, false},
+		{"un ítem", [][]float64{{1}, {2}, {3}}, 0, false},
+		{"una persona", [][]float64{{1, 2, 3}}, 0, false},
+		{"sin varianza total", [][]float64{{2, 2}, {2, 2}, {2, 2}}, 0, false},
+	}
+	for _, c := range casos {
+		t.Run(c.nombre, func(t *testing.T) {
+			got, ok := AlphaCronbach(c.m)
+			if ok != c.ok {
+				t.Fatalf("ok = %v, quiero %v (alpha %v)", ok, c.ok, got)
+			}
+			if ok && !cerca(got, c.quiero, 1e-12) {
+				t.Errorf("alpha = %v, quiero %v", got, c.quiero)
+			}
+		})
+	}
+}
+
+func TestAlphaDesdeVarianzasIgualAMatriz(t *testing.T) {
+	var vars []float64
+	for j := 0; j < 4; j++ {
+		v, _ := Varianza(columna(matrizEjemplo, j))
+		vars = append(vars, v)
+	}
+	vt, _ := Varianza(sumaFilas(matrizEjemplo, -1))
+	got, ok := AlphaDesdeVarianzas(vars, vt)
+	quiero, _ := AlphaCronbach(matrizEjemplo)
+	if !ok || !cerca(got, quiero, 1e-12) {
+		t.Errorf("AlphaDesdeVarianzas = %v, %v; quiero %v", got, ok, quiero)
+	}
+}
+
+func sumasDe(m [][]float64) SumasItems {
+	s := NuevasSumasItems(numItems(m))
+	for _, fila := range m {
+		s.AddFila(fila)
+	}
+	return s
+}
+
+func TestSumasItemsIgualAMatriz(t *testing.T) {
+	matrices := map[string][][]float64{
+		"ejemplo":              matrizEjemplo,
+		"correlación negativa": {{1, 4}, {2, 5}, {3, 1}, {4, 3}, {5, 2}},
+		"ítems opuestos":       {{1, 5}, {2, 4}, {3, 3}, {4, 2}},
+		"sin varianza total":   {{2, 2}, {2, 2}, {2, 2}},
+		"una persona":          {{1, 2, 3}},
+	}
+	mismo := func(a, b float64) bool {
+		return (math.IsNaN(a) && math.IsNaN(b)) || cerca(a, b, 1e-12)
+	}
+	for nombre, m := range matrices {
+		t.Run(nombre, func(t *testing.T) {
+			s := sumasDe(m)
+			got, ok := s.Alpha()
+			quiero, quieroOK := AlphaCronbach(m)
+			if ok != quieroOK || (ok && !cerca(got, quiero, 1e-12)) {
+				t.Errorf("Alpha = %v, %v; quiero %v, %v", got, ok, quiero, quieroOK)
+			}
+			if len(m) < 2 {
+				return
+			}
+			items := s.AnalisisItems()
+			for j, q := range AnalisisItems(m) {
+				g := items[j]
+				if !mismo(g.Media, q.Media) || !mismo(g.Varianza, q.Varianza) ||
+					!mismo(g.CorrelacionItemTotal, q.CorrelacionItemTotal) || !mismo(g.AlphaSiSeElimina, q.AlphaSiSeElimina) {
+					t.Errorf("ítem %d = %+v, quiero %+v", j, g, q)
+				}
+			}
+			corr := s.Correlaciones()
+			for j, fila := range MatrizCorrelaciones(m) {
+				for l, q := range fila {
+					if !mismo(corr[j][l], q) {
+						t.Errorf("r[%d][%d] = %v, quiero %v", j, l, corr[j][l], q)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestSumasItemsSub(t *testing.T) {
+	cols := []int{3, 1}
+	sub := make([][]float64, len(matrizEjemplo))
+	for i, fila := range matrizEjemplo {
+		sub[i] = []float64{fila[3], fila[1]}
+	}
+	got, ok := sumasDe(matrizEjemplo).Sub(cols).Alpha()
+	quiero, _ := AlphaCronbach(sub)
+	if !ok || !cerca(got, quiero, 1e-12) {
+		t.Errorf("Sub(%v).Alpha = %v, %v; quiero %v", cols, got, ok, quiero)
+	}
+}
+
+func TestIntervaloAlphaFeldt(t *testing.T) {
+	casos := []struct {
+		nombre string
+		alpha  float64
+		n, k   int
+		lo, hi float64
+		ok     bool
+	}{
+		{"ejemplo 8×4", 0.9307593307593307, 8, 4, 0.79445, 0.98445, true},
+		// alpha perfecto: el intervalo colapsa en 1
+		{"alpha 1", 1, 50, 5, 1, 1, true},
+		{"n=1", 0.8, 1, 4, 0, 0, false},
+		{"k=1", 0.8, 30, 1, 0, 0, false},
+	}
+	for _, c := range casos {
+		t.Run(c.nombre, func(t *testing.T) {
+			lo, hi, ok := IntervaloAlphaFeldt(c.alpha, c.n, c.k, 0.95)
+			if ok != c.ok {
+				t.Fatalf("ok = %v, quiero %v", ok, c.ok)
+			}
+			if ok && (!cerca(lo, c.lo, 1e-4) || !cerca(hi, c.hi, 1e-4)) {
+				t.Errorf("Feldt = [%v, %v], quiero [%v, %v]", lo, hi, c.lo, c.hi)
+			}
+		})
+	}
+}
+
+func TestAnalisisItems(t *testing.T) {
+	quiero := []struct {
+		media, corr, alphaSin float64
+	}{
+		{3.25, 0.9858156239720778, 0.8571428571428571},
+		{3.625, 0.7489037752971558, 0.9375},
+		{3.5, 0.763855412450558, 0.9331713244228433},
+		{3.25, 0.8782655052238401, 0.8961593172119489},
+	}
+	got := AnalisisItems(matrizEjemplo)
+	if len(got) != len(quiero) {
+		t.Fatalf("%d ítems, quiero %d", len(got), len(quiero))
+	}
+	for j, q := range quiero {
+		g := got[j]
+		if !cerca(g.Media, q.media, 1e-12) || !cerca(g.CorrelacionItemTotal, q.corr, 1e-12) || !cerca(g.AlphaSiSeElimina, q.alphaSin, 1e-12) {
+			t.Errorf("ítem %d = %+v, quiero %+v", j, g, q)
+		}
+	}
+
+	// con 2 ítems, quitar uno deja una escala de 1: alpha indefinido
+	dos := AnalisisItems([][]float64{{1, 2}, {2, 3}, {3, 5}})
+	for j, it := range dos {
+		if !math.IsNaN(it.AlphaSiSeElimina) {
+			t.Errorf("ítem %d: AlphaSiSeElimina = %v, quiero NaN", j, it.AlphaSiSeElimina)
+		}
+	}
+}
+
+func TestCorrelacion(t *testing.T) {
+	casos := []struct {
+		nombre string
+		x, y   []float64
+		quiero float64
+	}{
+		{"perfecta", []float64{1, 2, 3}, []float64{2, 4, 6}, 1},
+		{"inversa", []float64{1, 2, 3}, []float64{3, 2, 1}, -1},
+		{"ítems 1 y 2 del ejemplo", columna(matrizEjemplo, 0), columna(matrizEjemplo, 1), 0.8689085346857014},
+		{"sin varianza", []float64{1, 1, 1}, []float64{1, 2, 3}, math.NaN()},
+	}
+	for _, c := range casos {
+		t.Run(c.nombre, func(t *testing.T) {
+			got := Correlacion(c.x, c.y)
+			if math.IsNaN(c.quiero) != math.IsNaN(got) || (!math.IsNaN(got) && !cerca(got, c.quiero, 1e-12)) {
+				t.Errorf("Correlacion = %v, quiero %v", got, c.quiero)
+			}
+		})
+	}
+
+	m := MatrizCorrelaciones(matrizEjemplo)
+	if m[0][0] != 1 || !cerca(m[0][1], 0.8689085346857014, 1e-12) || m[1][0] != m[0][1] {
+		t.Errorf("MatrizCorrelaciones[0] = %v", m[0])
+	}
+}
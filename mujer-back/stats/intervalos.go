@@ -0,0 +1,42 @@
+package stats
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// IntervaloT es media ± t(1-γ/2, n-1)·de/√n. Con n < 2 o sin dispersión
+// regresa la media en ambos extremos.
+func IntervaloT(media, de float64, n int64, confianza float64) (float64, float64) {
+	if n < 2 || de == 0 {
+		return media, media
+	}
+	t := CuantilT(1-(1-confianza)/2, float64(n-1))
+	d := t * de / math.Sqrt(float64(n))
+	return media - d, media + d
+}
+
+// IntervaloBootstrap es el intervalo percentil del estadístico sobre
+// `replicas` remuestreos con reemplazo de x. rng fija la semilla para que
+// el mismo reporte dé el mismo intervalo.
+func IntervaloBootstrap(x []float64, estadistico func([]float64) float64, replicas int, confianza float64, rng *rand.Rand) (float64, float64) {
+	if len(x) == 0 {
+		return 0, 0
+	}
+	if len(x) == 1 || replicas < 1 {
+		v := estadistico(x)
+		return v, v
+	}
+	muestra := make([]float64, len(x))
+	valores := make([]float64, replicas)
+	for r := range valores {
+		for i := range muestra {
+			muestra[i] = x[rng.Intn(len(x))]
+		}
+		valores[r] = estadistico(muestra)
+	}
+	sort.Float64s(valores)
+	g := (1 - confianza) / 2
+	return Percentil(valores, g), Percentil(valores, 1-g)
+}
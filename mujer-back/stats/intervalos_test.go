@@ -0,0 +1,72 @@
+package stats
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestIntervaloT(t *testing.T) {
+	casos := []struct {
+		nombre    string
+		media, de float64
+		n         int64
+		conf      float64
+		lo, hi    float64
+	}{
+		// 7 encuestas: t(0.975, 6) = 2.446912, no 1.96
+		{"n chico", 3, 1, 7, 0.95, 3 - 2.446912/math.Sqrt(7), 3 + 2.446912/math.Sqrt(7)},
+		{"n=11", 2.5, 0.8, 11, 0.95, 2.5 - 2.228139*0.8/math.Sqrt(11), 2.5 + 2.228139*0.8/math.Sqrt(11)},
+		{"90%", 0, 2, 11, 0.90, -1.812461 * 2 / math.Sqrt(11), 1.812461 * 2 / math.Sqrt(11)},
+		{"sin dispersión", 4, 0, 30, 0.95, 4, 4},
+		{"n=1", 4, 1, 1, 0.95, 4, 4},
+	}
+	for _, c := range casos {
+		t.Run(c.nombre, func(t *testing.T) {
+			lo, hi := IntervaloT(c.media, c.de, c.n, c.conf)
+			if !cerca(lo, c.lo, 1e-5) || !cerca(hi, c.hi, 1e-5) {
+				t.Errorf("IntervaloT = [%v, %v], quiero [%v, %v]", lo, hi, c.lo, c.hi)
+			}
+		})
+	}
+}
+
+func TestIntervaloBootstrap(t *testing.T) {
+	normal := make([]float64, 400)
+	gen := rand.New(rand.NewSource(7))
+	for i := range normal {
+		normal[i] = 3 + gen.NormFloat64()
+	}
+
+	casos := []struct {
+		nombre string
+		x      []float64
+		lo, hi float64
+		tol    float64
+	}{
+		{"constante", []float64{2, 2, 2, 2}, 2, 2, 0},
+		{"un valor", []float64{4}, 4, 4, 0},
+		{"vacía", nil, 0, 0, 0},
+		// con n grande el percentil bootstrap de la media se acerca al IC t
+		{"normal n=400", normal, 3 - 1.966*DesvEst(normal)/20 + (Media(normal) - 3), 3 + 1.966*DesvEst(normal)/20 + (Media(normal) - 3), 0.02},
+	}
+	for _, c := range casos {
+		t.Run(c.nombre, func(t *testing.T) {
+			lo, hi := IntervaloBootstrap(c.x, Media, 2000, 0.95, rand.New(rand.NewSource(1)))
+			if !cerca(lo, c.lo, c.tol) || !cerca(hi, c.hi, c.tol) {
+				t.Errorf("IntervaloBootstrap = [%v, %v], quiero [%v, %v] ± %v", lo, hi, c.lo, c.hi, c.tol)
+			}
+		})
+	}
+
+	// misma semilla, mismo intervalo
+	x := []float64{1, 2, 2, 3, 5, 4, 4, 1}
+	lo1, hi1 := IntervaloBootstrap(x, Media, 500, 0.95, rand.New(rand.NewSource(42)))
+	lo2, hi2 := IntervaloBootstrap(x, Media, 500, 0.95, rand.New(rand.NewSource(42)))
+	if lo1 != lo2 || hi1 != hi2 {
+		t.Errorf("no es reproducible: [%v, %v] vs [%v, %v]", lo1, hi1, lo2, hi2)
+	}
+	if m := Media(x); lo1 > m || hi1 < m {
+		t.Errorf("[%v, %v] no contiene la media %v", lo1, hi1, m)
+	}
+}
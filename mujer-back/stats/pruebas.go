@@ -0,0 +1,139 @@
+package stats
+
+import (
+	"math"
+	"sort"
+)
+
+// Pruebas entre dos muestras independientes A (referencia) y B. Los
+// estadísticos van en el sentido B - A.
+
+// ResultadoT es la prueba t de Welch
+type ResultadoT struct {
+	T  float64
+	GL float64 // Welch–Satterthwaite
+	P  float64 // bilateral
+}
+
+// Welch: t = (x̄B - x̄A) / √(s²A/nA + s²B/nB). ok=false con menos de 2
+// observaciones por grupo o sin varianza.
+func Welch(a, b []float64) (ResultadoT, bool) {
+	na, nb := float64(len(a)), float64(len(b))
+	if na < 2 || nb < 2 {
+		return ResultadoT{}, false
+	}
+	va, _ := Varianza(a)
+	vb, _ := Varianza(b)
+	sa, sb := va/na, vb/nb
+	se2 := sa + sb
+	if se2 == 0 {
+		return ResultadoT{}, false
+	}
+	t := (Media(b) - Media(a)) / math.Sqrt(se2)
+	gl := se2 * se2 / (sa*sa/(na-1) + sb*sb/(nb-1))
+	return ResultadoT{T: t, GL: gl, P: PValorT(t, gl)}, true
+}
+
+// ResultadoU es la prueba de Mann–Whitney
+type ResultadoU struct {
+	U float64 // pares (b, a) con b > a; empates cuentan 1/2
+	Z float64 // aproximación normal con corrección por empates y continuidad
+	P float64 // bilateral
+}
+
+// MannWhitney con rangos promedio en empates (como wilcox.test(correct=TRUE))
+func MannWhitney(a, b []float64) ResultadoU {
+	type obs struct {
+		v float64
+		b bool
+	}
+	todas := make([]obs, 0, len(a)+len(b))
+	for _, v := range a {
+		todas = append(todas, obs{v, false})
+	}
+	for _, v := range b {
+		todas = append(todas, obs{v, true})
+	}
+	sort.Slice(todas, func(i, j int) bool { return todas[i].v < todas[j].v })
+
+	n := float64(len(todas))
+	var rb, empates float64
+	for i := 0; i < len(todas); {
+		j := i
+		for j < len(todas) && todas[j].v == todas[i].v {
+			j++
+		}
+		rango := float64(i+j+1) / 2
+		for _, o := range todas[i:j] {
+			if o.b {
+				rb += rango
+			}
+		}
+		t := float64(j - i)
+		empates += t*t*t - t
+		i = j
+	}
+
+	na, nb := float64(len(a)), float64(len(b))
+	res := ResultadoU{U: rb - nb*(nb+1)/2, P: 1}
+	if na == 0 || nb == 0 {
+		return res
+	}
+	sigma := math.Sqrt(na * nb / 12 * ((n + 1) - empates/(n*(n-1))))
+	if sigma == 0 {
+		return res
+	}
+	d := res.U - na*nb/2
+	switch {
+	case d > 0:
+		d = math.Max(0, d-0.5)
+	case d < 0:
+		d = math.Min(0, d+0.5)
+	}
+	res.Z = d / sigma
+	res.P = math.Erfc(math.Abs(res.Z) / math.Sqrt2)
+	return res
+}
+
+// CohenD con desviación estándar combinada. ok=false sin dispersión.
+func CohenD(a, b []float64) (float64, bool) {
+	na, nb := float64(len(a)), float64(len(b))
+	if na < 1 || nb < 1 || na+nb < 3 {
+		return 0, false
+	}
+	va, _ := Varianza(a)
+	vb, _ := Varianza(b)
+	sp := math.Sqrt(((na-1)*va + (nb-1)*vb) / (na + nb - 2))
+	if sp == 0 {
+		return 0, false
+	}
+	return (Media(b) - Media(a)) / sp, true
+}
+
+// CliffDelta = P(B > A) - P(B < A)
+func CliffDelta(a, b []float64) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	u := MannWhitney(a, b).U
+	return 2*u/float64(len(a)*len(b)) - 1
+}
+
+// Holm regresa los p ajustados (step-down de Holm–Bonferroni) en el mismo
+// orden, como p.adjust(method = "holm")
+func Holm(ps []float64) []float64 {
+	m := len(ps)
+	idx := make([]int, m)
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.SliceStable(idx, func(i, j int) bool { return ps[idx[i]] < ps[idx[j]] })
+
+	out := make([]float64, m)
+	prev := 0.0
+	for r, i := range idx {
+		prev = math.Max(prev, math.Min(1, float64(m-r)*ps[i]))
+		out[i] = prev
+	}
+	return out
+}
@@ -0,0 +1,117 @@
+package stats
+
+import "testing"
+
+// datos "sleep" de R (Student 1908), usados en la documentación de
+// t.test y wilcox.test
+var (
+	sleep1 = []float64{0.7, -1.6, -0.2, -1.2, -0.1, 3.4, 3.7, 0.8, 0.0, 2.0}
+	sleep2 = []float64{1.9, 0.8, 1.1, 0.1, -0.1, 4.4, 5.5, 1.6, 4.6, 3.4}
+)
+
+func TestWelch(t *testing.T) {
+	casos := []struct {
+		nombre   string
+		a, b     []float64
+		quiero   ResultadoT
+		ok       bool
+		tolerado float64
+	}{
+		// t.test(extra ~ group, data = sleep): t = -1.8608, df = 17.776, p = 0.07939
+		{"sleep", sleep1, sleep2, ResultadoT{T: 1.860813, GL: 17.77647, P: 0.07939414}, true, 1e-5},
+		{"simétrico", sleep2, sleep1, ResultadoT{T: -1.860813, GL: 17.77647, P: 0.07939414}, true, 1e-5},
+		{"n=1", []float64{1}, []float64{1, 2}, ResultadoT{}, false, 0},
+		{"sin varianza", []float64{2, 2}, []float64{3, 3}, ResultadoT{}, false, 0},
+	}
+	for _, c := range casos {
+		t.Run(c.nombre, func(t *testing.T) {
+			got, ok := Welch(c.a, c.b)
+			if ok != c.ok {
+				t.Fatalf("ok = %v, quiero %v", ok, c.ok)
+			}
+			if ok && (!cerca(got.T, c.quiero.T, c.tolerado) || !cerca(got.GL, c.quiero.GL, c.tolerado) || !cerca(got.P, c.quiero.P, c.tolerado)) {
+				t.Errorf("Welch = %+v, quiero %+v", got, c.quiero)
+			}
+		})
+	}
+}
+
+func TestMannWhitney(t *testing.T) {
+	casos := []struct {
+		nombre string
+		a, b   []float64
+		u, p   float64
+	}{
+		// wilcox.test(extra ~ group, data = sleep): W = 25.5, p = 0.06933
+		// (W es la U del primer grupo; aquí U es la de b: 10·10 - 25.5)
+		{"sleep", sleep1, sleep2, 74.5, 0.06932758},
+		// z = (9 - 4.5 - 0.5) / √5.25 = 1.745743
+		{"sin traslape", []float64{1, 2, 3}, []float64{4, 5, 6}, 9, 0.0808556},
+		{"iguales", []float64{1, 2, 3}, []float64{1, 2, 3}, 4.5, 1},
+		{"todo empatado", []float64{2, 2}, []float64{2, 2}, 2, 1},
+	}
+	for _, c := range casos {
+		t.Run(c.nombre, func(t *testing.T) {
+			got := MannWhitney(c.a, c.b)
+			if !cerca(got.U, c.u, 1e-12) || !cerca(got.P, c.p, 1e-6) {
+				t.Errorf("MannWhitney = %+v, quiero U %v p %v", got, c.u, c.p)
+			}
+		})
+	}
+}
+
+func TestTamanosDeEfecto(t *testing.T) {
+	casos := []struct {
+		nombre string
+		a, b   []float64
+		d      float64
+		dOK    bool
+		delta  float64
+	}{
+		{"sleep", sleep1, sleep2, 0.8321, true, 0.49},
+		{"sin traslape", []float64{1, 2, 3}, []float64{4, 5, 6}, 3, true, 1},
+		{"invertido", []float64{4, 5, 6}, []float64{1, 2, 3}, -3, true, -1},
+		{"iguales", []float64{1, 2, 3}, []float64{1, 2, 3}, 0, true, 0},
+		{"sin dispersión", []float64{2, 2}, []float64{2, 2}, 0, false, 0},
+	}
+	for _, c := range casos {
+		t.Run(c.nombre, func(t *testing.T) {
+			d, ok := CohenD(c.a, c.b)
+			if ok != c.dOK || !cerca(d, c.d, 1e-4) {
+				t.Errorf("CohenD = %v, %v; quiero %v, %v", d, ok, c.d, c.dOK)
+			}
+			if got := CliffDelta(c.a, c.b); !cerca(got, c.delta, 1e-12) {
+				t.Errorf("CliffDelta = %v, quiero %v", got, c.delta)
+			}
+		})
+	}
+}
+
+func TestHolm(t *testing.T) {
+	casos := []struct {
+		nombre string
+		ps     []float64
+		quiero []float64
+	}{
+		// p.adjust(c(0.01, 0.04, 0.03, 0.005), "holm")
+		{"R", []float64{0.01, 0.04, 0.03, 0.005}, []float64{0.03, 0.06, 0.06, 0.02}},
+		{"tope en 1", []float64{0.5, 0.6}, []float64{1, 1}},
+		{"uno", []float64{0.04}, []float64{0.04}},
+		{"empates", []float64{0.01, 0.01, 0.2}, []float64{0.03, 0.03, 0.2}},
+		{"vacío", nil, []float64{}},
+	}
+	for _, c := range casos {
+		t.Run(c.nombre, func(t *testing.T) {
+			got := Holm(c.ps)
+			if len(got) != len(c.quiero) {
+				t.Fatalf("Holm = %v, quiero %v", got, c.quiero)
+			}
+			for i := range got {
+				if !cerca(got[i], c.quiero[i], 1e-12) {
+					t.Errorf("Holm = %v, quiero %v", got, c.quiero)
+					break
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,232 @@
+"use client";
+
+import { useCallback, useEffect, useState } from "react";
+import { api } from "@/lib/api";
+
+import { Button } from "@/components/ui/button";
+
+import { Play, RefreshCw } from "lucide-react";
+
+type Estado = "activa" | "resuelta";
+type Severidad = "baja" | "media" | "alta";
+
+type Alerta = {
+  id: number;
+  centro_id: number;
+  centro_nombre: string;
+  regla_id: string;
+  regla_nombre: string;
+  severidad: Severidad;
+  condicion: "umbral" | "alza_anual";
+  metrica: string;
+  tipo_num?: number;
+  tipo_nombre?: string;
+  year: number;
+  estado: Estado;
+  valor: number;
+  referencia?: number;
+  umbral: number;
+  encuestas: number;
+  mensaje: string;
+  detectada_at: string;
+  actualizada_at: string;
+  resuelta_at?: string;
+};
+
+type Regla = {
+  id: string;
+  nombre: string;
+  descripcion?: string;
+  severidad: Severidad;
+  metrica: string;
+  tipo_num?: number;
+  condicion: "umbral" | "alza_anual";
+  operador?: string;
+  valor: number;
+  min_encuestas?: number;
+};
+
+type ListResponse = {
+  items: Alerta[];
+  total: number;
+  conteos: Record<Estado, number>;
+  reglas: Regla[];
+};
+
+type Evaluacion = { centros: number; activas: number; nuevas: number; resueltas: number };
+
+const ESTADOS: { value: Estado; label: string }[] = [
+  { value: "activa", label: "Activas" },
+  { value: "resuelta", label: "Resueltas" },
+];
+
+const SEVERIDAD_CLASS: Record<Severidad, string> = {
+  alta: "bg-red-100 text-red-800",
+  media: "bg-amber-100 text-amber-900",
+  baja: "bg-slate-100 text-slate-700",
+};
+
+const PAGE = 50;
+
+function cx(...v: Array<string | false | null | undefined>) {
+  return v.filter(Boolean).join(" ");
+}
+
+function describirRegla(r: Regla) {
+  const ambito = r.tipo_num ? `tipo ${r.tipo_num}` : "todos los tipos";
+  if (r.condicion === "alza_anual") return `${r.metrica} (${ambito}) sube más de ${r.valor} vs. año anterior`;
+  return `${r.metrica} (${ambito}) ${r.operador} ${r.valor}`;
+}
+
+export default function AdminAlertasPage() {
+  const [estado, setEstado] = useState<Estado>("activa");
+  const [offset, setOffset] = useState(0);
+  const [data, setData] = useState<ListResponse | null>(null);
+  const [loading, setLoading] = useState(true);
+  const [evaluando, setEvaluando] = useState(false);
+  const [err, setErr] = useState("");
+  const [msg, setMsg] = useState("");
+
+  const load = useCallback(async () => {
+    setErr("");
+    setLoading(true);
+    try {
+      const qs = new URLSearchParams({ estado, limit: String(PAGE), offset: String(offset) });
+      const res = await api<ListResponse>(`/api/admin/alertas?${qs.toString()}`);
+      setData(res);
+    } catch (e) {
+      setErr(e instanceof Error ? e.message : "No se pudo cargar");
+    } finally {
+      setLoading(false);
+    }
+  }, [estado, offset]);
+
+  useEffect(() => {
+    load();
+  }, [load]);
+
+  async function evaluar() {
+    setEvaluando(true);
+    setErr("");
+    setMsg("");
+    try {
+      const r = await api<Evaluacion>("/api/admin/alertas/evaluar", { method: "POST" });
+      setMsg(`${r.centros} centros evaluados: ${r.activas} alertas activas (${r.nuevas} nuevas), ${r.resueltas} resueltas.`);
+      await load();
+    } catch (e) {
+      const m = e instanceof Error ? e.message : "";
+      setErr(m.includes("sin_reglas") ? "No hay reglas de alerta configuradas." : m || "No se pudo evaluar");
+    } finally {
+      setEvaluando(false);
+    }
+  }
+
+  const total = data?.total ?? 0;
+
+  return (
+    <div className="grid gap-4">
+      <div className="flex flex-wrap items-center gap-2">
+        {ESTADOS.map((e) => (
+          <Button
+            key={e.value}
+            variant={estado === e.value ? "default" : "outline"}
+            className="rounded-full font-semibold"
+            style={estado === e.value ? { backgroundColor: "#7F017F" } : { borderColor: "#7F017F", color: "#7F017F" }}
+            onClick={() => {
+              setEstado(e.value);
+              setOffset(0);
+            }}
+          >
+            {e.label}
+            <span className="ml-2 rounded-full bg-white/20 px-2 text-xs">{data?.conteos?.[e.value] ?? 0}</span>
+          </Button>
+        ))}
+        <div className="ml-auto flex gap-2">
+          <Button variant="outline" className="rounded-full" onClick={evaluar} disabled={evaluando}>
+            <Play className="mr-2 h-4 w-4" />
+            {evaluando ? "Evaluando…" : "Evaluar ahora"}
+          </Button>
+          <Button variant="outline" className="rounded-full" onClick={load} disabled={loading}>
+            <RefreshCw className={cx("mr-2 h-4 w-4", loading && "animate-spin")} />
+            Recargar
+          </Button>
+        </div>
+      </div>
+
+      {err && <p className="rounded-xl border border-red-200 bg-red-50 p-3 text-sm text-red-700">{err}</p>}
+      {msg && <p className="rounded-xl border border-emerald-200 bg-emerald-50 p-3 text-sm text-emerald-800">{msg}</p>}
+
+      {!loading && data && data.items.length === 0 && (
+        <div className="rounded-2xl border border-dashed border-neutral-300 bg-white p-5 text-sm text-neutral-600">
+          No hay alertas en este estado.
+        </div>
+      )}
+
+      {data && data.items.length > 0 && (
+        <div className="overflow-x-auto rounded-2xl border border-neutral-200 bg-white shadow-sm">
+          <table className="w-full text-sm">
+            <thead className="bg-neutral-50 text-left text-xs uppercase tracking-wide text-neutral-500">
+              <tr>
+                <th className="px-4 py-3">Severidad</th>
+                <th className="px-4 py-3">Centro</th>
+                <th className="px-4 py-3">Regla</th>
+                <th className="px-4 py-3">Detalle</th>
+                <th className="px-4 py-3">Año</th>
+                <th className="px-4 py-3">{estado === "activa" ? "Detectada" : "Resuelta"}</th>
+              </tr>
+            </thead>
+            <tbody>
+              {data.items.map((a) => (
+                <tr key={a.id} className="border-t border-neutral-100 align-top">
+                  <td className="px-4 py-3">
+                    <span className={cx("rounded-full px-2 py-0.5 text-xs font-semibold", SEVERIDAD_CLASS[a.severidad])}>
+                      {a.severidad}
+                    </span>
+                  </td>
+                  <td className="px-4 py-3 font-semibold text-neutral-800">{a.centro_nombre}</td>
+                  <td className="px-4 py-3 text-neutral-700">{a.regla_nombre}</td>
+                  <td className="px-4 py-3 text-neutral-600">{a.mensaje}</td>
+                  <td className="px-4 py-3 text-neutral-600">{a.year}</td>
+                  <td className="px-4 py-3 whitespace-nowrap text-neutral-500">
+                    {estado === "activa" ? a.detectada_at : a.resuelta_at}
+                  </td>
+                </tr>
+              ))}
+            </tbody>
+          </table>
+        </div>
+      )}
+
+      {total > PAGE && (
+        <div className="flex items-center justify-center gap-3 text-sm text-neutral-600">
+          <Button variant="outline" className="rounded-full" disabled={offset === 0} onClick={() => setOffset(Math.max(0, offset - PAGE))}>
+            Anterior
+          </Button>
+          <span>
+            {offset + 1}–{Math.min(offset + PAGE, total)} de {total}
+          </span>
+          <Button variant="outline" className="rounded-full" disabled={offset + PAGE >= total} onClick={() => setOffset(offset + PAGE)}>
+            Siguiente
+          </Button>
+        </div>
+      )}
+
+      {data && data.reglas.length > 0 && (
+        <div className="rounded-2xl border border-neutral-200 bg-white p-5 shadow-sm">
+          <div className="text-sm font-semibold text-neutral-800">Reglas configuradas</div>
+          <ul className="mt-3 grid gap-2 text-sm text-neutral-600">
+            {data.reglas.map((r) => (
+              <li key={r.id}>
+                <span className={cx("mr-2 rounded-full px-2 py-0.5 text-xs font-semibold", SEVERIDAD_CLASS[r.severidad])}>
+                  {r.severidad}
+                </span>
+                <span className="font-semibold text-neutral-800">{r.nombre}</span>
+                <span className="ml-2 text-neutral-500">{describirRegla(r)}</span>
+              </li>
+            ))}
+          </ul>
+        </div>
+      )}
+    </div>
+  );
+}
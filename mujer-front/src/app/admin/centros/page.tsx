@@ -37,6 +37,8 @@ type Centro = {
   ciudad?: string;
   estado?: string;
   activo?: boolean;
+  estado_clave?: string; // ✅ NUEVO: claves INEGI
+  municipio_clave?: string;
 };
 
 type CentroForm = {
@@ -45,6 +47,7 @@ type CentroForm = {
   clave: string;
   ciudad: string;
   estado: string;
+  municipio_clave: string;
 };
 
 function readAuth(): { token: string; user: AuthUser | null } {
@@ -86,6 +89,7 @@ export default function AdminCentrosPage() {
     clave: "",
     ciudad: "",
     estado: "",
+    municipio_clave: "",
   });
 
   // Guard (extra): si no hay auth, regresa a home
@@ -143,6 +147,7 @@ export default function AdminCentrosPage() {
       clave: "",
       ciudad: "",
       estado: "",
+      municipio_clave: "",
     });
     setOpen(true);
   }
@@ -156,6 +161,7 @@ export default function AdminCentrosPage() {
       clave: c.clave || "",
       ciudad: c.ciudad || "",
       estado: c.estado || "",
+      municipio_clave: c.municipio_clave || "",
     });
     setOpen(true);
   }
@@ -182,6 +188,7 @@ export default function AdminCentrosPage() {
             clave: form.clave.trim(),
             ciudad: form.ciudad.trim(),
             estado: form.estado.trim(),
+            municipio_clave: form.municipio_clave.trim(),
           }),
         });
       } else {
@@ -194,6 +201,7 @@ export default function AdminCentrosPage() {
             clave: form.clave.trim(),
             ciudad: form.ciudad.trim(),
             estado: form.estado.trim(),
+            municipio_clave: form.municipio_clave.trim(),
           }),
         });
       }
@@ -369,6 +377,22 @@ export default function AdminCentrosPage() {
                   />
                 </div>
 
+                {/* ✅ NUEVO: vínculo INEGI; vacío = se liga por estado/ciudad */}
+                <div className="grid gap-2">
+                  <Label htmlFor="municipio_clave">Municipio (clave INEGI)</Label>
+                  <Input
+                    id="municipio_clave"
+                    value={form.municipio_clave}
+                    onChange={(e) =>
+                      setForm((f) => ({ ...f, municipio_clave: e.target.value }))
+                    }
+                    placeholder="Ej. 09003 (opcional)"
+                    inputMode="numeric"
+                    maxLength={5}
+                    disabled={saving}
+                  />
+                </div>
+
                 {err ? <p className="text-sm text-red-600">{err}</p> : null}
 
                 <div className="flex items-center justify-end gap-2 pt-1">
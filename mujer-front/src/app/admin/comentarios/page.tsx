@@ -0,0 +1,264 @@
+"use client";
+
+import { useCallback, useEffect, useState } from "react";
+import { api } from "@/lib/api";
+
+import { Button } from "@/components/ui/button";
+
+import { Check, EyeOff, RefreshCw, Scissors, Undo2 } from "lucide-react";
+
+type Estado = "pendiente" | "aprobado" | "redactado" | "oculto";
+
+type Hallazgo = {
+  tipo: "email" | "telefono" | "curp" | "rfc" | "nombre";
+  texto: string;
+  inicio: number;
+  fin: number;
+};
+
+type Comentario = {
+  encuesta_id: string;
+  centro_id: number;
+  centro_nombre: string;
+  fecha: string;
+  estado: Estado;
+  texto: string;
+  texto_publicado?: string;
+  hallazgos: Hallazgo[];
+  sugerido: string;
+  md5: string;
+  revisado_por?: string;
+  revisado_at?: string;
+  updated_at: string;
+};
+
+type ListResponse = {
+  items: Comentario[];
+  total: number;
+  conteos: Record<Estado, number>;
+};
+
+const ESTADOS: { value: Estado; label: string }[] = [
+  { value: "pendiente", label: "Pendientes" },
+  { value: "aprobado", label: "Aprobados" },
+  { value: "redactado", label: "Redactados" },
+  { value: "oculto", label: "Ocultos" },
+];
+
+const TIPO_LABEL: Record<Hallazgo["tipo"], string> = {
+  email: "correo",
+  telefono: "teléfono",
+  curp: "CURP",
+  rfc: "RFC",
+  nombre: "nombre",
+};
+
+const PAGE = 20;
+
+// al aprobar se publica el original; la propuesta de redacción parte de lo ya redactado o de la sugerencia
+function textoRedactado(c: Comentario) {
+  return c.estado === "redactado" && c.texto_publicado ? c.texto_publicado : c.sugerido;
+}
+
+function cx(...v: Array<string | false | null | undefined>) {
+  return v.filter(Boolean).join(" ");
+}
+
+// Resalta los hallazgos (posiciones en caracteres) dentro del texto original
+function Resaltado({ texto, hallazgos }: { texto: string; hallazgos: Hallazgo[] }) {
+  const chars = Array.from(texto);
+  const out: React.ReactNode[] = [];
+  let pos = 0;
+  hallazgos.forEach((h, i) => {
+    if (h.inicio < pos) return;
+    out.push(chars.slice(pos, h.inicio).join(""));
+    out.push(
+      <mark key={i} className="rounded bg-amber-100 px-0.5 text-amber-900" title={TIPO_LABEL[h.tipo]}>
+        {chars.slice(h.inicio, h.fin).join("")}
+      </mark>
+    );
+    pos = h.fin;
+  });
+  out.push(chars.slice(pos).join(""));
+  return <p className="whitespace-pre-wrap text-sm text-neutral-800">{out}</p>;
+}
+
+export default function AdminComentariosPage() {
+  const [estado, setEstado] = useState<Estado>("pendiente");
+  const [offset, setOffset] = useState(0);
+  const [data, setData] = useState<ListResponse | null>(null);
+  const [loading, setLoading] = useState(true);
+  const [err, setErr] = useState("");
+
+  // texto redactado en edición por comentario
+  const [edits, setEdits] = useState<Record<string, string>>({});
+  const [busy, setBusy] = useState<string | null>(null);
+
+  const load = useCallback(async () => {
+    setErr("");
+    setLoading(true);
+    try {
+      const qs = new URLSearchParams({ estado, limit: String(PAGE), offset: String(offset) });
+      const res = await api<ListResponse>(`/api/admin/comentarios?${qs.toString()}`);
+      setData(res);
+      setEdits({});
+    } catch (e) {
+      setErr(e instanceof Error ? e.message : "No se pudo cargar");
+    } finally {
+      setLoading(false);
+    }
+  }, [estado, offset]);
+
+  useEffect(() => {
+    load();
+  }, [load]);
+
+  async function revisar(c: Comentario, nuevo: Estado) {
+    setBusy(c.encuesta_id);
+    setErr("");
+    try {
+      const body: Record<string, string> = { estado: nuevo, md5: c.md5 };
+      if (nuevo === "redactado") body.texto_publicado = edits[c.encuesta_id] ?? textoRedactado(c);
+      await api<Comentario>(`/api/admin/comentarios/${c.encuesta_id}`, {
+        method: "PUT",
+        body: JSON.stringify(body),
+      });
+      await load();
+    } catch (e) {
+      const msg = e instanceof Error ? e.message : "";
+      setErr(msg.includes("comentario_modificado") ? "El comentario cambió mientras lo revisabas; recarga la lista." : msg || "No se pudo guardar");
+    } finally {
+      setBusy(null);
+    }
+  }
+
+  const total = data?.total ?? 0;
+
+  return (
+    <div className="grid gap-4">
+      <div className="flex flex-wrap items-center gap-2">
+        {ESTADOS.map((e) => (
+          <Button
+            key={e.value}
+            variant={estado === e.value ? "default" : "outline"}
+            className="rounded-full font-semibold"
+            style={estado === e.value ? { backgroundColor: "#7F017F" } : { borderColor: "#7F017F", color: "#7F017F" }}
+            onClick={() => {
+              setEstado(e.value);
+              setOffset(0);
+            }}
+          >
+            {e.label}
+            <span className="ml-2 rounded-full bg-white/20 px-2 text-xs">{data?.conteos?.[e.value] ?? 0}</span>
+          </Button>
+        ))}
+        <Button variant="outline" className="ml-auto rounded-full" onClick={load} disabled={loading}>
+          <RefreshCw className={cx("mr-2 h-4 w-4", loading && "animate-spin")} />
+          Recargar
+        </Button>
+      </div>
+
+      {err && <p className="rounded-xl border border-red-200 bg-red-50 p-3 text-sm text-red-700">{err}</p>}
+
+      {!loading && data && data.items.length === 0 && (
+        <div className="rounded-2xl border border-dashed border-neutral-300 bg-white p-5 text-sm text-neutral-600">
+          No hay comentarios en este estado.
+        </div>
+      )}
+
+      {data?.items.map((c) => (
+        <div key={c.encuesta_id} className="rounded-2xl border border-neutral-200 bg-white p-5 shadow-sm">
+          <div className="flex flex-wrap items-center gap-x-3 gap-y-1 text-xs text-neutral-500">
+            <span className="font-semibold text-neutral-800">{c.centro_nombre}</span>
+            <span>{c.fecha}</span>
+            {c.revisado_at && (
+              <span>
+                Revisado {c.revisado_at}
+                {c.revisado_por ? ` por ${c.revisado_por}` : ""}
+              </span>
+            )}
+            {c.hallazgos.length > 0 && (
+              <span className="rounded-full bg-amber-100 px-2 py-0.5 font-semibold text-amber-900">
+                {c.hallazgos.length} posible{c.hallazgos.length === 1 ? "" : "s"} dato{c.hallazgos.length === 1 ? "" : "s"} personal
+                {c.hallazgos.length === 1 ? "" : "es"}
+              </span>
+            )}
+          </div>
+
+          <div className="mt-3">
+            <Resaltado texto={c.texto} hallazgos={c.hallazgos} />
+          </div>
+
+          <label className="mt-4 block text-xs font-semibold text-neutral-600">Texto a publicar si se redacta</label>
+          <textarea
+            className="mt-1 w-full rounded-xl border border-neutral-300 p-3 text-sm"
+            rows={3}
+            value={edits[c.encuesta_id] ?? textoRedactado(c)}
+            onChange={(e) => setEdits((m) => ({ ...m, [c.encuesta_id]: e.target.value }))}
+          />
+
+          <div className="mt-3 flex flex-wrap gap-2">
+            <Button
+              className="rounded-full font-semibold"
+              style={{ backgroundColor: "#7F017F" }}
+              disabled={busy === c.encuesta_id}
+              onClick={() => revisar(c, "aprobado")}
+            >
+              <Check className="mr-2 h-4 w-4" />
+              Aprobar original
+            </Button>
+            <Button
+              variant="outline"
+              className="rounded-full font-semibold"
+              style={{ borderColor: "#7F017F", color: "#7F017F" }}
+              disabled={busy === c.encuesta_id}
+              onClick={() => revisar(c, "redactado")}
+            >
+              <Scissors className="mr-2 h-4 w-4" />
+              Publicar redactado
+            </Button>
+            <Button
+              variant="outline"
+              className="rounded-full"
+              disabled={busy === c.encuesta_id}
+              onClick={() => revisar(c, "oculto")}
+            >
+              <EyeOff className="mr-2 h-4 w-4" />
+              Ocultar
+            </Button>
+            {c.estado !== "pendiente" && (
+              <Button
+                variant="ghost"
+                className="rounded-full"
+                disabled={busy === c.encuesta_id}
+                onClick={() => revisar(c, "pendiente")}
+              >
+                <Undo2 className="mr-2 h-4 w-4" />
+                Regresar a pendiente
+              </Button>
+            )}
+          </div>
+        </div>
+      ))}
+
+      {total > PAGE && (
+        <div className="flex items-center justify-center gap-3 text-sm text-neutral-600">
+          <Button variant="outline" className="rounded-full" disabled={offset === 0} onClick={() => setOffset(Math.max(0, offset - PAGE))}>
+            Anterior
+          </Button>
+          <span>
+            {offset + 1}–{Math.min(offset + PAGE, total)} de {total}
+          </span>
+          <Button
+            variant="outline"
+            className="rounded-full"
+            disabled={offset + PAGE >= total}
+            onClick={() => setOffset(offset + PAGE)}
+          >
+            Siguiente
+          </Button>
+        </div>
+      )}
+    </div>
+  );
+}
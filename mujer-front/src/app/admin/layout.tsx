@@ -7,9 +7,14 @@ import { Button } from "@/components/ui/button";
 import { Separator } from "@/components/ui/separator";
 
 import {
+  BarChart3,
+  BellRing,
   Building2,
+  FlaskConical,
   LayoutDashboard,
   LogOut,
+  Map as MapIcon,
+  MessageSquareWarning,
   Settings,
   ShieldCheck,
   Users,
@@ -70,6 +75,36 @@ function headerFor(pathname: string) {
       desc: "Gestión de usuarios, roles y asignación a centros.",
     };
   }
+  if (pathname.startsWith("/admin/comentarios")) {
+    return {
+      title: "Comentarios",
+      desc: "Moderación de comentarios abiertos antes de llegar al reporte del centro.",
+    };
+  }
+  if (pathname.startsWith("/admin/alertas")) {
+    return {
+      title: "Alertas",
+      desc: "Centros que cruzaron un umbral de riesgo configurado.",
+    };
+  }
+  if (pathname.startsWith("/admin/resultados")) {
+    return {
+      title: "Resultados",
+      desc: "Comparación entre centros por tipo, estado y ciudad, con umbral de anonimato.",
+    };
+  }
+  if (pathname.startsWith("/admin/mapa")) {
+    return {
+      title: "Mapa",
+      desc: "Índices agregados por estado y municipio (claves INEGI), con umbral de anonimato.",
+    };
+  }
+  if (pathname.startsWith("/admin/psicometria")) {
+    return {
+      title: "Psicometría",
+      desc: "Desempeño de cada tarjeta del instrumento: piso/techo, correlación ítem-total y alpha.",
+    };
+  }
   if (pathname.startsWith("/admin/config")) {
     return {
       title: "Configuración",
@@ -122,6 +157,11 @@ export default function AdminLayout({
     { label: "Dashboard", href: "/admin", icon: LayoutDashboard },
     { label: "Centros", href: "/admin/centros", icon: Building2 },
     { label: "Usuarios", href: "/admin/usuarios", icon: Users },
+    { label: "Comentarios", href: "/admin/comentarios", icon: MessageSquareWarning },
+    { label: "Resultados", href: "/admin/resultados", icon: BarChart3 },
+    { label: "Mapa", href: "/admin/mapa", icon: MapIcon },
+    { label: "Psicometría", href: "/admin/psicometria", icon: FlaskConical },
+    { label: "Alertas", href: "/admin/alertas", icon: BellRing },
     { label: "Configuración", href: "/admin/config", icon: Settings },
   ];
 
@@ -0,0 +1,327 @@
+"use client";
+
+import { useCallback, useEffect, useMemo, useState } from "react";
+import { api } from "@/lib/api";
+
+import { Button } from "@/components/ui/button";
+import { Input } from "@/components/ui/input";
+
+import { RefreshCw } from "lucide-react";
+
+type Nivel = "estados" | "municipios";
+type Metrica = "total" | "frecuencia" | "normalidad" | "gravedad";
+
+type Posicion = [number, number];
+type Geometria =
+  | { type: "Polygon"; coordinates: Posicion[][] }
+  | { type: "MultiPolygon"; coordinates: Posicion[][][] };
+
+type PropiedadesRegion = {
+  clave: string;
+  nombre: string;
+  estado_clave: string;
+  centros: number;
+  encuestas: number;
+  respuestas: number;
+  suprimido: boolean; // menos de k encuestas: índices nulos
+  frecuencia: number | null;
+  normalidad: number | null;
+  gravedad: number | null;
+  total: number | null;
+};
+
+type FeatureRegion = {
+  type: "Feature";
+  id: string;
+  geometry: Geometria | null; // null si aún no se importa el polígono
+  properties: PropiedadesRegion;
+};
+
+type MapaResponse = {
+  type: "FeatureCollection";
+  features: FeatureRegion[];
+  anonimato: { k: number; suprimido: boolean; campos?: string[] };
+};
+
+type EstadoCatalogo = { clave: string; nombre: string; abreviatura: string; geometria: boolean };
+
+const METRICAS: { value: Metrica; label: string }[] = [
+  { value: "total", label: "Índice total" },
+  { value: "frecuencia", label: "Frecuencia" },
+  { value: "normalidad", label: "Normalidad" },
+  { value: "gravedad", label: "Gravedad" },
+];
+
+function cx(...v: Array<string | false | null | undefined>) {
+  return v.filter(Boolean).join(" ");
+}
+
+function f2(v?: number | null) {
+  return typeof v === "number" ? v.toFixed(2) : "—";
+}
+
+// escala 1–5 → del blanco al morado institucional
+function colorValor(v: number | null) {
+  if (typeof v !== "number") return "#f5f5f5";
+  const t = Math.max(0, Math.min(1, (v - 1) / 4));
+  const mezcla = (a: number, b: number) => Math.round(a + (b - a) * t);
+  return `rgb(${mezcla(250, 127)}, ${mezcla(235, 1)}, ${mezcla(250, 127)})`;
+}
+
+function anillos(g: Geometria): Posicion[][] {
+  return g.type === "Polygon" ? g.coordinates : g.coordinates.flat();
+}
+
+// proyección equirectangular simple (suficiente a escala nacional)
+function useProyeccion(features: FeatureRegion[], ancho: number, alto: number) {
+  return useMemo(() => {
+    let minX = Infinity, minY = Infinity, maxX = -Infinity, maxY = -Infinity;
+    for (const f of features) {
+      if (!f.geometry) continue;
+      for (const anillo of anillos(f.geometry)) {
+        for (const [x, y] of anillo) {
+          minX = Math.min(minX, x);
+          maxX = Math.max(maxX, x);
+          minY = Math.min(minY, y);
+          maxY = Math.max(maxY, y);
+        }
+      }
+    }
+    if (!isFinite(minX)) return null;
+    const s = Math.min(ancho / (maxX - minX || 1), alto / (maxY - minY || 1));
+    return (g: Geometria) =>
+      anillos(g)
+        .map(
+          (anillo) =>
+            anillo.map(([x, y], i) => `${i === 0 ? "M" : "L"}${((x - minX) * s).toFixed(1)},${((maxY - y) * s).toFixed(1)}`).join("") +
+            "Z"
+        )
+        .join("");
+  }, [features, ancho, alto]);
+}
+
+export default function AdminMapaPage() {
+  const [nivel, setNivel] = useState<Nivel>("estados");
+  const [metrica, setMetrica] = useState<Metrica>("total");
+  const [tipo, setTipo] = useState<"" | "escolar" | "laboral">("");
+  const [estado, setEstado] = useState("");
+  const [year, setYear] = useState(String(new Date().getFullYear()));
+
+  const [estados, setEstados] = useState<EstadoCatalogo[]>([]);
+  const [mapa, setMapa] = useState<MapaResponse | null>(null);
+  const [sel, setSel] = useState<PropiedadesRegion | null>(null);
+  const [loading, setLoading] = useState(true);
+  const [err, setErr] = useState("");
+
+  useEffect(() => {
+    api<EstadoCatalogo[]>("/api/admin/geografia/estados")
+      .then(setEstados)
+      .catch(() => setEstados([]));
+  }, []);
+
+  const load = useCallback(async () => {
+    setErr("");
+    setLoading(true);
+    setSel(null);
+    try {
+      const qs = new URLSearchParams();
+      if (tipo) qs.set("tipo", tipo);
+      if (year.trim()) qs.set("year", year.trim());
+      if (nivel === "municipios" && estado) qs.set("estado", estado);
+      setMapa(await api<MapaResponse>(`/api/admin/mapa/${nivel}?${qs.toString()}`));
+    } catch (e) {
+      setErr(e instanceof Error ? e.message : "No se pudo cargar");
+    } finally {
+      setLoading(false);
+    }
+  }, [nivel, tipo, estado, year]);
+
+  useEffect(() => {
+    load();
+    // el año se aplica con "Aplicar" para no consultar en cada tecla
+    // eslint-disable-next-line react-hooks/exhaustive-deps
+  }, [nivel, tipo, estado]);
+
+  const features = mapa?.features ?? [];
+  const conGeometria = useMemo(() => (mapa?.features ?? []).filter((f) => f.geometry), [mapa]);
+  const proyectar = useProyeccion(conGeometria, 800, 520);
+  const k = mapa?.anonimato.k ?? 0;
+  const suprimidas = features.filter((f) => f.properties.suprimido).length;
+  const tabla = [...features]
+    .filter((f) => f.properties.centros > 0)
+    .sort((a, b) => (b.properties[metrica] ?? -1) - (a.properties[metrica] ?? -1));
+
+  return (
+    <div className="grid gap-4">
+      <div className="flex flex-wrap items-end gap-2">
+        <div className="flex gap-2">
+          {(["estados", "municipios"] as const).map((n) => (
+            <Button
+              key={n}
+              variant={nivel === n ? "default" : "outline"}
+              className="rounded-full font-semibold"
+              style={nivel === n ? { backgroundColor: "#7F017F" } : { borderColor: "#7F017F", color: "#7F017F" }}
+              onClick={() => setNivel(n)}
+            >
+              {n === "estados" ? "Estados" : "Municipios"}
+            </Button>
+          ))}
+        </div>
+        {nivel === "municipios" && (
+          <select
+            className="h-9 rounded-full border border-neutral-200 bg-white px-3 text-sm"
+            value={estado}
+            onChange={(e) => setEstado(e.target.value)}
+          >
+            <option value="">Todos los estados (solo con centros)</option>
+            {estados.map((e) => (
+              <option key={e.clave} value={e.clave}>
+                {e.nombre}
+              </option>
+            ))}
+          </select>
+        )}
+        <select
+          className="h-9 rounded-full border border-neutral-200 bg-white px-3 text-sm"
+          value={tipo}
+          onChange={(e) => setTipo(e.target.value as "" | "escolar" | "laboral")}
+        >
+          <option value="">Todos los tipos</option>
+          <option value="escolar">Escolar</option>
+          <option value="laboral">Laboral</option>
+        </select>
+        <Input className="w-24 rounded-full" value={year} onChange={(e) => setYear(e.target.value)} placeholder="Año" />
+        <Button variant="outline" className="ml-auto rounded-full" onClick={load} disabled={loading}>
+          <RefreshCw className={cx("mr-2 h-4 w-4", loading && "animate-spin")} />
+          Aplicar
+        </Button>
+      </div>
+
+      {err && <p className="rounded-xl border border-red-200 bg-red-50 p-3 text-sm text-red-700">{err}</p>}
+
+      <div className="rounded-2xl border border-neutral-200 bg-white p-5 shadow-sm">
+        <div className="flex flex-wrap items-center gap-2">
+          <div className="text-sm font-semibold text-neutral-800">Mapa por {nivel === "estados" ? "estado" : "municipio"}</div>
+          <div className="ml-auto flex flex-wrap gap-2">
+            {METRICAS.map((m) => (
+              <Button
+                key={m.value}
+                size="sm"
+                variant={metrica === m.value ? "default" : "outline"}
+                className="rounded-full"
+                style={metrica === m.value ? { backgroundColor: "#7F017F" } : undefined}
+                onClick={() => setMetrica(m.value)}
+              >
+                {m.label}
+              </Button>
+            ))}
+          </div>
+        </div>
+
+        {proyectar ? (
+          <div className="mt-4 grid gap-4 lg:grid-cols-[1fr_16rem]">
+            <svg viewBox="0 0 800 520" className="h-auto w-full">
+              {conGeometria.map((f) => (
+                <path
+                  key={f.id}
+                  d={proyectar(f.geometry as Geometria)}
+                  fill={colorValor(f.properties[metrica])}
+                  stroke={sel?.clave === f.id ? "#7F017F" : "#d4d4d4"}
+                  strokeWidth={sel?.clave === f.id ? 1.5 : 0.5}
+                  className="cursor-pointer"
+                  onClick={() => setSel(f.properties)}
+                >
+                  <title>
+                    {f.properties.nombre}: {f.properties.suprimido ? `menos de ${k} encuestas` : f2(f.properties[metrica])}
+                  </title>
+                </path>
+              ))}
+            </svg>
+
+            <div className="grid content-start gap-3 text-sm">
+              <div className="flex items-center gap-2 text-xs text-neutral-500">
+                <span>1</span>
+                <div className="h-3 flex-1 rounded-full" style={{ background: `linear-gradient(to right, ${colorValor(1)}, ${colorValor(5)})` }} />
+                <span>5</span>
+              </div>
+              {sel ? (
+                <div className="rounded-xl border border-neutral-200 p-3">
+                  <div className="font-semibold text-neutral-800">{sel.nombre}</div>
+                  <div className="text-xs text-neutral-500">
+                    {sel.clave} · {sel.centros} centro(s) · {sel.encuestas} encuestas
+                  </div>
+                  {sel.suprimido ? (
+                    <p className="mt-2 text-xs text-neutral-500">Menos de {k} encuestas: índices ocultos.</p>
+                  ) : (
+                    <dl className="mt-2 grid grid-cols-2 gap-1 text-xs">
+                      {METRICAS.map((m) => (
+                        <div key={m.value} className="contents">
+                          <dt className="text-neutral-500">{m.label}</dt>
+                          <dd className="text-right tabular-nums">{f2(sel[m.value])}</dd>
+                        </div>
+                      ))}
+                    </dl>
+                  )}
+                </div>
+              ) : (
+                <p className="text-xs text-neutral-500">Selecciona una región para ver su detalle.</p>
+              )}
+            </div>
+          </div>
+        ) : (
+          !loading && (
+            <p className="mt-4 text-sm text-neutral-600">
+              Aún no se han importado polígonos para este nivel (POST /api/admin/geografia/importar). Se muestra solo la tabla.
+            </p>
+          )
+        )}
+
+        {suprimidas > 0 && (
+          <p className="mt-3 text-xs text-neutral-500">
+            {suprimidas} región(es) con menos de {k} encuestas aparecen sin valores.
+          </p>
+        )}
+      </div>
+
+      <div className="rounded-2xl border border-neutral-200 bg-white p-5 shadow-sm">
+        <div className="text-sm font-semibold text-neutral-800">Regiones con centros</div>
+        {!loading && tabla.length === 0 && (
+          <p className="mt-4 text-sm text-neutral-600">No hay centros vinculados a esta geografía.</p>
+        )}
+        {tabla.length > 0 && (
+          <div className="mt-4 overflow-x-auto">
+            <table className="w-full text-sm">
+              <thead className="bg-neutral-50 text-left text-xs uppercase tracking-wide text-neutral-500">
+                <tr>
+                  <th className="px-3 py-2">Clave</th>
+                  <th className="px-3 py-2">Región</th>
+                  <th className="px-3 py-2 text-right">Centros</th>
+                  <th className="px-3 py-2 text-right">Encuestas</th>
+                  <th className="px-3 py-2 text-right">F</th>
+                  <th className="px-3 py-2 text-right">N</th>
+                  <th className="px-3 py-2 text-right">G</th>
+                  <th className="px-3 py-2 text-right">Total</th>
+                </tr>
+              </thead>
+              <tbody>
+                {tabla.map((f) => (
+                  <tr key={f.id} className={cx("border-t border-neutral-100", f.properties.suprimido && "text-neutral-400")}>
+                    <td className="px-3 py-2 tabular-nums">{f.properties.clave}</td>
+                    <td className="px-3 py-2 font-semibold text-neutral-800">{f.properties.nombre}</td>
+                    <td className="px-3 py-2 text-right tabular-nums">{f.properties.centros}</td>
+                    <td className="px-3 py-2 text-right tabular-nums">{f.properties.encuestas}</td>
+                    {(["frecuencia", "normalidad", "gravedad", "total"] as const).map((d) => (
+                      <td key={d} className={cx("px-3 py-2 text-right tabular-nums", d === metrica && "font-semibold")}>
+                        {f2(f.properties[d])}
+                      </td>
+                    ))}
+                  </tr>
+                ))}
+              </tbody>
+            </table>
+          </div>
+        )}
+      </div>
+    </div>
+  );
+}
@@ -1,11 +1,12 @@
 "use client";
 
-import { useMemo } from "react";
+import { useMemo, useState } from "react";
 import { useRouter } from "next/navigation";
 
 import { Button } from "@/components/ui/button";
+import { apiDownload } from "@/lib/api";
 
-import { Building2, Users } from "lucide-react";
+import { Building2, Download, Users } from "lucide-react";
 
 type AuthUser = {
   user_id: string;
@@ -41,6 +42,27 @@ export default function AdminPage() {
     return d.toLocaleString();
   }, [user?.expires_at]);
 
+  // Microdatos anonimizados (investigación)
+  const [mdYear, setMdYear] = useState("");
+  const [mdTipo, setMdTipo] = useState("");
+  const [mdBusy, setMdBusy] = useState(false);
+  const [mdError, setMdError] = useState("");
+
+  const descargarMicrodatos = async (format: "csv" | "sav" | "parquet") => {
+    setMdError("");
+    setMdBusy(true);
+    try {
+      const qs = new URLSearchParams({ format });
+      if (mdYear.trim()) qs.set("year", mdYear.trim());
+      if (mdTipo) qs.set("centro_tipo", mdTipo);
+      await apiDownload(`/api/admin/microdatos?${qs.toString()}`, `microdatos.${format}`);
+    } catch (e) {
+      setMdError(e instanceof Error ? e.message : "No se pudo descargar");
+    } finally {
+      setMdBusy(false);
+    }
+  };
+
   return (
     <div className="grid gap-6">
       {/* Quick cards */}
@@ -85,6 +107,47 @@ export default function AdminPage() {
         </div>
       </div>
 
+      {/* Microdatos */}
+      <div className="rounded-2xl border border-neutral-200 bg-white p-5 shadow-sm">
+        <p className="text-sm font-semibold text-neutral-900">Microdatos anonimizados</p>
+        <p className="mt-2 text-sm text-neutral-600">
+          Una fila por encuesta, sin datos identificables. Centros con pocas
+          encuestas se omiten y las combinaciones raras de género/edad se vacían.
+        </p>
+        <div className="mt-4 flex flex-wrap items-center gap-2">
+          <input
+            className="h-9 w-24 rounded-full border border-neutral-300 px-3 text-sm"
+            placeholder="Año"
+            inputMode="numeric"
+            value={mdYear}
+            onChange={(e) => setMdYear(e.target.value)}
+          />
+          <select
+            className="h-9 rounded-full border border-neutral-300 px-3 text-sm"
+            value={mdTipo}
+            onChange={(e) => setMdTipo(e.target.value)}
+          >
+            <option value="">Todos los centros</option>
+            <option value="escolar">Escolar</option>
+            <option value="laboral">Laboral</option>
+          </select>
+          {(["csv", "sav", "parquet"] as const).map((f) => (
+            <Button
+              key={f}
+              variant="outline"
+              disabled={mdBusy}
+              className="rounded-full font-semibold"
+              style={{ borderColor: "#7F017F", color: "#7F017F" }}
+              onClick={() => descargarMicrodatos(f)}
+            >
+              <Download className="mr-2 h-4 w-4" />
+              {f === "sav" ? "SPSS" : f.toUpperCase()}
+            </Button>
+          ))}
+        </div>
+        {mdError && <p className="mt-2 text-sm text-red-600">{mdError}</p>}
+      </div>
+
       {/* Placeholder */}
       <div className="rounded-2xl border border-dashed border-neutral-300 bg-white p-5">
         <p className="text-sm text-neutral-700">
@@ -0,0 +1,335 @@
+"use client";
+
+import { useCallback, useEffect, useMemo, useState } from "react";
+import { api } from "@/lib/api";
+
+import { Button } from "@/components/ui/button";
+
+import { RefreshCw } from "lucide-react";
+
+type InstrumentoInfo = { instrument_id: string; name: string; version: string; default: boolean };
+
+type ItemPsicometrico = {
+  pregunta_id: string;
+  dimension: string;
+  tipo_num: number;
+  tipo_nombre: string;
+  n: number;
+  promedio: number;
+  varianza: number;
+  escala_min: number;
+  escala_max: number;
+  piso: number; // proporción en el mínimo
+  techo: number; // proporción en el máximo
+  efecto_piso: boolean;
+  efecto_techo: boolean;
+  correlacion_item_total: number | null; // dentro de su dimensión
+  alpha_si_se_elimina: number | null;
+};
+
+type ItemEscala = {
+  pregunta_id: string;
+  dimension: string;
+  correlacion_item_total: number | null;
+  alpha_si_se_elimina: number | null;
+};
+
+type Escala = {
+  clave: string;
+  nombre: string;
+  n_encuestas_completas: number;
+  alpha: number | null;
+  alpha_ic95_inferior: number | null;
+  alpha_ic95_superior: number | null;
+  correlacion_media: number | null;
+  items: ItemEscala[];
+  correlaciones: (number | null)[][] | null;
+};
+
+type AnalisisResponse = {
+  instrumento_id: string;
+  version: string;
+  n_encuestas: number;
+  items: ItemPsicometrico[] | null;
+  dimensiones: Escala[] | null;
+  tipos: Escala[] | null;
+  suprimido?: boolean;
+  k: number;
+};
+
+// correlación ítem-total corregida por debajo de esto = ítem a revisar
+const R_MINIMA = 0.3;
+
+function cx(...v: Array<string | false | null | undefined>) {
+  return v.filter(Boolean).join(" ");
+}
+
+function f2(v?: number | null) {
+  return typeof v === "number" ? v.toFixed(2) : "—";
+}
+
+function pct(v: number) {
+  return `${Math.round(v * 100)}%`;
+}
+
+// -1 (rojo) · 0 (blanco) · 1 (morado institucional)
+function colorCorrelacion(r: number | null) {
+  if (typeof r !== "number") return "#f5f5f5";
+  const t = Math.min(1, Math.abs(r));
+  const mezcla = (a: number, b: number) => Math.round(a + (b - a) * t);
+  return r >= 0
+    ? `rgb(${mezcla(255, 127)}, ${mezcla(255, 1)}, ${mezcla(255, 127)})`
+    : `rgb(${mezcla(255, 220)}, ${mezcla(255, 38)}, ${mezcla(255, 38)})`;
+}
+
+function TablaEscalas({ titulo, escalas }: { titulo: string; escalas: Escala[] }) {
+  return (
+    <div className="rounded-2xl border border-neutral-200 bg-white p-5 shadow-sm">
+      <div className="text-sm font-semibold text-neutral-800">{titulo}</div>
+      <div className="mt-4 grid gap-4">
+        {escalas.map((e) => (
+          <div key={e.clave} className="rounded-xl border border-neutral-100">
+            <div className="flex flex-wrap items-center gap-3 bg-neutral-50 px-3 py-2 text-sm">
+              <span className="font-semibold text-neutral-800">{e.nombre}</span>
+              <span className="text-xs text-neutral-500">{e.n_encuestas_completas} encuestas completas</span>
+              <span className="ml-auto tabular-nums">
+                α = <b>{f2(e.alpha)}</b>
+                {typeof e.alpha_ic95_inferior === "number" && (
+                  <span className="text-xs text-neutral-500">
+                    {" "}
+                    [{f2(e.alpha_ic95_inferior)} – {f2(e.alpha_ic95_superior)}]
+                  </span>
+                )}
+              </span>
+              <span className="text-xs tabular-nums text-neutral-500">r̄ = {f2(e.correlacion_media)}</span>
+            </div>
+            <table className="w-full text-sm">
+              <thead className="text-left text-xs uppercase tracking-wide text-neutral-500">
+                <tr>
+                  <th className="px-3 py-2">Ítem</th>
+                  <th className="px-3 py-2 text-right">r ítem-total</th>
+                  <th className="px-3 py-2 text-right">α si se elimina</th>
+                </tr>
+              </thead>
+              <tbody>
+                {e.items.map((it) => {
+                  const bajo = typeof it.correlacion_item_total === "number" && it.correlacion_item_total < R_MINIMA;
+                  const sube =
+                    typeof it.alpha_si_se_elimina === "number" && typeof e.alpha === "number" && it.alpha_si_se_elimina > e.alpha;
+                  return (
+                    <tr key={it.pregunta_id + it.dimension} className="border-t border-neutral-100">
+                      <td className="px-3 py-1.5">
+                        {it.pregunta_id} <span className="text-xs text-neutral-500">{it.dimension}</span>
+                      </td>
+                      <td className={cx("px-3 py-1.5 text-right tabular-nums", bajo && "font-semibold text-red-700")}>
+                        {f2(it.correlacion_item_total)}
+                      </td>
+                      <td className={cx("px-3 py-1.5 text-right tabular-nums", sube && "font-semibold text-red-700")}>
+                        {f2(it.alpha_si_se_elimina)}
+                      </td>
+                    </tr>
+                  );
+                })}
+              </tbody>
+            </table>
+          </div>
+        ))}
+      </div>
+    </div>
+  );
+}
+
+export default function AdminPsicometriaPage() {
+  const [instrumentos, setInstrumentos] = useState<InstrumentoInfo[]>([]);
+  const [instrumento, setInstrumento] = useState("");
+  const [data, setData] = useState<AnalisisResponse | null>(null);
+  const [dimSel, setDimSel] = useState("");
+  const [loading, setLoading] = useState(true);
+  const [err, setErr] = useState("");
+
+  useEffect(() => {
+    api<InstrumentoInfo[]>("/api/instrumentos")
+      .then((list) => {
+        setInstrumentos(list);
+        const def = list.find((i) => i.default) ?? list[0];
+        if (def) setInstrumento(def.instrument_id);
+      })
+      .catch(() => setInstrumentos([]));
+  }, []);
+
+  const load = useCallback(async () => {
+    setErr("");
+    setLoading(true);
+    try {
+      const qs = new URLSearchParams();
+      if (instrumento) qs.set("instrumento", instrumento);
+      const res = await api<AnalisisResponse>(`/api/admin/psicometria?${qs.toString()}`);
+      setData(res);
+      setDimSel((prev) => prev || res.dimensiones?.[0]?.clave || "");
+    } catch (e) {
+      setData(null);
+      setErr(e instanceof Error ? e.message : "No se pudo cargar");
+    } finally {
+      setLoading(false);
+    }
+  }, [instrumento]);
+
+  useEffect(() => {
+    load();
+  }, [load]);
+
+  const items = data?.items ?? [];
+  const dimensiones = data?.dimensiones ?? [];
+  const tipos = data?.tipos ?? [];
+  const matriz = useMemo(() => dimensiones.find((d) => d.clave === dimSel) ?? null, [dimensiones, dimSel]);
+  const conPisoTecho = items.filter((it) => it.efecto_piso || it.efecto_techo).length;
+
+  return (
+    <div className="grid gap-4">
+      <div className="flex flex-wrap items-end gap-2">
+        <select
+          className="h-9 rounded-full border border-neutral-200 bg-white px-3 text-sm"
+          value={instrumento}
+          onChange={(e) => setInstrumento(e.target.value)}
+        >
+          {instrumentos.map((i) => (
+            <option key={i.instrument_id} value={i.instrument_id}>
+              {i.name} · v{i.version}
+              {i.default ? " (default)" : ""}
+            </option>
+          ))}
+        </select>
+        <Button variant="outline" className="ml-auto rounded-full" onClick={load} disabled={loading}>
+          <RefreshCw className={cx("mr-2 h-4 w-4", loading && "animate-spin")} />
+          Actualizar
+        </Button>
+      </div>
+
+      {err && <p className="rounded-xl border border-red-200 bg-red-50 p-3 text-sm text-red-700">{err}</p>}
+
+      {data && (
+        <div className="rounded-2xl border border-neutral-200 bg-white p-5 shadow-sm text-sm text-neutral-700">
+          <b>{data.n_encuestas}</b> encuestas finalizadas del instrumento {data.instrumento_id} (v{data.version}).
+          {data.suprimido ? (
+            <> Se necesitan al menos {data.k} encuestas para mostrar el análisis.</>
+          ) : (
+            <>
+              {" "}
+              {conPisoTecho} tarjeta(s) con efecto piso o techo (15% o más de respuestas en un extremo de la escala).
+            </>
+          )}
+        </div>
+      )}
+
+      {items.length > 0 && (
+        <div className="rounded-2xl border border-neutral-200 bg-white p-5 shadow-sm">
+          <div className="text-sm font-semibold text-neutral-800">Tarjetas</div>
+          <p className="mt-1 text-xs text-neutral-500">
+            Correlación ítem-total corregida y α si se elimina, dentro de la dimensión. En rojo: r &lt; {R_MINIMA} o piso/techo.
+          </p>
+          <div className="mt-4 overflow-x-auto">
+            <table className="w-full text-sm">
+              <thead className="bg-neutral-50 text-left text-xs uppercase tracking-wide text-neutral-500">
+                <tr>
+                  <th className="px-3 py-2">Pregunta</th>
+                  <th className="px-3 py-2">Dimensión</th>
+                  <th className="px-3 py-2">Tipo</th>
+                  <th className="px-3 py-2 text-right">n</th>
+                  <th className="px-3 py-2 text-right">Media</th>
+                  <th className="px-3 py-2 text-right">Varianza</th>
+                  <th className="px-3 py-2 text-right">Piso</th>
+                  <th className="px-3 py-2 text-right">Techo</th>
+                  <th className="px-3 py-2 text-right">r ítem-total</th>
+                  <th className="px-3 py-2 text-right">α si se elimina</th>
+                </tr>
+              </thead>
+              <tbody>
+                {items.map((it) => (
+                  <tr key={it.pregunta_id + it.dimension} className="border-t border-neutral-100">
+                    <td className="px-3 py-2 font-semibold text-neutral-800">{it.pregunta_id}</td>
+                    <td className="px-3 py-2">{it.dimension}</td>
+                    <td className="px-3 py-2 text-xs text-neutral-500">{it.tipo_nombre}</td>
+                    <td className="px-3 py-2 text-right tabular-nums">{it.n}</td>
+                    <td className="px-3 py-2 text-right tabular-nums">{f2(it.promedio)}</td>
+                    <td className="px-3 py-2 text-right tabular-nums">{f2(it.varianza)}</td>
+                    <td className={cx("px-3 py-2 text-right tabular-nums", it.efecto_piso && "font-semibold text-red-700")}>
+                      {pct(it.piso)}
+                    </td>
+                    <td className={cx("px-3 py-2 text-right tabular-nums", it.efecto_techo && "font-semibold text-red-700")}>
+                      {pct(it.techo)}
+                    </td>
+                    <td
+                      className={cx(
+                        "px-3 py-2 text-right tabular-nums",
+                        typeof it.correlacion_item_total === "number" && it.correlacion_item_total < R_MINIMA && "font-semibold text-red-700"
+                      )}
+                    >
+                      {f2(it.correlacion_item_total)}
+                    </td>
+                    <td className="px-3 py-2 text-right tabular-nums">{f2(it.alpha_si_se_elimina)}</td>
+                  </tr>
+                ))}
+              </tbody>
+            </table>
+          </div>
+        </div>
+      )}
+
+      {matriz?.correlaciones && (
+        <div className="rounded-2xl border border-neutral-200 bg-white p-5 shadow-sm">
+          <div className="flex flex-wrap items-center gap-2">
+            <div className="text-sm font-semibold text-neutral-800">Correlaciones entre ítems</div>
+            <div className="ml-auto flex flex-wrap gap-2">
+              {dimensiones.map((d) => (
+                <Button
+                  key={d.clave}
+                  size="sm"
+                  variant={dimSel === d.clave ? "default" : "outline"}
+                  className="rounded-full"
+                  style={dimSel === d.clave ? { backgroundColor: "#7F017F" } : undefined}
+                  onClick={() => setDimSel(d.clave)}
+                >
+                  {d.nombre}
+                </Button>
+              ))}
+            </div>
+          </div>
+          <div className="mt-4 overflow-x-auto">
+            <table className="text-[11px] tabular-nums">
+              <thead>
+                <tr>
+                  <th />
+                  {matriz.items.map((it) => (
+                    <th key={it.pregunta_id} className="px-1 py-1 font-semibold text-neutral-500">
+                      {it.pregunta_id}
+                    </th>
+                  ))}
+                </tr>
+              </thead>
+              <tbody>
+                {matriz.correlaciones.map((fila, i) => (
+                  <tr key={matriz.items[i]?.pregunta_id ?? i}>
+                    <th className="pr-2 text-right font-semibold text-neutral-500">{matriz.items[i]?.pregunta_id}</th>
+                    {fila.map((r, j) => (
+                      <td
+                        key={j}
+                        className="h-8 w-10 text-center"
+                        style={{ background: colorCorrelacion(r), color: typeof r === "number" && Math.abs(r) > 0.6 ? "#fff" : "#262626" }}
+                        title={`${matriz.items[i]?.pregunta_id} × ${matriz.items[j]?.pregunta_id}: ${f2(r)}`}
+                      >
+                        {i === j ? "" : f2(r)}
+                      </td>
+                    ))}
+                  </tr>
+                ))}
+              </tbody>
+            </table>
+          </div>
+        </div>
+      )}
+
+      {dimensiones.length > 0 && <TablaEscalas titulo="Consistencia interna por dimensión" escalas={dimensiones} />}
+      {tipos.length > 0 && <TablaEscalas titulo="Consistencia interna por tipo de violencia (dentro de cada dimensión)" escalas={tipos} />}
+    </div>
+  );
+}
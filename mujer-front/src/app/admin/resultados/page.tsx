@@ -0,0 +1,319 @@
+"use client";
+
+import { useCallback, useEffect, useState } from "react";
+import { api } from "@/lib/api";
+
+import { Button } from "@/components/ui/button";
+import { Input } from "@/components/ui/input";
+
+import { RefreshCw } from "lucide-react";
+
+type Metrica = "total" | "frecuencia" | "normalidad" | "gravedad";
+type Por = "tipo" | "estado" | "ciudad";
+
+type Dimensiones = { frecuencia: number; normalidad: number; gravedad: number; total: number };
+
+type CentroBenchmark = {
+  centro_id: number;
+  nombre: string;
+  tipo: "escolar" | "laboral";
+  estado?: string;
+  ciudad?: string;
+  encuestas: number;
+  respuestas: number;
+  valores?: Dimensiones; // sin valores = menos de k encuestas
+  posicion?: number;
+  percentil?: number;
+};
+
+type Distribucion = { promedio: number; min: number; p25: number; mediana: number; p75: number; max: number };
+
+type DistribucionDimensiones = {
+  centros: number;
+  frecuencia: Distribucion;
+  normalidad: Distribucion;
+  gravedad: Distribucion;
+  total: Distribucion;
+};
+
+type AnonimatoInfo = { k: number; suprimido: boolean; campos?: string[] };
+
+type RankingResponse = {
+  metrica: Metrica;
+  centros: CentroBenchmark[];
+  distribucion?: DistribucionDimensiones;
+  anonimato: AnonimatoInfo;
+};
+
+type GrupoBenchmark = {
+  clave: string;
+  label: string;
+  centros: number;
+  publicables: number;
+  encuestas: number;
+  distribucion?: DistribucionDimensiones;
+};
+
+type CompararResponse = { por: Por; grupos: GrupoBenchmark[]; anonimato: AnonimatoInfo };
+
+const METRICAS: { value: Metrica; label: string }[] = [
+  { value: "total", label: "Índice total" },
+  { value: "frecuencia", label: "Frecuencia" },
+  { value: "normalidad", label: "Normalidad" },
+  { value: "gravedad", label: "Gravedad" },
+];
+
+const PORES: { value: Por; label: string }[] = [
+  { value: "tipo", label: "Tipo de centro" },
+  { value: "estado", label: "Estado" },
+  { value: "ciudad", label: "Ciudad" },
+];
+
+function cx(...v: Array<string | false | null | undefined>) {
+  return v.filter(Boolean).join(" ");
+}
+
+function f2(v?: number) {
+  return typeof v === "number" ? v.toFixed(2) : "—";
+}
+
+// caja min–p25–mediana–p75–max sobre la escala 1–5
+function Caja({ d }: { d: Distribucion }) {
+  const x = (v: number) => `${Math.max(0, Math.min(100, ((v - 1) / 4) * 100))}%`;
+  return (
+    <div className="relative h-4 w-full min-w-40 rounded-full bg-neutral-100" title={`min ${f2(d.min)} · p25 ${f2(d.p25)} · mediana ${f2(d.mediana)} · p75 ${f2(d.p75)} · max ${f2(d.max)}`}>
+      <div className="absolute top-1/2 h-px -translate-y-1/2 bg-neutral-400" style={{ left: x(d.min), right: `calc(100% - ${x(d.max)})` }} />
+      <div
+        className="absolute top-0.5 bottom-0.5 rounded"
+        style={{ left: x(d.p25), right: `calc(100% - ${x(d.p75)})`, backgroundColor: "rgba(127,1,127,0.25)" }}
+      />
+      <div className="absolute top-0 bottom-0 w-0.5" style={{ left: x(d.mediana), backgroundColor: "#7F017F" }} />
+    </div>
+  );
+}
+
+export default function AdminResultadosPage() {
+  const [metrica, setMetrica] = useState<Metrica>("total");
+  const [por, setPor] = useState<Por>("tipo");
+  const [tipo, setTipo] = useState<"" | "escolar" | "laboral">("");
+  const [estado, setEstado] = useState("");
+  const [ciudad, setCiudad] = useState("");
+  const [year, setYear] = useState(String(new Date().getFullYear()));
+
+  const [ranking, setRanking] = useState<RankingResponse | null>(null);
+  const [grupos, setGrupos] = useState<CompararResponse | null>(null);
+  const [loading, setLoading] = useState(true);
+  const [err, setErr] = useState("");
+
+  const load = useCallback(async () => {
+    setErr("");
+    setLoading(true);
+    try {
+      const qs = new URLSearchParams();
+      if (tipo) qs.set("tipo", tipo);
+      if (estado.trim()) qs.set("estado", estado.trim());
+      if (ciudad.trim()) qs.set("ciudad", ciudad.trim());
+      if (year.trim()) qs.set("year", year.trim());
+
+      const qr = new URLSearchParams(qs);
+      qr.set("metrica", metrica);
+      const qg = new URLSearchParams(qs);
+      qg.set("por", por);
+
+      const [r, g] = await Promise.all([
+        api<RankingResponse>(`/api/admin/resultados?${qr.toString()}`),
+        api<CompararResponse>(`/api/admin/resultados/comparar?${qg.toString()}`),
+      ]);
+      setRanking(r);
+      setGrupos(g);
+    } catch (e) {
+      setErr(e instanceof Error ? e.message : "No se pudo cargar");
+    } finally {
+      setLoading(false);
+    }
+  }, [metrica, por, tipo, estado, ciudad, year]);
+
+  useEffect(() => {
+    load();
+    // estado/ciudad se aplican con "Aplicar" para no consultar en cada tecla
+    // eslint-disable-next-line react-hooks/exhaustive-deps
+  }, [metrica, por, tipo, year]);
+
+  const k = ranking?.anonimato.k ?? 0;
+  const suprimidos = ranking?.centros.filter((c) => !c.valores).length ?? 0;
+
+  return (
+    <div className="grid gap-4">
+      <div className="flex flex-wrap items-end gap-2">
+        <div className="flex gap-2">
+          {(["", "escolar", "laboral"] as const).map((t) => (
+            <Button
+              key={t || "todos"}
+              variant={tipo === t ? "default" : "outline"}
+              className="rounded-full font-semibold"
+              style={tipo === t ? { backgroundColor: "#7F017F" } : { borderColor: "#7F017F", color: "#7F017F" }}
+              onClick={() => setTipo(t)}
+            >
+              {t === "" ? "Todos" : t === "escolar" ? "Escolar" : "Laboral"}
+            </Button>
+          ))}
+        </div>
+        <Input className="w-24 rounded-full" value={year} onChange={(e) => setYear(e.target.value)} placeholder="Año" />
+        <Input className="w-40 rounded-full" value={estado} onChange={(e) => setEstado(e.target.value)} placeholder="Estado" />
+        <Input className="w-40 rounded-full" value={ciudad} onChange={(e) => setCiudad(e.target.value)} placeholder="Ciudad" />
+        <Button variant="outline" className="ml-auto rounded-full" onClick={load} disabled={loading}>
+          <RefreshCw className={cx("mr-2 h-4 w-4", loading && "animate-spin")} />
+          Aplicar
+        </Button>
+      </div>
+
+      {err && <p className="rounded-xl border border-red-200 bg-red-50 p-3 text-sm text-red-700">{err}</p>}
+
+      {/* Ranking */}
+      <div className="rounded-2xl border border-neutral-200 bg-white p-5 shadow-sm">
+        <div className="flex flex-wrap items-center gap-2">
+          <div className="text-sm font-semibold text-neutral-800">Ranking de centros</div>
+          <div className="ml-auto flex flex-wrap gap-2">
+            {METRICAS.map((m) => (
+              <Button
+                key={m.value}
+                size="sm"
+                variant={metrica === m.value ? "default" : "outline"}
+                className="rounded-full"
+                style={metrica === m.value ? { backgroundColor: "#7F017F" } : undefined}
+                onClick={() => setMetrica(m.value)}
+              >
+                {m.label}
+              </Button>
+            ))}
+          </div>
+        </div>
+
+        {ranking?.distribucion && (
+          <div className="mt-4 grid gap-2 text-xs text-neutral-600 sm:grid-cols-[10rem_1fr] sm:items-center">
+            <span className="font-semibold text-neutral-700">Distribución ({ranking.distribucion.centros} centros)</span>
+            <div className="flex items-center gap-3">
+              <Caja d={ranking.distribucion[metrica]} />
+              <span className="whitespace-nowrap">
+                mediana {f2(ranking.distribucion[metrica].mediana)} · p25–p75 {f2(ranking.distribucion[metrica].p25)}–
+                {f2(ranking.distribucion[metrica].p75)}
+              </span>
+            </div>
+          </div>
+        )}
+
+        {!loading && ranking && ranking.centros.length === 0 && (
+          <p className="mt-4 text-sm text-neutral-600">No hay centros con encuestas en este periodo.</p>
+        )}
+
+        {ranking && ranking.centros.length > 0 && (
+          <div className="mt-4 overflow-x-auto">
+            <table className="w-full text-sm">
+              <thead className="bg-neutral-50 text-left text-xs uppercase tracking-wide text-neutral-500">
+                <tr>
+                  <th className="px-3 py-2">#</th>
+                  <th className="px-3 py-2">Centro</th>
+                  <th className="px-3 py-2">Tipo</th>
+                  <th className="px-3 py-2">Ubicación</th>
+                  <th className="px-3 py-2 text-right">Encuestas</th>
+                  <th className="px-3 py-2 text-right">F</th>
+                  <th className="px-3 py-2 text-right">N</th>
+                  <th className="px-3 py-2 text-right">G</th>
+                  <th className="px-3 py-2 text-right">Total</th>
+                  <th className="px-3 py-2 text-right">Percentil</th>
+                </tr>
+              </thead>
+              <tbody>
+                {ranking.centros.map((c) => (
+                  <tr key={c.centro_id} className={cx("border-t border-neutral-100", !c.valores && "text-neutral-400")}>
+                    <td className="px-3 py-2 font-semibold">{c.posicion ?? "—"}</td>
+                    <td className="px-3 py-2 font-semibold text-neutral-800">{c.nombre}</td>
+                    <td className="px-3 py-2 capitalize">{c.tipo}</td>
+                    <td className="px-3 py-2">{[c.ciudad, c.estado].filter(Boolean).join(", ") || "—"}</td>
+                    <td className="px-3 py-2 text-right tabular-nums">{c.encuestas}</td>
+                    {(["frecuencia", "normalidad", "gravedad", "total"] as const).map((d) => (
+                      <td key={d} className={cx("px-3 py-2 text-right tabular-nums", d === metrica && "font-semibold")}>
+                        {f2(c.valores?.[d])}
+                      </td>
+                    ))}
+                    <td className="px-3 py-2 text-right tabular-nums">
+                      {typeof c.percentil === "number" ? `${c.percentil.toFixed(0)}%` : "—"}
+                    </td>
+                  </tr>
+                ))}
+              </tbody>
+            </table>
+          </div>
+        )}
+
+        {suprimidos > 0 && (
+          <p className="mt-3 text-xs text-neutral-500">
+            {suprimidos} centro(s) con menos de {k} encuestas aparecen sin valores y no entran al ranking ni a la distribución.
+          </p>
+        )}
+      </div>
+
+      {/* Comparación por grupo */}
+      <div className="rounded-2xl border border-neutral-200 bg-white p-5 shadow-sm">
+        <div className="flex flex-wrap items-center gap-2">
+          <div className="text-sm font-semibold text-neutral-800">Comparar por</div>
+          <div className="flex flex-wrap gap-2">
+            {PORES.map((p) => (
+              <Button
+                key={p.value}
+                size="sm"
+                variant={por === p.value ? "default" : "outline"}
+                className="rounded-full"
+                style={por === p.value ? { backgroundColor: "#7F017F" } : undefined}
+                onClick={() => setPor(p.value)}
+              >
+                {p.label}
+              </Button>
+            ))}
+          </div>
+          <span className="ml-auto text-xs text-neutral-500">
+            {METRICAS.find((m) => m.value === metrica)?.label} · escala 1–5
+          </span>
+        </div>
+
+        {grupos && grupos.grupos.length > 0 && (
+          <div className="mt-4 overflow-x-auto">
+            <table className="w-full text-sm">
+              <thead className="bg-neutral-50 text-left text-xs uppercase tracking-wide text-neutral-500">
+                <tr>
+                  <th className="px-3 py-2">Grupo</th>
+                  <th className="px-3 py-2 text-right">Centros</th>
+                  <th className="px-3 py-2 text-right">Encuestas</th>
+                  <th className="px-3 py-2 text-right">Promedio</th>
+                  <th className="px-3 py-2 text-right">Mediana</th>
+                  <th className="px-3 py-2">Distribución</th>
+                </tr>
+              </thead>
+              <tbody>
+                {grupos.grupos.map((g) => (
+                  <tr key={g.clave || g.label} className="border-t border-neutral-100">
+                    <td className="px-3 py-2 font-semibold text-neutral-800">{g.label}</td>
+                    <td className="px-3 py-2 text-right tabular-nums">
+                      {g.publicables}
+                      {g.centros > g.publicables && <span className="text-neutral-400"> / {g.centros}</span>}
+                    </td>
+                    <td className="px-3 py-2 text-right tabular-nums">{g.encuestas}</td>
+                    <td className="px-3 py-2 text-right tabular-nums">{f2(g.distribucion?.[metrica].promedio)}</td>
+                    <td className="px-3 py-2 text-right tabular-nums">{f2(g.distribucion?.[metrica].mediana)}</td>
+                    <td className="px-3 py-2">
+                      {g.distribucion ? (
+                        <Caja d={g.distribucion[metrica]} />
+                      ) : (
+                        <span className="text-xs text-neutral-400">Menos de 3 centros publicables</span>
+                      )}
+                    </td>
+                  </tr>
+                ))}
+              </tbody>
+            </table>
+          </div>
+        )}
+      </div>
+    </div>
+  );
+}
@@ -13,9 +13,11 @@ import {
   Grid3X3,
   ArrowUpRight,
   Calendar,
+  Download,
+  BellRing,
 } from "lucide-react";
 
-import { api } from "@/lib/api";
+import { api, apiDownload } from "@/lib/api";
 import { Button } from "@/components/ui/button";
 import { Separator } from "@/components/ui/separator";
 import { Card, CardContent, CardHeader, CardTitle } from "@/components/ui/card";
@@ -80,11 +82,45 @@ type GeneroDimItem = {
 };
 
 type ComentarioItem = {
-  encuesta_id: string;
-  fecha: string;
-  genero: string;
-  edad: number;
+  fecha: string; // YYYY-MM (solo mes)
+  genero?: string; // vacío si el grupo tiene menos de k encuestas
+  rango_edad?: string; // ej. "25-34"
   texto: string;
+  tipos?: number[]; // tipo_num que menciona (etiquetado por palabras clave)
+};
+
+/* ✅ NUEVO: comentarios publicados por tipo (junto a la matriz) */
+type EtiquetaTipo = { tipo_num: number; tipo_nombre: string; comentarios: number };
+
+type EtiquetasComentarios = {
+  publicados: number;
+  etiquetados: number;
+  tipos: EtiquetaTipo[];
+};
+
+/* ✅ NUEVO: índice compuesto de riesgo (scoring.risk_index del instrumento) */
+type NivelRiesgo = { key: string; label: string; min: number };
+
+type IndiceRiesgo = {
+  tipo_num?: number;
+  tipo_nombre?: string;
+  indice: number; // 0–100
+  nivel: string;
+  nivel_label: string;
+};
+
+type ResumenRiesgo = {
+  metodo: string;
+  notas?: string;
+  niveles: NivelRiesgo[];
+  global?: IndiceRiesgo;
+  tipos: IndiceRiesgo[];
+};
+
+type AnonimatoInfo = {
+  k: number;
+  suprimido: boolean;
+  campos?: string[];
 };
 
 type CentroStats = {
@@ -99,18 +135,114 @@ type CentroStats = {
   respuestas_por_edad: CountItem[];
 
   resumen_por_genero: GeneroDimItem[];
-  comentarios?: ComentarioItem[];
 };
 
+/* ✅ NUEVO: comentarios moderados, paginados aparte (/api/centro/comentarios) */
+type CentroComentariosResponse = {
+  items: ComentarioItem[];
+  total: number; // con búsqueda
+  publicados: number; // sin búsqueda
+  en_revision: number;
+  next_cursor?: string;
+  anonimato: AnonimatoInfo;
+};
+
+/* ✅ NUEVO: alertas de riesgo (/api/centro/alertas) */
+type AlertaItem = {
+  id: number;
+  centro_nombre: string;
+  regla_nombre: string;
+  severidad: "baja" | "media" | "alta";
+  year: number;
+  mensaje: string;
+  detectada_at: string;
+};
+
+/* ✅ NUEVO: posición frente a centros pares del mismo tipo (/api/centro/benchmark) */
+type ReferenciaPares = {
+  valor: number;
+  percentil: number; // % de pares por debajo
+  promedio: number;
+  p25: number;
+  mediana: number;
+  p75: number;
+};
+
+type BenchmarkCentro = {
+  centro_id: number;
+  nombre: string;
+  tipo: "escolar" | "laboral";
+  encuestas: number;
+  pares: number;
+  publicable: boolean;
+  motivo?: "centro_bajo_k" | "pocos_pares" | "sin_datos";
+  frecuencia?: ReferenciaPares;
+  normalidad?: ReferenciaPares;
+  gravedad?: ReferenciaPares;
+  total?: ReferenciaPares;
+  anonimato: AnonimatoInfo;
+};
+
+/* ✅ NUEVO: ¿el cambio entre dos años es real? (/api/centro/comparar) */
+type TamanoEfecto = { valor: number; magnitud: string };
+
+type ComparacionMetrica = {
+  ambito: "dimension" | "tipo";
+  dimension?: string;
+  tipo_num?: number;
+  tipo_nombre?: string;
+  a: { n: number; media: number; de: number; mediana: number };
+  b: { n: number; media: number; de: number; mediana: number };
+  diferencia: number;
+  welch?: { t: number; gl: number; p: number };
+  mann_whitney?: { u: number; z: number; p: number };
+  cohen_d?: TamanoEfecto;
+  cliff_delta?: TamanoEfecto;
+  p_welch_holm?: number;
+  p_mann_whitney_holm?: number;
+  significativa: boolean;
+};
+
+type ComparacionResponse = {
+  por: string;
+  a: string;
+  b: string;
+  encuestas_a: number;
+  encuestas_b: number;
+  alpha: number;
+  metricas: ComparacionMetrica[];
+  anonimato: AnonimatoInfo;
+};
+
+function fmtP(p?: number) {
+  if (typeof p !== "number") return "—";
+  return p < 0.001 ? "< 0.001" : p.toFixed(3);
+}
+
 type CentroResumenResponse = {
   centros: number[];
   global: ResumenGlobal;
   matriz: MatrizItem[];
   stats: CentroStats;
+  anonimato?: AnonimatoInfo;
+  etiquetas_comentarios?: EtiquetasComentarios;
+  riesgo?: ResumenRiesgo;
 };
 
 const PURPLE = "#7F017F";
 
+/* ✅ NUEVO: color por nivel de riesgo (las keys vienen del instrumento) */
+const NIVEL_RIESGO_COLOR: Record<string, { bg: string; fg: string }> = {
+  bajo: { bg: "#DCFCE7", fg: "#166534" },
+  medio: { bg: "#FEF3C7", fg: "#92400E" },
+  alto: { bg: "#FFEDD5", fg: "#C2410C" },
+  critico: { bg: "#FEE2E2", fg: "#B91C1C" },
+};
+
+function colorNivel(key: string) {
+  return NIVEL_RIESGO_COLOR[key] ?? { bg: "rgba(127,1,127,0.10)", fg: PURPLE };
+}
+
 /* =======================
    ✅ NUEVO: Estadística avanzada
    ======================= */
@@ -139,6 +271,19 @@ type AdvRow = {
   ic95_inferior_encuestas: number;
   ic95_superior_encuestas: number;
 
+  /* ✅ NUEVO: bootstrap, IC de alpha (Feldt) y análisis por ítem */
+  ic95_inferior_bootstrap?: number;
+  ic95_superior_bootstrap?: number;
+  alpha_ic95_inferior?: number;
+  alpha_ic95_superior?: number;
+  n_encuestas_completas?: number;
+  items?: {
+    pregunta_id: string;
+    promedio: number;
+    std_dev: number;
+    correlacion_item_total: number | null;
+    alpha_si_se_elimina: number | null;
+  }[];
 };
 
 
@@ -304,6 +449,9 @@ function fallbackYears(): YearOption[] {
 
 export default function CentroPage() {
   const [data, setData] = useState<CentroResumenResponse | null>(null);
+  const [alertas, setAlertas] = useState<AlertaItem[]>([]);
+  const [bench, setBench] = useState<BenchmarkCentro | null>(null);
+  const [cambio, setCambio] = useState<ComparacionResponse | null>(null);
   const [loading, setLoading] = useState(true);
   const [err, setErr] = useState("");
 
@@ -321,6 +469,67 @@ export default function CentroPage() {
   const [advLoading, setAdvLoading] = useState(false);
   const [advErr, setAdvErr] = useState("");
 
+  /* ✅ NUEVO: comentarios paginados con búsqueda y filtros */
+  const [comentarios, setComentarios] = useState<ComentarioItem[]>([]);
+  const [comInfo, setComInfo] = useState<Omit<CentroComentariosResponse, "items"> | null>(null);
+  const [comLoading, setComLoading] = useState(false);
+  const [comErr, setComErr] = useState("");
+  const [comQ, setComQ] = useState("");
+  const [comGenero, setComGenero] = useState("all");
+  const [comRango, setComRango] = useState("all");
+
+  async function loadComentarios(opts?: { cursor?: string; year?: string; q?: string }) {
+    const y = opts?.year ?? year;
+    const q = (opts?.q ?? comQ).trim();
+
+    setComLoading(true);
+    setComErr("");
+    try {
+      const qs = new URLSearchParams();
+      if (y && y !== "all") qs.set("year", y);
+      if (q) qs.set("q", q);
+      if (comGenero !== "all") qs.set("genero", comGenero);
+      if (comRango !== "all") qs.set("rango_edad", comRango);
+      if (opts?.cursor) qs.set("cursor", opts.cursor);
+
+      const res = await api<CentroComentariosResponse>(`/api/centro/comentarios?${qs.toString()}`);
+      const { items, ...info } = res;
+      setComentarios((prev) => (opts?.cursor ? prev.concat(items || []) : items || []));
+      setComInfo(info);
+    } catch (e: any) {
+      setComErr(e?.message || "No se pudieron cargar los comentarios");
+      if (!opts?.cursor) {
+        setComentarios([]);
+        setComInfo(null);
+      }
+    } finally {
+      setComLoading(false);
+    }
+  }
+
+  /* ✅ NUEVO: exportar resultados (CSV/XLSX) con los mismos filtros */
+  const [exporting, setExporting] = useState<"" | "csv" | "xlsx" | "pdf">("");
+
+  async function exportar(format: "csv" | "xlsx" | "pdf") {
+    setExporting(format);
+    try {
+      const qs = new URLSearchParams();
+      if (year && year !== "all") qs.set("year", year);
+      if (format === "pdf") {
+        // ✅ Informe de diagnóstico: requiere año
+        await apiDownload(`/api/centro/reporte.pdf?${qs.toString()}`, "informe_diagnostico.pdf");
+        return;
+      }
+      qs.set("format", format);
+      const ext = format === "xlsx" ? "xlsx" : "csv.zip";
+      await apiDownload(`/api/centro/export?${qs.toString()}`, `resultados_centro.${ext}`);
+    } catch (e: any) {
+      setErr(e?.message || "No se pudo exportar");
+    } finally {
+      setExporting("");
+    }
+  }
+
   async function load(selectedYear?: string) {
     const y = selectedYear ?? year;
 
@@ -402,6 +611,7 @@ export default function CentroPage() {
           setYear(initialYear);
           await load(initialYear);
           await loadAdvanced(initialYear);
+          await loadComentarios({ year: initialYear });
           return;
         }
       } catch {
@@ -415,18 +625,54 @@ export default function CentroPage() {
       setYear(initialYear);
       await load(initialYear);
       await loadAdvanced(initialYear);
+      await loadComentarios({ year: initialYear });
     })();
     // eslint-disable-next-line react-hooks/exhaustive-deps
   }, []);
 
+  // ✅ NUEVO: alertas activas (todas las del centro, sin importar el año elegido)
+  useEffect(() => {
+    api<{ items: AlertaItem[] }>(`/api/centro/alertas`)
+      .then((r) => setAlertas(safeArr(r?.items)))
+      .catch(() => setAlertas([]));
+  }, []);
+
+  // ✅ NUEVO: posición frente a pares (mismo año que el dashboard)
+  useEffect(() => {
+    const qs = year && year !== "all" ? `?year=${encodeURIComponent(year)}` : "";
+    api<BenchmarkCentro>(`/api/centro/benchmark${qs}`)
+      .then((r) => setBench(r))
+      .catch(() => setBench(null));
+  }, [year]);
+
+  // ✅ NUEVO: significancia del cambio frente al año anterior
+  useEffect(() => {
+    if (!year || year === "all") {
+      setCambio(null);
+      return;
+    }
+    const prev = String(Number(year) - 1);
+    api<ComparacionResponse>(`/api/centro/comparar?por=year&a=${encodeURIComponent(prev)}&b=${encodeURIComponent(year)}`)
+      .then((r) => setCambio(r))
+      .catch(() => setCambio(null));
+  }, [year]);
+
   // ✅ si usuario cambia año, recarga todo
   useEffect(() => {
     if (!data && loading) return;
     load(year);
     loadAdvanced(year);
+    loadComentarios({ year });
     // eslint-disable-next-line react-hooks/exhaustive-deps
   }, [year]);
 
+  // ✅ filtros de comentarios (la búsqueda se aplica con Enter / botón)
+  useEffect(() => {
+    if (!data) return;
+    loadComentarios();
+    // eslint-disable-next-line react-hooks/exhaustive-deps
+  }, [comGenero, comRango]);
+
   /* =======================
      HeatMap data prep
      ======================= */
@@ -463,6 +709,14 @@ export default function CentroPage() {
     };
   }, [data]);
 
+  // nombre por tipo_num para las etiquetas de los comentarios
+  const tipoNombre = useMemo(() => {
+    const m = new Map<number, string>();
+    for (const r of data?.matriz ?? []) m.set(r.tipo_num, r.tipo_nombre);
+    for (const t of data?.etiquetas_comentarios?.tipos ?? []) if (!m.has(t.tipo_num)) m.set(t.tipo_num, t.tipo_nombre);
+    return m;
+  }, [data]);
+
   /* =======================
      Radar data prep
      ======================= */
@@ -515,29 +769,29 @@ export default function CentroPage() {
     return t.slice(0, n).trimEnd() + "…";
   }
 
-  function formatFechaES(iso?: string) {
-    if (!iso) return "";
-    const d = new Date(iso);
-    if (isNaN(d.getTime())) return iso;
+  // ✅ NUEVO: comentarios con fecha a nivel mes ("YYYY-MM") para no delatar el día
+  function formatMesES(ym?: string) {
+    if (!ym) return "";
+    const m = /^(\d{4})-(\d{2})$/.exec(ym);
+    if (!m) return ym;
 
     return new Intl.DateTimeFormat("es-MX", {
-      day: "2-digit",
       month: "short",
       year: "numeric",
-      hour: "2-digit",
-      minute: "2-digit",
-    }).format(d);
+    }).format(new Date(Number(m[1]), Number(m[2]) - 1, 1));
   }
 
-  // ✅ hook SIEMPRE antes de returns condicionales
-  const comentarios = useMemo(() => {
-    if (!data) return [];
-    return safeArr(data.stats.comentarios)
-      .filter((c) => (c?.texto || "").trim().length > 0)
-      .slice();
-  }, [data]);
+  // ✅ hook SIEMPRE antes de returns condicionales: opciones de filtro de comentarios
+  const comGeneroOpts = useMemo(
+    () => safeArr(data?.stats.encuestas_por_genero).filter((g) => g.clave && g.clave !== "otros"),
+    [data]
+  );
+  const comRangoOpts = useMemo(
+    () => safeArr(data?.stats.encuestas_por_edad).filter((g) => g.clave && g.clave !== "otros"),
+    [data]
+  );
 
-  const comentariosCount = comentarios.length;
+  const comentariosCount = comInfo?.total ?? 0;
 
   if (loading) {
     return (
@@ -762,7 +1016,35 @@ export default function CentroPage() {
                   </Button>
                 </div>
 
-                <div className="flex items-center gap-3"></div>
+                {/* ✅ NUEVO: exportar (CSV zip / Excel) */}
+                <div className="flex items-center gap-3">
+                  {(["csv", "xlsx"] as const).map((fmt) => (
+                    <Button
+                      key={fmt}
+                      type="button"
+                      variant="outline"
+                      className="h-10 rounded-2xl bg-white border-slate-200 hover:border-purple-300"
+                      onClick={() => exportar(fmt)}
+                      disabled={!!exporting || !data}
+                      title={fmt === "csv" ? "Descargar CSV (zip)" : "Descargar Excel"}
+                    >
+                      <Download className="mr-2 h-4 w-4" style={{ color: PURPLE }} />
+                      {exporting === fmt ? "Exportando…" : fmt === "csv" ? "CSV" : "Excel"}
+                    </Button>
+                  ))}
+
+                  <Button
+                    type="button"
+                    variant="outline"
+                    className="h-10 rounded-2xl bg-white border-slate-200 hover:border-purple-300"
+                    onClick={() => exportar("pdf")}
+                    disabled={!!exporting || !data || year === "all"}
+                    title={year === "all" ? "Selecciona un año para el informe" : "Descargar informe de diagnóstico (PDF)"}
+                  >
+                    <Download className="mr-2 h-4 w-4" style={{ color: PURPLE }} />
+                    {exporting === "pdf" ? "Generando…" : "Informe PDF"}
+                  </Button>
+                </div>
               </div>
             </div>
           </div>
@@ -770,6 +1052,46 @@ export default function CentroPage() {
       </div>
 
       <main className="mx-auto max-w-[1400px] space-y-8">
+        {/* ✅ NUEVO: alertas de riesgo activas */}
+        {alertas.length > 0 ? (
+          <Card className="rounded-[2rem] border-red-200 bg-red-50/40 shadow-sm">
+            <CardHeader className="pb-3">
+              <div className="flex items-center justify-between">
+                <CardTitle className="text-sm font-black tracking-wide text-red-900">
+                  Alertas de riesgo activas
+                </CardTitle>
+                <BellRing className="h-4 w-4 text-red-700" />
+              </div>
+            </CardHeader>
+            <CardContent className="grid gap-3">
+              {alertas.map((a) => (
+                <div key={a.id} className="flex flex-wrap items-start gap-3 rounded-2xl border border-red-100 bg-white p-4">
+                  <Badge
+                    variant="secondary"
+                    className="rounded-full font-black text-[10px] uppercase tracking-widest"
+                    style={
+                      a.severidad === "alta"
+                        ? { background: "#FEE2E2", color: "#991B1B" }
+                        : a.severidad === "media"
+                        ? { background: "#FEF3C7", color: "#92400E" }
+                        : undefined
+                    }
+                  >
+                    {a.severidad}
+                  </Badge>
+                  <div className="min-w-0 flex-1">
+                    <div className="text-sm font-black text-slate-900">{a.regla_nombre}</div>
+                    <div className="mt-1 text-xs font-semibold text-slate-600">{a.mensaje}</div>
+                  </div>
+                  <span className="text-[11px] font-black text-slate-400">
+                    {a.centro_nombre} · {a.year}
+                  </span>
+                </div>
+              ))}
+            </CardContent>
+          </Card>
+        ) : null}
+
         {/* KPI + RADAR */}
         <section className="grid gap-6 lg:grid-cols-12">
           <Card className="lg:col-span-7 rounded-[2rem] border-slate-200 shadow-sm">
@@ -1120,9 +1442,208 @@ export default function CentroPage() {
                 </div>
               </div>
             </div>
+
+            {/* ✅ NUEVO: comentarios publicados que mencionan cada tipo */}
+            {data?.etiquetas_comentarios && data.etiquetas_comentarios.tipos.length > 0 ? (
+              <div className="mt-4">
+                <div className="text-xs font-black uppercase tracking-widest text-slate-500">
+                  Comentarios que mencionan cada tipo
+                  <span className="ml-2 normal-case tracking-normal font-semibold text-slate-400">
+                    {data.etiquetas_comentarios.etiquetados} de {data.etiquetas_comentarios.publicados} comentarios
+                    publicados tienen al menos una etiqueta (por palabras clave)
+                  </span>
+                </div>
+                <div className="mt-3 flex flex-wrap gap-2">
+                  {data.etiquetas_comentarios.tipos.map((t) => (
+                    <Badge
+                      key={t.tipo_num}
+                      variant="secondary"
+                      className="rounded-full font-black text-[11px]"
+                      style={{
+                        background: t.comentarios > 0 ? "rgba(127,1,127,0.10)" : "rgba(2,6,23,0.04)",
+                        color: t.comentarios > 0 ? PURPLE : "#64748b",
+                      }}
+                    >
+                      {t.tipo_nombre}
+                      <span className="ml-2 rounded-full bg-white/70 px-2">{t.comentarios}</span>
+                    </Badge>
+                  ))}
+                </div>
+              </div>
+            ) : null}
           </CardContent>
         </Card>
 
+        {/* ✅ NUEVO: posición frente a centros pares del mismo tipo */}
+        {bench ? (
+          <Card className="rounded-[2rem] border-slate-200 shadow-sm">
+            <CardHeader className="pb-3">
+              <CardTitle className="text-sm font-black tracking-wide">
+                Tu centro frente a otros centros de tipo {bench.tipo}
+              </CardTitle>
+            </CardHeader>
+            <CardContent>
+              <Separator className="mb-5" />
+              {bench.publicable ? (
+                <>
+                  <div className="grid gap-3 md:grid-cols-4">
+                    {(["frecuencia", "normalidad", "gravedad", "total"] as const).map((d) => {
+                      const r = bench[d];
+                      if (!r) return null;
+                      return (
+                        <div key={d} className="rounded-2xl bg-slate-50 p-4">
+                          <div className="text-xs font-black uppercase tracking-widest text-slate-500">
+                            {d === "total" ? "Índice total" : d}
+                          </div>
+                          <div className="mt-2 text-2xl font-black tabular-nums" style={{ color: PURPLE }}>
+                            {r.valor.toFixed(2)}
+                          </div>
+                          <div className="mt-1 text-xs font-semibold text-slate-600">
+                            Mediana de pares {r.mediana.toFixed(2)} (p25–p75: {r.p25.toFixed(2)}–{r.p75.toFixed(2)})
+                          </div>
+                          <div className="mt-2 text-xs font-bold text-slate-700">
+                            Más alto que el {r.percentil.toFixed(0)}% de los pares
+                          </div>
+                        </div>
+                      );
+                    })}
+                  </div>
+                  <p className="mt-4 text-xs font-semibold text-slate-500">
+                    Comparación anónima con {bench.pares} centros del mismo tipo que tienen al menos {bench.anonimato.k} encuestas.
+                    Valores más altos indican una percepción más frecuente, normalizada o grave de la violencia.
+                  </p>
+                </>
+              ) : (
+                <p className="text-sm font-semibold text-slate-500">
+                  {bench.motivo === "centro_bajo_k"
+                    ? `Tu centro necesita al menos ${bench.anonimato.k} encuestas en el periodo para compararse.`
+                    : bench.motivo === "pocos_pares"
+                    ? "Aún no hay suficientes centros del mismo tipo con datos para una comparación anónima."
+                    : "Sin datos en el periodo seleccionado."}
+                </p>
+              )}
+            </CardContent>
+          </Card>
+        ) : null}
+
+        {/* ✅ NUEVO: cambio frente al año anterior con pruebas de significancia */}
+        {cambio ? (
+          <Card className="rounded-[2rem] border-slate-200 shadow-sm">
+            <CardHeader className="pb-3">
+              <CardTitle className="text-sm font-black tracking-wide">
+                ¿Cambió respecto a {cambio.a}?
+              </CardTitle>
+            </CardHeader>
+            <CardContent>
+              <Separator className="mb-5" />
+              {cambio.anonimato.suprimido ? (
+                <p className="text-sm font-semibold text-slate-500">
+                  Se necesitan al menos {cambio.anonimato.k} encuestas en cada año para comparar ({cambio.a}: {cambio.encuestas_a},{" "}
+                  {cambio.b}: {cambio.encuestas_b}).
+                </p>
+              ) : (
+                <>
+                  <div className="overflow-x-auto">
+                    <table className="w-full text-sm">
+                      <thead className="text-left text-xs font-black uppercase tracking-widest text-slate-500">
+                        <tr>
+                          <th className="py-2 pr-3">Métrica</th>
+                          <th className="py-2 pr-3 text-right">{cambio.a}</th>
+                          <th className="py-2 pr-3 text-right">{cambio.b}</th>
+                          <th className="py-2 pr-3 text-right">Diferencia</th>
+                          <th className="py-2 pr-3 text-right">p (Welch / MW)</th>
+                          <th className="py-2 pr-3">Efecto</th>
+                          <th className="py-2" />
+                        </tr>
+                      </thead>
+                      <tbody>
+                        {cambio.metricas
+                          .filter((m) => m.ambito === "dimension" || m.significativa)
+                          .map((m) => (
+                            <tr key={`${m.ambito}-${m.dimension ?? m.tipo_num}`} className="border-t border-slate-100">
+                              <td className="py-2 pr-3 font-semibold capitalize">
+                                {m.ambito === "tipo" ? m.tipo_nombre : m.dimension === "total" ? "Índice total" : m.dimension}
+                              </td>
+                              <td className="py-2 pr-3 text-right tabular-nums">{fmt2(m.a.media)}</td>
+                              <td className="py-2 pr-3 text-right tabular-nums">{fmt2(m.b.media)}</td>
+                              <td className="py-2 pr-3 text-right font-bold tabular-nums">
+                                {m.diferencia > 0 ? "+" : ""}
+                                {m.diferencia.toFixed(2)}
+                              </td>
+                              <td className="py-2 pr-3 text-right tabular-nums">
+                                {fmtP(m.p_welch_holm)} / {fmtP(m.p_mann_whitney_holm)}
+                              </td>
+                              <td className="py-2 pr-3 text-xs text-slate-600">
+                                d {m.cohen_d ? m.cohen_d.valor.toFixed(2) : "—"} · δ {m.cliff_delta ? m.cliff_delta.valor.toFixed(2) : "—"}
+                                {m.cliff_delta ? ` (${m.cliff_delta.magnitud})` : ""}
+                              </td>
+                              <td className="py-2">
+                                {m.significativa ? (
+                                  <Badge className="rounded-full text-xs font-black" style={{ background: PURPLE }}>
+                                    Significativo
+                                  </Badge>
+                                ) : (
+                                  <span className="text-xs font-semibold text-slate-400">No concluyente</span>
+                                )}
+                              </td>
+                            </tr>
+                          ))}
+                      </tbody>
+                    </table>
+                  </div>
+                  <p className="mt-4 text-xs font-semibold text-slate-500">
+                    Comparación entre encuestas de {cambio.a} (n = {cambio.encuestas_a}) y {cambio.b} (n = {cambio.encuestas_b}). Un
+                    cambio es significativo si Welch y Mann–Whitney dan p &lt; {cambio.alpha} tras la corrección de Holm; los tipos de
+                    violencia solo se listan si su cambio es significativo.
+                  </p>
+                </>
+              )}
+            </CardContent>
+          </Card>
+        ) : null}
+
+        {/* ✅ NUEVO: índice compuesto de riesgo por tipo */}
+        {data?.riesgo && data.riesgo.global ? (
+          <Card className="rounded-[2rem] border-slate-200 shadow-sm">
+            <CardHeader className="pb-3">
+              <div className="flex flex-wrap items-center justify-between gap-3">
+                <CardTitle className="text-sm font-black tracking-wide">Índice compuesto de riesgo</CardTitle>
+                <Badge
+                  variant="secondary"
+                  className="rounded-full font-black text-xs"
+                  style={{ background: colorNivel(data.riesgo.global.nivel).bg, color: colorNivel(data.riesgo.global.nivel).fg }}
+                >
+                  Global {data.riesgo.global.indice.toFixed(1)} · {data.riesgo.global.nivel_label}
+                </Badge>
+              </div>
+            </CardHeader>
+            <CardContent>
+              <Separator className="mb-5" />
+              <div className="grid gap-3">
+                {data.riesgo.tipos.map((t) => {
+                  const c = colorNivel(t.nivel);
+                  return (
+                    <div key={t.tipo_num} className="grid grid-cols-[minmax(0,14rem)_1fr_auto] items-center gap-3">
+                      <div className="truncate text-sm font-bold text-slate-700">{t.tipo_nombre}</div>
+                      <div className="h-3 rounded-full bg-slate-100">
+                        <div className="h-3 rounded-full" style={{ width: `${Math.min(100, t.indice)}%`, background: c.fg }} />
+                      </div>
+                      <Badge variant="secondary" className="rounded-full font-black text-[11px]" style={{ background: c.bg, color: c.fg }}>
+                        {t.indice.toFixed(1)} · {t.nivel_label}
+                      </Badge>
+                    </div>
+                  );
+                })}
+              </div>
+              <p className="mt-4 text-xs font-semibold text-slate-500">
+                Escala 0–100: combina frecuencia y normalidad, ponderadas por la gravedad percibida. Niveles:{" "}
+                {data.riesgo.niveles.map((n) => `${n.label} desde ${n.min}`).join(", ")}.
+                {data.riesgo.notas ? ` ${data.riesgo.notas}` : ""}
+              </p>
+            </CardContent>
+          </Card>
+        ) : null}
+
         {/* Barras por género (vectores) */}
         <Card className="rounded-[2rem] border-slate-200 shadow-sm">
           <CardHeader className="pb-3">
@@ -1454,6 +1975,23 @@ export default function CentroPage() {
                                     {Number(row.ic95_superior_encuestas ?? 0).toFixed(2)}
                                     <span className="opacity-70">· encuestas</span>
                                   </div>
+
+                                  {/* ✅ NUEVO: IC bootstrap (sin suponer normalidad) */}
+                                  {typeof row.ic95_inferior_bootstrap === "number" ? (
+                                    <div
+                                      className="inline-flex items-center gap-2 rounded-full px-3 py-1 text-[11px] font-black"
+                                      style={{
+                                        background: "rgba(2,6,23,0.04)",
+                                        color: "#0f172a",
+                                        border: "1px solid rgba(2,6,23,0.10)",
+                                      }}
+                                      title="IC 95% bootstrap (percentil) del promedio entre encuestas"
+                                    >
+                                      {row.ic95_inferior_bootstrap.toFixed(2)} –{" "}
+                                      {Number(row.ic95_superior_bootstrap ?? 0).toFixed(2)}
+                                      <span className="opacity-70">· bootstrap</span>
+                                    </div>
+                                  ) : null}
                                 </div>
                               </td>
 
@@ -1464,6 +2002,14 @@ export default function CentroPage() {
                                 >
                                   {alpha.toFixed(2)}
                                 </span>
+                                {typeof row.alpha_ic95_inferior === "number" ? (
+                                  <div
+                                    className="mt-1 text-[11px] font-semibold text-slate-500"
+                                    title="IC 95% de Feldt para alpha"
+                                  >
+                                    {row.alpha_ic95_inferior.toFixed(2)} – {Number(row.alpha_ic95_superior ?? 0).toFixed(2)}
+                                  </div>
+                                ) : null}
                               </td>
                               <td className="px-4 py-3 text-sm font-black text-slate-900">
                                 {row.k_items ?? 0}
@@ -1486,8 +2032,8 @@ export default function CentroPage() {
                       <span className="font-black" style={{ color: PURPLE }}>
                         Nota:
                       </span>{" "}
-                      IC 95% calculado con aproximación normal (1.96·σ/√n).
-                      Percentiles (P25, mediana y P75). Alpha por dimensión.
+                      IC 95% con la t de Student (t·σ/√n, n − 1 grados de libertad) y por bootstrap entre encuestas.
+                      Percentiles (P25, mediana y P75). Alpha por dimensión con su IC de Feldt.
 
                   </div>
                 </div>
@@ -1898,6 +2444,16 @@ export default function CentroPage() {
                   {comentariosCount.toLocaleString("es-MX")} comentarios
                 </Badge>
 
+                {comInfo && comInfo.en_revision > 0 ? (
+                  <Badge
+                    variant="secondary"
+                    className="rounded-full font-black text-[10px] uppercase tracking-widest"
+                    style={{ background: "rgba(2,6,23,0.04)", color: "#64748b" }}
+                  >
+                    {comInfo.en_revision.toLocaleString("es-MX")} en revisión
+                  </Badge>
+                ) : null}
+
                 <Badge
                   variant="secondary"
                   className="rounded-full font-black text-[10px] uppercase tracking-widest"
@@ -1912,18 +2468,70 @@ export default function CentroPage() {
           <CardContent>
             <Separator className="mb-5" />
 
-            {comentariosCount === 0 ? (
+            {/* ✅ Búsqueda + filtros */}
+            <form
+              className="mb-5 flex flex-wrap items-center gap-2"
+              onSubmit={(e) => {
+                e.preventDefault();
+                loadComentarios();
+              }}
+            >
+              <input
+                className="h-10 min-w-[220px] flex-1 rounded-full border border-slate-200 px-4 text-sm font-semibold"
+                placeholder="Buscar en comentarios…"
+                value={comQ}
+                onChange={(e) => setComQ(e.target.value)}
+              />
+
+              <Select value={comGenero} onValueChange={setComGenero}>
+                <SelectTrigger className="h-10 w-[170px] rounded-full font-semibold">
+                  <SelectValue placeholder="Género" />
+                </SelectTrigger>
+                <SelectContent>
+                  <SelectItem value="all">Todos los géneros</SelectItem>
+                  {comGeneroOpts.map((g) => (
+                    <SelectItem key={g.clave} value={g.clave}>
+                      {g.label}
+                    </SelectItem>
+                  ))}
+                </SelectContent>
+              </Select>
+
+              <Select value={comRango} onValueChange={setComRango}>
+                <SelectTrigger className="h-10 w-[170px] rounded-full font-semibold">
+                  <SelectValue placeholder="Edad" />
+                </SelectTrigger>
+                <SelectContent>
+                  <SelectItem value="all">Todas las edades</SelectItem>
+                  {comRangoOpts.map((g) => (
+                    <SelectItem key={g.clave} value={g.clave}>
+                      {g.label} años
+                    </SelectItem>
+                  ))}
+                </SelectContent>
+              </Select>
+
+              <Button type="submit" variant="outline" className="h-10 rounded-full font-black" disabled={comLoading}>
+                Buscar
+              </Button>
+            </form>
+
+            {comErr ? (
+              <div className="mb-4 rounded-2xl border border-red-200 bg-red-50 p-3 text-sm text-red-700">{comErr}</div>
+            ) : null}
+
+            {comentarios.length === 0 ? (
               <div className="rounded-2xl border border-slate-200 bg-white p-4 text-sm text-slate-600">
                 <span className="font-black" style={{ color: PURPLE }}>
                   Sin comentarios
                 </span>{" "}
-                para el periodo seleccionado.
+                {comQ.trim() ? "que coincidan con la búsqueda." : "para el periodo seleccionado."}
               </div>
             ) : (
               <div className="grid gap-4 md:grid-cols-2 xl:grid-cols-3">
                 {comentarios.map((c, idx) => (
                   <div
-                    key={`${c.encuesta_id}-${idx}`}
+                    key={idx}
                     className="group relative overflow-hidden rounded-[1.5rem] border border-slate-200 bg-white p-5 shadow-[0_10px_30px_rgba(15,23,42,0.06)]"
                   >
                     <div
@@ -1946,19 +2554,19 @@ export default function CentroPage() {
                           </Badge>
                         ) : null}
 
-                        {Number.isFinite(c.edad) && c.edad > 0 ? (
+                        {c.rango_edad ? (
                           <Badge
                             variant="secondary"
                             className="rounded-full font-black text-[10px] uppercase tracking-widest"
                             style={{ background: "rgba(2,6,23,0.04)", color: "#0f172a" }}
                           >
-                            {c.edad} años
+                            {c.rango_edad} años
                           </Badge>
                         ) : null}
 
                         {c.fecha ? (
                           <span className="ml-auto text-[11px] font-black text-slate-400">
-                            {formatFechaES(c.fecha)}
+                            {formatMesES(c.fecha)}
                           </span>
                         ) : null}
                       </div>
@@ -1967,6 +2575,20 @@ export default function CentroPage() {
                         {truncate(c.texto, 180)}
                       </p>
 
+                      {c.tipos && c.tipos.length > 0 ? (
+                        <div className="mt-3 flex flex-wrap gap-1.5">
+                          {c.tipos.map((n) => (
+                            <span
+                              key={n}
+                              className="rounded-full border px-2 py-0.5 text-[10px] font-black"
+                              style={{ borderColor: "rgba(127,1,127,0.25)", color: PURPLE }}
+                            >
+                              {tipoNombre.get(n) ?? `Tipo ${n}`}
+                            </span>
+                          ))}
+                        </div>
+                      ) : null}
+
                       {c.texto.length > 180 && (
                         <Dialog>
                           <DialogTrigger asChild>
@@ -1999,18 +2621,18 @@ export default function CentroPage() {
                                 </Badge>
                               )}
 
-                              {Number.isFinite(c.edad) && c.edad > 0 && (
+                              {c.rango_edad && (
                                 <Badge
                                   variant="secondary"
                                   className="rounded-full font-black text-[10px] uppercase tracking-widest"
                                 >
-                                  {c.edad} años
+                                  {c.rango_edad} años
                                 </Badge>
                               )}
 
                               {c.fecha && (
                                 <span className="ml-auto text-[11px] font-black text-slate-400">
-                                  {formatFechaES(c.fecha)}
+                                  {formatMesES(c.fecha)}
                                 </span>
                               )}
                             </div>
@@ -2026,6 +2648,20 @@ export default function CentroPage() {
                 ))}
               </div>
             )}
+
+            {comInfo?.next_cursor ? (
+              <div className="mt-5 flex justify-center">
+                <Button
+                  variant="outline"
+                  className="rounded-full font-black"
+                  disabled={comLoading}
+                  onClick={() => loadComentarios({ cursor: comInfo.next_cursor })}
+                >
+                  {comLoading ? <RefreshCw className="mr-2 h-4 w-4 animate-spin" /> : null}
+                  Ver más ({comentarios.length.toLocaleString("es-MX")} de {comentariosCount.toLocaleString("es-MX")})
+                </Button>
+              </div>
+            ) : null}
           </CardContent>
         </Card>
 
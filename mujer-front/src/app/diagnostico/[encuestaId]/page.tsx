@@ -13,7 +13,7 @@ import { Button } from "../../../components/ui/button";
 import { Separator } from "../../../components/ui/separator";
 import { Textarea } from "../../../components/ui/textarea";
 
-import { api } from "../../../lib/api";
+import { api, apiEncuesta, clearResumeToken } from "../../../lib/api";
 
 type LikertOption = { value: number; label: string };
 type DimensionKey = "frecuencia" | "normalidad" | "gravedad";
@@ -115,6 +115,13 @@ type RespuestaItem = {
   valor: number;
 };
 
+// GET /api/encuestas/{id}/progreso
+type ProgresoResponse = {
+  respuestas: RespuestaItem[];
+  comentario?: string;
+  finalizada: boolean;
+};
+
 // ===== LocalStorage helpers (NUEVO) =====
 const LS_VERSION = 1;
 
@@ -203,9 +210,11 @@ export default function DiagnosticoEncuestaPage() {
   const saveTimerRef = useRef<number | null>(null);
 
   useEffect(() => {
+    if (!encuestaId) return;
     (async () => {
       try {
-        const raw = await api<any>("/api/instrumento");
+        // ✅ instrumento con el que se creó esta encuesta (puede no ser el default)
+        const raw = await api<any>(`/api/encuestas/${encodeURIComponent(encuestaId)}/instrumento`);
         const payload = pickInstPayload(raw);
 
         if (isObject(payload)) {
@@ -233,7 +242,7 @@ export default function DiagnosticoEncuestaPage() {
         setLoading(false);
       }
     })();
-  }, []);
+  }, [encuestaId]);
 
   const questions = useMemo(() => {
     if (!inst) return [];
@@ -304,7 +313,26 @@ export default function DiagnosticoEncuestaPage() {
     window.scrollTo({ top: 0, behavior: "smooth" });
   }
 
+  // ✅ NUEVO: guarda en el servidor las tarjetas de la pregunta actual
+  // (sin bloquear; el respaldo local sigue existiendo si falla)
+  function guardarPregunta(q: (typeof questions)[number] | null) {
+    if (!q || !Array.isArray(q.cards)) return;
+    const respuestas: RespuestaItem[] = [];
+    q.cards.forEach((c) => {
+      const v = answers[`${q.question_id}:${c.dimension}`];
+      if (typeof v === "number") {
+        respuestas.push({ pregunta_id: q.question_id, dimension: c.dimension, valor: v });
+      }
+    });
+    if (!respuestas.length) return;
+    apiEncuesta(encuestaId, "/respuestas", {
+      method: "PUT",
+      body: JSON.stringify({ respuestas }),
+    }).catch((err) => console.error("guardar avance:", err));
+  }
+
   function goNext() {
+    guardarPregunta(current);
     setQIndex((i) => Math.min(totalQuestions, i + 1));
     window.scrollTo({ top: 0, behavior: "smooth" });
   }
@@ -327,6 +355,7 @@ export default function DiagnosticoEncuestaPage() {
     if (!encuestaId) return;
     if (!inst) return; // esperamos a que haya instrumento
     if (!questions.length) return; // esperamos preguntas reales
+    if (hydratedRef.current) return;
 
     const key = storageKey(encuestaId);
     const saved = safeReadProgress(key);
@@ -344,9 +373,34 @@ export default function DiagnosticoEncuestaPage() {
       return;
     }
 
-    // si no hay saved, marcamos hidratado para habilitar autoguardado
-    hydratedRef.current = true;
-  }, [encuestaId, inst, questions.length, totalQuestions]);
+    // ✅ NUEVO: sin avance local, se retoma lo guardado en el servidor
+    // (otro dispositivo o storage borrado); luego se habilita el autoguardado
+    (async () => {
+      try {
+        const p = await apiEncuesta<ProgresoResponse>(encuestaId, "/progreso");
+        if (p.finalizada) {
+          router.replace(`/resumen/${encuestaId}`);
+          return;
+        }
+        const prev: Record<string, number> = {};
+        (p.respuestas || []).forEach((r) => {
+          prev[`${r.pregunta_id}:${r.dimension}`] = Number(r.valor);
+        });
+        if (Object.keys(prev).length) {
+          const idx = questions.findIndex(
+            (q) => !Array.isArray(q.cards) || q.cards.some((c) => typeof prev[`${q.question_id}:${c.dimension}`] !== "number")
+          );
+          setAnswers(prev);
+          setQIndex(idx === -1 ? totalQuestions : idx);
+        }
+        if (p.comentario) setComentario(p.comentario);
+      } catch (err) {
+        console.error("progreso:", err);
+      } finally {
+        hydratedRef.current = true;
+      }
+    })();
+  }, [encuestaId, inst, questions, totalQuestions, router]);
 
   // ===== NUEVO: autoguardado (debounced) =====
   useEffect(() => {
@@ -409,17 +463,19 @@ export default function DiagnosticoEncuestaPage() {
     try {
       const cleanComment = comentario.trim();
 
-      await api<{ ok: boolean }>("/api/respuestas", {
-        method: "POST",
+      // ✅ NUEVO: guardar todo (PUT) y finalizar; el servidor revisa completitud
+      await apiEncuesta<{ ok: boolean }>(encuestaId, "/respuestas", {
+        method: "PUT",
         body: JSON.stringify({
-          encuesta_id: encuestaId,
           respuestas,
           comentario: cleanComment ? cleanComment : undefined,
         }),
       });
+      await apiEncuesta<{ ok: boolean }>(encuestaId, "/finalizar", { method: "POST" });
 
-      // ✅ NUEVO: al finalizar, borrar progreso guardado
+      // ✅ NUEVO: al finalizar, borrar progreso guardado y el token
       safeRemoveProgress(storageKey(encuestaId));
+      clearResumeToken(encuestaId);
 
       router.push(`/resumen/${encuestaId}`);
     } catch (err: any) {
@@ -29,7 +29,7 @@ import {
   BadgeCheck,
 } from "lucide-react";
 
-import { api } from "../../lib/api";
+import { api, clearResumeToken, saveResumeToken } from "../../lib/api";
 
 type Centro = {
   id: number;
@@ -247,6 +247,9 @@ export default function DiagnosticoInicioPage() {
   const [edad, setEdad] = useState<string>("");
   const [email, setEmail] = useState<string>("");
 
+  // ✅ Código de acceso de la campaña (llega por QR/enlace como ?codigo=)
+  const [codigo, setCodigo] = useState<string>("");
+
   // resume existente
   const [resume, setResume] = useState<{ encuestaId: string; updatedAt: number } | null>(null);
 
@@ -256,6 +259,13 @@ export default function DiagnosticoInicioPage() {
   // ✅ NUEVO: bloqueo por “ya finalizó” en este navegador
   const [doneBlocked, setDoneBlocked] = useState<{ remainingMs: number } | null>(null);
 
+  useEffect(() => {
+    try {
+      const fromUrl = new URLSearchParams(window.location.search).get("codigo");
+      if (fromUrl) setCodigo(fromUrl.trim().toUpperCase());
+    } catch {}
+  }, []);
+
   useEffect(() => {
     (async () => {
       try {
@@ -343,13 +353,14 @@ export default function DiagnosticoInicioPage() {
     return (
       centroId !== "" &&
       generoId !== "" &&
+      codigo.trim() !== "" &&
       Number.isFinite(e) &&
       e >= 15 &&
       e <= 75 &&
       emailOk &&
       !submitting
     );
-  }, [centroId, generoId, edad, emailOk, submitting]);
+  }, [centroId, generoId, codigo, edad, emailOk, submitting]);
 
   const blockedByLock = Boolean(lock && lock.remainingMs > 0);
   const blockedByResume = Boolean(resume); // ✅ si hay progreso, no permitir nueva
@@ -384,13 +395,16 @@ export default function DiagnosticoInicioPage() {
         genero_id: Number(generoId),
         edad: Number(edad),
         email: emailTrim ? emailTrim : undefined,
+        codigo: codigo.trim(),
       };
 
-      const resp = await api<{ encuesta_id: string }>("/api/encuestas", {
+      const resp = await api<{ encuesta_id: string; resume_token: string }>("/api/encuestas", {
         method: "POST",
         body: JSON.stringify(body),
       });
 
+      // ✅ NUEVO: token para guardar avance y finalizar (X-Resume-Token)
+      saveResumeToken(resp.encuesta_id, resp.resume_token);
       writeLock(centroId, resp.encuesta_id);
       router.push(`/diagnostico/${resp.encuesta_id}`);
     } catch (err: any) {
@@ -411,6 +425,7 @@ export default function DiagnosticoInicioPage() {
     try {
       window.localStorage.removeItem(`${LS_PREFIX}${resume.encuestaId}${LS_SUFFIX}`);
       clearLockByEncuestaId(resume.encuestaId);
+      clearResumeToken(resume.encuestaId);
     } catch {}
     setResume(null);
 
@@ -610,6 +625,19 @@ export default function DiagnosticoInicioPage() {
                     </div>
                   </div>
 
+                  <div className="space-y-2">
+                    <Label className="text-sm">Código de acceso</Label>
+                    <Input
+                      className="h-12 rounded-xl shadow-sm tracking-widest uppercase"
+                      placeholder="Ej. ABCDE-FGHJK"
+                      value={codigo}
+                      onChange={(e) => setCodigo(e.target.value.toUpperCase())}
+                    />
+                    <p className="text-xs text-neutral-500">
+                      Lo entrega tu centro (enlace o código QR). No se guarda junto con tus respuestas.
+                    </p>
+                  </div>
+
                   <div className="space-y-2">
                     <Label className="text-sm">Edad</Label>
                     <Input
@@ -23,7 +23,7 @@ import {
   DrawerFooter,
 } from "@/components/ui/drawer";
 
-import { ArrowLeft, Home, RefreshCw } from "lucide-react";
+import { ArrowLeft, ExternalLink, FileText, HeartHandshake, Home, Phone, RefreshCw } from "lucide-react";
 import { api } from "@/lib/api";
 
 // =========================
@@ -45,10 +45,57 @@ type MatrizItemBE = {
   promedio: number;
 };
 
+type IndiceRiesgoBE = {
+  tipo_num?: number;
+  tipo_nombre?: string;
+  indice: number; // 0–100
+  nivel: string;
+  nivel_label: string;
+};
+
+type ResumenRiesgoBE = {
+  metodo: string;
+  notas?: string;
+  niveles: { key: string; label: string; min: number }[];
+  global?: IndiceRiesgoBE;
+  tipos: IndiceRiesgoBE[];
+};
+
+type TarjetaRetroBE = {
+  regla_id: string;
+  tipo_num?: number;
+  tipo_nombre?: string;
+  dimension: BackendDimension;
+  dimension_label: string;
+  valor: number;
+  nivel: string;
+  titulo: string;
+  mensaje: string;
+  recursos: string[];
+};
+
+type RecursoApoyoBE = {
+  id: string;
+  kind: "hotline" | "protocol" | "service";
+  name: string;
+  description?: string;
+  phone?: string;
+  url?: string;
+  hours?: string;
+};
+
+type RetroalimentacionBE = {
+  intro?: string;
+  tarjetas: TarjetaRetroBE[];
+  recursos: RecursoApoyoBE[];
+};
+
 type EncuestaResumenResponseBE = {
   encuesta_id: string;
   global: ResumenGlobalBE;
   matriz: MatrizItemBE[];
+  riesgo?: ResumenRiesgoBE; // solo si el instrumento define scoring.risk_index
+  retroalimentacion?: RetroalimentacionBE; // solo si el instrumento define feedback
 };
 
 // =========================
@@ -60,6 +107,11 @@ const DIM_LABEL_FULL: Record<BackendDimension, string> = {
   normalidad: "Normalización",
   gravedad: "Gravedad",
 };
+const RECURSO_ICON: Record<RecursoApoyoBE["kind"], React.ElementType> = {
+  hotline: Phone,
+  protocol: FileText,
+  service: HeartHandshake,
+};
 const DIM_LABEL_SHORT: Record<BackendDimension, string> = {
   frecuencia: "F",
   normalidad: "N",
@@ -120,6 +172,15 @@ function levelBadgeVariant(v: number): "default" | "secondary" | "destructive" |
   return "destructive";
 }
 
+// los niveles de riesgo vienen del instrumento en orden ascendente
+function riskBadgeVariant(nivel: string, niveles: { key: string }[]): "default" | "secondary" | "destructive" | "outline" {
+  const i = niveles.findIndex(n => n.key === nivel);
+  if (i <= 0) return "secondary";
+  if (i === 1) return "outline";
+  if (i === 2) return "default";
+  return "destructive";
+}
+
 function useIsMobile(breakpointPx = 768) {
   const [isMobile, setIsMobile] = useState(false);
   useEffect(() => {
@@ -186,6 +247,7 @@ export default function ResultadosEncuestaPage() {
   }, [encuestaId]);
 
   const global = data?.global;
+  const nivelesRiesgo = data?.riesgo?.niveles ?? [];
 
   const tipos = useMemo(() => {
     const map = new Map<number, string>();
@@ -453,6 +515,123 @@ export default function ResultadosEncuestaPage() {
               </CardContent>
             </Card>
 
+            {/* Índice compuesto de riesgo */}
+            {data?.riesgo?.global && (
+              <>
+                <Separator className="my-10" />
+                <Card className="overflow-hidden rounded-3xl border-neutral-200 shadow-sm">
+                  <CardHeader>
+                    <CardTitle className="text-lg font-semibold">Índice de riesgo por tipo</CardTitle>
+                    <p className="text-sm text-neutral-600">
+                      Escala 0–100: combina frecuencia y normalización, ponderadas por la gravedad que percibes.
+                    </p>
+                  </CardHeader>
+                  <CardContent>
+                    <div className="mb-6 rounded-2xl bg-neutral-50 p-5 text-center">
+                      <p className="text-sm font-medium text-neutral-600">Índice global</p>
+                      <p className="mt-3 text-3xl font-bold tabular-nums text-neutral-900">
+                        {data.riesgo.global.indice.toFixed(1)}
+                      </p>
+                      <Badge variant={riskBadgeVariant(data.riesgo.global.nivel, nivelesRiesgo)} className="mt-3">
+                        {data.riesgo.global.nivel_label}
+                      </Badge>
+                    </div>
+                    <div className="space-y-3">
+                      {data.riesgo.tipos.map(t => (
+                        <div key={t.tipo_num} className="flex items-center gap-3">
+                          <span className="w-8 shrink-0 text-sm font-bold tabular-nums text-neutral-500">{t.tipo_num}</span>
+                          <div className="min-w-0 flex-1">
+                            <p className="truncate text-sm text-neutral-800">{t.tipo_nombre}</p>
+                            <div className="mt-1 h-2 rounded-full bg-neutral-100">
+                              <div
+                                className="h-2 rounded-full"
+                                style={{ width: `${Math.min(100, t.indice)}%`, backgroundColor: "var(--primary)" }}
+                              />
+                            </div>
+                          </div>
+                          <span className="w-12 text-right text-sm font-semibold tabular-nums">{t.indice.toFixed(1)}</span>
+                          <Badge variant={riskBadgeVariant(t.nivel, nivelesRiesgo)} className="w-20 justify-center">
+                            {t.nivel_label}
+                          </Badge>
+                        </div>
+                      ))}
+                    </div>
+                  </CardContent>
+                </Card>
+              </>
+            )}
+
+            {/* Retroalimentación y recursos de apoyo */}
+            {data?.retroalimentacion && (data.retroalimentacion.tarjetas.length > 0 || data.retroalimentacion.recursos.length > 0) && (
+              <>
+                <Separator className="my-10" />
+                <Card className="overflow-hidden rounded-3xl border-neutral-200 shadow-sm">
+                  <CardHeader>
+                    <CardTitle className="text-lg font-semibold">Para reflexionar</CardTitle>
+                    {data.retroalimentacion.intro && (
+                      <p className="text-sm text-neutral-600">{data.retroalimentacion.intro}</p>
+                    )}
+                  </CardHeader>
+                  <CardContent className="space-y-4">
+                    {data.retroalimentacion.tarjetas.map(t => (
+                      <div key={`${t.regla_id}-${t.tipo_num ?? 0}`} className="rounded-2xl border border-neutral-200 bg-neutral-50 p-5">
+                        <div className="flex flex-wrap items-center gap-2">
+                          <Badge variant={levelBadgeVariant(t.valor)}>
+                            {t.dimension_label}: {t.nivel} ({fmt(t.valor)})
+                          </Badge>
+                          {t.tipo_num ? (
+                            <span className="text-xs font-medium text-neutral-500">
+                              {t.tipo_num}. {t.tipo_nombre}
+                            </span>
+                          ) : null}
+                        </div>
+                        <p className="mt-3 font-semibold text-neutral-900">{t.titulo}</p>
+                        <p className="mt-1 text-sm leading-relaxed text-neutral-700">{t.mensaje}</p>
+                      </div>
+                    ))}
+
+                    {data.retroalimentacion.recursos.length > 0 && (
+                      <div className="pt-2">
+                        <p className="mb-3 text-sm font-medium text-neutral-700">Dónde pedir orientación o apoyo</p>
+                        <div className="grid gap-3 sm:grid-cols-2">
+                          {data.retroalimentacion.recursos.map(rec => {
+                            const Icon = RECURSO_ICON[rec.kind] ?? HeartHandshake;
+                            return (
+                              <div key={rec.id} className="flex gap-3 rounded-2xl border border-neutral-200 bg-white p-4">
+                                <Icon className="mt-0.5 h-5 w-5 shrink-0" style={{ color: "#7F017F" }} />
+                                <div className="min-w-0 text-sm">
+                                  <p className="font-semibold text-neutral-900">{rec.name}</p>
+                                  {rec.description && <p className="mt-1 text-neutral-600">{rec.description}</p>}
+                                  <div className="mt-2 flex flex-wrap gap-x-4 gap-y-1 text-neutral-800">
+                                    {rec.phone && (
+                                      <a href={`tel:${rec.phone.replace(/\s+/g, "")}`} className="font-semibold underline-offset-2 hover:underline">
+                                        {rec.phone}
+                                      </a>
+                                    )}
+                                    {rec.url && (
+                                      <a
+                                        href={rec.url}
+                                        target="_blank"
+                                        rel="noopener noreferrer"
+                                        className="inline-flex items-center gap-1 font-semibold underline-offset-2 hover:underline"
+                                      >
+                                        Sitio web <ExternalLink className="h-3.5 w-3.5" />
+                                      </a>
+                                    )}
+                                    {rec.hours && <span className="text-neutral-500">{rec.hours}</span>}
+                                  </div>
+                                </div>
+                              </div>
+                            );
+                          })}
+                        </div>
+                      </div>
+                    )}
+                  </CardContent>
+                </Card>
+              </>
+            )}
+
             <Separator className="my-10" />
 
             {/* Acciones finales */}
@@ -29,3 +29,65 @@ export async function api<T>(path: string, init?: RequestInit): Promise<T> {
 
   return res.json() as Promise<T>;
 }
+
+// Descarga un archivo (export CSV/XLSX) con el token y lo guarda con el
+// nombre que manda el back en Content-Disposition
+export async function apiDownload(path: string, fallbackName: string): Promise<void> {
+  const token = getToken();
+
+  const res = await fetch(`${API_BASE}${path}`, {
+    headers: token ? { Authorization: `Bearer ${token}` } : {},
+    cache: "no-store",
+  });
+
+  if (!res.ok) {
+    const txt = await res.text().catch(() => "");
+    throw new Error(txt || `HTTP ${res.status}`);
+  }
+
+  const cd = res.headers.get("Content-Disposition") || "";
+  const m = /filename="?([^";]+)"?/i.exec(cd);
+  const name = m?.[1] || fallbackName;
+
+  const blob = await res.blob();
+  const url = URL.createObjectURL(blob);
+  const a = document.createElement("a");
+  a.href = url;
+  a.download = name;
+  document.body.appendChild(a);
+  a.click();
+  a.remove();
+  URL.revokeObjectURL(url);
+}
+
+// ===== Token para retomar una encuesta (X-Resume-Token) =====
+// POST /api/encuestas lo regresa una sola vez; sin él no se puede guardar
+// avance ni finalizar esa encuesta.
+function resumeTokenKey(encuestaId: string) {
+  return `mujer_alerta:resume:${encuestaId}`;
+}
+
+export function saveResumeToken(encuestaId: string, token: string) {
+  if (typeof window === "undefined") return;
+  try {
+    localStorage.setItem(resumeTokenKey(encuestaId), token);
+  } catch {
+    // storage bloqueado: la encuesta no se podrá retomar en otra pestaña
+  }
+}
+
+export function clearResumeToken(encuestaId: string) {
+  if (typeof window === "undefined") return;
+  try {
+    localStorage.removeItem(resumeTokenKey(encuestaId));
+  } catch {}
+}
+
+// api() para las rutas de avance de una encuesta (progreso, respuestas, finalizar)
+export async function apiEncuesta<T>(encuestaId: string, path: string, init?: RequestInit): Promise<T> {
+  const token = typeof window === "undefined" ? "" : localStorage.getItem(resumeTokenKey(encuestaId)) || "";
+  return api<T>(`/api/encuestas/${encodeURIComponent(encuestaId)}${path}`, {
+    ...init,
+    headers: { ...(init?.headers || {}), ...(token ? { "X-Resume-Token": token } : {}) },
+  });
+}